@@ -12,6 +12,7 @@ import (
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/adapter"
+	"github.com/skosovsky/prompty/schemacompat"
 )
 
 const defaultMaxTokens int64 = 1024
@@ -21,6 +22,7 @@ const defaultMaxTokens int64 = 1024
 type Adapter struct {
 	defaultModel anthropic.Model
 	client       *anthropic.Client
+	downgrade    bool // WithSchemaDowngrade
 }
 
 // Option configures an Adapter (e.g. WithModel, WithClient).
@@ -36,6 +38,13 @@ func WithClient(c *anthropic.Client) Option {
 	return func(a *Adapter) { a.client = c }
 }
 
+// WithSchemaDowngrade rewrites response and tool schemas with schemacompat.Downgrade for the Anthropic profile
+// before sending them: refs inlined where unsupported, unsupported constraints moved into descriptions, oneOf
+// turned into anyOf or nullable types. Output is still validated against the original schema.
+func WithSchemaDowngrade() Option {
+	return func(a *Adapter) { a.downgrade = true }
+}
+
 // New returns an Adapter with a default model. Options can override the default model.
 func New(opts ...Option) *Adapter {
 	a := &Adapter{defaultModel: anthropic.ModelClaudeSonnet4_5_20250929}
@@ -47,6 +56,15 @@ func New(opts ...Option) *Adapter {
 
 const outputFormatToolName = "output_format"
 
+// compatSchema returns schema downgraded for the provider when WithSchemaDowngrade is set.
+func (a *Adapter) compatSchema(schema map[string]any) map[string]any {
+	if !a.downgrade || schema == nil {
+		return schema
+	}
+	out, _ := schemacompat.Downgrade(schemacompat.Anthropic(), schema)
+	return out
+}
+
 // Translate converts PromptExecution into *anthropic.MessageNewParams.
 func (a *Adapter) Translate(exec *prompty.PromptExecution) (*anthropic.MessageNewParams, error) {
 	if exec == nil {
@@ -114,7 +132,7 @@ func (a *Adapter) Translate(exec *prompty.PromptExecution) (*anthropic.MessageNe
 	params.Messages = messages
 	// ResponseFormat: add mandatory output_format tool with schema; force tool_choice.
 	if exec.ResponseFormat != nil && len(exec.ResponseFormat.Schema) > 0 {
-		schema := schemaToToolInput(exec.ResponseFormat.Schema)
+		schema := schemaToToolInput(a.compatSchema(exec.ResponseFormat.Schema))
 		desc := exec.ResponseFormat.Description
 		if desc == "" {
 			desc = "Output must strictly follow this JSON schema"
@@ -129,7 +147,7 @@ func (a *Adapter) Translate(exec *prompty.PromptExecution) (*anthropic.MessageNe
 			params.Tools = make([]anthropic.ToolUnionParam, 0, len(exec.Tools))
 		}
 		for _, t := range exec.Tools {
-			schema := toolSchemaFromParameters(t.Parameters)
+			schema := toolSchemaFromParameters(a.compatSchema(t.Parameters))
 			tool := anthropic.ToolUnionParamOfTool(schema, t.Name)
 			if t.Description != "" {
 				tool.OfTool.Description = anthropic.String(t.Description)
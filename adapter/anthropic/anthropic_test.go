@@ -742,6 +742,27 @@ func TestTranslate_ResponseFormat_PassesFullSchemaWithAdditionalProperties(t *te
 	assert.Equal(t, "Strict output schema", schema.ExtraFields["description"])
 }
 
+func TestTranslate_SchemaDowngrade(t *testing.T) {
+	t.Parallel()
+	exec := &prompty.PromptExecution{
+		Tools: []prompty.ToolDefinition{{
+			Name: "search",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": 50},
+				},
+				"required": []any{"limit"},
+			},
+		}},
+	}
+	params, err := New(WithSchemaDowngrade()).Translate(exec)
+	require.NoError(t, err)
+	require.Len(t, params.Tools, 1)
+	limit := params.Tools[0].OfTool.InputSchema.Properties.(map[string]any)["limit"]
+	assert.Equal(t, map[string]any{"type": "integer", "description": "maximum: 50; minimum: 1"}, limit)
+}
+
 func TestParseStreamChunk_NotImplemented(t *testing.T) {
 	t.Parallel()
 	a := New()
@@ -9,6 +9,7 @@ import (
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/adapter"
+	"github.com/skosovsky/prompty/schemacompat"
 
 	"google.golang.org/genai"
 )
@@ -25,6 +26,7 @@ type Request struct {
 type Adapter struct {
 	defaultModel string
 	client       *genai.Client
+	downgrade    bool // WithSchemaDowngrade
 }
 
 // Option configures an Adapter (e.g. WithModel, WithClient).
@@ -40,6 +42,13 @@ func WithClient(c *genai.Client) Option {
 	return func(a *Adapter) { a.client = c }
 }
 
+// WithSchemaDowngrade rewrites response and tool schemas with schemacompat.Downgrade for the Gemini profile
+// before sending them: refs inlined where unsupported, unsupported constraints moved into descriptions, oneOf
+// turned into anyOf or nullable types. Output is still validated against the original schema.
+func WithSchemaDowngrade() Option {
+	return func(a *Adapter) { a.downgrade = true }
+}
+
 // New returns an Adapter with default model "gemini-2.0-flash".
 func New(opts ...Option) *Adapter {
 	a := &Adapter{defaultModel: "gemini-2.0-flash"}
@@ -49,6 +58,15 @@ func New(opts ...Option) *Adapter {
 	return a
 }
 
+// compatSchema returns schema downgraded for the provider when WithSchemaDowngrade is set.
+func (a *Adapter) compatSchema(schema map[string]any) map[string]any {
+	if !a.downgrade || schema == nil {
+		return schema
+	}
+	out, _ := schemacompat.Downgrade(schemacompat.Gemini(), schema)
+	return out
+}
+
 // Translate converts PromptExecution into *Request (Contents + Config).
 func (a *Adapter) Translate(exec *prompty.PromptExecution) (*Request, error) {
 	if exec == nil {
@@ -124,7 +142,7 @@ func (a *Adapter) Translate(exec *prompty.PromptExecution) (*Request, error) {
 				Name:                 t.Name,
 				Description:          t.Description,
 				Parameters:           nil,
-				ParametersJsonSchema: t.Parameters,
+				ParametersJsonSchema: a.compatSchema(t.Parameters),
 			})
 		}
 	}
@@ -138,7 +156,7 @@ func (a *Adapter) Translate(exec *prompty.PromptExecution) (*Request, error) {
 	}
 	if exec.ResponseFormat != nil && len(exec.ResponseFormat.Schema) > 0 {
 		config.ResponseMIMEType = "application/json"
-		schema, err := mapToGenaiSchema(exec.ResponseFormat.Schema)
+		schema, err := mapToGenaiSchema(a.compatSchema(exec.ResponseFormat.Schema))
 		if err != nil {
 			return nil, fmt.Errorf("response_format schema: %w", err)
 		}
@@ -469,6 +469,78 @@ func TestTranslate_ResponseFormat(t *testing.T) {
 	assert.Equal(t, genai.TypeObject, req.Config.ResponseSchema.Type)
 }
 
+type schemaTreeNode struct {
+	Label    string           `json:"label"`
+	Children []schemaTreeNode `json:"children"`
+}
+
+func TestTranslate_ResponseFormat_InlinesRefs(t *testing.T) {
+	t.Parallel()
+	type answer struct {
+		Tree  schemaTreeNode `json:"tree"`
+		Other schemaTreeNode `json:"other"`
+	}
+	exec := &prompty.PromptExecution{
+		Messages:       []prompty.ChatMessage{prompty.NewUserMessage("Reply with JSON")},
+		ResponseFormat: &prompty.SchemaDefinition{Name: "out", Schema: prompty.ExtractSchema(answer{})},
+	}
+	req, err := New().Translate(exec)
+	require.NoError(t, err)
+	root := req.Config.ResponseSchema
+	require.NotNil(t, root)
+	assert.ElementsMatch(t, []string{"tree", "other"}, root.Required)
+
+	// Expanded maxRefDepth levels, then the recursive property is dropped.
+	node := root.Properties["tree"]
+	for range maxRefDepth - 1 {
+		require.NotNil(t, node)
+		assert.Equal(t, genai.TypeObject, node.Type)
+		assert.Contains(t, node.Properties, "label")
+		require.Contains(t, node.Properties, "children")
+		node = node.Properties["children"].Items
+	}
+	require.NotNil(t, node)
+	assert.NotContains(t, node.Properties, "children")
+	assert.Equal(t, []string{"label"}, node.Required)
+
+	_, err = New().Translate(&prompty.PromptExecution{
+		ResponseFormat: &prompty.SchemaDefinition{Schema: map[string]any{
+			"type": "object", "properties": map[string]any{"x": map[string]any{"$ref": "#/$defs/missing"}},
+		}},
+	})
+	require.ErrorContains(t, err, `unresolved $ref "#/$defs/missing"`)
+}
+
+func TestTranslate_ResponseFormat_SchemaDowngrade(t *testing.T) {
+	t.Parallel()
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"email": map[string]any{"type": "string", "format": "email", "maxLength": 80},
+			"note":  map[string]any{"oneOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+			"ids":   map[string]any{"type": []any{"array", "null"}, "items": map[string]any{"type": "integer"}},
+		},
+	}
+	exec := &prompty.PromptExecution{ResponseFormat: &prompty.SchemaDefinition{Schema: schema}}
+
+	req, err := New().Translate(exec)
+	require.NoError(t, err)
+	assert.Equal(t, "email", req.Config.ResponseSchema.Properties["email"].Format)
+	assert.Equal(t, genai.TypeArray, req.Config.ResponseSchema.Properties["ids"].Type)
+	assert.True(t, *req.Config.ResponseSchema.Properties["ids"].Nullable)
+
+	req, err = New(WithSchemaDowngrade()).Translate(exec)
+	require.NoError(t, err)
+	email := req.Config.ResponseSchema.Properties["email"]
+	assert.Empty(t, email.Format)
+	assert.Equal(t, "format: email", email.Description)
+	assert.Equal(t, int64(80), *email.MaxLength)
+	note := req.Config.ResponseSchema.Properties["note"]
+	assert.Equal(t, genai.TypeString, note.Type)
+	assert.True(t, *note.Nullable)
+	assert.Contains(t, schema["properties"].(map[string]any)["email"], "format", "the template schema is not modified")
+}
+
 func TestParseStreamChunk_Text(t *testing.T) {
 	t.Parallel()
 	a := New()
@@ -2,20 +2,86 @@ package gemini
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"google.golang.org/genai"
 )
 
+// maxRefDepth bounds how many times one "$ref" is expanded on a path. genai.Schema has no references, so refs
+// are inlined and a recursive type is cut after maxRefDepth levels (the property or array that would recurse
+// further is dropped).
+const maxRefDepth = 3
+
 // mapToGenaiSchema converts a JSON Schema (map[string]any) to genai.Schema.
-// Handles type, properties, items, required. Recursive for nested objects and arrays.
+// Handles type (including ["T", "null"] as nullable), properties, items, required, anyOf, nullable, enum,
+// description, title, format, pattern, numeric and length bounds, default, example and propertyOrdering.
+// Recursive for nested objects and arrays.
+// "$ref" to "#" or "#/$defs/{name}" is inlined (see maxRefDepth).
 func mapToGenaiSchema(m map[string]any) (*genai.Schema, error) {
 	if m == nil {
 		return nil, nil
 	}
+	defs, _ := m["$defs"].(map[string]any)
+	c := &schemaConverter{root: m, defs: defs, expanding: map[string]int{"#": 1}}
+	return c.convert(m)
+}
+
+// schemaConverter inlines "$ref" while converting; expanding counts the refs being expanded on the current path.
+type schemaConverter struct {
+	root      map[string]any
+	defs      map[string]any
+	expanding map[string]int
+}
+
+// resolve returns the schema a "$ref" points to.
+func (c *schemaConverter) resolve(ref string) (map[string]any, error) {
+	if ref == "#" {
+		return c.root, nil
+	}
+	if name, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		if def, ok := c.defs[name].(map[string]any); ok {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("unresolved $ref %q", ref)
+}
+
+// convert returns nil for a node cut at maxRefDepth.
+func (c *schemaConverter) convert(m map[string]any) (*genai.Schema, error) {
+	if ref, ok := m["$ref"].(string); ok {
+		target, err := c.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		if c.expanding[ref] >= maxRefDepth {
+			return nil, nil
+		}
+		c.expanding[ref]++
+		defer func() { c.expanding[ref]-- }()
+		merged := maps.Clone(target)
+		for k, v := range m {
+			if k != "$ref" {
+				merged[k] = v
+			}
+		}
+		return c.convert(merged)
+	}
 	s := &genai.Schema{}
-	if t, ok := m["type"].(string); ok && t != "" {
+	switch t := m["type"].(type) {
+	case string:
 		s.Type = jsonSchemaTypeToGenai(t)
+	case []any:
+		for _, x := range t {
+			if x == "null" {
+				s.Nullable = genai.Ptr(true)
+			} else if str, ok := x.(string); ok {
+				s.Type = jsonSchemaTypeToGenai(str)
+			}
+		}
 	}
+	cut := make(map[string]bool)
 	if p, ok := m["properties"].(map[string]any); ok {
 		s.Properties = make(map[string]*genai.Schema)
 		for k, v := range p {
@@ -23,12 +89,14 @@ func mapToGenaiSchema(m map[string]any) (*genai.Schema, error) {
 			if !ok {
 				continue
 			}
-			conv, err := mapToGenaiSchema(sub)
+			conv, err := c.convert(sub)
 			if err != nil {
 				return nil, fmt.Errorf("property %q: %w", k, err)
 			}
 			if conv != nil {
 				s.Properties[k] = conv
+			} else {
+				cut[k] = true
 			}
 		}
 	}
@@ -43,21 +111,41 @@ func mapToGenaiSchema(m map[string]any) (*genai.Schema, error) {
 	} else if r, ok := m["required"].([]string); ok {
 		s.Required = r
 	}
+	if len(cut) > 0 {
+		s.Required = slices.DeleteFunc(slices.Clone(s.Required), func(name string) bool { return cut[name] })
+	}
 	if items, ok := m["items"]; ok {
 		sub, ok := items.(map[string]any)
 		if ok {
-			conv, err := mapToGenaiSchema(sub)
+			conv, err := c.convert(sub)
 			if err != nil {
 				return nil, fmt.Errorf("items: %w", err)
 			}
+			if conv == nil {
+				return nil, nil // the element type was cut, so is the array
+			}
+			s.Items = conv
+		}
+	}
+	if anyOf, ok := m["anyOf"].([]any); ok {
+		for i, branch := range anyOf {
+			sub, ok := branch.(map[string]any)
+			if !ok {
+				continue
+			}
+			conv, err := c.convert(sub)
+			if err != nil {
+				return nil, fmt.Errorf("anyOf[%d]: %w", i, err)
+			}
 			if conv != nil {
-				s.Items = conv
+				s.AnyOf = append(s.AnyOf, conv)
 			}
 		}
 	}
 	if desc, ok := m["description"].(string); ok {
 		s.Description = desc
 	}
+	applySchemaConstraints(s, m)
 	if enum, ok := m["enum"].([]any); ok {
 		strs := make([]string, 0, len(enum))
 		for _, e := range enum {
@@ -70,6 +158,56 @@ func mapToGenaiSchema(m map[string]any) (*genai.Schema, error) {
 	return s, nil
 }
 
+// applySchemaConstraints copies the scalar keywords genai.Schema supports.
+func applySchemaConstraints(s *genai.Schema, m map[string]any) {
+	s.Title, _ = m["title"].(string)
+	s.Format, _ = m["format"].(string)
+	s.Pattern, _ = m["pattern"].(string)
+	s.Default = m["default"]
+	s.Example = m["example"]
+	if nullable, ok := m["nullable"].(bool); ok {
+		s.Nullable = genai.Ptr(nullable)
+	}
+	s.Minimum = schemaFloat(m["minimum"])
+	s.Maximum = schemaFloat(m["maximum"])
+	s.MinLength = schemaInt(m["minLength"])
+	s.MaxLength = schemaInt(m["maxLength"])
+	s.MinItems = schemaInt(m["minItems"])
+	s.MaxItems = schemaInt(m["maxItems"])
+	s.MinProperties = schemaInt(m["minProperties"])
+	s.MaxProperties = schemaInt(m["maxProperties"])
+	switch order := m["propertyOrdering"].(type) {
+	case []string:
+		s.PropertyOrdering = order
+	case []any:
+		for _, x := range order {
+			if str, ok := x.(string); ok {
+				s.PropertyOrdering = append(s.PropertyOrdering, str)
+			}
+		}
+	}
+}
+
+func schemaFloat(v any) *float64 {
+	switch x := v.(type) {
+	case float64:
+		return &x
+	case int:
+		return genai.Ptr(float64(x))
+	case int64:
+		return genai.Ptr(float64(x))
+	default:
+		return nil
+	}
+}
+
+func schemaInt(v any) *int64 {
+	if f := schemaFloat(v); f != nil {
+		return genai.Ptr(int64(*f))
+	}
+	return nil
+}
+
 func jsonSchemaTypeToGenai(t string) genai.Type {
 	switch t {
 	case "string":
@@ -16,6 +16,7 @@ import (
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/adapter"
+	"github.com/skosovsky/prompty/schemacompat"
 )
 
 // Adapter implements adapter.ProviderAdapter for the OpenAI Chat Completions API.
@@ -23,6 +24,7 @@ import (
 type Adapter struct {
 	defaultModel shared.ChatModel
 	client       *openai.Client
+	downgrade    bool // WithSchemaDowngrade
 }
 
 // Option configures an Adapter (e.g. WithModel, WithClient).
@@ -38,6 +40,13 @@ func WithClient(c *openai.Client) Option {
 	return func(a *Adapter) { a.client = c }
 }
 
+// WithSchemaDowngrade rewrites response and tool schemas with schemacompat.Downgrade for the OpenAI profile
+// before sending them: refs inlined where unsupported, unsupported constraints moved into descriptions, oneOf
+// turned into anyOf or nullable types. Output is still validated against the original schema.
+func WithSchemaDowngrade() Option {
+	return func(a *Adapter) { a.downgrade = true }
+}
+
 // New returns an Adapter with default model set to gpt-4o. Options can override the default model.
 func New(opts ...Option) *Adapter {
 	a := &Adapter{defaultModel: openai.ChatModelGPT4o}
@@ -49,6 +58,8 @@ func New(opts ...Option) *Adapter {
 
 // normalizeSchemaForStrict returns a schema copy with additionalProperties: false for type object
 // (required by OpenAI strict mode). Does not mutate the original.
+// "$defs" are normalized too; "$ref" nodes lose sibling keywords (strict mode rejects them) and optional
+// references become anyOf [$ref, null].
 func normalizeSchemaForStrict(schema any) any {
 	return normalizeStrictSchemaNode(cloneStrictSchemaNode(schema))
 }
@@ -58,6 +69,19 @@ func normalizeStrictSchemaNode(schema any) any {
 	if !ok {
 		return schema
 	}
+	if ref, ok := m["$ref"].(string); ok {
+		return map[string]any{"$ref": ref}
+	}
+	if defs, ok := m["$defs"].(map[string]any); ok {
+		for name, def := range defs {
+			defs[name] = normalizeStrictSchemaNode(def)
+		}
+	}
+	if anyOf, ok := m["anyOf"].([]any); ok {
+		for i, item := range anyOf {
+			anyOf[i] = normalizeStrictSchemaNode(item)
+		}
+	}
 
 	if properties, ok := m["properties"].(map[string]any); ok {
 		required := requiredNames(m["required"])
@@ -176,6 +200,11 @@ func schemaTypeIncludes(value any, target string) bool {
 }
 
 func makeStrictPropertyNullable(schema map[string]any) {
+	if ref, ok := schema["$ref"].(string); ok {
+		delete(schema, "$ref")
+		schema["anyOf"] = []any{map[string]any{"$ref": ref}, map[string]any{"type": "null"}}
+		return
+	}
 	switch t := schema["type"].(type) {
 	case string:
 		schema["type"] = []any{t, "null"}
@@ -196,6 +225,15 @@ func makeStrictPropertyNullable(schema map[string]any) {
 	}
 }
 
+// compatSchema returns schema downgraded for the provider when WithSchemaDowngrade is set.
+func (a *Adapter) compatSchema(schema map[string]any) map[string]any {
+	if !a.downgrade || schema == nil {
+		return schema
+	}
+	out, _ := schemacompat.Downgrade(schemacompat.OpenAI(), schema)
+	return out
+}
+
 // Translate converts PromptExecution into *openai.ChatCompletionNewParams.
 func (a *Adapter) Translate(exec *prompty.PromptExecution) (*openai.ChatCompletionNewParams, error) {
 	if exec == nil {
@@ -236,7 +274,7 @@ func (a *Adapter) Translate(exec *prompty.PromptExecution) (*openai.ChatCompleti
 			params.Tools = append(params.Tools, openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
 				Name:        t.Name,
 				Description: openai.String(t.Description),
-				Parameters:  shared.FunctionParameters(t.Parameters),
+				Parameters:  shared.FunctionParameters(a.compatSchema(t.Parameters)),
 			}))
 		}
 	}
@@ -245,7 +283,7 @@ func (a *Adapter) Translate(exec *prompty.PromptExecution) (*openai.ChatCompleti
 		if name == "" {
 			name = "response_schema"
 		}
-		schema := normalizeSchemaForStrict(exec.ResponseFormat.Schema)
+		schema := normalizeSchemaForStrict(a.compatSchema(exec.ResponseFormat.Schema))
 		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
 			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
 				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
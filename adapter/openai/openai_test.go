@@ -578,6 +578,26 @@ func TestTranslate_ResponseFormat(t *testing.T) {
 	assert.Equal(t, true, jsonSchema["strict"], "serialized JSON must contain strict: true for OpenAI strict mode")
 }
 
+func TestTranslate_ResponseFormat_SchemaDowngrade(t *testing.T) {
+	t.Parallel()
+	exec := &prompty.PromptExecution{
+		ResponseFormat: &prompty.SchemaDefinition{Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code": map[string]any{"type": "string", "minLength": 3, "format": "uri"},
+			},
+			"required": []any{"code"},
+		}},
+	}
+	params, err := New(WithSchemaDowngrade()).Translate(exec)
+	require.NoError(t, err)
+	schema, ok := params.ResponseFormat.OfJSONSchema.JSONSchema.Schema.(map[string]any)
+	require.True(t, ok)
+	code := schema["properties"].(map[string]any)["code"]
+	assert.Equal(t, map[string]any{"type": "string", "description": "format: uri; minLength: 3"}, code)
+	assert.Equal(t, false, schema["additionalProperties"], "strict normalization still applies")
+}
+
 func TestTranslate_ResponseFormat_RecursivelyNormalizesStrictSchema(t *testing.T) {
 	t.Parallel()
 	a := New()
@@ -632,6 +652,44 @@ func TestTranslate_ResponseFormat_RecursivelyNormalizesStrictSchema(t *testing.T
 	assert.Equal(t, original, schema, "strict normalization must not mutate caller-owned schema")
 }
 
+func TestTranslate_ResponseFormat_StrictSchemaWithDefs(t *testing.T) {
+	t.Parallel()
+	type node struct {
+		Label    string `json:"label"`
+		Children []node `json:"children"`
+	}
+	type address struct {
+		City string `json:"city,omitempty"`
+	}
+	type answer struct {
+		Tree     node     `json:"tree"`
+		Billing  address  `json:"billing" jsonschema:"description=Billing address"`
+		Shipping *address `json:"shipping,omitempty"`
+	}
+	schema := prompty.ExtractSchema(answer{})
+	require.Contains(t, schema, "$defs")
+	exec := &prompty.PromptExecution{
+		Messages:       []prompty.ChatMessage{prompty.NewUserMessage("Reply with JSON")},
+		ResponseFormat: &prompty.SchemaDefinition{Name: "answer", Schema: schema},
+	}
+	params, err := New().Translate(exec)
+	require.NoError(t, err)
+	got := params.ResponseFormat.OfJSONSchema.JSONSchema.Schema.(map[string]any)
+	props := got["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/node"}, props["tree"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/address"}, props["billing"], "strict mode rejects $ref siblings")
+	assert.Equal(t, map[string]any{"anyOf": []any{
+		map[string]any{"$ref": "#/$defs/address"}, map[string]any{"type": "null"},
+	}}, props["shipping"])
+	assert.ElementsMatch(t, []string{"tree", "billing", "shipping"}, got["required"])
+
+	defs := got["$defs"].(map[string]any)
+	addr := defs["address"].(map[string]any)
+	assert.Equal(t, []string{"city"}, addr["required"], "definitions are normalized")
+	assert.Equal(t, []any{"string", "null"}, addr["properties"].(map[string]any)["city"].(map[string]any)["type"])
+	assert.Contains(t, schema["properties"].(map[string]any)["billing"], "description", "caller schema is not mutated")
+}
+
 func TestTranslate_BatchedToolResultsSurviveOpenAITranslation(t *testing.T) {
 	t.Parallel()
 	a := New()
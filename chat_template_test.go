@@ -660,6 +660,32 @@ func TestFormatStruct_ChatHistory_SpliceAfterDeveloper(t *testing.T) {
 	assert.Equal(t, "last", exec.Messages[3].Content[0].(TextPart).Text)
 }
 
+func TestFormatWithHistory(t *testing.T) {
+	t.Parallel()
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("System.")},
+		{Role: RoleUser, Content: TextContent("{{ .query }}")},
+	})
+	require.NoError(t, err)
+	history := []ChatMessage{
+		{Role: RoleUser, Content: []ContentPart{TextPart{Text: "hist_user"}}},
+		{Role: RoleAssistant, Content: []ContentPart{TextPart{Text: "hist_assistant"}}},
+	}
+	exec, err := tpl.FormatWithHistory(map[string]any{"query": "last"}, history)
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 4)
+	assert.Equal(t, RoleSystem, exec.Messages[0].Role)
+	assert.Equal(t, "hist_user", exec.Messages[1].Content[0].(TextPart).Text)
+	assert.Equal(t, "hist_assistant", exec.Messages[2].Content[0].(TextPart).Text)
+	assert.Equal(t, "last", exec.Messages[3].Content[0].(TextPart).Text)
+
+	history[0].Content[0] = TextPart{Text: "changed"}
+	assert.Equal(t, "hist_user", exec.Messages[1].Content[0].(TextPart).Text, "history is copied")
+
+	_, err = tpl.FormatWithHistory(nil, history)
+	require.ErrorIs(t, err, ErrMissingVariable)
+}
+
 func TestFormatStruct_ToolsInjection(t *testing.T) {
 	t.Parallel()
 	tpl, err := NewChatPromptTemplate([]MessageTemplate{
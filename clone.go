@@ -115,6 +115,9 @@ func cloneMessageTemplates(messages []MessageTemplate) []MessageTemplate {
 			Role:         msg.Role,
 			Content:      slicesCloneTemplateParts(msg.Content),
 			Optional:     msg.Optional,
+			When:         msg.When,
+			ForEach:      msg.ForEach,
+			Turns:        cloneMessageTemplates(msg.Turns),
 			CacheControl: cloneCacheControl(msg.CacheControl),
 			Metadata:     cloneMapAny(msg.Metadata),
 		}
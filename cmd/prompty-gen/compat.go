@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/skosovsky/prompty/fileregistry"
+	"github.com/skosovsky/prompty/parser/yaml"
+	"github.com/skosovsky/prompty/schemacompat"
+)
+
+// compatSchema is one schema of a manifest: its response format or a tool's parameters.
+type compatSchema struct {
+	where  string // "response_format" or "tool {name}"
+	schema map[string]any
+}
+
+// runCompat checks the response format and tool schemas of every manifest under the given directories against
+// provider profiles (see schemacompat). Issues Downgrade cannot fix make it fail.
+func runCompat(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("compat", flag.ContinueOnError)
+	providers := flags.String("provider", strings.Join(schemacompat.Names(), ","),
+		"Comma-separated providers to check (openai, gemini, anthropic)")
+	fix := flags.Bool("fix", false, "Print each schema with issues as rewritten by schemacompat.Downgrade")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("compat: at least one manifest directory is required")
+	}
+	var profiles []schemacompat.Profile
+	for _, name := range strings.Split(*providers, ",") {
+		p, ok := schemacompat.Lookup(strings.TrimSpace(name))
+		if !ok {
+			return fmt.Errorf("compat: unknown provider %q (use %s)", name, strings.Join(schemacompat.Names(), ", "))
+		}
+		profiles = append(profiles, p)
+	}
+	total, unfixable := 0, 0
+	for _, dir := range flags.Args() {
+		schemas, err := manifestSchemas(dir)
+		if err != nil {
+			return fmt.Errorf("compat %s: %w", dir, err)
+		}
+		for _, id := range slices.Sorted(maps.Keys(schemas)) {
+			for _, s := range schemas[id] {
+				for _, p := range profiles {
+					issues, err := reportCompat(stdout, filepath.Join(dir, id)+" "+s.where+" "+p.Name, p, s.schema, *fix)
+					if err != nil {
+						return fmt.Errorf("compat %s: %w", id, err)
+					}
+					total += len(issues)
+					for _, issue := range issues {
+						if !issue.Fixable {
+							unfixable++
+						}
+					}
+				}
+			}
+		}
+	}
+	_, _ = fmt.Fprintf(stdout, "%d issues (%d not fixable)\n", total, unfixable)
+	if unfixable > 0 {
+		return fmt.Errorf("compat: %d issues cannot be fixed by downgrading the schema", unfixable)
+	}
+	return nil
+}
+
+// reportCompat prints the issues of schema for p, prefixed with label, and the downgraded schema when fix is set.
+func reportCompat(
+	stdout io.Writer,
+	label string,
+	p schemacompat.Profile,
+	schema map[string]any,
+	fix bool,
+) ([]schemacompat.Issue, error) {
+	issues := schemacompat.Check(p, schema)
+	for _, issue := range issues {
+		_, _ = fmt.Fprintf(stdout, "%s: %s\n", label, issue)
+	}
+	if fix && len(issues) > 0 {
+		fixed, _ := schemacompat.Downgrade(p, schema)
+		data, err := json.MarshalIndent(fixed, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		_, _ = fmt.Fprintf(stdout, "%s downgraded:\n%s\n", label, data)
+	}
+	return issues, nil
+}
+
+// manifestSchemas loads every template under dir and returns its schemas by id.
+func manifestSchemas(dir string) (map[string][]compatSchema, error) {
+	reg, err := fileregistry.New(dir, fileregistry.WithParser(yaml.New()))
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	ids, err := reg.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]compatSchema, len(ids))
+	for _, id := range ids {
+		tpl, err := reg.GetTemplate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		var schemas []compatSchema
+		if tpl.ResponseFormat != nil && len(tpl.ResponseFormat.Schema) > 0 {
+			schemas = append(schemas, compatSchema{where: "response_format", schema: tpl.ResponseFormat.Schema})
+		}
+		for _, tool := range tpl.Tools {
+			if len(tool.Parameters) > 0 {
+				schemas = append(schemas, compatSchema{where: "tool " + tool.Name, schema: tool.Parameters})
+			}
+		}
+		out[id] = schemas
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const compatManifest = `id: ticket
+messages:
+  - role: system
+    content: Triage the ticket.
+response_format:
+  name: ticket
+  schema:
+    type: object
+    properties:
+      email:
+        type: string
+        format: email
+      note:
+        oneOf:
+          - type: string
+          - type: "null"
+    required: [email]
+tools:
+  - name: search
+    description: Search tickets
+    parameters:
+      type: object
+      properties:
+        limit:
+          type: integer
+          minimum: 1
+`
+
+func TestRunCompat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ticket.yaml"), []byte(compatManifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runCompat([]string{"-provider", "gemini", "-fix", dir}, &out); err != nil {
+		t.Fatalf("runCompat: %v\n%s", err, out.String())
+	}
+	got := out.String()
+	for _, want := range []string{
+		filepath.Join(dir, "ticket") + ` response_format gemini: #/properties/email: format "email" is not supported`,
+		"response_format gemini: #/properties/note: oneOf is not supported by gemini; rewritten as anyOf",
+		`"description": "format: email"`,
+		`"nullable": true`,
+		"2 issues (0 not fixable)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "tool search") {
+		t.Errorf("tool schema is gemini-compatible, got:\n%s", got)
+	}
+
+	out.Reset()
+	if err := runCompat([]string{"-provider", "anthropic", dir}, &out); err != nil {
+		t.Fatalf("runCompat anthropic: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "tool search anthropic: #/properties/limit: minimum is not supported") {
+		t.Errorf("output = %s", out.String())
+	}
+
+	unfixable := strings.Replace(compatManifest, "    required: [email]\n",
+		"    required: [email]\n    additionalProperties:\n      type: string\n", 1)
+	if err := os.WriteFile(filepath.Join(dir, "ticket.yaml"), []byte(unfixable), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runCompat([]string{"-provider", "openai", dir}, &out); err == nil {
+		t.Errorf("open object must fail for openai:\n%s", out.String())
+	}
+	if err := runCompat([]string{"-provider", "cohere", dir}, &out); err == nil {
+		t.Error("unknown provider must fail")
+	}
+	if err := runCompat(nil, &out); err == nil {
+		t.Error("missing directory must fail")
+	}
+}
@@ -16,6 +16,10 @@ const (
 	extJSON    = ".json"
 	modeConsts = "consts"
 	modeTypes  = "types"
+
+	emitJSONSchema = "jsonschema"
+	emitTypeScript = "typescript"
+	emitOpenAPI    = "openapi"
 )
 
 // Config is the prompty-gen configuration (prompty.yaml).
@@ -32,6 +36,8 @@ type Package struct {
 	Queries     []string `yaml:"queries"` // paths or globs for manifest files
 	PackageName string   `yaml:"package"` // Go package name (default: name)
 	Mode        string   `yaml:"mode"`    // "consts" | "types" (default: "types")
+	Execute     bool     `yaml:"execute"` // types mode: also emit Execute<Name>/Stream<Name>
+	Emit        []string `yaml:"emit"`    // types mode: extra outputs "jsonschema", "typescript", "openapi"
 }
 
 // IsConsts returns true when mode is "consts".
@@ -45,6 +51,16 @@ func (p *Package) IsTypes() bool {
 	return m == "" || m == modeTypes
 }
 
+// Emits returns true when kind is listed in emit.
+func (p *Package) Emits(kind string) bool {
+	for _, e := range p.Emit {
+		if strings.ToLower(e) == kind {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadConfig reads and parses prompty.yaml from path.
 // Uses KnownFields(true) so legacy fields (max_retries, enable_validation) cause hard-fail.
 func LoadConfig(path string) (*Config, error) {
@@ -73,6 +89,19 @@ func LoadConfig(path string) (*Config, error) {
 		if m != modeConsts && m != modeTypes {
 			return nil, fmt.Errorf("package %q: invalid mode %q (use consts or types)", p.Name, p.Mode)
 		}
+		if p.Execute && m != modeTypes {
+			return nil, fmt.Errorf("package %q: execute requires mode types", p.Name)
+		}
+		for _, e := range p.Emit {
+			switch strings.ToLower(e) {
+			case emitJSONSchema, emitTypeScript, emitOpenAPI:
+			default:
+				return nil, fmt.Errorf("package %q: invalid emit %q (use jsonschema, typescript or openapi)", p.Name, e)
+			}
+		}
+		if len(p.Emit) > 0 && m != modeTypes {
+			return nil, fmt.Errorf("package %q: emit requires mode types", p.Name)
+		}
 	}
 	return &c, nil
 }
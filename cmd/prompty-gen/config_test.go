@@ -67,6 +67,69 @@ packages:
 	}
 }
 
+func TestLoadConfig_Execute(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "prompty.yaml")
+	for _, tc := range []struct {
+		mode    string
+		wantErr bool
+	}{
+		{"types", false},
+		{"consts", true},
+	} {
+		cfg := "version: \"1\"\npackages:\n  - name: pkg\n    path: out\n    queries: [\"./*.yaml\"]\n    mode: " +
+			tc.mode + "\n    execute: true\n"
+		if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+			t.Fatalf("write temp config: %v", err)
+		}
+		c, err := LoadConfig(cfgPath)
+		if tc.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "execute requires mode types") {
+				t.Errorf("mode %s: expected execute error, got %v", tc.mode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("mode %s: %v", tc.mode, err)
+		}
+		if !c.Packages[0].Execute {
+			t.Error("execute not parsed")
+		}
+	}
+}
+
+func TestLoadConfig_Emit(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "prompty.yaml")
+	for _, tc := range []struct {
+		mode, emit, wantErr string
+	}{
+		{"types", "[jsonschema, TypeScript, openapi]", ""},
+		{"types", "[protobuf]", `invalid emit "protobuf"`},
+		{"consts", "[typescript]", "emit requires mode types"},
+	} {
+		cfg := "version: \"1\"\npackages:\n  - name: pkg\n    path: out\n    queries: [\"./*.yaml\"]\n    mode: " +
+			tc.mode + "\n    emit: " + tc.emit + "\n"
+		if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+			t.Fatalf("write temp config: %v", err)
+		}
+		c, err := LoadConfig(cfgPath)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("emit %s: expected %q error, got %v", tc.emit, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("emit %s: %v", tc.emit, err)
+		}
+		p := c.Packages[0]
+		if !p.Emits(emitJSONSchema) || !p.Emits(emitTypeScript) || !p.Emits(emitOpenAPI) {
+			t.Errorf("emit not parsed: %v", p.Emit)
+		}
+	}
+}
+
 func TestLoadConfig_UnknownFieldsFails(t *testing.T) {
 	// KnownFields(true) must hard-fail on legacy max_retries
 	tmp := t.TempDir()
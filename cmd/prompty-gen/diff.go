@@ -0,0 +1,574 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/internal/cast"
+	"github.com/skosovsky/prompty/manifest"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// diffReport collects the changes of one prompt; breaking counts input changes that break callers.
+type diffReport struct {
+	lines    []string
+	breaking int
+}
+
+func (r *diffReport) add(format string, args ...any) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func (r *diffReport) addBreaking(format string, args ...any) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...)+" [breaking]")
+	r.breaking++
+}
+
+// runDiff compares the manifests of two git revisions (the config directory at each revision) or two directories
+// and prints a semantic diff: added/removed prompts, input_schema fields, tools, model_config, response_format,
+// the message templates and, for prompts with a fixture input, the rendered messages.
+func runDiff(configPath string, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fixturesPath := flags.String("fixtures", "", "JSON file mapping prompt id to input variables for the rendered diff")
+	failOnBreaking := flags.Bool("fail-on-breaking", false, "Exit non-zero on breaking input_schema changes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("diff: want two git revisions or two directories")
+	}
+	fixtures := map[string]map[string]any{}
+	if *fixturesPath != "" {
+		if err := readJSONFile(*fixturesPath, &fixtures); err != nil {
+			return fmt.Errorf("diff: fixtures: %w", err)
+		}
+	}
+	old, err := loadDiffSide(configPath, flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("diff %s: %w", flags.Arg(0), err)
+	}
+	cur, err := loadDiffSide(configPath, flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("diff %s: %w", flags.Arg(1), err)
+	}
+
+	changed, breaking := 0, 0
+	for _, id := range slices.Sorted(maps.Keys(mergeKeys(old, cur))) {
+		a, inOld := old[id]
+		b, inCur := cur[id]
+		switch {
+		case !inOld:
+			_, _ = fmt.Fprintf(stdout, "+ %s\n", id)
+			changed++
+		case !inCur:
+			_, _ = fmt.Fprintf(stdout, "- %s\n", id)
+			changed++
+		default:
+			r := diffTemplates(a, b, fixtures[id])
+			if len(r.lines) == 0 {
+				continue
+			}
+			_, _ = fmt.Fprintf(stdout, "~ %s\n", id)
+			for _, line := range r.lines {
+				_, _ = fmt.Fprintf(stdout, "    %s\n", line)
+			}
+			changed++
+			breaking += r.breaking
+		}
+	}
+	_, _ = fmt.Fprintf(stdout, "%d prompts changed, %d breaking input changes\n", changed, breaking)
+	if *failOnBreaking && breaking > 0 {
+		return fmt.Errorf("diff: %d breaking input changes", breaking)
+	}
+	return nil
+}
+
+func mergeKeys(a, b map[string]*prompty.ChatPromptTemplate) map[string]bool {
+	out := make(map[string]bool, len(a)+len(b))
+	for id := range a {
+		out[id] = true
+	}
+	for id := range b {
+		out[id] = true
+	}
+	return out
+}
+
+// loadDiffSide loads the templates of one side: the manifests matched by the config queries in a directory that
+// holds the config file (a checkout of the config directory) or in the config directory as of a git revision,
+// otherwise every manifest under the directory.
+func loadDiffSide(configPath, side string) (map[string]*prompty.ChatPromptTemplate, error) {
+	if info, err := os.Stat(side); err == nil && info.IsDir() {
+		sideConfig := filepath.Join(side, filepath.Base(configPath))
+		if _, err := os.Stat(sideConfig); err != nil {
+			return loadDiffTemplates(side, []Package{{Name: "diff", Queries: []string{"."}}}, true)
+		}
+		cfg, err := LoadConfig(sideConfig)
+		if err != nil {
+			return nil, err
+		}
+		return loadDiffTemplates(side, cfg.Packages, false)
+	}
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("config path: %w", err)
+	}
+	cfg, err := LoadConfig(absConfig)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "prompty-diff-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := extractRevision(filepath.Dir(absConfig), side, tmp); err != nil {
+		return nil, err
+	}
+	return loadDiffTemplates(tmp, cfg.Packages, false)
+}
+
+// extractRevision writes the files of configDir as of git revision rev into tmp. Run from a subdirectory, git
+// archive only includes that subtree, with paths relative to it, so tmp mirrors configDir.
+func extractRevision(configDir, rev, tmp string) error {
+	if strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid revision %q", rev)
+	}
+	// #nosec G204 -- configDir is the config directory, rev is passed as a single argument.
+	cmd := exec.CommandContext(context.Background(), "git", "archive", "--format=tar", rev)
+	cmd.Dir = configDir
+	archive, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git archive %s: %w", rev, gitError(err))
+	}
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("git archive %s: unsafe path %q", rev, hdr.Name)
+		}
+		path := filepath.Join(tmp, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitError adds git's stderr to an exec error.
+func gitError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+// loadDiffTemplates builds every manifest the packages match under configDir, by id. Env overlays are skipped:
+// they only patch a base manifest at runtime. With skipOther, files that are not manifests (configs, fixtures)
+// are skipped too.
+func loadDiffTemplates(
+	configDir string,
+	pkgs []Package,
+	skipOther bool,
+) (map[string]*prompty.ChatPromptTemplate, error) {
+	out := make(map[string]*prompty.ChatPromptTemplate)
+	for _, pkg := range pkgs {
+		files, err := pkg.ResolveSources(configDir)
+		if err != nil {
+			return nil, fmt.Errorf("package %q: %w", pkg.Name, err)
+		}
+		for _, fpath := range files {
+			data, err := os.ReadFile(fpath)
+			if err != nil {
+				return nil, err
+			}
+			if skipOther && !looksLikeManifest(fpath, data) {
+				continue
+			}
+			u, err := manifestParser(fpath)
+			if err != nil {
+				return nil, fmt.Errorf("manifest %s: %w", fpath, err)
+			}
+			var raw manifest.RawManifest
+			if err := u.Unmarshal(data, &raw); err != nil {
+				return nil, fmt.Errorf("manifest %s: %w", fpath, err)
+			}
+			if raw.Overlay {
+				continue
+			}
+			if raw.ID == "" {
+				raw.ID = idFromRelativePath(fpath, configDir, pkg.Queries)
+			}
+			tpl, err := buildManifest(&raw, u, fpath, configDir, pkg.Queries)
+			if err != nil {
+				return nil, fmt.Errorf("manifest %s: %w", fpath, err)
+			}
+			out[tpl.Metadata.ID] = tpl
+		}
+	}
+	return out, nil
+}
+
+// looksLikeManifest reports whether data has a top-level messages, extends or overlay key. Files that do not
+// parse count as manifests, so the loader reports their syntax errors.
+func looksLikeManifest(fpath string, data []byte) bool {
+	var top map[string]any
+	var err error
+	if strings.ToLower(filepath.Ext(fpath)) == extJSON {
+		err = json.Unmarshal(data, &top)
+	} else {
+		err = yamlv3.Unmarshal(data, &top)
+	}
+	if err != nil {
+		return true
+	}
+	_, messages := top["messages"]
+	_, extends := top["extends"]
+	_, overlay := top["overlay"]
+	return messages || extends || overlay
+}
+
+// diffTemplates compares two versions of a prompt; vars (optional) is the fixture input for the rendered diff.
+func diffTemplates(a, b *prompty.ChatPromptTemplate, vars map[string]any) diffReport {
+	var r diffReport
+	diffInputSchema(&r, schemaOf(a.InputSchema), schemaOf(b.InputSchema))
+	diffTools(&r, a.Tools, b.Tools)
+	diffModelOptions(&r, a.ModelOptions, b.ModelOptions)
+	if !jsonEqual(schemaOf(a.ResponseFormat), schemaOf(b.ResponseFormat)) {
+		r.add("response_format: schema changed")
+	}
+	diffMessageTemplates(&r, "", "message", a.Messages, b.Messages)
+	if vars != nil {
+		diffRendered(&r, a, b, vars)
+	}
+	return r
+}
+
+func schemaOf(def *prompty.SchemaDefinition) map[string]any {
+	if def == nil {
+		return nil
+	}
+	return def.Schema
+}
+
+// diffInputSchema reports input_schema property changes. New required inputs, removed inputs, type changes and
+// inputs that become required break callers (and generated Input structs).
+func diffInputSchema(r *diffReport, a, b map[string]any) {
+	propsA, _ := a["properties"].(map[string]any)
+	propsB, _ := b["properties"].(map[string]any)
+	reqA, _ := cast.ToStringSlice(a["required"])
+	reqB, _ := cast.ToStringSlice(b["required"])
+	for _, name := range slices.Sorted(maps.Keys(propsA)) {
+		if _, ok := propsB[name]; !ok {
+			r.addBreaking("input %s: removed", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(propsB)) {
+		propB, _ := propsB[name].(map[string]any)
+		rawA, ok := propsA[name]
+		if !ok {
+			if slices.Contains(reqB, name) {
+				r.addBreaking("input %s: added (required)", name)
+			} else {
+				r.add("input %s: added (optional)", name)
+			}
+			continue
+		}
+		propA, _ := rawA.(map[string]any)
+		wasRequired, isRequired := slices.Contains(reqA, name), slices.Contains(reqB, name)
+		switch {
+		case !wasRequired && isRequired:
+			r.addBreaking("input %s: now required", name)
+		case wasRequired && !isRequired:
+			r.add("input %s: now optional", name)
+		}
+		if typeA, typeB := jsonString(propA["type"]), jsonString(propB["type"]); typeA != typeB {
+			r.addBreaking("input %s: type %s -> %s", name, typeA, typeB)
+			continue
+		}
+		if !jsonEqual(propA, propB) {
+			r.add("input %s: schema changed", name)
+		}
+	}
+}
+
+// diffTools reports added and removed tools and changed descriptions or parameters.
+func diffTools(r *diffReport, a, b []prompty.ToolDefinition) {
+	byName := func(tools []prompty.ToolDefinition) map[string]prompty.ToolDefinition {
+		out := make(map[string]prompty.ToolDefinition, len(tools))
+		for _, tool := range tools {
+			out[tool.Name] = tool
+		}
+		return out
+	}
+	toolsA, toolsB := byName(a), byName(b)
+	for _, name := range slices.Sorted(maps.Keys(toolsA)) {
+		if _, ok := toolsB[name]; !ok {
+			r.add("tool %s: removed", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(toolsB)) {
+		toolA, ok := toolsA[name]
+		if !ok {
+			r.add("tool %s: added", name)
+			continue
+		}
+		toolB := toolsB[name]
+		if toolA.Description != toolB.Description {
+			r.add("tool %s: description changed", name)
+		}
+		if jsonEqual(toolA.Parameters, toolB.Parameters) {
+			continue
+		}
+		var sig diffReport
+		diffInputSchema(&sig, toolA.Parameters, toolB.Parameters)
+		if len(sig.lines) == 0 {
+			r.add("tool %s: parameters changed", name)
+		}
+		for _, line := range sig.lines {
+			r.add("tool %s: %s", name, strings.TrimSuffix(strings.TrimPrefix(line, "input "), " [breaking]"))
+		}
+	}
+}
+
+// diffModelOptions reports changed model_config keys (provider settings by key).
+func diffModelOptions(r *diffReport, a, b *prompty.ModelOptions) {
+	flat := func(opts *prompty.ModelOptions) map[string]any {
+		out := map[string]any{}
+		if opts == nil {
+			return out
+		}
+		data, _ := json.Marshal(opts)
+		_ = json.Unmarshal(data, &out)
+		settings, _ := out["provider_settings"].(map[string]any)
+		delete(out, "provider_settings")
+		for key, value := range settings {
+			out["provider_settings."+key] = value
+		}
+		return out
+	}
+	optsA, optsB := flat(a), flat(b)
+	for _, key := range slices.Sorted(maps.Keys(mergeAny(optsA, optsB))) {
+		valueA, inA := optsA[key]
+		valueB, inB := optsB[key]
+		switch {
+		case !inA:
+			r.add("model_config.%s: set to %s", key, jsonString(valueB))
+		case !inB:
+			r.add("model_config.%s: removed (was %s)", key, jsonString(valueA))
+		case !jsonEqual(valueA, valueB):
+			r.add("model_config.%s: %s -> %s", key, jsonString(valueA), jsonString(valueB))
+		}
+	}
+}
+
+func mergeAny(a, b map[string]any) map[string]any {
+	out := maps.Clone(a)
+	maps.Copy(out, b)
+	return out
+}
+
+// diffMessageTemplates compares messages as written, with or without fixtures: count, role, the when, for_each and
+// optional directives, and a line diff of the template source; turns are compared the same way. noun names the
+// items ("message", "turn") after prefix.
+func diffMessageTemplates(r *diffReport, prefix, noun string, a, b []prompty.MessageTemplate) {
+	for i := range max(len(a), len(b)) {
+		name := fmt.Sprintf("%s%s %d", prefix, noun, i)
+		switch {
+		case i >= len(a):
+			r.add("%s (%s): added", name, b[i].Role)
+			continue
+		case i >= len(b):
+			r.add("%s (%s): removed", name, a[i].Role)
+			continue
+		}
+		ma, mb := a[i], b[i]
+		if ma.Role != mb.Role {
+			r.add("%s: role %q -> %q", name, ma.Role, mb.Role)
+		}
+		diffDirective(r, name+" when", ma.When, mb.When)
+		diffDirective(r, name+" for_each", ma.ForEach, mb.ForEach)
+		if ma.Optional != mb.Optional {
+			r.add("%s optional: %t -> %t", name, ma.Optional, mb.Optional)
+		}
+		if srcA, srcB := templateText(ma), templateText(mb); srcA != srcB {
+			r.add("%s (%s) template:", name, mb.Role)
+			for _, line := range lineDiff(srcA, srcB) {
+				r.add("  %s", line)
+			}
+		}
+		diffMessageTemplates(r, name+" ", "turn", ma.Turns, mb.Turns)
+	}
+}
+
+// diffDirective reports a changed directive expression of a message (when, for_each).
+func diffDirective(r *diffReport, name, a, b string) {
+	switch {
+	case a == b:
+	case a == "":
+		r.add("%s: set to %q", name, b)
+	case b == "":
+		r.add("%s: removed (was %q)", name, a)
+	default:
+		r.add("%s: %q -> %q", name, a, b)
+	}
+}
+
+// templateText joins the template source of the parts of m; a part condition prefixes its part as "[when expr]"
+// and media parts read like partText.
+func templateText(m prompty.MessageTemplate) string {
+	texts := make([]string, 0, len(m.Content))
+	for _, part := range m.Content {
+		text := part.Text
+		if part.Type == "media" {
+			text = fmt.Sprintf("[%s %s %s]", part.MediaType, part.MIMEType, part.URL)
+		}
+		if part.When != "" {
+			text = fmt.Sprintf("[when %s] %s", part.When, text)
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// diffRendered formats both versions with vars and reports a line diff of each changed message.
+func diffRendered(r *diffReport, a, b *prompty.ChatPromptTemplate, vars map[string]any) {
+	execA, errA := a.Format(vars)
+	execB, errB := b.Format(vars)
+	switch {
+	case errA != nil && errB != nil:
+		return
+	case errA != nil:
+		r.add("render: fixture now renders (was: %v)", errA)
+		return
+	case errB != nil:
+		r.add("render: fixture no longer renders: %v", errB)
+		return
+	}
+	for i := range max(len(execA.Messages), len(execB.Messages)) {
+		var textA, textB, role string
+		if i < len(execA.Messages) {
+			role, textA = string(execA.Messages[i].Role), messageText(execA.Messages[i])
+		}
+		if i < len(execB.Messages) {
+			role, textB = string(execB.Messages[i].Role), messageText(execB.Messages[i])
+		}
+		if textA == textB && i < len(execA.Messages) && i < len(execB.Messages) &&
+			execA.Messages[i].Role == execB.Messages[i].Role {
+			continue
+		}
+		r.add("message %d (%s) rendered:", i, role)
+		for _, line := range lineDiff(textA, textB) {
+			r.add("  %s", line)
+		}
+	}
+}
+
+// messageText joins the parts of msg as text (see partText).
+func messageText(msg prompty.ChatMessage) string {
+	texts := make([]string, 0, len(msg.Content))
+	for _, part := range msg.Content {
+		texts = append(texts, partText(part))
+	}
+	return strings.Join(texts, "\n")
+}
+
+// lineDiff returns a minimal line diff of a and b ("- " removed, "+ " added, "  " kept), from their longest common
+// subsequence.
+func lineDiff(a, b string) []string {
+	split := func(s string) []string {
+		s = strings.TrimSuffix(s, "\n")
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "\n")
+	}
+	la, lb := split(a), split(b)
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i] == lb[j]:
+			out = append(out, "  "+la[i])
+			i++
+			j++
+		case i < len(la) && (j == len(lb) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+la[i])
+			i++
+		default:
+			out = append(out, "+ "+lb[j])
+			j++
+		}
+	}
+	return out
+}
+
+// jsonEqual compares values by their JSON encoding (so int 1 and float 1 are equal).
+func jsonEqual(a, b any) bool {
+	var na, nb any
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	_ = json.Unmarshal(da, &na)
+	_ = json.Unmarshal(db, &nb)
+	return reflect.DeepEqual(na, nb)
+}
+
+// jsonString formats v as compact JSON for change lines ("none" for nil).
+func jsonString(v any) string {
+	if v == nil {
+		return "none"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
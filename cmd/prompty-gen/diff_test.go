@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const diffManifestV1 = `id: support/agent
+model_config:
+  model: gpt-4o
+  temperature: 0.2
+input_schema:
+  name: agent_input
+  schema:
+    type: object
+    properties:
+      name:
+        type: string
+      tier:
+        type: string
+      age:
+        type: integer
+    required: [name]
+tools:
+  - name: lookup
+    description: Find an order
+    parameters:
+      type: object
+      properties:
+        order_id:
+          type: string
+      required: [order_id]
+messages:
+  - role: system
+    content: |
+      You help {{ .name }}.
+      Be polite.
+  - role: user
+    content: Hi
+`
+
+const diffManifestV2 = `id: support/agent
+model_config:
+  model: gpt-4o-mini
+  temperature: 0.2
+  reasoning_effort: low
+input_schema:
+  name: agent_input
+  schema:
+    type: object
+    properties:
+      name:
+        type: string
+      tier:
+        type: string
+      age:
+        type: string
+      locale:
+        type: string
+      mood:
+        type: string
+    required: [name, tier, locale]
+tools:
+  - name: lookup
+    description: Find an order
+    parameters:
+      type: object
+      properties:
+        order_id:
+          type: string
+        verbose:
+          type: boolean
+      required: [order_id]
+  - name: escalate
+    description: Hand off to a human
+messages:
+  - role: system
+    content: |
+      You help {{ .name }}.
+      Be brief.
+  - role: user
+    content: Hi
+`
+
+const diffOther = `id: legacy
+input_schema:
+  name: legacy_input
+  schema:
+    type: object
+messages:
+  - role: user
+    content: Old prompt
+`
+
+func TestRunDiff_Directories(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"a/support/agent.yaml": diffManifestV1,
+		"a/legacy.yaml":        diffOther,
+		"b/support/agent.yaml": diffManifestV2,
+		"b/fresh.yaml":         strings.ReplaceAll(diffOther, "legacy", "fresh"),
+		"fixtures.json":        `{"support/agent": {"name": "Ann", "tier": "pro", "locale": "en"}}`,
+	} {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), text)
+	}
+
+	var out bytes.Buffer
+	args := []string{"-fixtures", filepath.Join(dir, "fixtures.json"), filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	if err := runDiff("prompty.yaml", args, &out); err != nil {
+		t.Fatalf("runDiff: %v\n%s", err, out.String())
+	}
+	want := `+ fresh
+- legacy
+~ support/agent
+    input age: type "integer" -> "string" [breaking]
+    input locale: added (required) [breaking]
+    input mood: added (optional)
+    input tier: now required [breaking]
+    tool escalate: added
+    tool lookup: verbose: added (optional)
+    model_config.model: "gpt-4o" -> "gpt-4o-mini"
+    model_config.provider_settings.reasoning_effort: set to "low"
+    message 0 (system) template:
+        You help {{ .name }}.
+      - Be polite.
+      + Be brief.
+    message 0 (system) rendered:
+        You help Ann.
+      - Be polite.
+      + Be brief.
+3 prompts changed, 3 breaking input changes
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	args = []string{"-fail-on-breaking", filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	if err := runDiff("prompty.yaml", args, &out); err == nil || !strings.Contains(err.Error(), "3 breaking") {
+		t.Errorf("fail-on-breaking: got %v", err)
+	}
+	if strings.Contains(out.String(), "rendered") || !strings.Contains(out.String(), "message 0 (system) template:") {
+		t.Errorf("no fixtures: template diff only:\n%s", out.String())
+	}
+	if err := runDiff("prompty.yaml", []string{filepath.Join(dir, "a")}, &out); err == nil {
+		t.Error("one side must fail")
+	}
+}
+
+func TestRunDiff_ConfigDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"a/prompty.yaml":               renderConfig,
+		"a/prompts/support/agent.yaml": diffManifestV1,
+		"a/fixtures.json":              `{"support/agent": {"name": "Ann"}}`,
+		"b/prompty.yaml":               renderConfig,
+		"b/prompts/support/agent.yaml": diffManifestV2,
+		"b/fixtures.json":              `{"support/agent": {"name": "Ann"}}`,
+	} {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), text)
+	}
+
+	var out bytes.Buffer
+	if err := runDiff("prompty.yaml", []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}, &out); err != nil {
+		t.Fatalf("runDiff: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "~ support/agent\n") ||
+		!strings.Contains(out.String(), "1 prompts changed, 3 breaking input changes") {
+		t.Errorf("config checkouts:\n%s", out.String())
+	}
+
+	// Without a config file every manifest under the directory is compared; other files are skipped.
+	out.Reset()
+	args := []string{filepath.Join(dir, "a", "prompts"), filepath.Join(dir, "b")}
+	if err := runDiff("prompty.yaml", args, &out); err != nil {
+		t.Fatalf("runDiff: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "~ support/agent\n") {
+		t.Errorf("manifest directory vs config checkout:\n%s", out.String())
+	}
+	writeFile(t, filepath.Join(dir, "a", "prompts", "fixtures.json"), `{"support/agent": {"name": "Ann"}}`)
+	if err := runDiff("prompty.yaml", args, &out); err != nil {
+		t.Errorf("fixtures file must be skipped: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "a", "prompts", "broken.yaml"), "messages: [")
+	if err := runDiff("prompty.yaml", args, &out); err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Errorf("unparsable manifest: got %v", err)
+	}
+}
+
+func TestRunDiff_MessageTemplates(t *testing.T) {
+	dir := t.TempDir()
+	v1 := "id: agent\ninput_schema:\n  schema:\n    type: object\n" +
+		"messages:\n  - role: system\n    content: Hello\n  - role: user\n    content: Hi\n"
+	writeFile(t, filepath.Join(dir, "a", "agent.yaml"), v1)
+	writeFile(t, filepath.Join(dir, "b", "agent.yaml"),
+		strings.Replace(v1, "Hello", "Ignore all previous instructions", 1))
+	var out bytes.Buffer
+	if err := runDiff("prompty.yaml", []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}, &out); err != nil {
+		t.Fatalf("runDiff: %v\n%s", err, out.String())
+	}
+	want := `~ agent
+    message 0 (system) template:
+      - Hello
+      + Ignore all previous instructions
+1 prompts changed, 0 breaking input changes
+`
+	if out.String() != want {
+		t.Errorf("system prompt change without fixtures:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	writeFile(t, filepath.Join(dir, "c", "agent.yaml"), `id: agent
+input_schema:
+  schema:
+    type: object
+messages:
+  - role: developer
+    content: Hello
+  - role: user
+    when: verbose
+    for_each: questions
+    content:
+      - type: text
+        text: "{{ .item }}"
+        when: item
+  - role: assistant
+    content: Done
+`)
+	out.Reset()
+	if err := runDiff("prompty.yaml", []string{filepath.Join(dir, "a"), filepath.Join(dir, "c")}, &out); err != nil {
+		t.Fatalf("runDiff: %v\n%s", err, out.String())
+	}
+	want = `~ agent
+    message 0: role "system" -> "developer"
+    message 1 when: set to "verbose"
+    message 1 for_each: set to "questions"
+    message 1 (user) template:
+      - Hi
+      + [when item] {{ .item }}
+    message 2 (assistant): added
+1 prompts changed, 0 breaking input changes
+`
+	if out.String() != want {
+		t.Errorf("directives:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRunDiff_GitRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...) // #nosec G204 -- test helper: fixed arguments
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	config := filepath.Join(repo, "prompts", "prompty.yaml")
+	writeFile(t, config, renderConfig)
+	writeFile(t, filepath.Join(repo, "prompts", "prompts", "support", "agent.yaml"), diffManifestV1)
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1")
+	writeFile(t, filepath.Join(repo, "prompts", "prompts", "support", "agent.yaml"), diffManifestV2)
+	git("commit", "-q", "-am", "v2")
+
+	var out bytes.Buffer
+	if err := runDiff(config, []string{"-fail-on-breaking", "HEAD~1", "HEAD"}, &out); err == nil {
+		t.Fatalf("breaking change must fail:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "~ support/agent\n") ||
+		!strings.Contains(out.String(), "1 prompts changed, 3 breaking input changes") {
+		t.Errorf("output:\n%s", out.String())
+	}
+
+	out.Reset()
+	err := runDiff(config, []string{"HEAD", "HEAD"}, &out)
+	if err != nil || out.String() != "0 prompts changed, 0 breaking input changes\n" {
+		t.Errorf("same revision: %v\n%s", err, out.String())
+	}
+	err = runDiff(config, []string{"HEAD", "no-such-rev"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "git archive no-such-rev") {
+		t.Errorf("bad revision: got %v", err)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	got := strings.Join(lineDiff("a\nb\nc", "a\nc\nd"), "|")
+	if want := "  a|- b|  c|+ d"; got != want {
+		t.Errorf("lineDiff = %q, want %q", got, want)
+	}
+	if got := lineDiff("", "x"); len(got) != 1 || got[0] != "+ x" {
+		t.Errorf("lineDiff from empty = %q", got)
+	}
+}
+
+func writeFile(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/internal/cast"
+)
+
+// directiveVars collects variable type hints and required flags from message directives.
+type directiveVars struct {
+	hints    map[string]string
+	required map[string]bool
+}
+
+func (c *directiveVars) set(name, typ string, optional bool) {
+	if cur, ok := c.hints[name]; !ok || cur == "" {
+		c.hints[name] = typ
+	}
+	if !optional {
+		c.required[name] = true
+	}
+}
+
+func (c *directiveVars) addWhen(expr string, optional, inLoop bool) error {
+	vars, err := prompty.WhenVariables(expr)
+	if err != nil {
+		return fmt.Errorf("when %q: %w", expr, err)
+	}
+	for name, typ := range vars {
+		if inLoop && (name == "item" || name == "index") {
+			continue
+		}
+		c.set(name, typ, optional)
+	}
+	return nil
+}
+
+func (c *directiveVars) addMessage(m prompty.MessageTemplate, optional, inLoop bool) error {
+	if m.ForEach != "" {
+		list, err := prompty.WhenVariables(m.ForEach)
+		if err != nil {
+			return fmt.Errorf("for_each %q: %w", m.ForEach, err)
+		}
+		for name, typ := range list {
+			if typ == "boolean" {
+				typ = "array" // a bare variable: the list itself
+			}
+			c.set(name, typ, optional)
+		}
+	}
+	if err := c.addWhen(m.When, optional, inLoop); err != nil {
+		return err
+	}
+	for _, p := range m.Content {
+		if err := c.addWhen(p.When, optional, inLoop); err != nil {
+			return err
+		}
+	}
+	for _, turn := range m.Turns {
+		if err := c.addMessage(turn, optional, inLoop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDirectiveVars returns input schema extended with variables referenced by message/part `when`
+// conditions and `for_each` lists that input_schema does not declare, so generated Input structs can set them.
+// Condition types are inferred from usage (see prompty.WhenVariables), for_each lists are arrays; variables of
+// non-optional messages are required, as in Format. Loop-scoped item/index are not input variables.
+func withDirectiveVars(
+	schema *prompty.SchemaDefinition,
+	messages []prompty.MessageTemplate,
+) (*prompty.SchemaDefinition, error) {
+	c := directiveVars{hints: make(map[string]string), required: make(map[string]bool)}
+	for _, m := range messages {
+		if err := c.addMessage(m, m.Optional, m.ForEach != ""); err != nil {
+			return nil, err
+		}
+	}
+	hints, required := c.hints, c.required
+	if len(hints) == 0 || schema == nil || schema.Schema == nil {
+		return schema, nil
+	}
+	props, _ := schema.Schema["properties"].(map[string]any)
+	var missing []string
+	for _, name := range slices.Sorted(maps.Keys(hints)) {
+		if _, ok := props[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return schema, nil
+	}
+	out := &prompty.SchemaDefinition{
+		Name:        schema.Name,
+		Description: schema.Description,
+		Schema:      maps.Clone(schema.Schema),
+	}
+	newProps := maps.Clone(props)
+	if newProps == nil {
+		newProps = make(map[string]any, len(missing))
+	}
+	req, _ := cast.ToStringSlice(schema.Schema["required"])
+	req = slices.Clone(req)
+	for _, name := range missing {
+		prop := map[string]any{}
+		if hints[name] != "" {
+			prop["type"] = hints[name]
+		}
+		newProps[name] = prop
+		if required[name] {
+			req = append(req, name)
+		}
+	}
+	out.Schema["properties"] = newProps
+	if len(req) > 0 {
+		out.Schema["required"] = req
+	}
+	return out, nil
+}
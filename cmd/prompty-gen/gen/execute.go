@@ -0,0 +1,113 @@
+package gen
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+const promptyPkg = "github.com/skosovsky/prompty"
+
+// Option configures manifest code generation.
+type Option func(*options)
+
+type options struct {
+	execute bool
+}
+
+// WithExecute also emits Execute<Name> and Stream<Name>, which render the prompt and call a prompty.Invoker.
+// With an Output type they decode through prompty.ExecuteWithStructuredOutput / prompty.StreamStructuredOutput;
+// otherwise they return the response text.
+func WithExecute() Option {
+	return func(o *options) { o.execute = true }
+}
+
+// addExecuteHelpers appends Execute<Name> and Stream<Name> to f.
+func addExecuteHelpers(f *jen.File, rootName string, hasOutput bool) {
+	f.ImportName("iter", "iter")
+	renderName := "Render" + rootName
+	executeName := "Execute" + rootName
+	streamName := "Stream" + rootName
+	params := []jen.Code{
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("invoker").Qual(promptyPkg, "Invoker"),
+		jen.Id("input").Id(rootName + "Input"),
+	}
+	render := jen.List(jen.Id("exec"), jen.Id("err")).Op(":=").Id("p").Dot(renderName).Call(jen.Id("ctx"), jen.Id("input"))
+
+	// Both variants render inside the stream iterator and wrap invoker errors as "execute: %w"; render errors
+	// are returned as RenderXxx reports them.
+	wrapExecute := func(err jen.Code) jen.Code { return jen.Qual("fmt", "Errorf").Call(jen.Lit("execute: %w"), err) }
+
+	if hasOutput {
+		outputType := rootName + "Output"
+		f.Line()
+		f.Commentf("%s renders the prompt, calls invoker and decodes the response into %s.", executeName, outputType)
+		f.Func().Params(jen.Id("p").Op("*").Id("Prompts")).Id(executeName).Params(params...).
+			Parens(jen.List(jen.Op("*").Id(outputType), jen.Error())).Block(
+			render,
+			jen.If(jen.Id("err").Op("!=").Nil()).Block(jen.Return(jen.List(jen.Nil(), jen.Id("err")))),
+			jen.List(jen.Id("out"), jen.Id("err")).Op(":=").Qual(promptyPkg, "ExecuteWithStructuredOutput").
+				Types(jen.Id(outputType)).Call(jen.Id("ctx"), jen.Id("invoker"), jen.Id("exec")),
+			jen.If(jen.Id("err").Op("!=").Nil()).Block(jen.Return(jen.List(jen.Nil(), wrapExecute(jen.Id("err"))))),
+			jen.Return(jen.List(jen.Id("out"), jen.Nil())),
+		)
+		f.Line()
+		f.Commentf("%s renders the prompt and streams each %s decoded from the response.", streamName, outputType)
+		f.Func().Params(jen.Id("p").Op("*").Id("Prompts")).Id(streamName).Params(params...).
+			Qual("iter", "Seq2").Types(jen.Id(outputType), jen.Error()).Block(
+			jen.Return(jen.Func().Params(jen.Id("yield").Func().Params(jen.Id(outputType), jen.Error()).Bool()).Block(
+				render,
+				jen.If(jen.Id("err").Op("!=").Nil()).Block(
+					jen.Id("yield").Call(jen.Id(outputType).Values(), jen.Id("err")),
+					jen.Return(),
+				),
+				jen.For(jen.List(jen.Id("out"), jen.Id("err")).Op(":=").Range().
+					Qual(promptyPkg, "StreamStructuredOutput").Types(jen.Id(outputType)).
+					Call(jen.Id("ctx"), jen.Id("invoker"), jen.Id("exec"))).Block(
+					jen.If(jen.Id("err").Op("!=").Nil()).Block(
+						jen.Id("yield").Call(jen.Id(outputType).Values(), wrapExecute(jen.Id("err"))),
+						jen.Return(),
+					),
+					jen.If(jen.Op("!").Id("yield").Call(jen.Id("out"), jen.Nil())).Block(jen.Return()),
+				),
+			)),
+		)
+		return
+	}
+
+	f.Line()
+	f.Commentf("%s renders the prompt, calls invoker and returns the response text.", executeName)
+	f.Func().Params(jen.Id("p").Op("*").Id("Prompts")).Id(executeName).Params(params...).
+		Parens(jen.List(jen.String(), jen.Error())).Block(
+		render,
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(jen.Return(jen.List(jen.Lit(""), jen.Id("err")))),
+		jen.List(jen.Id("resp"), jen.Id("err")).Op(":=").Id("invoker").Dot("Execute").Call(jen.Id("ctx"), jen.Id("exec")),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(
+			jen.Return(jen.List(jen.Lit(""), wrapExecute(jen.Id("err")))),
+		),
+		jen.Return(jen.List(jen.Id("resp").Dot("Text").Call(), jen.Nil())),
+	)
+	f.Line()
+	f.Commentf("%s renders the prompt and streams the response text chunk by chunk.", streamName)
+	f.Func().Params(jen.Id("p").Op("*").Id("Prompts")).Id(streamName).Params(params...).
+		Qual("iter", "Seq2").Types(jen.String(), jen.Error()).Block(
+		jen.Return(jen.Func().Params(jen.Id("yield").Func().Params(jen.String(), jen.Error()).Bool()).Block(
+			render,
+			jen.If(jen.Id("err").Op("!=").Nil()).Block(
+				jen.Id("yield").Call(jen.Lit(""), jen.Id("err")),
+				jen.Return(),
+			),
+			jen.For(jen.List(jen.Id("chunk"), jen.Id("err")).Op(":=").Range().Id("invoker").Dot("ExecuteStream").
+				Call(jen.Id("ctx"), jen.Id("exec"))).Block(
+				jen.If(jen.Id("err").Op("!=").Nil()).Block(
+					jen.Id("yield").Call(jen.Lit(""), wrapExecute(jen.Id("err"))),
+					jen.Return(),
+				),
+				jen.If(jen.Id("chunk").Op("==").Nil()).Block(jen.Continue()),
+				jen.If(
+					jen.Id("text").Op(":=").Qual(promptyPkg, "TextFromParts").Call(jen.Id("chunk").Dot("Content")),
+					jen.Id("text").Op("!=").Lit("").Op("&&").Op("!").Id("yield").Call(jen.Id("text"), jen.Nil()),
+				).Block(jen.Return()),
+			),
+		)),
+	)
+}
@@ -211,6 +211,131 @@ func TestGenerateManifestTypes_WithResponseFormat(t *testing.T) {
 	if !strings.Contains(out, "(*prompty.PromptExecution, error)") {
 		t.Error("Render must return (*PromptExecution, error)")
 	}
+	if strings.Contains(out, "ExecuteWithStructuredOutput") || strings.Contains(out, "ExecuteGreeter") {
+		t.Error("Execute helpers are opt-in (WithExecute)")
+	}
+}
+
+func TestGenerateManifestTypes_WithExecute(t *testing.T) {
+	spec := greeterSpec()
+	for _, tc := range []struct {
+		name  string
+		spec  *PromptSpec
+		wants []string
+	}{
+		{"structured", spec, []string{
+			"func (p *Prompts) ExecuteGreeter(ctx context.Context, invoker prompty.Invoker, input GreeterInput) (*GreeterOutput, error)",
+			"out, err := prompty.ExecuteWithStructuredOutput[GreeterOutput](ctx, invoker, exec)",
+			"input GreeterInput) iter.Seq2[GreeterOutput, error]",
+			"for out, err := range prompty.StreamStructuredOutput[GreeterOutput](ctx, invoker, exec)",
+			`yield(GreeterOutput{}, fmt.Errorf("execute: %w", err))`,
+		}},
+		{"plain text", &PromptSpec{ID: spec.ID, InputSchema: spec.InputSchema}, []string{
+			"input GreeterInput) (string, error)",
+			"return resp.Text(), nil",
+			"input GreeterInput) iter.Seq2[string, error]",
+			"prompty.TextFromParts(chunk.Content)",
+			`yield("", fmt.Errorf("execute: %w", err))`,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := GenerateManifestTypes(tc.spec, "prompts", WithExecute())
+			if err != nil {
+				t.Fatalf("GenerateManifestTypes: %v", err)
+			}
+			var buf strings.Builder
+			if err := f.Render(&buf); err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			for _, want := range tc.wants {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateManifestTypes_Tools(t *testing.T) {
+	f, err := GenerateManifestTypes(toolsSpec(), "prompts")
+	if err != nil {
+		t.Fatalf("GenerateManifestTypes: %v", err)
+	}
+	var buf strings.Builder
+	if err := f.Render(&buf); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"type SupportAgentLookupOrderArgs struct",
+		"OrderId string `json:\"order_id\" prompt:\"order_id\" validate:\"required,min=3\"`",
+		"type SupportAgentEscalateArgs struct{}",
+		"type SupportAgentTools interface",
+		`// LookupOrder handles tool "lookup_order": Find an order by id`,
+		"LookupOrder(ctx context.Context, args SupportAgentLookupOrderArgs) (any, error)",
+		"var _ prompty.ToolValidator = (*SupportAgentToolDispatcher)(nil)",
+		"func NewSupportAgentToolDispatcher(tools SupportAgentTools) *SupportAgentToolDispatcher",
+		"func (d *SupportAgentToolDispatcher) Dispatch(ctx context.Context, call prompty.ToolCallPart) prompty.ToolResultPart",
+		`case "lookup_order":`,
+		"return d.tools.LookupOrder(ctx, args)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	spec := toolsSpec()
+	spec.Tools = append(spec.Tools, prompty.ToolDefinition{Name: "lookup-order"})
+	if _, err := GenerateManifestTypes(spec, "prompts"); err == nil || !strings.Contains(err.Error(), "both produce method LookupOrder") {
+		t.Errorf("expected method collision error, got %v", err)
+	}
+	spec = toolsSpec()
+	spec.Tools[0].Parameters = map[string]any{"type": "array", "properties": map[string]any{"x": map[string]any{}}}
+	if _, err := GenerateManifestTypes(spec, "prompts"); err == nil || !strings.Contains(err.Error(), `tool "lookup_order"`) {
+		t.Errorf("expected parameters error, got %v", err)
+	}
+}
+
+func toolsSpec() *PromptSpec {
+	spec := greeterSpec()
+	spec.ID = "support_agent"
+	spec.ResponseFormat = nil
+	spec.Tools = []prompty.ToolDefinition{
+		{
+			Name:        "lookup_order",
+			Description: "Find an order by id",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"order_id": map[string]any{"type": "string", "minLength": 3},
+					"verbose":  map[string]any{"type": "boolean"},
+				},
+				"required": []any{"order_id"},
+			},
+		},
+		{Name: "escalate"},
+	}
+	return spec
+}
+
+func greeterSpec() *PromptSpec {
+	return &PromptSpec{
+		ID: "greeter",
+		InputSchema: &prompty.SchemaDefinition{
+			Schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+				"required":   []any{"name"},
+			},
+		},
+		ResponseFormat: &prompty.SchemaDefinition{
+			Schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"message": map[string]any{"type": "string"}},
+				"required":   []any{"message"},
+			},
+		},
+	}
 }
 
 // --- task17-3: nested type naming regression tests ---
@@ -671,6 +796,228 @@ func TestGenerateManifestTypes_Default(t *testing.T) {
 	}
 }
 
+// --- JSON Schema, TypeScript and OpenAPI ---
+
+func routerSpec() *PromptSpec {
+	return &PromptSpec{
+		ID: "ticket-router",
+		InputSchema: &prompty.SchemaDefinition{
+			Description: "Ticket to route",
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"subject": map[string]any{"type": "string", "description": "Ticket subject line"},
+					"priority": map[string]any{
+						"type":    "string",
+						"enum":    []any{"low", "high"},
+						"default": "low",
+					},
+					"customer": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"id":   map[string]any{"type": "integer"},
+							"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						},
+						"required": []any{"id"},
+					},
+					"meta":      map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "number"}},
+					"user-note": map[string]any{"type": "string"},
+				},
+				"required": []any{"subject", "customer"},
+			},
+		},
+		ResponseFormat: &prompty.SchemaDefinition{
+			Name: "route",
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"queue": map[string]any{"type": "string"},
+					"steps": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type":       "object",
+							"properties": map[string]any{"action": map[string]any{"type": "string"}},
+						},
+					},
+					"urgent": map[string]any{"type": "boolean"},
+				},
+				"required": []any{"queue", "urgent"},
+			},
+		},
+	}
+}
+
+// treeSpec has a recursive response_format: $defs with a self-referencing node, a ref to the root, unions and
+// nullable types.
+func treeSpec() *PromptSpec {
+	return &PromptSpec{
+		ID: "tree",
+		ResponseFormat: &prompty.SchemaDefinition{
+			Schema: map[string]any{
+				"$defs": map[string]any{
+					"node": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"label":    map[string]any{"type": "string"},
+							"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/node"}},
+							"note":     map[string]any{"type": []any{"string", "null"}},
+							"value": map[string]any{"oneOf": []any{
+								map[string]any{"type": "number"},
+								map[string]any{"type": "object", "properties": map[string]any{"unit": map[string]any{"type": "string"}}},
+							}},
+						},
+						"required": []any{"label"},
+					},
+					"label": map[string]any{"type": "string", "enum": []any{"a", "b"}},
+				},
+				"type": "object",
+				"properties": map[string]any{
+					"root":   map[string]any{"$ref": "#/$defs/node"},
+					"parent": map[string]any{"anyOf": []any{map[string]any{"$ref": "#"}, map[string]any{"type": "null"}}},
+					"tags":   map[string]any{"type": []any{"array", "null"}, "items": map[string]any{"$ref": "#/$defs/label"}},
+				},
+				"required": []any{"root"},
+			},
+		},
+	}
+}
+
+func TestGenerateJSONSchemas(t *testing.T) {
+	input, output, err := GenerateJSONSchemas(routerSpec())
+	if err != nil {
+		t.Fatalf("GenerateJSONSchemas: %v", err)
+	}
+	for _, want := range []string{
+		`"$schema": "https://json-schema.org/draft/2020-12/schema"`,
+		`"title": "TicketRouterInput"`,
+		`"description": "Ticket to route"`,
+	} {
+		if !strings.Contains(string(input), want) {
+			t.Errorf("input schema missing %s:\n%s", want, input)
+		}
+	}
+	if !strings.Contains(string(output), `"title": "TicketRouterOutput"`) {
+		t.Errorf("output schema:\n%s", output)
+	}
+
+	input, output, err = GenerateJSONSchemas(&PromptSpec{ID: "plain"})
+	if err != nil || output != nil || !strings.Contains(string(input), `"type": "object"`) {
+		t.Errorf("no schemas: input=%s output=%s err=%v", input, output, err)
+	}
+	bad := &PromptSpec{ID: "bad", InputSchema: &prompty.SchemaDefinition{Schema: map[string]any{"type": "string"}}}
+	if _, _, err := GenerateJSONSchemas(bad); err == nil {
+		t.Error("non-object input schema must fail")
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	data, err := GenerateTypeScript([]*PromptSpec{routerSpec(), greeterSpec()})
+	if err != nil {
+		t.Fatalf("GenerateTypeScript: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`export type PromptID = "greeter" | "ticket-router";`,
+		"export interface TicketRouterInput {\n",
+		"  customer: TicketRouterInputCustomer;\n",
+		"  meta?: Record<string, number>;\n",
+		"  /** @default \"low\" */\n  priority?: \"low\" | \"high\";\n",
+		`  "user-note"?: string;`,
+		"  tags?: string[];\n",
+		"  steps?: TicketRouterOutputStepsItem[];\n",
+		"export interface TicketRouterOutputStepsItem {\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "GreeterInput") > strings.Index(out, "TicketRouterInput") {
+		t.Error("specs must be sorted by id")
+	}
+
+	data, err = GenerateTypeScript([]*PromptSpec{{
+		ID:             "lister",
+		ResponseFormat: &prompty.SchemaDefinition{Schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"}}},
+	}})
+	if err != nil {
+		t.Fatalf("GenerateTypeScript: %v", err)
+	}
+	for _, want := range []string{
+		"export type ListerInput = Record<string, never>;\n",
+		"export type ListerOutput = string[];\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestGenerateTypeScript_RefsAndUnions(t *testing.T) {
+	data, err := GenerateTypeScript([]*PromptSpec{treeSpec()})
+	if err != nil {
+		t.Fatalf("GenerateTypeScript: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"  root: TreeOutputNode;\n",
+		"  parent?: TreeOutput | null;\n",
+		"  tags?: TreeOutputLabel[] | null;\n",
+		"export interface TreeOutputNode {\n",
+		"  children?: TreeOutputNode[];\n",
+		"  note?: string | null;\n",
+		"  value?: number | TreeOutputNodeValueOption2;\n",
+		"export interface TreeOutputNodeValueOption2 {\n",
+		"export type TreeOutputLabel = \"a\" | \"b\";\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "unknown") || strings.Count(out, "export interface TreeOutputNode {") != 1 {
+		t.Errorf("every type must be declared once:\n%s", out)
+	}
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	data, err := GenerateOpenAPI("prompts", []*PromptSpec{routerSpec(), greeterSpec()})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI: %v", err)
+	}
+	for _, want := range []string{
+		`"openapi": "3.1.0"`,
+		`"GreeterOutput": {`,
+		`"TicketRouterInput": {`,
+		`"x-prompty-id": "ticket-router"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output missing %s:\n%s", want, data)
+		}
+	}
+	data, err = GenerateOpenAPI("prompts", []*PromptSpec{treeSpec()})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI: %v", err)
+	}
+	for _, want := range []string{
+		`"TreeOutputNode": {`,
+		`"TreeOutputLabel": {`,
+		`"$ref": "#/components/schemas/TreeOutputNode"`,
+		`"$ref": "#/components/schemas/TreeOutputLabel"`,
+		`"$ref": "#/components/schemas/TreeOutput"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output missing %s:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "$defs") {
+		t.Errorf("$defs must move to components.schemas:\n%s", data)
+	}
+	dup := greeterSpec()
+	dup.ID = "Greeter"
+	if _, err := GenerateOpenAPI("prompts", []*PromptSpec{greeterSpec(), dup}); err == nil {
+		t.Error("schema name collision must fail")
+	}
+}
+
 // --- Golden test ---
 
 func TestGenerate_Golden(t *testing.T) {
@@ -718,6 +1065,25 @@ func TestGenerate_Golden(t *testing.T) {
 	}
 	constsPath := filepath.Join(goldenFlag(), "consts_gen.go.golden")
 	writeGolden(t, consts, constsPath)
+
+	// Execute helpers
+	execute, err := GenerateManifestTypes(greeterSpec(), "prompts", WithExecute())
+	if err != nil {
+		t.Fatalf("GenerateManifestTypes: %v", err)
+	}
+	writeGolden(t, execute, filepath.Join(goldenFlag(), "greeter_execute_gen.go.golden"))
+
+	// Tools
+	tools, err := GenerateManifestTypes(toolsSpec(), "prompts")
+	if err != nil {
+		t.Fatalf("GenerateManifestTypes: %v", err)
+	}
+	writeGolden(t, tools, filepath.Join(goldenFlag(), "support_agent_tools_gen.go.golden"))
+
+	// JSON Schema, TypeScript, OpenAPI
+	for name, data := range schemaGoldens(t) {
+		writeGoldenBytes(t, data, filepath.Join(goldenFlag(), name))
+	}
 }
 
 // TestGenerate_GoldenCompare compares generated output to golden files (regression test).
@@ -773,6 +1139,51 @@ func TestGenerate_GoldenCompare(t *testing.T) {
 		_ = f.Render(&b)
 		return b.String(), nil
 	})
+	compareGolden(t, goldenDir, "greeter_execute_gen.go.golden", func() (string, error) {
+		f, err := GenerateManifestTypes(greeterSpec(), "prompts", WithExecute())
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		_ = f.Render(&b)
+		return b.String(), nil
+	})
+	compareGolden(t, goldenDir, "support_agent_tools_gen.go.golden", func() (string, error) {
+		f, err := GenerateManifestTypes(toolsSpec(), "prompts")
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		_ = f.Render(&b)
+		return b.String(), nil
+	})
+	for name, data := range schemaGoldens(t) {
+		compareGolden(t, goldenDir, name, func() (string, error) { return string(data), nil })
+	}
+}
+
+// schemaGoldens generates the JSON Schema, TypeScript and OpenAPI golden files by name.
+func schemaGoldens(t *testing.T) map[string][]byte {
+	t.Helper()
+	input, output, err := GenerateJSONSchemas(routerSpec())
+	if err != nil {
+		t.Fatalf("GenerateJSONSchemas: %v", err)
+	}
+	specs := []*PromptSpec{routerSpec(), greeterSpec(), treeSpec()}
+	ts, err := GenerateTypeScript(specs)
+	if err != nil {
+		t.Fatalf("GenerateTypeScript: %v", err)
+	}
+	openapi, err := GenerateOpenAPI("prompts", specs)
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI: %v", err)
+	}
+	return map[string][]byte{
+		"ticket_router.input.schema.json.golden":  input,
+		"ticket_router.output.schema.json.golden": output,
+		"prompts.gen.ts.golden":                   ts,
+		"prompts.openapi.json.golden":             openapi,
+	}
 }
 
 func compareGolden(t *testing.T, dir, name string, gen func() (string, error)) {
@@ -817,3 +1228,11 @@ func writeGolden(t *testing.T, f *jen.File, path string) {
 	}
 	t.Logf("wrote %s", path)
 }
+
+func writeGoldenBytes(t *testing.T, data []byte, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Logf("wrote %s", path)
+}
@@ -0,0 +1,183 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// jsonSchemaDialect is the $schema of standalone JSON Schema files (OpenAPI 3.1 uses the same dialect).
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateJSONSchemas produces standalone JSON Schema documents for the manifest input and response format.
+// Output is nil when the manifest has no response_format schema. Each document gets $schema and a title
+// (the Go type name, e.g. SupportAgentInput) unless the schema sets its own.
+func GenerateJSONSchemas(spec *PromptSpec) ([]byte, []byte, error) {
+	docs, err := schemaDocuments(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	var out [2][]byte
+	for i, doc := range docs {
+		if doc.schema == nil {
+			continue
+		}
+		schema := maps.Clone(doc.schema)
+		schema["$schema"] = jsonSchemaDialect
+		if out[i], err = marshalDocument(schema); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", doc.name, err)
+		}
+	}
+	return out[0], out[1], nil
+}
+
+// GenerateOpenAPI produces an OpenAPI 3.1 document whose components.schemas hold the input and response format
+// of every manifest, keyed by Go type name and tagged with x-prompty-id. Specs are sorted by id.
+// $defs entries become components of their own, named like the TypeScript types ({Root}{Name}, e.g.
+// TreeOutputNode), and local $refs are rewritten to point into components.schemas.
+func GenerateOpenAPI(title string, specs []*PromptSpec) ([]byte, error) {
+	schemas := make(map[string]any)
+	add := func(id, name string, schema map[string]any) error {
+		if _, ok := schemas[name]; ok {
+			return fmt.Errorf("manifest %q: schema name %q collision", id, name)
+		}
+		schema["x-prompty-id"] = id
+		schemas[name] = schema
+		return nil
+	}
+	for _, spec := range sortedSpecs(specs) {
+		docs, err := schemaDocuments(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if doc.schema == nil {
+				continue
+			}
+			defs, _ := doc.schema["$defs"].(map[string]any)
+			rewrite := componentRefs(doc.name, defs)
+			root, _ := rewriteRefs(doc.schema, rewrite).(map[string]any)
+			delete(root, "$defs")
+			if err := add(spec.ID, doc.name, root); err != nil {
+				return nil, err
+			}
+			for _, name := range sortedKeys(defs) {
+				def, _ := rewriteRefs(defs[name], rewrite).(map[string]any)
+				if def == nil {
+					continue
+				}
+				if err := add(spec.ID, doc.name+pascal(name), def); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return marshalDocument(map[string]any{
+		"openapi":    "3.1.0",
+		"info":       map[string]any{"title": title, "version": "1.0.0"},
+		"components": map[string]any{"schemas": schemas},
+	})
+}
+
+// componentRefs returns the $ref rewrite for a root schema stored as components.schemas[name]: "#/$defs/{def}..."
+// points to the component of the def, other local pointers ("#", "#/properties/...") into the root component.
+// Refs that are not local are kept.
+func componentRefs(name string, defs map[string]any) func(string) string {
+	const components = "#/components/schemas/"
+	return func(ref string) string {
+		if rest, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+			def, tail, _ := strings.Cut(rest, "/")
+			if _, ok := defs[def]; ok {
+				if tail != "" {
+					tail = "/" + tail
+				}
+				return components + name + pascal(def) + tail
+			}
+		}
+		if pointer, ok := strings.CutPrefix(ref, "#"); ok && (pointer == "" || strings.HasPrefix(pointer, "/")) {
+			return components + name + pointer
+		}
+		return ref
+	}
+}
+
+// rewriteRefs returns a deep copy of the schema value v with every "$ref" string passed through rewrite.
+func rewriteRefs(v any, rewrite func(string) string) any {
+	switch x := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for key, value := range x {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				out[key] = rewrite(ref)
+				continue
+			}
+			out[key] = rewriteRefs(value, rewrite)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, value := range x {
+			out[i] = rewriteRefs(value, rewrite)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// schemaDocument is a root schema named like its Go type.
+type schemaDocument struct {
+	name   string
+	schema map[string]any
+}
+
+// schemaDocuments returns copies of the input and response format schemas with title and description filled
+// from the manifest. A manifest without input_schema gets an empty object; the response format is nil when absent.
+func schemaDocuments(spec *PromptSpec) ([2]schemaDocument, error) {
+	rootName := toPascal(spec.ID)
+	docs := [2]schemaDocument{{name: rootName + "Input"}, {name: rootName + "Output"}}
+
+	docs[0].schema = map[string]any{"type": jsonSchemaTypeObject}
+	if spec.InputSchema != nil && spec.InputSchema.Schema != nil {
+		if err := validateObjectSchemaForInput(spec.InputSchema.Schema); err != nil {
+			return docs, fmt.Errorf("input schema: %w", err)
+		}
+		docs[0].schema = maps.Clone(spec.InputSchema.Schema)
+		setDefault(docs[0].schema, "description", spec.InputSchema.Description)
+	}
+	if spec.ResponseFormat != nil && spec.ResponseFormat.Schema != nil {
+		docs[1].schema = maps.Clone(spec.ResponseFormat.Schema)
+		setDefault(docs[1].schema, "description", spec.ResponseFormat.Description)
+	}
+	for _, doc := range docs {
+		if doc.schema != nil {
+			setDefault(doc.schema, "title", doc.name)
+		}
+	}
+	return docs, nil
+}
+
+// setDefault sets schema[key] to value unless value is empty or the key is already set.
+func setDefault(schema map[string]any, key, value string) {
+	if _, ok := schema[key]; !ok && value != "" {
+		schema[key] = value
+	}
+}
+
+// marshalDocument encodes v as indented JSON (object keys sorted) with a trailing newline.
+func marshalDocument(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// sortedSpecs returns specs ordered by id for deterministic package-wide output.
+func sortedSpecs(specs []*PromptSpec) []*PromptSpec {
+	out := slices.Clone(specs)
+	slices.SortFunc(out, func(a, b *PromptSpec) int { return strings.Compare(a.ID, b.ID) })
+	return out
+}
@@ -23,10 +23,11 @@ const (
 type schemaMapper struct {
 	rootName string
 	types    map[string]jen.Code
+	ts       *tsScope // TypeScript declarations of the root schema being written (writeTypeScript)
 }
 
 func newSchemaMapper(rootName string) *schemaMapper {
-	return &schemaMapper{rootName: rootName, types: make(map[string]jen.Code)}
+	return &schemaMapper{rootName: rootName, types: make(map[string]jen.Code), ts: nil}
 }
 
 // pascal converts snake_case and kebab-case to PascalCase.
@@ -7,4 +7,5 @@ type PromptSpec struct {
 	ID             string
 	InputSchema    *prompty.SchemaDefinition
 	ResponseFormat *prompty.SchemaDefinition
+	Tools          []prompty.ToolDefinition
 }
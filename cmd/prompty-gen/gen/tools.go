@@ -0,0 +1,193 @@
+package gen
+
+import (
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/skosovsky/prompty"
+)
+
+// toolNames holds the generated identifiers of one manifest tool.
+type toolNames struct {
+	tool   prompty.ToolDefinition
+	method string // handler method, e.g. LookupOrder
+	args   string // argument struct, e.g. SupportAgentLookupOrderArgs
+}
+
+// addToolTypes appends, for a manifest with tools, one argument struct per tool, the <Name>Tools handler
+// interface and the <Name>ToolDispatcher that decodes ToolCallPart.Args and calls the handler. The dispatcher
+// implements prompty.ToolValidator (arguments must decode and pass the generated validate tags).
+func addToolTypes(f *jen.File, m *schemaMapper, id, rootName string, tools []prompty.ToolDefinition) error {
+	if len(tools) == 0 {
+		return nil
+	}
+	names := make([]toolNames, 0, len(tools))
+	seen := make(map[string]string) // method -> tool name
+	for _, tool := range tools {
+		method := toPascal(tool.Name)
+		if method == "" || method[0] >= '0' && method[0] <= '9' {
+			return fmt.Errorf("tool %q produces invalid Go identifier %q", tool.Name, method)
+		}
+		if prev, ok := seen[method]; ok {
+			return fmt.Errorf("tools %q and %q both produce method %s", prev, tool.Name, method)
+		}
+		seen[method] = tool.Name
+		n := toolNames{tool: tool, method: method, args: rootName + method + "Args"}
+		if err := addToolArgs(f, m, n); err != nil {
+			return fmt.Errorf("tool %q: %w", tool.Name, err)
+		}
+		names = append(names, n)
+	}
+
+	f.ImportName("encoding/json", "json")
+	f.ImportName("strings", "strings")
+	toolsName := rootName + "Tools"
+	dispatcherName := rootName + "ToolDispatcher"
+	ctxParam := jen.Id("ctx").Qual("context", "Context")
+
+	methods := make([]jen.Code, 0, len(names))
+	for _, n := range names {
+		doc := fmt.Sprintf("%s handles tool %q.", n.method, n.tool.Name)
+		if n.tool.Description != "" {
+			doc = fmt.Sprintf("%s handles tool %q: %s", n.method, n.tool.Name, n.tool.Description)
+		}
+		methods = append(methods, jen.Comment(doc))
+		methods = append(methods, jen.Id(n.method).Params(ctxParam, jen.Id("args").Id(n.args)).
+			Parens(jen.List(jen.Id("any"), jen.Error())))
+	}
+	f.Commentf("%s handles the tool calls of prompt %q. A string result is sent as is, others as JSON.", toolsName, id)
+	f.Type().Id(toolsName).Interface(methods...)
+	f.Line()
+
+	f.Commentf("%s decodes tool calls into typed arguments and calls %s.", dispatcherName, toolsName)
+	f.Comment("It implements prompty.ToolValidator for prompty.ExecuteWithToolValidation.")
+	f.Type().Id(dispatcherName).Struct(jen.Id("tools").Id(toolsName))
+	f.Line()
+
+	f.Var().Id("_").Qual(promptyPkg, "ToolValidator").Op("=").Parens(jen.Op("*").Id(dispatcherName)).Call(jen.Nil())
+	f.Line()
+
+	f.Commentf("New%s returns a dispatcher that calls tools.", dispatcherName)
+	f.Func().Id("New" + dispatcherName).Params(jen.Id("tools").Id(toolsName)).Op("*").Id(dispatcherName).Block(
+		jen.Return(jen.Op("&").Id(dispatcherName).Values(jen.Dict{jen.Id("tools"): jen.Id("tools")})),
+	)
+	f.Line()
+
+	recv := jen.Id("d").Op("*").Id(dispatcherName)
+	f.Comment("ValidateToolCall implements prompty.ToolValidator: the tool must be defined and its arguments must decode")
+	f.Comment("and pass validation.")
+	f.Func().Params(recv.Clone()).Id("ValidateToolCall").Params(jen.Id("name"), jen.Id("argsJSON").String()).Error().Block(
+		jen.List(jen.Id("_"), jen.Id("err")).Op(":=").Id("d").Dot("bind").Call(jen.Id("name"), jen.Id("argsJSON")),
+		jen.Return(jen.Id("err")),
+	)
+	f.Line()
+
+	f.Comment("Call decodes argsJSON for tool name and calls the handler.")
+	f.Func().Params(recv.Clone()).Id("Call").Params(ctxParam, jen.Id("name"), jen.Id("argsJSON").String()).
+		Parens(jen.List(jen.Id("any"), jen.Error())).Block(
+		jen.List(jen.Id("run"), jen.Id("err")).Op(":=").Id("d").Dot("bind").Call(jen.Id("name"), jen.Id("argsJSON")),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(jen.Return(jen.List(jen.Nil(), jen.Id("err")))),
+		jen.Return(jen.Id("run").Call(jen.Id("ctx"))),
+	)
+	f.Line()
+
+	errorResult := func(errExpr jen.Code) jen.Code {
+		return jen.Return(jen.Qual(promptyPkg, "ToolResultPart").Values(jen.Dict{
+			jen.Id("ToolCallID"): jen.Id("call").Dot("ID"),
+			jen.Id("Name"):       jen.Id("call").Dot("Name"),
+			jen.Id("Content"): jen.Index().Qual(promptyPkg, "ContentPart").Values(
+				jen.Qual(promptyPkg, "TextPart").Values(jen.Dict{jen.Id("Text"): errExpr}),
+			),
+			jen.Id("IsError"): jen.True(),
+		}))
+	}
+	f.Comment("Dispatch runs call and returns its tool result; decoding, validation and handler errors become an")
+	f.Comment("error result the model can correct.")
+	f.Func().Params(recv.Clone()).Id("Dispatch").Params(ctxParam, jen.Id("call").Qual(promptyPkg, "ToolCallPart")).
+		Qual(promptyPkg, "ToolResultPart").Block(
+		jen.Id("args").Op(":=").Id("call").Dot("Args"),
+		jen.If(jen.Id("args").Op("==").Lit("")).Block(jen.Id("args").Op("=").Id("call").Dot("ArgsChunk")),
+		jen.List(jen.Id("result"), jen.Id("err")).Op(":=").Id("d").Dot("Call").
+			Call(jen.Id("ctx"), jen.Id("call").Dot("Name"), jen.Id("args")),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(errorResult(jen.Id("err").Dot("Error").Call())),
+		jen.List(jen.Id("text"), jen.Id("ok")).Op(":=").Id("result").Assert(jen.String()),
+		jen.If(jen.Op("!").Id("ok")).Block(
+			jen.List(jen.Id("data"), jen.Id("err")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("result")),
+			jen.If(jen.Id("err").Op("!=").Nil()).Block(
+				errorResult(jen.Qual("fmt", "Sprintf").Call(jen.Lit("encode result: %v"), jen.Id("err"))),
+			),
+			jen.Id("text").Op("=").String().Call(jen.Id("data")),
+		),
+		jen.Return(jen.Qual(promptyPkg, "ToolResultPart").Values(jen.Dict{
+			jen.Id("ToolCallID"): jen.Id("call").Dot("ID"),
+			jen.Id("Name"):       jen.Id("call").Dot("Name"),
+			jen.Id("Content"): jen.Index().Qual(promptyPkg, "ContentPart").Values(
+				jen.Qual(promptyPkg, "TextPart").Values(jen.Dict{jen.Id("Text"): jen.Id("text")}),
+			),
+		})),
+	)
+	f.Line()
+
+	cases := make([]jen.Code, 0, len(names)+1)
+	for _, n := range names {
+		cases = append(cases, jen.Case(jen.Lit(n.tool.Name)).Block(
+			jen.Var().Id("args").Id(n.args),
+			jen.If(
+				jen.Id("err").Op(":=").Qual("encoding/json", "Unmarshal").
+					Call(jen.Index().Byte().Parens(jen.Id("argsJSON")), jen.Op("&").Id("args")),
+				jen.Id("err").Op("!=").Nil(),
+			).Block(jen.Return(jen.List(jen.Nil(), jen.Id("argsErr").Call(jen.Id("name"), jen.Id("err"))))),
+			jen.If(
+				jen.Id("err").Op(":=").Id("validate").Dot("Struct").Call(jen.Op("&").Id("args")),
+				jen.Id("err").Op("!=").Nil(),
+			).Block(jen.Return(jen.List(jen.Nil(), jen.Id("argsErr").Call(jen.Id("name"), jen.Id("err"))))),
+			jen.Return(jen.List(
+				jen.Func().Params(ctxParam.Clone()).Parens(jen.List(jen.Id("any"), jen.Error())).Block(
+					jen.Return(jen.Id("d").Dot("tools").Dot(n.method).Call(jen.Id("ctx"), jen.Id("args"))),
+				),
+				jen.Nil(),
+			)),
+		))
+	}
+	cases = append(cases, jen.Default().Block(jen.Return(jen.List(
+		jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("tool %q is not defined"), jen.Id("name")),
+	))))
+	f.Comment("bind decodes and validates argsJSON (empty means {}) and returns the handler call for those arguments.")
+	f.Func().Params(recv.Clone()).Id("bind").Params(jen.Id("name"), jen.Id("argsJSON").String()).
+		Parens(jen.List(jen.Func().Params(jen.Qual("context", "Context")).Parens(jen.List(jen.Id("any"), jen.Error())),
+			jen.Error())).Block(
+		jen.Id("argsErr").Op(":=").Func().Params(jen.Id("name").String(), jen.Id("err").Error()).Error().Block(
+			jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("tool %q arguments: %w"), jen.Id("name"), jen.Id("err"))),
+		),
+		jen.If(jen.Qual("strings", "TrimSpace").Call(jen.Id("argsJSON")).Op("==").Lit("")).Block(
+			jen.Id("argsJSON").Op("=").Lit("{}"),
+		),
+		jen.Switch(jen.Id("name")).Block(cases...),
+	)
+	f.Line()
+	return nil
+}
+
+// addToolArgs appends the argument struct of one tool; tools without object properties get an empty struct.
+func addToolArgs(f *jen.File, m *schemaMapper, n toolNames) error {
+	f.Commentf("%s are the arguments of tool %q.", n.args, n.tool.Name)
+	props, _ := n.tool.Parameters["properties"].(map[string]any)
+	if len(props) == 0 {
+		f.Type().Id(n.args).Struct()
+		f.Line()
+		return nil
+	}
+	if err := validateObjectSchemaForInput(n.tool.Parameters); err != nil {
+		return fmt.Errorf("parameters: %w", err)
+	}
+	stmts, err := m.GenerateTypes(n.tool.Parameters, n.method+"Args")
+	if err != nil {
+		return fmt.Errorf("parameters: %w", err)
+	}
+	for _, stmt := range stmts {
+		f.Add(stmt)
+		f.Line()
+	}
+	return nil
+}
@@ -30,7 +30,13 @@ func isStructSchema(rf *prompty.SchemaDefinition) bool {
 // GenerateManifestTypes produces a per-manifest _gen.go file.
 // Contains: const PromptID, Input/Output types, Render<Name>(ctx, input) (*prompty.PromptExecution, error).
 // Output type is generated only when response_format has object schema with properties.
-func GenerateManifestTypes(spec *PromptSpec, pkgName string) (*jen.File, error) {
+// Manifests with tools also get argument structs, a <Name>Tools interface and a dispatcher (see addToolTypes).
+// WithExecute adds Execute<Name>/Stream<Name> (see addExecuteHelpers).
+func GenerateManifestTypes(spec *PromptSpec, pkgName string, opts ...Option) (*jen.File, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	f := jen.NewFile(pkgName)
 	f.HeaderComment("Code generated by prompty-gen. DO NOT EDIT.")
 
@@ -78,6 +84,10 @@ func GenerateManifestTypes(spec *PromptSpec, pkgName string) (*jen.File, error)
 		}
 	}
 
+	if err := addToolTypes(f, m, spec.ID, rootName, spec.Tools); err != nil {
+		return nil, err
+	}
+
 	// func (p *Prompts) Render<Name>(ctx, input) (*prompty.PromptExecution, error)
 	renderName := "Render" + rootName
 	varsBlocks, propsCount := buildVarsBlocks(spec)
@@ -111,6 +121,9 @@ func GenerateManifestTypes(spec *PromptSpec, pkgName string) (*jen.File, error)
 		jen.Id("input").Id(inputType),
 	).Parens(jen.List(jen.Op("*").Qual("github.com/skosovsky/prompty", "PromptExecution"), jen.Error())).Block(body...)
 
+	if o.execute {
+		addExecuteHelpers(f, rootName, hasOutputSchema)
+	}
 	return f, nil
 }
 
@@ -0,0 +1,292 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// tsIdentRe matches property keys that need no quotes in a TypeScript interface.
+var tsIdentRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`) //nolint:gochecknoglobals // compiled once, read-only
+
+// GenerateTypeScript produces one TypeScript module with the input and response format types of every manifest.
+// Type names match the Go output (SupportAgentInput, SupportAgentInputPatient, SupportAgentOutput); objects with
+// properties become interfaces, optional properties get ?, description and default go into JSDoc. The module also
+// exports the PromptID union and allPromptIDs. Specs are sorted by id.
+func GenerateTypeScript(specs []*PromptSpec) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("// Code generated by prompty-gen. DO NOT EDIT.\n\n")
+
+	sorted := sortedSpecs(specs)
+	ids := make([]string, 0, len(sorted))
+	for _, spec := range sorted {
+		ids = append(ids, tsLiteral(spec.ID))
+	}
+	if len(ids) == 0 {
+		b.WriteString("export type PromptID = never;\n\n")
+	} else {
+		fmt.Fprintf(&b, "export type PromptID = %s;\n\n", strings.Join(ids, " | "))
+	}
+	fmt.Fprintf(&b, "export const allPromptIDs: readonly PromptID[] = [%s];\n", strings.Join(ids, ", "))
+
+	for _, spec := range sorted {
+		docs, err := schemaDocuments(spec)
+		if err != nil {
+			return nil, fmt.Errorf("manifest %q: %w", spec.ID, err)
+		}
+		m := newSchemaMapper(toPascal(spec.ID))
+		for i, kind := range []string{"Input", "Output"} {
+			if docs[i].schema == nil {
+				continue
+			}
+			if err := m.writeTypeScript(&b, docs[i].schema, kind, spec.ID); err != nil {
+				return nil, fmt.Errorf("manifest %q: %w", spec.ID, err)
+			}
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// tsScope tracks the declarations of one root schema: named types already written and types referenced by
+// $ref, oneOf/anyOf branches or type unions that collectTypeSpecs does not reach, written after the others.
+type tsScope struct {
+	root     map[string]any
+	kind     string // "Input" or "Output"
+	declared map[string]bool
+	pending  []typeSpec
+}
+
+// writeTypeScript writes the declarations of one root schema: the root type, then nested types in GenerateTypes
+// order, then $defs and union branch types in order of first reference.
+func (m *schemaMapper) writeTypeScript(b *strings.Builder, schema map[string]any, kind, id string) error {
+	root := m.typeName(kind)
+	m.ts = &tsScope{root: schema, kind: kind, declared: map[string]bool{root: true}, pending: nil}
+	defer func() { m.ts = nil }()
+	b.WriteString("\n")
+	if kind == "Input" {
+		fmt.Fprintf(b, "/** Input variables of prompt %s. */\n", tsLiteral(id))
+	} else {
+		fmt.Fprintf(b, "/** Structured response of prompt %s. */\n", tsLiteral(id))
+	}
+	var specs []typeSpec
+	if err := m.collectTypeSpecs(schema, kind, &specs, make(map[string]string)); err != nil {
+		return err
+	}
+	for _, ts := range specs {
+		m.ts.declared[ts.Name] = true
+	}
+	if typ, _ := schema["type"].(string); typ == jsonSchemaTypeObject {
+		// The root comes last in specs; print it first, right under its comment.
+		m.writeInterface(b, specs[len(specs)-1])
+		specs = specs[:len(specs)-1]
+	} else {
+		// A response format that is not an object (e.g. an array) is a type alias.
+		fmt.Fprintf(b, "export type %s = %s;\n", root, m.tsType(schema, kind))
+	}
+	for _, ts := range specs {
+		if len(ts.Props) == 0 {
+			continue // property-less objects map to Record types, the declaration would be unused
+		}
+		b.WriteString("\n")
+		m.writeInterface(b, ts)
+	}
+	for len(m.ts.pending) > 0 {
+		ts := m.ts.pending[0]
+		m.ts.pending = m.ts.pending[1:]
+		b.WriteString("\n")
+		if len(ts.Props) == 0 {
+			// A $defs entry that is not an object with properties is a type alias.
+			fmt.Fprintf(b, "export type %s = %s;\n", ts.Name, m.tsType(ts.Schema, strings.TrimPrefix(ts.Name, m.rootName)))
+			continue
+		}
+		m.writeInterface(b, ts)
+	}
+	return nil
+}
+
+// declareTS returns name, queueing the declaration of schema under it unless already declared. Only objects
+// with properties become interfaces; with alias, any other schema becomes a type alias (for $defs entries).
+func (m *schemaMapper) declareTS(name string, schema map[string]any, alias bool) string {
+	if m.ts == nil || m.ts.declared[name] {
+		return name
+	}
+	props, _ := schema["properties"].(map[string]any)
+	if typ, _ := schema["type"].(string); typ != jsonSchemaTypeObject || len(props) == 0 {
+		if !alias {
+			return name
+		}
+		props = nil
+	}
+	m.ts.declared[name] = true
+	m.ts.pending = append(m.ts.pending, typeSpec{Name: name, Schema: schema, Required: getRequired(schema), Props: props})
+	return name
+}
+
+// tsRef maps a local $ref to a type name: "#" is the root type, "#/$defs/{name}" the type {Root}{Kind}{Name}
+// (the components/schemas name GenerateOpenAPI gives it). Other refs are unknown.
+func (m *schemaMapper) tsRef(ref string) (string, bool) {
+	if m.ts == nil {
+		return "", false
+	}
+	if ref == "#" {
+		return m.typeName(m.ts.kind), true
+	}
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok || strings.Contains(name, "/") {
+		return "", false
+	}
+	defs, _ := m.ts.root["$defs"].(map[string]any)
+	def, ok := defs[name].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	return m.declareTS(m.typeName(m.ts.kind, pascal(name)), def, true), true
+}
+
+// tsUnion joins TypeScript types with |, dropping duplicates.
+func tsUnion(types []string) string {
+	var out []string
+	for _, t := range types {
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return strings.Join(out, " | ")
+}
+
+// writeInterface writes one object type; a root object without properties is an empty Record alias.
+func (m *schemaMapper) writeInterface(b *strings.Builder, ts typeSpec) {
+	if len(ts.Props) == 0 {
+		fmt.Fprintf(b, "export type %s = Record<string, never>;\n", ts.Name)
+		return
+	}
+	basePath := strings.TrimPrefix(ts.Name, m.rootName)
+	fmt.Fprintf(b, "export interface %s {\n", ts.Name)
+	for _, propName := range sortedKeys(ts.Props) {
+		propSchema, _ := ts.Props[propName].(map[string]any)
+		writeJSDoc(b, propSchema)
+		key := propName
+		if !tsIdentRe.MatchString(key) {
+			key = tsLiteral(key)
+		}
+		if !ts.Required[propName] {
+			key += "?"
+		}
+		fmt.Fprintf(b, "  %s: %s;\n", key, m.tsType(propSchema, basePath+pascal(propName)))
+	}
+	b.WriteString("}\n")
+}
+
+// writeJSDoc writes a property comment from description and default, if any.
+func writeJSDoc(b *strings.Builder, propSchema map[string]any) {
+	var lines []string
+	if desc, _ := propSchema["description"].(string); desc != "" {
+		lines = append(lines, strings.Split(strings.TrimSpace(desc), "\n")...)
+	}
+	if def, ok := propSchema["default"]; ok {
+		lines = append(lines, "@default "+tsLiteral(def))
+	}
+	switch len(lines) {
+	case 0:
+	case 1:
+		fmt.Fprintf(b, "  /** %s */\n", escapeJSDoc(lines[0]))
+	default:
+		b.WriteString("  /**\n")
+		for _, line := range lines {
+			fmt.Fprintf(b, "   * %s\n", escapeJSDoc(line))
+		}
+		b.WriteString("   */\n")
+	}
+}
+
+// escapeJSDoc keeps a comment line from closing the block comment.
+func escapeJSDoc(s string) string {
+	return strings.ReplaceAll(s, "*/", `*\/`)
+}
+
+// tsType maps a JSON Schema to a TypeScript type; path names nested object types like mapSchemaToGo does.
+// $ref, oneOf/anyOf (branches named {Path}Option{N}) and type lists such as ["string", "null"] map to named types
+// and unions.
+func (m *schemaMapper) tsType(schema map[string]any, path ...string) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if name, ok := m.tsRef(ref); ok {
+			return name
+		}
+		return "unknown"
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		vals := make([]string, 0, len(enum))
+		for _, e := range enum {
+			vals = append(vals, tsLiteral(e))
+		}
+		return strings.Join(vals, " | ")
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		branches, _ := schema[keyword].([]any)
+		if len(branches) == 0 {
+			continue
+		}
+		types := make([]string, 0, len(branches))
+		for i, branch := range branches {
+			branchSchema, _ := branch.(map[string]any)
+			types = append(types, m.tsType(branchSchema, append(slices.Clone(path), fmt.Sprintf("Option%d", i+1))...))
+		}
+		return tsUnion(types)
+	}
+	if list, ok := schema["type"].([]any); ok {
+		types := make([]string, 0, len(list))
+		for _, t := range list {
+			single := maps.Clone(schema)
+			single["type"] = t
+			types = append(types, m.tsType(single, path...))
+		}
+		return tsUnion(types)
+	}
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "null":
+		return "null"
+	case jsonSchemaTypeString:
+		return "string"
+	case jsonSchemaTypeInteger, jsonSchemaTypeNumber:
+		return "number"
+	case jsonSchemaTypeBoolean:
+		return "boolean"
+	case jsonSchemaTypeArray:
+		items, _ := schema["items"].(map[string]any)
+		elem := m.tsType(items, append(path, "Item")...)
+		if strings.Contains(elem, " | ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case jsonSchemaTypeObject:
+		props, _ := schema["properties"].(map[string]any)
+		if len(props) > 0 {
+			return m.declareTS(m.typeName(path...), schema, false)
+		}
+		// Same limitation as Go: only primitive additionalProperties produce a typed record.
+		if addl, ok := schema["additionalProperties"].(map[string]any); ok {
+			if addlTyp, _ := addl["type"].(string); addlTyp != "" && addlTyp != jsonSchemaTypeObject {
+				return "Record<string, " + m.tsType(addl, append(path, "Val")...) + ">"
+			}
+		}
+		return "Record<string, unknown>"
+	default:
+		return "unknown"
+	}
+}
+
+// tsLiteral renders a JSON value as a TypeScript literal (JSON string and number syntax is valid TypeScript).
+func tsLiteral(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "unknown"
+	}
+	return string(data)
+}
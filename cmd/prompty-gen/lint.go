@@ -0,0 +1,655 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+)
+
+// Lint severities (SARIF levels).
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// Lint output formats.
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+)
+
+// lintRule is one check of runLint; id is reported with every finding.
+type lintRule struct {
+	id          string
+	severity    string
+	description string
+}
+
+// Lint rules.
+const (
+	ruleInvalidManifest       = "invalid-manifest"
+	ruleTemplateParse         = "template-parse"
+	ruleUndeclaredVariable    = "undeclared-variable"
+	ruleUnusedInput           = "unused-input"
+	ruleRequiredDefault       = "required-with-default"
+	ruleConflictingDirectives = "conflicting-directives"
+	ruleInvalidRole           = "invalid-role"
+	ruleUnknownModelConfig    = "unknown-model-config"
+	ruleUnresolvedPartial     = "unresolved-partial"
+)
+
+// lintRules lists every rule in report order (SARIF tool.driver.rules).
+func lintRules() []lintRule {
+	return []lintRule{
+		{ruleInvalidManifest, severityError, "Manifest cannot be parsed or built"},
+		{ruleTemplateParse, severityError, "Message template does not parse"},
+		{ruleUndeclaredVariable, severityError, "Template variable is not declared in input_schema"},
+		{ruleUnusedInput, severityWarning, "input_schema property is not used by any message"},
+		{ruleRequiredDefault, severityWarning, "Required input_schema property also has a default"},
+		{ruleConflictingDirectives, severityError, "Tools and response_format cannot be combined"},
+		{ruleInvalidRole, severityError, "Message role is not system, developer, user, assistant or tool"},
+		{ruleUnknownModelConfig, severityWarning, "model_config key is not a model option"},
+		{ruleUnresolvedPartial, severityError, "Template invokes a partial that is not defined"},
+	}
+}
+
+// lintFinding is one problem found by runLint.
+type lintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// lintText is a template or a when/for_each expression of a manifest message, with the node it came from.
+type lintText struct {
+	label string
+	text  string
+	expr  bool // when/for_each expression rather than a template
+	node  *yamlv3.Node
+}
+
+// lintPartials holds the templates defined by partial files: file base names and their {{ define }} blocks.
+type lintPartials struct {
+	glob    string
+	defined map[string]bool
+}
+
+// runLint checks every manifest matched by the config queries and prints the findings (file:line positions).
+// Any finding of error severity makes it fail, so it can run as a pre-merge check.
+func runLint(configPath string, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := flags.String("format", formatText, "Output format: text, json or sarif")
+	partialsGlob := flags.String("partials", "",
+		"Glob of partial templates relative to the config (e.g. _partials/*.tmpl)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !slices.Contains([]string{formatText, formatJSON, formatSARIF}, *format) {
+		return fmt.Errorf("lint: unknown format %q (use text, json or sarif)", *format)
+	}
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return fmt.Errorf("config path: %w", err)
+	}
+	configDir := filepath.Dir(absConfig)
+	cfg, err := LoadConfig(absConfig)
+	if err != nil {
+		return err
+	}
+	partials, err := loadLintPartials(configDir, *partialsGlob)
+	if err != nil {
+		return fmt.Errorf("lint: %w", err)
+	}
+
+	findings := []lintFinding{}
+	seen := make(map[string]bool)
+	for _, pkg := range cfg.Packages {
+		files, err := pkg.ResolveSources(configDir)
+		if err != nil {
+			return fmt.Errorf("package %q: %w", pkg.Name, err)
+		}
+		for _, fpath := range files {
+			if seen[fpath] {
+				continue
+			}
+			seen[fpath] = true
+			display := fpath
+			if rel, relErr := filepath.Rel(configDir, fpath); relErr == nil {
+				display = filepath.ToSlash(rel)
+			}
+			for _, f := range lintManifest(fpath, configDir, pkg.Queries, partials) {
+				f.File = display
+				findings = append(findings, f)
+			}
+		}
+	}
+	slices.SortStableFunc(findings, func(a, b lintFinding) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+
+	if err := writeLint(stdout, *format, findings); err != nil {
+		return fmt.Errorf("lint: %w", err)
+	}
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == severityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("lint: %d errors", errorCount)
+	}
+	return nil
+}
+
+// loadLintPartials parses the partial files matched by glob (relative to configDir) and collects their names.
+func loadLintPartials(configDir, glob string) (*lintPartials, error) {
+	p := &lintPartials{glob: "", defined: make(map[string]bool)}
+	if glob == "" {
+		return p, nil
+	}
+	p.glob = filepath.Join(configDir, glob)
+	files, err := filepath.Glob(p.glob)
+	if err != nil {
+		return nil, fmt.Errorf("partials %q: %w", glob, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("partials %q match no files", glob)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		trees, err := parseLintTemplate(filepath.Base(file), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("partial %s: %w", file, err)
+		}
+		for name := range trees {
+			p.defined[name] = true
+		}
+	}
+	return p, nil
+}
+
+// parseLintTemplate parses text without function checks (functions are checked when the manifest is built).
+func parseLintTemplate(name, text string) (map[string]*parse.Tree, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+// lintManifest runs every check on one manifest file.
+func lintManifest(fpath, configDir string, queries []string, partials *lintPartials) []lintFinding {
+	var out []lintFinding
+	report := func(line int, rule, format string, args ...any) {
+		severity := severityError
+		for _, r := range lintRules() {
+			if r.id == rule {
+				severity = r.severity
+			}
+		}
+		out = append(out, lintFinding{
+			File:     fpath,
+			Line:     max(line, 1),
+			Severity: severity,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		report(1, ruleInvalidManifest, "%v", err)
+		return out
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		report(errorLine(err), ruleInvalidManifest, "%v", err)
+		return out
+	}
+	root := &doc
+	if root.Kind == yamlv3.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	u, err := manifestParser(fpath)
+	if err != nil {
+		report(1, ruleInvalidManifest, "%v", err)
+		return out
+	}
+	var raw manifest.RawManifest
+	if err := u.Unmarshal(data, &raw); err != nil {
+		report(errorLine(err), ruleInvalidManifest, "%v", err)
+		return out
+	}
+
+	_, messagesNode := mappingValue(root, "messages")
+	texts := lintMessageTexts(messagesNode)
+	lintRoles(messagesNode, report)
+	lintModelConfig(root, report)
+	lintRequiredDefaults(root, report)
+	parsed := lintTemplates(texts, partials, report)
+	if !parsed || raw.Overlay {
+		return out // overlays only build over the manifest they patch
+	}
+
+	if raw.ID == "" {
+		raw.ID = idFromRelativePath(fpath, configDir, queries)
+	}
+	var opts []manifest.ParseOption
+	if partials.glob != "" {
+		opts = append(opts, manifest.WithPartialsGlob(partials.glob))
+	}
+	tpl, err := buildManifest(&raw, u, fpath, configDir, queries, opts...)
+	if err != nil {
+		report(messageLine(messagesNode, err), ruleInvalidManifest, "%v", err)
+		return out
+	}
+	if len(tpl.Tools) > 0 && tpl.ResponseFormat != nil {
+		key, _ := mappingValue(root, "response_format")
+		report(nodeLine(key), ruleConflictingDirectives,
+			"tools and response_format cannot be used together: %v", prompty.ErrConflictingDirectives)
+	}
+	lintVariables(tpl, root, messagesNode, texts, report)
+	return out
+}
+
+// lintRoles reports message (and turn) roles that are not prompty roles.
+func lintRoles(messages *yamlv3.Node, report func(int, string, string, ...any)) {
+	if messages == nil || messages.Kind != yamlv3.SequenceNode {
+		return
+	}
+	for _, m := range messages.Content {
+		if _, role := mappingValue(m, "role"); role != nil && role.Value != "" {
+			switch prompty.Role(role.Value) {
+			case prompty.RoleSystem, prompty.RoleDeveloper, prompty.RoleUser, prompty.RoleAssistant, prompty.RoleTool:
+			default:
+				report(role.Line, ruleInvalidRole, "invalid role %q", role.Value)
+			}
+		}
+		_, turns := mappingValue(m, "turns")
+		lintRoles(turns, report)
+	}
+}
+
+// lintModelConfig reports model_config keys that DecodeModelOptions moves into provider_settings.
+func lintModelConfig(root *yamlv3.Node, report func(int, string, string, ...any)) {
+	_, cfg := mappingValue(root, "model_config")
+	if cfg == nil || cfg.Kind != yamlv3.MappingNode {
+		return
+	}
+	known := manifest.ModelOptionKeys()
+	for i := 0; i+1 < len(cfg.Content); i += 2 {
+		key := cfg.Content[i]
+		if !slices.Contains(known, key.Value) {
+			report(key.Line, ruleUnknownModelConfig,
+				"unknown model_config key %q is passed as a provider setting; move it under provider_settings", key.Value)
+		}
+	}
+}
+
+// lintRequiredDefaults reports input_schema properties that are required and have a default as well.
+func lintRequiredDefaults(root *yamlv3.Node, report func(int, string, string, ...any)) {
+	_, input := mappingValue(root, "input_schema")
+	_, schema := mappingValue(input, "schema")
+	_, props := mappingValue(schema, "properties")
+	_, requiredNode := mappingValue(schema, "required")
+	if props == nil || requiredNode == nil {
+		return
+	}
+	var required []string
+	if err := requiredNode.Decode(&required); err != nil {
+		return
+	}
+	for i := 0; i+1 < len(props.Content); i += 2 {
+		key := props.Content[i]
+		if _, def := mappingValue(props.Content[i+1], "default"); def != nil && slices.Contains(required, key.Value) {
+			report(key.Line, ruleRequiredDefault, "input %q is required but has a default", key.Value)
+		}
+	}
+}
+
+// lintTemplates parses every message template and reports parse errors and partials that do not resolve.
+// Returns false when a template does not parse.
+func lintTemplates(texts []lintText, partials *lintPartials, report func(int, string, string, ...any)) bool {
+	defined := maps.Clone(partials.defined)
+	parsed := make([]map[string]*parse.Tree, len(texts))
+	ok := true
+	for i, t := range texts {
+		if t.expr {
+			continue
+		}
+		trees, err := parseLintTemplate(t.label, t.text)
+		if err != nil {
+			line, msg := templateErrorLine(t.label, err)
+			report(textLine(t.node, line), ruleTemplateParse, "%s: %s", t.label, msg)
+			ok = false
+			continue
+		}
+		parsed[i] = trees
+		for name := range trees {
+			if name != t.label {
+				defined[name] = true // {{ define }} blocks are shared by all messages
+			}
+		}
+	}
+	for i, trees := range parsed {
+		for _, tree := range trees {
+			walkTemplateCalls(tree.Root, func(n *parse.TemplateNode) {
+				if defined[n.Name] {
+					return
+				}
+				line, _ := templateErrorLine(texts[i].label, errors.New(templateLocation(tree, n)))
+				report(textLine(texts[i].node, line), ruleUnresolvedPartial,
+					"%s: partial %q is not defined", texts[i].label, n.Name)
+			})
+		}
+	}
+	return ok
+}
+
+// lintVariables reports template variables missing from input_schema and declared inputs no message uses.
+func lintVariables(
+	tpl *prompty.ChatPromptTemplate,
+	root, messages *yamlv3.Node,
+	texts []lintText,
+	report func(int, string, string, ...any),
+) {
+	declared := make(map[string]bool)
+	var order []string
+	if tpl.InputSchema != nil {
+		props, _ := tpl.InputSchema.Schema["properties"].(map[string]any)
+		order = slices.Sorted(maps.Keys(props))
+		for _, name := range order {
+			declared[name] = true
+		}
+	}
+	schema, err := withDirectiveVars(tpl.InputSchema, tpl.Messages)
+	if err == nil && schema != nil {
+		props, _ := schema.Schema["properties"].(map[string]any)
+		for name := range props {
+			declared[name] = true // when/for_each variables are added to the generated input
+		}
+	}
+	referenced, complete := tpl.ReferencedVariables()
+	for _, name := range referenced {
+		if !declared[name] {
+			report(variableLine(texts, name, nodeLine(messages)), ruleUndeclaredVariable,
+				"variable %q is not declared in input_schema", name)
+		}
+	}
+	if !complete {
+		return // the whole payload is passed somewhere, so any input may be used
+	}
+	_, input := mappingValue(root, "input_schema")
+	_, schemaNode := mappingValue(input, "schema")
+	_, propsNode := mappingValue(schemaNode, "properties")
+	for _, name := range order {
+		if slices.Contains(referenced, name) {
+			continue
+		}
+		key, _ := mappingValue(propsNode, name)
+		if key == nil {
+			continue // inherited through extends; reported for the parent
+		}
+		report(key.Line, ruleUnusedInput, "input %q is declared but not used by any message", name)
+	}
+}
+
+// lintMessageTexts collects the templates and when/for_each expressions of messages, turns and parts.
+func lintMessageTexts(messages *yamlv3.Node) []lintText {
+	if messages == nil || messages.Kind != yamlv3.SequenceNode {
+		return nil
+	}
+	var out []lintText
+	for i, m := range messages.Content {
+		out = append(out, messageTexts(m, fmt.Sprintf("message %d", i))...)
+	}
+	return out
+}
+
+func messageTexts(m *yamlv3.Node, label string) []lintText {
+	var out []lintText
+	for _, key := range []string{"when", "for_each"} {
+		if _, v := mappingValue(m, key); v != nil && v.Kind == yamlv3.ScalarNode {
+			out = append(out, lintText{label: label + " " + key, text: v.Value, expr: true, node: v})
+		}
+	}
+	_, content := mappingValue(m, "content")
+	switch {
+	case content == nil:
+	case content.Kind == yamlv3.ScalarNode:
+		out = append(out, lintText{label: label, text: content.Value, expr: false, node: content})
+	case content.Kind == yamlv3.SequenceNode:
+		for j, part := range content.Content {
+			partLabel := fmt.Sprintf("%s part %d", label, j)
+			if part.Kind == yamlv3.ScalarNode {
+				out = append(out, lintText{label: partLabel, text: part.Value, expr: false, node: part})
+				continue
+			}
+			for _, key := range []string{"text", "url", "media_type", "mime_type", "when"} {
+				if _, v := mappingValue(part, key); v != nil && v.Kind == yamlv3.ScalarNode {
+					out = append(out, lintText{label: partLabel, text: v.Value, expr: key == "when", node: v})
+				}
+			}
+		}
+	}
+	if _, turns := mappingValue(m, "turns"); turns != nil && turns.Kind == yamlv3.SequenceNode {
+		for t, turn := range turns.Content {
+			out = append(out, messageTexts(turn, fmt.Sprintf("%s turn %d", label, t))...)
+		}
+	}
+	return out
+}
+
+// walkTemplateCalls calls fn for every {{ template }} action under node.
+func walkTemplateCalls(node parse.Node, fn func(*parse.TemplateNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkTemplateCalls(c, fn)
+		}
+	case *parse.IfNode:
+		walkTemplateCalls(n.List, fn)
+		walkTemplateCalls(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateCalls(n.List, fn)
+		walkTemplateCalls(n.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateCalls(n.List, fn)
+		walkTemplateCalls(n.ElseList, fn)
+	case *parse.TemplateNode:
+		fn(n)
+	}
+}
+
+// templateLocation formats the position of node like a text/template parse error.
+func templateLocation(tree *parse.Tree, node parse.Node) string {
+	location, _ := tree.ErrorContext(node)
+	return "template: " + location + ": "
+}
+
+// templateErrorLine extracts the line (within the template text) and message of a text/template error for name.
+func templateErrorLine(name string, err error) (int, string) {
+	msg := err.Error()
+	rest, ok := strings.CutPrefix(msg, "template: "+name+":")
+	if !ok {
+		return 1, msg
+	}
+	lineText, after, _ := strings.Cut(rest, ":")
+	line, convErr := strconv.Atoi(lineText)
+	if convErr != nil {
+		return 1, msg
+	}
+	if col, tail, found := strings.Cut(after, ":"); found {
+		if _, colErr := strconv.Atoi(col); colErr == nil {
+			after = tail // ErrorContext adds a column
+		}
+	}
+	return line, strings.TrimSpace(after)
+}
+
+// textLine maps a line within the scalar node's value to the file line. Block scalars (| and >) start on the
+// line after the indicator.
+func textLine(n *yamlv3.Node, line int) int {
+	if n == nil {
+		return 1
+	}
+	if n.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 {
+		return n.Line + line
+	}
+	return n.Line + line - 1
+}
+
+// variableLine returns the file line where name is first referenced: in a template, then in a when/for_each
+// expression, else fallback (e.g. a variable read only by a partial).
+func variableLine(texts []lintText, name string, fallback int) int {
+	quoted := regexp.QuoteMeta(name)
+	field := regexp.MustCompile(`(?:^|[^\w)\]])\.` + quoted + `\b`)
+	word := regexp.MustCompile(`\b` + quoted + `\b`)
+	for _, t := range texts {
+		if loc := field.FindStringIndex(t.text); !t.expr && loc != nil {
+			return textLine(t.node, 1+strings.Count(t.text[:loc[0]], "\n"))
+		}
+	}
+	for _, t := range texts {
+		if t.expr && word.MatchString(t.text) {
+			return textLine(t.node, 1)
+		}
+	}
+	return fallback
+}
+
+// messageLine returns the line of the message named in a build error ("message 2 ..."), or 1.
+func messageLine(messages *yamlv3.Node, err error) int {
+	m := regexp.MustCompile(`message (\d+)`).FindStringSubmatch(err.Error())
+	if m == nil || messages == nil || messages.Kind != yamlv3.SequenceNode {
+		return 1
+	}
+	i, _ := strconv.Atoi(m[1])
+	if i >= len(messages.Content) {
+		return 1
+	}
+	return messages.Content[i].Line
+}
+
+// errorLine extracts "line N" from a YAML or JSON parser error, or returns 1.
+func errorLine(err error) int {
+	m := regexp.MustCompile(`line (\d+)`).FindStringSubmatch(err.Error())
+	if m == nil {
+		return 1
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+// mappingValue returns the key and value nodes of key in mapping n, or nils.
+func mappingValue(n *yamlv3.Node, key string) (*yamlv3.Node, *yamlv3.Node) {
+	if n == nil || n.Kind != yamlv3.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i], n.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// nodeLine returns the line of n, or 1 for nil.
+func nodeLine(n *yamlv3.Node) int {
+	if n == nil {
+		return 1
+	}
+	return n.Line
+}
+
+// writeLint prints findings in format: text (file:line: severity: message [rule]), json or sarif (SARIF 2.1.0).
+func writeLint(w io.Writer, format string, findings []lintFinding) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	case formatSARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sarifLog(findings))
+	default:
+		errorCount := 0
+		for _, f := range findings {
+			_, _ = fmt.Fprintf(w, "%s:%d: %s: %s [%s]\n", f.File, f.Line, f.Severity, f.Message, f.Rule)
+			if f.Severity == severityError {
+				errorCount++
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%d errors, %d warnings\n", errorCount, len(findings)-errorCount)
+		return nil
+	}
+}
+
+// sarifLog builds a minimal SARIF 2.1.0 log with one run.
+func sarifLog(findings []lintFinding) map[string]any {
+	rules := make([]map[string]any, 0, len(lintRules()))
+	for _, r := range lintRules() {
+		rules = append(rules, map[string]any{
+			"id":                   r.id,
+			"shortDescription":     map[string]any{"text": r.description},
+			"defaultConfiguration": map[string]any{"level": r.severity},
+		})
+	}
+	results := make([]map[string]any, 0, len(findings))
+	for _, f := range findings {
+		results = append(results, map[string]any{
+			"ruleId":  f.Rule,
+			"level":   f.Severity,
+			"message": map[string]any{"text": f.Message},
+			"locations": []map[string]any{{
+				"physicalLocation": map[string]any{
+					"artifactLocation": map[string]any{"uri": f.File},
+					"region":           map[string]any{"startLine": f.Line},
+				},
+			}},
+		})
+	}
+	return map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool": map[string]any{
+				"driver": map[string]any{
+					"name":           "prompty-gen",
+					"informationUri": "https://github.com/skosovsky/prompty",
+					"rules":          rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintConfig = `version: "1"
+packages:
+  - name: prompts
+    path: gen
+    queries:
+      - "prompts/*.yaml"
+`
+
+const lintBadManifest = `id: bad
+model_config:
+  model: gpt-4o
+  temperature: 0.2
+  reasoning_effort: high
+input_schema:
+  name: bad_input
+  schema:
+    type: object
+    properties:
+      name:
+        type: string
+      tone:
+        type: string
+        default: friendly
+      unused:
+        type: string
+    required: [name, tone]
+tools:
+  - name: search
+    description: Search
+    parameters:
+      type: object
+response_format:
+  name: answer
+  schema:
+    type: object
+messages:
+  - role: system
+    content: |
+      You are helpful.
+      Speak {{ .tone }} to {{ .name }}.
+      Mention {{ .topic }}.
+  - role: robot
+    content: Hi
+`
+
+const lintPartialManifest = `id: partial
+input_schema:
+  name: partial_input
+  schema:
+    type: object
+messages:
+  - role: user
+    content:
+      - type: text
+        text: "{{ template \"sig\" . }}"
+      - type: text
+        text: |
+          Thanks.
+          {{ template "footer" . }}
+`
+
+const lintGoodManifest = `id: good
+input_schema:
+  name: good_input
+  schema:
+    type: object
+    properties:
+      name:
+        type: string
+      items:
+        type: array
+    required: [name]
+messages:
+  - role: system
+    content: |
+      {{ define "row" }}- {{ .title }}{{ end }}Hello {{ .name }}.
+      {{ range .items }}{{ template "row" . }}{{ end }}
+      {{ template "sig" . }}
+`
+
+func writeLintTree(t *testing.T, manifests map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "prompts", "_partials"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"prompty.yaml":               lintConfig,
+		"prompts/_partials/sig.tmpl": `{{ define "sig" }}-- {{ .name }}{{ end }}`,
+	}
+	for name, text := range manifests {
+		files["prompts/"+name] = text
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "prompty.yaml")
+}
+
+func TestRunLint(t *testing.T) {
+	config := writeLintTree(t, map[string]string{
+		"bad.yaml":     lintBadManifest,
+		"partial.yaml": lintPartialManifest,
+		"good.yaml":    lintGoodManifest,
+	})
+
+	var out bytes.Buffer
+	err := runLint(config, []string{"-partials", "prompts/_partials/*.tmpl"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "lint: 5 errors") {
+		t.Fatalf("runLint error = %v\n%s", err, out.String())
+	}
+	got := out.String()
+	for _, want := range []string{
+		`prompts/bad.yaml:5: warning: unknown model_config key "reasoning_effort"`,
+		`prompts/bad.yaml:13: warning: input "tone" is required but has a default [required-with-default]`,
+		`prompts/bad.yaml:16: warning: input "unused" is declared but not used by any message [unused-input]`,
+		`prompts/bad.yaml:24: error: tools and response_format cannot be used together`,
+		`prompts/bad.yaml:33: error: variable "topic" is not declared in input_schema [undeclared-variable]`,
+		`prompts/bad.yaml:34: error: invalid role "robot" [invalid-role]`,
+		`prompts/partial.yaml:7: error: variable "name" is not declared in input_schema [undeclared-variable]`,
+		`prompts/partial.yaml:14: error: message 0 part 1: partial "footer" is not defined [unresolved-partial]`,
+		"5 errors, 3 warnings",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "good.yaml") {
+		t.Errorf("good manifest must be clean:\n%s", got)
+	}
+}
+
+func TestRunLint_ParseError(t *testing.T) {
+	broken := strings.Replace(lintGoodManifest, "Hello {{ .name }}.", "Hello {{ .name }.", 1)
+	config := writeLintTree(t, map[string]string{"good.yaml": broken})
+
+	var out bytes.Buffer
+	if err := runLint(config, []string{"-partials", "prompts/_partials/*.tmpl"}, &out); err == nil {
+		t.Fatalf("parse error must fail:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), `prompts/good.yaml:15: error: message 0: unexpected "}" in operand [template-parse]`) {
+		t.Errorf("output = %s", out.String())
+	}
+
+	invalid := "id: good\nmessages: [\n"
+	config = writeLintTree(t, map[string]string{"good.yaml": invalid})
+	out.Reset()
+	if err := runLint(config, nil, &out); err == nil || !strings.Contains(out.String(), "[invalid-manifest]") {
+		t.Errorf("invalid YAML: err = %v, output = %s", err, out.String())
+	}
+}
+
+func TestRunLint_JSONAndSARIF(t *testing.T) {
+	config := writeLintTree(t, map[string]string{"bad.yaml": lintBadManifest})
+
+	var out bytes.Buffer
+	if err := runLint(config, []string{"-format", "json"}, &out); err == nil {
+		t.Fatal("want error")
+	}
+	var findings []lintFinding
+	if err := json.Unmarshal(out.Bytes(), &findings); err != nil {
+		t.Fatalf("json output: %v\n%s", err, out.String())
+	}
+	if len(findings) == 0 || findings[0].File != "prompts/bad.yaml" || findings[0].Line != 5 ||
+		findings[0].Rule != ruleUnknownModelConfig || findings[0].Severity != severityWarning {
+		t.Errorf("findings = %+v", findings)
+	}
+
+	out.Reset()
+	if err := runLint(config, []string{"-format", "sarif"}, &out); err == nil {
+		t.Fatal("want error")
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("sarif output: %v\n%s", err, out.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "prompty-gen" {
+		t.Fatalf("sarif = %s", out.String())
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(lintRules()) || len(run.Results) != len(findings) {
+		t.Errorf("rules = %d, results = %d, findings = %d", len(run.Tool.Driver.Rules), len(run.Results), len(findings))
+	}
+	first := run.Results[0]
+	if first.RuleID != ruleUnknownModelConfig || first.Level != severityWarning ||
+		first.Locations[0].PhysicalLocation.ArtifactLocation.URI != "prompts/bad.yaml" ||
+		first.Locations[0].PhysicalLocation.Region.StartLine != 5 {
+		t.Errorf("first result = %+v", first)
+	}
+
+	if err := runLint(config, []string{"-format", "xml"}, &out); err == nil {
+		t.Error("unknown format must fail")
+	}
+}
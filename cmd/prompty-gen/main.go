@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -11,6 +12,8 @@ import (
 
 	yamlv3 "gopkg.in/yaml.v3"
 
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/fileregistry"
 	"github.com/skosovsky/prompty/manifest"
 	"github.com/skosovsky/prompty/parser/yaml"
 
@@ -37,8 +40,34 @@ func main() {
 			fmt.Fprintf(os.Stderr, "prompty-gen: %v\n", err)
 			os.Exit(1)
 		}
+	case "sign":
+		if err := runSign(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "prompty-gen: %v\n", err)
+			os.Exit(1)
+		}
+	case "compat":
+		if err := runCompat(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "prompty-gen: %v\n", err)
+			os.Exit(1)
+		}
+	case "lint":
+		if err := runLint(*configPath, flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "prompty-gen: %v\n", err)
+			os.Exit(1)
+		}
+	case "render":
+		if err := runRender(*configPath, flag.Args()[1:], os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "prompty-gen: %v\n", err)
+			os.Exit(1)
+		}
+	case "diff":
+		if err := runDiff(*configPath, flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "prompty-gen: %v\n", err)
+			os.Exit(1)
+		}
 	default:
-		fmt.Fprintf(os.Stderr, "prompty-gen: unknown command %q (use generate or list)\n", cmd)
+		fmt.Fprintf(os.Stderr, "prompty-gen: unknown command %q (use generate, list, sign, compat, lint, render or diff)\n",
+			cmd)
 		os.Exit(1)
 	}
 }
@@ -86,10 +115,18 @@ func runGenerate(configPath string) error {
 // Uses the longest matching base from queries; strips extension, returns slash format (canonical ID).
 // Example: base=prompts/, fpath=prompts/workers/image_analyze.yaml -> "workers/image_analyze".
 func idFromRelativePath(fpath string, configDir string, queries []string) string {
+	_, rel := queryRoot(fpath, configDir, queries)
+	// No basename fallback: path must resolve relative to queries or callers return error.
+	ext := filepath.Ext(rel)
+	rel = strings.TrimSuffix(rel, ext)
+	return filepath.ToSlash(rel)
+}
+
+// queryRoot returns the longest query base directory containing fpath and fpath relative to it.
+func queryRoot(fpath string, configDir string, queries []string) (string, string) {
 	fpath = filepath.Clean(fpath)
 	configDir = filepath.Clean(configDir)
-	var bestRel string
-	var bestBaseLen int
+	var bestBase, bestRel string
 	for _, q := range queries {
 		base := filepath.Join(configDir, q)
 		base = filepath.Clean(base)
@@ -103,15 +140,12 @@ func idFromRelativePath(fpath string, configDir string, queries []string) string
 		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
 			continue
 		}
-		if len(base) > bestBaseLen {
-			bestBaseLen = len(base)
+		if len(base) > len(bestBase) {
+			bestBase = base
 			bestRel = rel
 		}
 	}
-	// No basename fallback: path must resolve relative to queries or callers return error.
-	ext := filepath.Ext(bestRel)
-	bestRel = strings.TrimSuffix(bestRel, ext)
-	return filepath.ToSlash(bestRel)
+	return bestBase, bestRel
 }
 
 // runConsts generates one _consts_gen.go file with PromptID consts and AllPromptIDs.
@@ -145,7 +179,8 @@ func runConsts(configDir string, files []string, pkg *Package, outDir string) er
 	return nil
 }
 
-// runTypes generates shared _shared_gen.go plus per-manifest _gen.go (hybrid types mode).
+// runTypes generates shared _shared_gen.go plus per-manifest _gen.go (hybrid types mode), and the emit outputs:
+// per-manifest .input/.output.schema.json, package-wide .gen.ts and .openapi.json.
 func runTypes(configDir string, files []string, pkg *Package, outDir string) error {
 	var specs []*gen.PromptSpec
 	var ids []string
@@ -180,9 +215,13 @@ func runTypes(configDir string, files []string, pkg *Package, outDir string) err
 	}
 	_, _ = fmt.Fprintf(os.Stdout, "Generated %s\n", sharedPath)
 
-	// Per-manifest files: const, Input/Output, Render<Name>
+	// Per-manifest files: const, Input/Output, Render<Name> (+ Execute<Name>/Stream<Name>)
+	var genOpts []gen.Option
+	if pkg.Execute {
+		genOpts = append(genOpts, gen.WithExecute())
+	}
 	for i, fpath := range files {
-		manifestFile, err := gen.GenerateManifestTypes(specs[i], pkg.PackageName)
+		manifestFile, err := gen.GenerateManifestTypes(specs[i], pkg.PackageName, genOpts...)
 		if err != nil {
 			return fmt.Errorf("generate %s: %w", fpath, err)
 		}
@@ -193,7 +232,51 @@ func runTypes(configDir string, files []string, pkg *Package, outDir string) err
 			return fmt.Errorf("write %s: %w", outPath, err)
 		}
 		_, _ = fmt.Fprintf(os.Stdout, "Generated %s\n", outPath)
+
+		if pkg.Emits(emitJSONSchema) {
+			input, output, err := gen.GenerateJSONSchemas(specs[i])
+			if err != nil {
+				return fmt.Errorf("generate %s: %w", fpath, err)
+			}
+			if err := writeGenerated(filepath.Join(outDir, base+".input.schema.json"), input); err != nil {
+				return err
+			}
+			if output != nil {
+				if err := writeGenerated(filepath.Join(outDir, base+".output.schema.json"), output); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Package-wide documents for non-Go clients: TypeScript types and OpenAPI components
+	if pkg.Emits(emitTypeScript) {
+		data, err := gen.GenerateTypeScript(specs)
+		if err != nil {
+			return fmt.Errorf("generate typescript: %w", err)
+		}
+		if err := writeGenerated(filepath.Join(outDir, pkg.PackageName+".gen.ts"), data); err != nil {
+			return err
+		}
 	}
+	if pkg.Emits(emitOpenAPI) {
+		data, err := gen.GenerateOpenAPI(pkg.PackageName, specs)
+		if err != nil {
+			return fmt.Errorf("generate openapi: %w", err)
+		}
+		if err := writeGenerated(filepath.Join(outDir, pkg.PackageName+".openapi.json"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGenerated writes a generated non-Go file and reports it like jen output.
+func writeGenerated(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0644); err != nil { // #nosec G306 -- generated code is committed, like jen output
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Generated %s\n", path)
 	return nil
 }
 
@@ -232,14 +315,9 @@ func loadSpec(fpath string, configDir string, queries []string) (*gen.PromptSpec
 		return nil, err
 	}
 
-	var u manifest.Unmarshaler
-	switch strings.ToLower(filepath.Ext(fpath)) {
-	case extYAML, extYML:
-		u = yaml.New()
-	case extJSON:
-		u = manifest.NewJSONParser()
-	default:
-		return nil, errors.New("unsupported manifest format")
+	u, err := manifestParser(fpath)
+	if err != nil {
+		return nil, err
 	}
 
 	var raw manifest.RawManifest
@@ -252,26 +330,69 @@ func loadSpec(fpath string, configDir string, queries []string) (*gen.PromptSpec
 			return nil, errors.New("manifest has no id field and could not derive id from path")
 		}
 	}
-	// Clean Break v2.0: types mode requires messages and input_schema
-	if len(raw.Messages) == 0 {
-		return nil, errors.New("manifest missing messages block (v2.0 required)")
-	}
-	if raw.InputSchema == nil {
-		return nil, errors.New("manifest missing input_schema block (v2.0 required)")
+	tpl, err := buildManifest(&raw, u, fpath, configDir, queries)
+	if err != nil {
+		return nil, err
 	}
-
-	tpl, err := manifest.BuildFromRaw(&raw, nil)
+	inputSchema, err := withDirectiveVars(tpl.InputSchema, tpl.Messages)
 	if err != nil {
 		return nil, err
 	}
 
 	return &gen.PromptSpec{
 		ID:             tpl.Metadata.ID,
-		InputSchema:    tpl.InputSchema,
+		InputSchema:    inputSchema,
 		ResponseFormat: tpl.ResponseFormat,
+		Tools:          tpl.Tools,
 	}, nil
 }
 
+// manifestParser returns the parser for the manifest file extension.
+func manifestParser(fpath string) (manifest.Unmarshaler, error) {
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case extYAML, extYML:
+		return yaml.New(), nil
+	case extJSON:
+		return manifest.NewJSONParser(), nil
+	default:
+		return nil, errors.New("unsupported manifest format")
+	}
+}
+
+// buildManifest builds the template, resolving extends against the query root; opts are passed to manifest.Build.
+func buildManifest(
+	raw *manifest.RawManifest,
+	u manifest.Unmarshaler,
+	fpath, configDir string,
+	queries []string,
+	opts ...manifest.ParseOption,
+) (*prompty.ChatPromptTemplate, error) {
+	if raw.Extends != "" {
+		// Parents are resolved relative to the query root, like fileregistry does at runtime.
+		root, _ := queryRoot(fpath, configDir, queries)
+		reg, err := fileregistry.New(root, fileregistry.WithParser(u))
+		if err != nil {
+			return nil, err
+		}
+		tpl, err := manifest.Build(raw, append(opts, manifest.WithBaseRegistry(context.Background(), reg))...)
+		if err != nil {
+			return nil, err
+		}
+		if tpl.InputSchema == nil {
+			return nil, errors.New("manifest missing input_schema block (v2.0 required)")
+		}
+		return tpl, nil
+	}
+	// Clean Break v2.0: types mode requires messages and input_schema
+	if len(raw.Messages) == 0 {
+		return nil, errors.New("manifest missing messages block (v2.0 required)")
+	}
+	if raw.InputSchema == nil {
+		return nil, errors.New("manifest missing input_schema block (v2.0 required)")
+	}
+	return manifest.Build(raw, opts...)
+}
+
 // loadManifestID reads the manifest id field and validates v2.0 clean-break (messages, input_schema).
 // Priority 1: explicit id from YAML/JSON. Priority 2: fallback from relative path.
 // Consts flow rejects legacy manifests missing messages or input_schema.
@@ -282,6 +403,7 @@ func loadManifestID(fpath string, configDir string, queries []string) (string, e
 	}
 	var v2Check struct {
 		ID          string `json:"id"           yaml:"id"`
+		Extends     string `json:"extends"      yaml:"extends"`
 		Messages    []any  `json:"messages"     yaml:"messages"`
 		InputSchema any    `json:"input_schema" yaml:"input_schema"`
 	}
@@ -297,12 +419,14 @@ func loadManifestID(fpath string, configDir string, queries []string) (string, e
 	default:
 		return "", errors.New("unsupported manifest format")
 	}
-	// Clean Break v2.0: consts mode requires messages and input_schema
-	if len(v2Check.Messages) == 0 {
-		return "", errors.New("manifest missing messages block (v2.0 required)")
-	}
-	if v2Check.InputSchema == nil {
-		return "", errors.New("manifest missing input_schema block (v2.0 required)")
+	// Clean Break v2.0: consts mode requires messages and input_schema (unless inherited via extends)
+	if v2Check.Extends == "" {
+		if len(v2Check.Messages) == 0 {
+			return "", errors.New("manifest missing messages block (v2.0 required)")
+		}
+		if v2Check.InputSchema == nil {
+			return "", errors.New("manifest missing input_schema block (v2.0 required)")
+		}
 	}
 	if v2Check.ID != "" {
 		return v2Check.ID, nil
@@ -2,11 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/skosovsky/prompty/cmd/prompty-gen/gen"
 )
 
 const legacyClientTypeName = "LLM" + "Client"
@@ -389,6 +393,65 @@ packages:
 	}
 }
 
+func TestRunGenerate_Emit(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "prompts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	body := `id: internal/router
+messages:
+  - role: system
+    content: "Route {{ .subject }}"
+input_schema:
+  type: object
+  properties:
+    subject:
+      type: string
+  required: [subject]
+response_format:
+  name: route
+  schema:
+    type: object
+    properties:
+      queue:
+        type: string
+    required: [queue]
+`
+	if err := os.WriteFile(filepath.Join(dir, "router.yaml"), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(tmp, "prompty.yaml")
+	cfg := `version: "1"
+packages:
+  - name: pkg
+    path: out
+    queries: ["prompts"]
+    emit: [jsonschema, typescript, openapi]
+`
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGenerate(cfgPath); err != nil {
+		t.Fatalf("runGenerate: %v", err)
+	}
+	for name, want := range map[string]string{
+		"router_gen.go":             "type InternalRouterInput struct",
+		"router.input.schema.json":  `"title": "InternalRouterInput"`,
+		"router.output.schema.json": `"title": "InternalRouterOutput"`,
+		"pkg.gen.ts":                "export interface InternalRouterOutput {\n  queue: string;\n}",
+		"pkg.openapi.json":          `"x-prompty-id": "internal/router"`,
+	} {
+		data, err := os.ReadFile(filepath.Join(tmp, "out", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s: missing %q:\n%s", name, want, data)
+		}
+	}
+}
+
 func TestRunGenerate_TypesMode_RequiresInputSchema(t *testing.T) {
 	tmp := t.TempDir()
 	dir := filepath.Join(tmp, "prompts")
@@ -464,3 +527,203 @@ packages:
 		t.Errorf("expected reserved/prompts error, got: %v", err)
 	}
 }
+
+func TestLoadSpec_Extends(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "prompts")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	base := `id: base
+messages:
+  - role: system
+    content: "Hi {{ .user }}"
+input_schema:
+  type: object
+  properties:
+    user:
+      type: string
+  required: [user]
+`
+	child := `extends: base
+input_schema:
+  type: object
+  properties:
+    query:
+      type: string
+`
+	if err := os.WriteFile(filepath.Join(dir, "base.yaml"), []byte(base), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	childPath := filepath.Join(dir, "child.yaml")
+	if err := os.WriteFile(childPath, []byte(child), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := loadSpec(childPath, tmp, []string{"prompts"})
+	if err != nil {
+		t.Fatalf("loadSpec: %v", err)
+	}
+	if spec.ID != "child" {
+		t.Fatalf("ID = %q, want child", spec.ID)
+	}
+	props, _ := spec.InputSchema.Schema["properties"].(map[string]any)
+	if _, ok := props["user"]; !ok {
+		t.Error("expected inherited property user")
+	}
+	if _, ok := props["query"]; !ok {
+		t.Error("expected own property query")
+	}
+	id, err := loadManifestID(childPath, tmp, []string{"prompts"})
+	if err != nil || id != "child" {
+		t.Fatalf("loadManifestID = %q, %v", id, err)
+	}
+}
+
+func TestLoadSpec_WhenVarsTyped(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "prompts")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	src := `id: cond
+messages:
+  - role: system
+    when: has_docs && tier == "pro"
+    content: "Docs: {{ .docs }}"
+  - role: user
+    optional: true
+    when: max_len > 10
+    content: "{{ .query }}"
+input_schema:
+  type: object
+  properties:
+    docs:
+      type: string
+    tier:
+      type: string
+  required: [docs]
+`
+	path := filepath.Join(dir, "cond.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := loadSpec(path, tmp, []string{"prompts"})
+	if err != nil {
+		t.Fatalf("loadSpec: %v", err)
+	}
+	props, _ := spec.InputSchema.Schema["properties"].(map[string]any)
+	hasDocs, _ := props["has_docs"].(map[string]any)
+	if hasDocs["type"] != "boolean" {
+		t.Errorf("has_docs = %v, want boolean property", props["has_docs"])
+	}
+	maxLen, _ := props["max_len"].(map[string]any)
+	if maxLen["type"] != "number" {
+		t.Errorf("max_len = %v, want number property", props["max_len"])
+	}
+	required, _ := spec.InputSchema.Schema["required"].([]string)
+	if !slices.Contains(required, "has_docs") || slices.Contains(required, "max_len") ||
+		slices.Contains(required, "tier") {
+		t.Errorf("required = %v, want has_docs added, max_len and declared tier untouched", required)
+	}
+	f, err := gen.GenerateManifestTypes(spec, "prompts")
+	if err != nil {
+		t.Fatalf("GenerateManifestTypes: %v", err)
+	}
+	code := fmt.Sprintf("%#v", f)
+	if !strings.Contains(code, "HasDocs") || !strings.Contains(code, "MaxLen") {
+		t.Errorf("generated input must include when vars:\n%s", code)
+	}
+}
+
+func TestLoadSpec_ForEachListTyped(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "prompts")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	src := `id: shots
+messages:
+  - for_each: examples
+    turns:
+      - role: user
+        when: item.enabled
+        content: "{{ .item.q }}"
+      - role: assistant
+        content: "{{ .item.a }}"
+  - role: user
+    content: "{{ .query }}"
+input_schema:
+  type: object
+  properties:
+    query:
+      type: string
+  required: [query]
+`
+	path := filepath.Join(dir, "shots.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := loadSpec(path, tmp, []string{"prompts"})
+	if err != nil {
+		t.Fatalf("loadSpec: %v", err)
+	}
+	props, _ := spec.InputSchema.Schema["properties"].(map[string]any)
+	examples, _ := props["examples"].(map[string]any)
+	if examples["type"] != "array" {
+		t.Errorf("examples = %v, want array property", props["examples"])
+	}
+	if _, ok := props["item"]; ok {
+		t.Error("loop variable item must not become an input property")
+	}
+	required, _ := spec.InputSchema.Schema["required"].([]string)
+	if !slices.Contains(required, "examples") {
+		t.Errorf("required = %v, want examples", required)
+	}
+}
+
+func TestLoadSpec_Tools(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "prompts")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	src := `id: support
+messages:
+  - role: system
+    content: Help the user.
+input_schema:
+  type: object
+tools:
+  - name: lookup_order
+    description: Find an order
+    parameters:
+      type: object
+      properties:
+        order_id:
+          type: string
+      required: [order_id]
+`
+	path := filepath.Join(dir, "support.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := loadSpec(path, tmp, []string{"prompts"})
+	if err != nil {
+		t.Fatalf("loadSpec: %v", err)
+	}
+	if len(spec.Tools) != 1 || spec.Tools[0].Name != "lookup_order" {
+		t.Fatalf("tools = %+v", spec.Tools)
+	}
+	f, err := gen.GenerateManifestTypes(spec, "prompts")
+	if err != nil {
+		t.Fatalf("GenerateManifestTypes: %v", err)
+	}
+	var buf strings.Builder
+	if err := f.Render(&buf); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(buf.String(), "type SupportLookupOrderArgs struct") ||
+		!strings.Contains(buf.String(), "type SupportToolDispatcher struct") {
+		t.Errorf("generated code must include tool types:\n%s", buf.String())
+	}
+}
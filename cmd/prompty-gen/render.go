@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/fileregistry"
+)
+
+// Provider render formats: the request each adapter's Translate builds. They are available only in builds with
+// the providers tag (render_providers.go), so the default module does not depend on the adapters.
+const (
+	formatOpenAI    = "openai"
+	formatAnthropic = "anthropic"
+	formatGemini    = "gemini"
+	formatOllama    = "ollama"
+)
+
+// renderPart is the JSON form of a content part (history input and -format json output).
+// Type is text, reasoning, tool_call, tool_result or a media type (image, audio, video, document).
+type renderPart struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	MIMEType   string `json:"mime_type,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Data       []byte `json:"data,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Args       string `json:"args,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	IsError    bool   `json:"is_error,omitempty"`
+}
+
+// renderMessage is the JSON form of a chat message. Content is a string (one text part) or a list of parts.
+type renderMessage struct {
+	Role    prompty.Role    `json:"role"`
+	Content json.RawMessage `json:"content"`
+	Tokens  int             `json:"tokens,omitempty"`
+}
+
+// renderOutput is the -format json output: the PromptExecution with token estimates.
+type renderOutput struct {
+	ID             string                    `json:"id"`
+	Version        string                    `json:"version,omitempty"`
+	ModelOptions   *prompty.ModelOptions     `json:"model_config,omitempty"`
+	Tools          []prompty.ToolDefinition  `json:"tools,omitempty"`
+	ResponseFormat *prompty.SchemaDefinition `json:"response_format,omitempty"`
+	Messages       []renderMessage           `json:"messages"`
+	Tokens         int                       `json:"tokens"`
+}
+
+// runRender formats the manifest with id (found via the config queries, like generate) with input variables and
+// optional history, and prints the PromptExecution as text or JSON, or the provider request built by an adapter.
+// Token estimates (prompty.CharFallbackCounter) go to stdout for text and JSON, to stderr for provider requests.
+func runRender(configPath string, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	inputPath := flags.String("input", "", "JSON file with input variables")
+	historyPath := flags.String("history", "", "JSON file with chat history messages ({role, content})")
+	env := flags.String("env", "", "Environment overlay to apply, e.g. prod ({id}.prod.yaml)")
+	partials := flags.String("partials", "",
+		"Partials pattern relative to the manifest directory (e.g. _partials/*.tmpl)")
+	format := flags.String("format", formatText,
+		"Output: text, json or the provider request (openai, anthropic, gemini, ollama)")
+	id, flagArgs := "", args
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, flagArgs = args[0], args[1:] // prompty-gen render <id> -input vars.json
+	}
+	if err := flags.Parse(flagArgs); err != nil {
+		return err
+	}
+	if id == "" && flags.NArg() > 0 {
+		id = flags.Arg(0)
+	}
+	if id == "" {
+		return errors.New("render: prompt id is required")
+	}
+	formats := []string{formatText, formatJSON, formatOpenAI, formatAnthropic, formatGemini, formatOllama}
+	if !slices.Contains(formats, *format) {
+		return fmt.Errorf("render: unknown format %q (use %s)", *format, strings.Join(formats, ", "))
+	}
+
+	vars := map[string]any{}
+	if *inputPath != "" {
+		if err := readJSONFile(*inputPath, &vars); err != nil {
+			return fmt.Errorf("render: input: %w", err)
+		}
+	}
+	var history []prompty.ChatMessage
+	if *historyPath != "" {
+		var raw []renderMessage
+		if err := readJSONFile(*historyPath, &raw); err != nil {
+			return fmt.Errorf("render: history: %w", err)
+		}
+		for i, m := range raw {
+			msg, err := m.chatMessage()
+			if err != nil {
+				return fmt.Errorf("render: history message %d: %w", i, err)
+			}
+			history = append(history, msg)
+		}
+	}
+
+	tpl, err := loadRenderTemplate(configPath, id, *env, *partials)
+	if err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+	exec, err := tpl.FormatWithHistory(vars, history)
+	if err != nil {
+		return fmt.Errorf("render %s: %w", id, err)
+	}
+	tokens, total, err := countTokens(exec.Messages)
+	if err != nil {
+		return fmt.Errorf("render %s: %w", id, err)
+	}
+
+	switch *format {
+	case formatText:
+		return writeRenderText(stdout, exec, tokens, total)
+	case formatJSON:
+		out := renderOutput{
+			ID:             exec.Metadata.ID,
+			Version:        exec.Metadata.Version,
+			ModelOptions:   exec.ModelOptions,
+			Tools:          exec.Tools,
+			ResponseFormat: exec.ResponseFormat,
+			Messages:       make([]renderMessage, 0, len(exec.Messages)),
+			Tokens:         total,
+		}
+		for i, msg := range exec.Messages {
+			m, err := newRenderMessage(msg)
+			if err != nil {
+				return fmt.Errorf("render %s: %w", id, err)
+			}
+			m.Tokens = tokens[i]
+			out.Messages = append(out.Messages, m)
+		}
+		return writeJSON(stdout, out)
+	default:
+		req, err := translate(*format, exec)
+		if err != nil {
+			return fmt.Errorf("render %s: %s: %w", id, *format, err)
+		}
+		if err := writeJSON(stdout, req); err != nil {
+			return err
+		}
+		for i, msg := range exec.Messages {
+			_, _ = fmt.Fprintf(stderr, "message %d %s: ~%d tokens\n", i, msg.Role, tokens[i])
+		}
+		_, _ = fmt.Fprintf(stderr, "total: ~%d tokens\n", total)
+		return nil
+	}
+}
+
+// loadRenderTemplate finds the manifest with id among the files matched by the config queries and loads it
+// through a fileregistry rooted at its query base, so extends, env overlays and partials resolve as at runtime.
+func loadRenderTemplate(configPath, id, env, partials string) (*prompty.ChatPromptTemplate, error) {
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("config path: %w", err)
+	}
+	configDir := filepath.Dir(absConfig)
+	cfg, err := LoadConfig(absConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range cfg.Packages {
+		files, err := pkg.ResolveSources(configDir)
+		if err != nil {
+			return nil, fmt.Errorf("package %q: %w", pkg.Name, err)
+		}
+		for _, fpath := range files {
+			pathID := idFromRelativePath(fpath, configDir, pkg.Queries)
+			if manifestID, idErr := loadManifestID(fpath, configDir, pkg.Queries); pathID != id &&
+				(idErr != nil || manifestID != id) {
+				continue
+			}
+			u, err := manifestParser(fpath)
+			if err != nil {
+				return nil, fmt.Errorf("manifest %s: %w", fpath, err)
+			}
+			opts := []fileregistry.Option{fileregistry.WithParser(u)}
+			if env != "" {
+				opts = append(opts, fileregistry.WithEnvironment(env))
+			}
+			if partials != "" {
+				opts = append(opts, fileregistry.WithPartials(partials))
+			}
+			root, _ := queryRoot(fpath, configDir, pkg.Queries)
+			reg, err := fileregistry.New(root, opts...)
+			if err != nil {
+				return nil, err
+			}
+			tpl, err := reg.GetTemplate(context.Background(), pathID)
+			if err != nil {
+				return nil, fmt.Errorf("manifest %s: %w", fpath, err)
+			}
+			return tpl, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q is not matched by the config queries", prompty.ErrTemplateNotFound, id)
+}
+
+// countTokens estimates tokens per message and in total with prompty.CharFallbackCounter.
+func countTokens(msgs []prompty.ChatMessage) ([]int, int, error) {
+	counter := &prompty.CharFallbackCounter{CharsPerToken: 0}
+	tokens := make([]int, len(msgs))
+	total := 0
+	for i, msg := range msgs {
+		n, err := counter.CountMessage(msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		tokens[i] = n
+		total += n
+	}
+	return tokens, total, nil
+}
+
+// writeRenderText prints the execution for reading: a header with id, model options, tools and response format,
+// then each message with its role and token estimate.
+func writeRenderText(w io.Writer, exec *prompty.PromptExecution, tokens []int, total int) error {
+	var details []string
+	if exec.Metadata.Version != "" {
+		details = append(details, "version "+exec.Metadata.Version)
+	}
+	if exec.Metadata.Environment != "" {
+		details = append(details, "env "+exec.Metadata.Environment)
+	}
+	header := exec.Metadata.ID
+	if len(details) > 0 {
+		header += " (" + strings.Join(details, ", ") + ")"
+	}
+	_, _ = fmt.Fprintf(w, "# %s\n", header)
+	if exec.ModelOptions != nil {
+		data, err := json.Marshal(exec.ModelOptions)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "model_config: %s\n", data)
+	}
+	if len(exec.Tools) > 0 {
+		names := make([]string, len(exec.Tools))
+		for i, tool := range exec.Tools {
+			names[i] = tool.Name
+		}
+		_, _ = fmt.Fprintf(w, "tools: %s\n", strings.Join(names, ", "))
+	}
+	if exec.ResponseFormat != nil {
+		_, _ = fmt.Fprintf(w, "response_format: %s\n", exec.ResponseFormat.Name)
+	}
+	for i, msg := range exec.Messages {
+		_, _ = fmt.Fprintf(w, "\n--- %s (~%d tokens)\n", msg.Role, tokens[i])
+		for _, part := range msg.Content {
+			_, _ = fmt.Fprintln(w, partText(part))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\ntotal: ~%d tokens\n", total)
+	return nil
+}
+
+// partText renders a content part as one text block; non-text parts are shown as [type ...] placeholders.
+func partText(part prompty.ContentPart) string {
+	switch p := part.(type) {
+	case prompty.TextPart:
+		return p.Text
+	case prompty.ReasoningPart:
+		return "[reasoning] " + p.Text
+	case prompty.MediaPart:
+		src := p.URL
+		if src == "" {
+			src = fmt.Sprintf("%d bytes", len(p.Data))
+		}
+		return fmt.Sprintf("[%s %s %s]", p.MediaType, p.MIMEType, src)
+	case prompty.ToolCallPart:
+		return fmt.Sprintf("[tool_call %s %s] %s", p.ID, p.Name, p.Args)
+	case prompty.ToolResultPart:
+		texts := make([]string, 0, len(p.Content))
+		for _, c := range p.Content {
+			texts = append(texts, partText(c))
+		}
+		return fmt.Sprintf("[tool_result %s %s] %s", p.ToolCallID, p.Name, strings.Join(texts, "\n"))
+	default:
+		return fmt.Sprintf("[%T]", part)
+	}
+}
+
+// newRenderMessage converts msg to its JSON form; a single text part is written as a string.
+func newRenderMessage(msg prompty.ChatMessage) (renderMessage, error) {
+	var content any
+	if len(msg.Content) == 1 {
+		if text, ok := msg.Content[0].(prompty.TextPart); ok {
+			content = text.Text
+		}
+	}
+	if content == nil {
+		parts := make([]renderPart, 0, len(msg.Content))
+		for _, part := range msg.Content {
+			parts = append(parts, newRenderPart(part))
+		}
+		content = parts
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return renderMessage{}, err
+	}
+	return renderMessage{Role: msg.Role, Content: data, Tokens: 0}, nil
+}
+
+func newRenderPart(part prompty.ContentPart) renderPart {
+	var out renderPart
+	switch p := part.(type) {
+	case prompty.TextPart:
+		out = renderPart{Type: "text", Text: p.Text}
+	case prompty.ReasoningPart:
+		out = renderPart{Type: "reasoning", Text: p.Text}
+	case prompty.MediaPart:
+		out = renderPart{Type: p.MediaType, MIMEType: p.MIMEType, URL: p.URL, Data: p.Data}
+	case prompty.ToolCallPart:
+		out = renderPart{Type: "tool_call", ID: p.ID, Name: p.Name, Args: p.Args}
+	case prompty.ToolResultPart:
+		texts := make([]string, 0, len(p.Content))
+		for _, c := range p.Content {
+			texts = append(texts, partText(c))
+		}
+		out = renderPart{
+			Type:       "tool_result",
+			Text:       strings.Join(texts, "\n"),
+			Name:       p.Name,
+			ToolCallID: p.ToolCallID,
+			IsError:    p.IsError,
+		}
+	default:
+		out = renderPart{Type: fmt.Sprintf("%T", part)}
+	}
+	return out
+}
+
+// chatMessage converts a history message from its JSON form.
+func (m renderMessage) chatMessage() (prompty.ChatMessage, error) {
+	if m.Role == "" {
+		return prompty.ChatMessage{}, errors.New("role is required")
+	}
+	msg := prompty.ChatMessage{Role: m.Role, Content: nil, CacheControl: nil, Metadata: nil}
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		msg.Content = []prompty.ContentPart{prompty.TextPart{Text: text}}
+		return msg, nil
+	}
+	var parts []renderPart
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return prompty.ChatMessage{}, errors.New("content must be a string or a list of parts")
+	}
+	for j, p := range parts {
+		part, err := p.contentPart()
+		if err != nil {
+			return prompty.ChatMessage{}, fmt.Errorf("part %d: %w", j, err)
+		}
+		msg.Content = append(msg.Content, part)
+	}
+	return msg, nil
+}
+
+func (p renderPart) contentPart() (prompty.ContentPart, error) {
+	switch p.Type {
+	case "text":
+		return prompty.TextPart{Text: p.Text}, nil
+	case "reasoning":
+		return prompty.ReasoningPart{Text: p.Text}, nil
+	case "image", "audio", "video", "document":
+		return prompty.MediaPart{MediaType: p.Type, MIMEType: p.MIMEType, URL: p.URL, Data: p.Data}, nil
+	case "tool_call":
+		return prompty.ToolCallPart{ID: p.ID, Name: p.Name, Args: p.Args}, nil
+	case "tool_result":
+		return prompty.ToolResultPart{
+			ToolCallID: p.ToolCallID,
+			Name:       p.Name,
+			Content:    []prompty.ContentPart{prompty.TextPart{Text: p.Text}},
+			IsError:    p.IsError,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown part type %q", p.Type)
+	}
+}
+
+// readJSONFile decodes the JSON file at path into v.
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
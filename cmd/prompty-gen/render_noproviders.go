@@ -0,0 +1,17 @@
+//go:build !providers
+
+package main
+
+import (
+	"errors"
+
+	"github.com/skosovsky/prompty"
+)
+
+// errNoProviders is returned for provider formats by a build without the adapters.
+var errNoProviders = errors.New("provider requests need prompty-gen built with -tags providers")
+
+// translate reports errNoProviders: this build does not link the adapters.
+func translate(string, *prompty.PromptExecution) (any, error) {
+	return nil, errNoProviders
+}
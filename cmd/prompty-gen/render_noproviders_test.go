@@ -0,0 +1,22 @@
+//go:build !providers
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRunRender_ProviderWithoutTag(t *testing.T) {
+	config, input, _ := writeRenderTree(t)
+
+	var out, stderr bytes.Buffer
+	err := runRender(config, []string{"support/agent", "-input", input, "-format", "openai"}, &out, &stderr)
+	if !errors.Is(err, errNoProviders) {
+		t.Fatalf("err = %v, want errNoProviders", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("stdout = %q, want empty", out.String())
+	}
+}
@@ -0,0 +1,25 @@
+//go:build providers
+
+package main
+
+import (
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/adapter/anthropic"
+	"github.com/skosovsky/prompty/adapter/gemini"
+	"github.com/skosovsky/prompty/adapter/ollama"
+	"github.com/skosovsky/prompty/adapter/openai"
+)
+
+// translate builds the provider request for exec with the adapter named by format (default options).
+func translate(format string, exec *prompty.PromptExecution) (any, error) {
+	switch format {
+	case formatOpenAI:
+		return openai.New().Translate(exec)
+	case formatAnthropic:
+		return anthropic.New().Translate(exec)
+	case formatGemini:
+		return gemini.New().Translate(exec)
+	default:
+		return ollama.New().Translate(exec)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build providers
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunRender_Provider(t *testing.T) {
+	config, input, _ := writeRenderTree(t)
+
+	for format, want := range map[string]string{
+		"openai":    `"model": "gpt-4o"`,
+		"anthropic": `"system"`,
+		"gemini":    `"Contents"`,
+		"ollama":    `"messages"`,
+	} {
+		var out, stderr bytes.Buffer
+		if err := runRender(config, []string{"support/agent", "-input", input, "-format", format}, &out, &stderr); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !json.Valid(out.Bytes()) || !strings.Contains(out.String(), want) ||
+			!strings.Contains(out.String(), "Where is my order?") {
+			t.Errorf("%s request:\n%s", format, out.String())
+		}
+		if !strings.Contains(stderr.String(), "message 1 user: ~5 tokens") ||
+			!strings.Contains(stderr.String(), "total: ~9 tokens") {
+			t.Errorf("%s tokens: %s", format, stderr.String())
+		}
+	}
+}
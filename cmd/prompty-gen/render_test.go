@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skosovsky/prompty"
+)
+
+const renderManifest = `id: support/agent
+version: "1.2.0"
+model_config:
+  model: gpt-4o
+  temperature: 0.2
+input_schema:
+  name: agent_input
+  schema:
+    type: object
+    properties:
+      name:
+        type: string
+      question:
+        type: string
+    required: [name, question]
+messages:
+  - role: system
+    content: You help {{ .name }}.
+  - role: user
+    content: "{{ .question }}"
+`
+
+const renderConfig = `version: "1"
+packages:
+  - name: prompts
+    path: gen
+    queries:
+      - prompts/
+`
+
+const renderOverlay = `overlay: true
+model_config:
+  model: gpt-4o-mini
+`
+
+func writeRenderTree(t *testing.T) (config, input, history string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "prompts", "support"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"prompty.yaml":                    renderConfig,
+		"prompts/support/agent.yaml":      renderManifest,
+		"prompts/support/agent.prod.yaml": renderOverlay,
+		"vars.json":                       `{"name": "Ann", "question": "Where is my order?"}`,
+		"history.json": `[
+  {"role": "user", "content": "Hi"},
+  {"role": "assistant", "content": [{"type": "text", "text": "Hello!"}]}
+]`,
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "prompty.yaml"), filepath.Join(dir, "vars.json"), filepath.Join(dir, "history.json")
+}
+
+func TestRunRender_Text(t *testing.T) {
+	config, input, history := writeRenderTree(t)
+
+	var out bytes.Buffer
+	if err := runRender(config, []string{"support/agent", "-input", input, "-history", history}, &out, nil); err != nil {
+		t.Fatalf("runRender: %v", err)
+	}
+	want := `# support/agent (version 1.2.0)
+model_config: {"model":"gpt-4o","temperature":0.2}
+
+--- system (~4 tokens)
+You help Ann.
+
+--- user (~1 tokens)
+Hi
+
+--- assistant (~2 tokens)
+Hello!
+
+--- user (~5 tokens)
+Where is my order?
+
+total: ~12 tokens
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := runRender(config, []string{"support/agent", "-input", input, "-env", "prod"}, &out, nil); err != nil {
+		t.Fatalf("runRender prod: %v", err)
+	}
+	if !strings.Contains(out.String(), "(version 1.2.0, env prod)") ||
+		!strings.Contains(out.String(), `"model":"gpt-4o-mini"`) {
+		t.Errorf("prod overlay not applied:\n%s", out.String())
+	}
+}
+
+func TestRunRender_JSON(t *testing.T) {
+	config, input, history := writeRenderTree(t)
+
+	var out bytes.Buffer
+	args := []string{"support/agent", "-input", input, "-history", history, "-format", "json"}
+	if err := runRender(config, args, &out, nil); err != nil {
+		t.Fatalf("runRender: %v", err)
+	}
+	var got renderOutput
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("json: %v\n%s", err, out.String())
+	}
+	if got.ID != "support/agent" || got.Version != "1.2.0" || got.Tokens != 12 || len(got.Messages) != 4 {
+		t.Fatalf("output = %s", out.String())
+	}
+	if string(got.Messages[3].Content) != `"Where is my order?"` || got.Messages[3].Tokens != 5 {
+		t.Errorf("last message = %+v", got.Messages[3])
+	}
+	msg, err := got.Messages[2].chatMessage()
+	if err != nil || msg.Role != prompty.RoleAssistant || partText(msg.Content[0]) != "Hello!" {
+		t.Errorf("history message round trip = %+v, %v", msg, err)
+	}
+}
+
+func TestRunRender_Errors(t *testing.T) {
+	config, input, _ := writeRenderTree(t)
+
+	if err := runRender(config, []string{"support/agent"}, &bytes.Buffer{}, nil); !errors.Is(err, prompty.ErrMissingVariable) {
+		t.Errorf("missing input: got %v", err)
+	}
+	if err := runRender(config, []string{"nope", "-input", input}, &bytes.Buffer{}, nil); !errors.Is(err, prompty.ErrTemplateNotFound) {
+		t.Errorf("unknown id: got %v", err)
+	}
+	if err := runRender(config, []string{"-input", input}, &bytes.Buffer{}, nil); err == nil {
+		t.Error("missing id must fail")
+	}
+	args := []string{"support/agent", "-input", input, "-format", "xml"}
+	if err := runRender(config, args, &bytes.Buffer{}, nil); err == nil {
+		t.Error("unknown format must fail")
+	}
+	bad := filepath.Join(filepath.Dir(config), "bad_history.json")
+	if err := os.WriteFile(bad, []byte(`[{"role": "user", "content": [{"type": "hologram"}]}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	args = []string{"support/agent", "-input", input, "-history", bad}
+	if err := runRender(config, args, &bytes.Buffer{}, nil); err == nil ||
+		!strings.Contains(err.Error(), `history message 0: part 0: unknown part type "hologram"`) {
+		t.Errorf("bad history: got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skosovsky/prompty/remoteregistry"
+)
+
+// runSign signs the manifests in each directory of args for remoteregistry verifiers:
+// a detached {id}.sig per manifest (SignatureVerifier) or, with -index, prompty.sum and prompty.sum.sig at the
+// directory root (IndexVerifier). Ids are paths relative to the directory without extension, as fetchers see them.
+func runSign(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sign", flag.ContinueOnError)
+	keyPath := flags.String("key", "", "Path to the ed25519 private key (PKCS #8 PEM or base64 seed)")
+	index := flags.Bool("index", false, "Write a signed checksum index instead of per-manifest signatures")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		return errors.New("sign: -key is required")
+	}
+	if flags.NArg() == 0 {
+		return errors.New("sign: at least one manifest directory is required")
+	}
+	keyData, err := os.ReadFile(*keyPath)
+	if err != nil {
+		return fmt.Errorf("sign: read key: %w", err)
+	}
+	key, err := remoteregistry.ParsePrivateKey(keyData)
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+	pub, _ := key.Public().(ed25519.PublicKey)
+	for _, dir := range flags.Args() {
+		manifests, err := collectManifests(dir)
+		if err != nil {
+			return fmt.Errorf("sign %s: %w", dir, err)
+		}
+		if *index {
+			err = writeSignedIndex(dir, key, manifests)
+		} else {
+			err = writeSignatures(dir, key, manifests)
+		}
+		if err != nil {
+			return fmt.Errorf("sign %s: %w", dir, err)
+		}
+		_, _ = fmt.Fprintf(stdout, "Signed %d manifests in %s (key %s)\n", len(manifests), dir, remoteregistry.KeyID(pub))
+	}
+	return nil
+}
+
+// collectManifests reads every .yaml/.yml/.json file under dir, keyed by id (relative slash path without
+// extension). Hidden directories are skipped; two files with the same id are an error.
+func collectManifests(dir string) (map[string][]byte, error) {
+	manifests := make(map[string][]byte)
+	paths := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != extYAML && ext != extYML && ext != extJSON {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		id := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		if prev, ok := paths[id]; ok {
+			return fmt.Errorf("duplicate id %q in %s and %s", id, prev, path)
+		}
+		data, err := os.ReadFile(path) // #nosec G304 -- path comes from walking the directory being signed
+		if err != nil {
+			return err
+		}
+		paths[id] = path
+		manifests[id] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, errors.New("no manifests found")
+	}
+	return manifests, nil
+}
+
+// writeSignatures writes {id}.sig next to each manifest, signed for its id (see remoteregistry.SignManifest).
+func writeSignatures(dir string, key ed25519.PrivateKey, manifests map[string][]byte) error {
+	for id, data := range manifests {
+		path := filepath.Join(dir, filepath.FromSlash(id)+remoteregistry.SignatureExt)
+		if err := writePublished(path, remoteregistry.SignManifest(key, id, data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSignedIndex writes the checksum index and its signature at the directory root.
+func writeSignedIndex(dir string, key ed25519.PrivateKey, manifests map[string][]byte) error {
+	index := remoteregistry.ChecksumIndex(manifests)
+	if err := writePublished(filepath.Join(dir, remoteregistry.ChecksumIndexName), index); err != nil {
+		return err
+	}
+	return writePublished(
+		filepath.Join(dir, remoteregistry.ChecksumIndexName+remoteregistry.SignatureExt),
+		remoteregistry.Sign(key, index),
+	)
+}
+
+// writePublished writes a file that is served next to the manifests (world-readable like them).
+func writePublished(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0644); err != nil { // #nosec G306 -- signatures are public, like the manifests
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skosovsky/prompty/parser/yaml"
+	"github.com/skosovsky/prompty/remoteregistry"
+)
+
+func TestRunSign(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	pub, _ := key.Public().(ed25519.PublicKey)
+	keyring, err := remoteregistry.NewKeyring(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "signing.key")
+	if err := os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(key.Seed())), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		verifier remoteregistry.Verifier
+		files    []string
+	}{
+		{"detached", nil, remoteregistry.NewSignatureVerifier(keyring), []string{"agent.sig", "team/router.sig"}},
+		{"index", []string{"-index"}, remoteregistry.NewIndexVerifier(keyring), []string{"prompty.sum", "prompty.sum.sig"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeManifest(t, dir, "agent.yaml", "agent")
+			writeManifest(t, dir, "team/router.yaml", "team/router")
+
+			var out bytes.Buffer
+			if err := runSign(append(tc.args, "-key", keyPath, dir), &out); err != nil {
+				t.Fatalf("runSign: %v", err)
+			}
+			if !strings.Contains(out.String(), "Signed 2 manifests") || !strings.Contains(out.String(), remoteregistry.KeyID(pub)) {
+				t.Errorf("output = %q", out.String())
+			}
+			for _, f := range tc.files {
+				if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+					t.Errorf("missing %s: %v", f, err)
+				}
+			}
+
+			srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+			defer srv.Close()
+			fetcher, err := remoteregistry.NewHTTPFetcher(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reg, err := remoteregistry.New(fetcher, remoteregistry.WithParser(yaml.New()), remoteregistry.WithVerifier(tc.verifier))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			for _, id := range []string{"agent", "team/router"} {
+				if _, err := reg.GetTemplate(ctx, id); err != nil {
+					t.Errorf("GetTemplate(%q): %v", id, err)
+				}
+			}
+			writeManifest(t, dir, "agent.yaml", "tampered")
+			later := time.Now().Add(time.Hour) // Last-Modified has second precision; defeat the 304 revalidation
+			if err := os.Chtimes(filepath.Join(dir, "agent.yaml"), later, later); err != nil {
+				t.Fatal(err)
+			}
+			_, err = reg.GetTemplate(ctx, "agent")
+			if !errors.Is(err, remoteregistry.ErrVerificationFailed) {
+				t.Errorf("tampered manifest: got %v, want ErrVerificationFailed", err)
+			}
+		})
+	}
+}
+
+func TestRunSign_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if err := runSign([]string{dir}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "-key") {
+		t.Errorf("missing key: got %v", err)
+	}
+	keyPath := filepath.Join(dir, "k")
+	if err := os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSign([]string{"-key", keyPath, dir}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "no manifests") {
+		t.Errorf("empty dir: got %v", err)
+	}
+	writeManifest(t, dir, "a.yaml", "a")
+	if err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id":"a"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSign([]string{"-key", keyPath, dir}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "duplicate id") {
+		t.Errorf("duplicate id: got %v", err)
+	}
+}
+
+func writeManifest(t *testing.T, dir, rel, text string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	body := "id: " + strings.TrimSuffix(rel, filepath.Ext(rel)) + "\nmessages:\n  - role: system\n    content: " + text + "\n"
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
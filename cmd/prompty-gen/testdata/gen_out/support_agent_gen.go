@@ -4,8 +4,10 @@ package prompts
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/skosovsky/prompty"
+	"strings"
 )
 
 const SupportAgent PromptID = "support_agent"
@@ -15,6 +17,107 @@ type SupportAgentInput struct {
 	UserQuery string  `json:"user_query" prompt:"user_query" validate:"required"`
 }
 
+// SupportAgentGetOrderStatusArgs are the arguments of tool "get_order_status".
+type SupportAgentGetOrderStatusArgs struct {
+	OrderId *string `json:"order_id,omitempty" prompt:"order_id"`
+}
+
+// SupportAgentTools handles the tool calls of prompt "support_agent". A string result is sent as is, others as JSON.
+type SupportAgentTools interface {
+	// GetOrderStatus handles tool "get_order_status": Get order status
+	GetOrderStatus(ctx context.Context, args SupportAgentGetOrderStatusArgs) (any, error)
+}
+
+// SupportAgentToolDispatcher decodes tool calls into typed arguments and calls SupportAgentTools.
+// It implements prompty.ToolValidator for prompty.ExecuteWithToolValidation.
+type SupportAgentToolDispatcher struct {
+	tools SupportAgentTools
+}
+
+var _ prompty.ToolValidator = (*SupportAgentToolDispatcher)(nil)
+
+// NewSupportAgentToolDispatcher returns a dispatcher that calls tools.
+func NewSupportAgentToolDispatcher(tools SupportAgentTools) *SupportAgentToolDispatcher {
+	return &SupportAgentToolDispatcher{tools: tools}
+}
+
+// ValidateToolCall implements prompty.ToolValidator: the tool must be defined and its arguments must decode
+// and pass validation.
+func (d *SupportAgentToolDispatcher) ValidateToolCall(name, argsJSON string) error {
+	_, err := d.bind(name, argsJSON)
+	return err
+}
+
+// Call decodes argsJSON for tool name and calls the handler.
+func (d *SupportAgentToolDispatcher) Call(ctx context.Context, name, argsJSON string) (any, error) {
+	run, err := d.bind(name, argsJSON)
+	if err != nil {
+		return nil, err
+	}
+	return run(ctx)
+}
+
+// Dispatch runs call and returns its tool result; decoding, validation and handler errors become an
+// error result the model can correct.
+func (d *SupportAgentToolDispatcher) Dispatch(ctx context.Context, call prompty.ToolCallPart) prompty.ToolResultPart {
+	args := call.Args
+	if args == "" {
+		args = call.ArgsChunk
+	}
+	result, err := d.Call(ctx, call.Name, args)
+	if err != nil {
+		return prompty.ToolResultPart{
+			Content:    []prompty.ContentPart{prompty.TextPart{Text: err.Error()}},
+			IsError:    true,
+			Name:       call.Name,
+			ToolCallID: call.ID,
+		}
+	}
+	text, ok := result.(string)
+	if !ok {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return prompty.ToolResultPart{
+				Content:    []prompty.ContentPart{prompty.TextPart{Text: fmt.Sprintf("encode result: %v", err)}},
+				IsError:    true,
+				Name:       call.Name,
+				ToolCallID: call.ID,
+			}
+		}
+		text = string(data)
+	}
+	return prompty.ToolResultPart{
+		Content:    []prompty.ContentPart{prompty.TextPart{Text: text}},
+		Name:       call.Name,
+		ToolCallID: call.ID,
+	}
+}
+
+// bind decodes and validates argsJSON (empty means {}) and returns the handler call for those arguments.
+func (d *SupportAgentToolDispatcher) bind(name, argsJSON string) (func(context.Context) (any, error), error) {
+	argsErr := func(name string, err error) error {
+		return fmt.Errorf("tool %q arguments: %w", name, err)
+	}
+	if strings.TrimSpace(argsJSON) == "" {
+		argsJSON = "{}"
+	}
+	switch name {
+	case "get_order_status":
+		var args SupportAgentGetOrderStatusArgs
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return nil, argsErr(name, err)
+		}
+		if err := validate.Struct(&args); err != nil {
+			return nil, argsErr(name, err)
+		}
+		return func(ctx context.Context) (any, error) {
+			return d.tools.GetOrderStatus(ctx, args)
+		}, nil
+	default:
+		return nil, fmt.Errorf("tool %q is not defined", name)
+	}
+}
+
 func (p *Prompts) RenderSupportAgent(ctx context.Context, input SupportAgentInput) (*prompty.PromptExecution, error) {
 	if err := validate.Struct(&input); err != nil {
 		return nil, fmt.Errorf("validate input: %w", err)
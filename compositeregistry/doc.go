@@ -0,0 +1,6 @@
+// Package compositeregistry provides a registry that overlays an ordered list of registries, e.g. operator
+// overrides from a directory or git repo on top of defaults shipped with embedregistry.
+// New takes the sources in precedence order; PrecedenceFirstMatch (default) serves the first source that has the
+// template, PrecedenceHighestVersion the one with the highest semantic Metadata.Version.
+// Lookup (and Metadata.Extras[SourceKey]) reports which source served a template.
+package compositeregistry
@@ -0,0 +1,310 @@
+package compositeregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/skosovsky/prompty"
+)
+
+// Ensures Registry implements prompty.Registry, Lister, Statter, and Versioner.
+var (
+	_ prompty.Registry  = (*Registry)(nil)
+	_ prompty.Lister    = (*Registry)(nil)
+	_ prompty.Statter   = (*Registry)(nil)
+	_ prompty.Versioner = (*Registry)(nil)
+)
+
+// SourceKey is the Metadata.Extras key set on served templates to the name of the source that served them.
+const SourceKey = "prompty_source"
+
+// Precedence selects which source serves a template when several have it.
+type Precedence int
+
+const (
+	// PrecedenceFirstMatch serves the template from the first source (in New order) that has it.
+	PrecedenceFirstMatch Precedence = iota
+	// PrecedenceHighestVersion queries every source and serves the template with the highest semantic
+	// Metadata.Version; versions that are not semver rank lowest and ties go to the earlier source.
+	PrecedenceHighestVersion
+)
+
+// Source is one named registry in the overlay.
+type Source struct {
+	Name     string
+	Registry prompty.Registry
+}
+
+// Registry overlays sources in precedence order. Stateless; safe for concurrent use if the sources are.
+//
+// A source that does not have the template (prompty.ErrTemplateNotFound) or fails with a transient error
+// (network, fetch, ...) is skipped. Content errors are returned as is and never masked by a lower source:
+// prompty.ErrInvalidManifest, ErrTemplateParse, ErrExtendsCycle, ErrFuncConflict, ErrInvalidName, ErrInvalidVersion.
+type Registry struct {
+	sources    []Source
+	precedence Precedence
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithPrecedence sets how sources are chosen (default PrecedenceFirstMatch).
+func WithPrecedence(p Precedence) Option {
+	return func(r *Registry) { r.precedence = p }
+}
+
+// New creates a Registry over sources, highest precedence first.
+// Returns error if sources is empty, a registry is nil, or names are empty or duplicated.
+func New(sources []Source, opts ...Option) (*Registry, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("compositeregistry: at least one source is required")
+	}
+	seen := make(map[string]bool, len(sources))
+	for i, s := range sources {
+		if s.Registry == nil {
+			return nil, fmt.Errorf("compositeregistry: source %d (%q) has a nil registry", i, s.Name)
+		}
+		if strings.TrimSpace(s.Name) == "" {
+			return nil, fmt.Errorf("compositeregistry: source %d has an empty name", i)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("compositeregistry: duplicate source name %q", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	r := &Registry{sources: slices.Clone(sources), precedence: PrecedenceFirstMatch}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// fatal reports errors that a lower-precedence source must not mask: a broken manifest or an invalid id.
+func fatal(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, prompty.ErrInvalidManifest) ||
+		errors.Is(err, prompty.ErrTemplateParse) ||
+		errors.Is(err, prompty.ErrExtendsCycle) ||
+		errors.Is(err, prompty.ErrFuncConflict) ||
+		errors.Is(err, prompty.ErrInvalidName) ||
+		errors.Is(err, prompty.ErrInvalidVersion)
+}
+
+// notFound builds the error returned when no source served id: ErrTemplateNotFound, plus the transient
+// errors of skipped sources so callers can tell "missing everywhere" from "remote down".
+func notFound(id string, transient []error) error {
+	if len(transient) == 0 {
+		return fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	}
+	return fmt.Errorf("%w: %q: %w", prompty.ErrTemplateNotFound, id, errors.Join(transient...))
+}
+
+// GetTemplate returns the template from the source chosen by the precedence (see Lookup).
+// Metadata.Extras[SourceKey] holds the source name.
+func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	tpl, _, err := r.Lookup(ctx, id)
+	return tpl, err
+}
+
+// Lookup is GetTemplate that also returns the name of the source that served the template.
+func (r *Registry) Lookup(ctx context.Context, id string) (*prompty.ChatPromptTemplate, string, error) {
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+	var (
+		best       *prompty.ChatPromptTemplate
+		bestSource string
+		transient  []error
+	)
+	for _, s := range r.sources {
+		tpl, err := s.Registry.GetTemplate(ctx, id)
+		switch {
+		case err == nil:
+		case fatal(err):
+			return nil, "", fmt.Errorf("compositeregistry: source %q: %w", s.Name, err)
+		case errors.Is(err, prompty.ErrTemplateNotFound):
+			continue
+		default:
+			transient = append(transient, fmt.Errorf("source %q: %w", s.Name, err))
+			continue
+		}
+		if r.precedence == PrecedenceFirstMatch {
+			return withSource(tpl, s.Name), s.Name, nil
+		}
+		if best == nil || newer(tpl.Metadata.Version, best.Metadata.Version) {
+			best, bestSource = tpl, s.Name
+		}
+	}
+	if best == nil {
+		return nil, "", notFound(id, transient)
+	}
+	return withSource(best, bestSource), bestSource, nil
+}
+
+// newer reports whether version a outranks b: semver beats non-semver, then semver precedence.
+func newer(a, b string) bool {
+	va, errA := prompty.ParseVersion(a)
+	vb, errB := prompty.ParseVersion(b)
+	switch {
+	case errA != nil:
+		return false
+	case errB != nil:
+		return true
+	default:
+		return va.Compare(vb) > 0
+	}
+}
+
+func withSource(tpl *prompty.ChatPromptTemplate, source string) *prompty.ChatPromptTemplate {
+	if tpl.Metadata.Extras == nil {
+		tpl.Metadata.Extras = make(map[string]any, 1)
+	}
+	tpl.Metadata.Extras[SourceKey] = source
+	return tpl
+}
+
+// List returns the sorted union of ids from sources that implement prompty.Lister.
+// Sources whose List fails are skipped; an error is returned only if every lister failed.
+func (r *Registry) List(ctx context.Context) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var (
+		ids     []string
+		errs    []error
+		listers int
+	)
+	for _, s := range r.sources {
+		lister, ok := s.Registry.(prompty.Lister)
+		if !ok {
+			continue
+		}
+		listers++
+		got, err := lister.List(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("source %q: %w", s.Name, err))
+			continue
+		}
+		ids = append(ids, got...)
+	}
+	if listers > 0 && len(errs) == listers {
+		return nil, fmt.Errorf("compositeregistry: list: %w", errors.Join(errs...))
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// Stat returns metadata from the source that serves id, consulting sources in GetTemplate's precedence:
+// with PrecedenceFirstMatch the first source that has id, with PrecedenceHighestVersion the source chosen by
+// Lookup. A source that does not implement prompty.Statter, or cannot stat an id it serves, reports ID and
+// Metadata.Version of the template it serves.
+func (r *Registry) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	if ctx.Err() != nil {
+		return prompty.TemplateInfo{}, ctx.Err()
+	}
+	if r.precedence == PrecedenceHighestVersion {
+		tpl, name, err := r.Lookup(ctx, id)
+		if err != nil {
+			return prompty.TemplateInfo{}, err
+		}
+		return r.statSource(ctx, r.source(name), id, tpl)
+	}
+	var transient []error
+	for _, s := range r.sources {
+		info, err := r.statSource(ctx, s, id, nil)
+		switch {
+		case err == nil:
+			return info, nil
+		case fatal(err):
+			return prompty.TemplateInfo{}, fmt.Errorf("compositeregistry: source %q: %w", s.Name, err)
+		case !errors.Is(err, prompty.ErrTemplateNotFound):
+			transient = append(transient, fmt.Errorf("source %q: %w", s.Name, err))
+		}
+	}
+	return prompty.TemplateInfo{}, notFound(id, transient)
+}
+
+// statSource stats id in one source. When the source cannot stat id, whether it serves id is decided by
+// GetTemplate, as in Lookup; tpl, when already loaded, avoids that second call.
+func (r *Registry) statSource(
+	ctx context.Context,
+	s Source,
+	id string,
+	tpl *prompty.ChatPromptTemplate,
+) (prompty.TemplateInfo, error) {
+	if statter, ok := s.Registry.(prompty.Statter); ok {
+		info, err := statter.Stat(ctx, id)
+		if err == nil || fatal(err) {
+			return info, err
+		}
+	}
+	if tpl == nil {
+		var err error
+		if tpl, err = s.Registry.GetTemplate(ctx, id); err != nil {
+			return prompty.TemplateInfo{}, err
+		}
+	}
+	return prompty.TemplateInfo{ID: id, Version: tpl.Metadata.Version, UpdatedAt: time.Time{}}, nil
+}
+
+func (r *Registry) source(name string) Source {
+	for _, s := range r.sources {
+		if s.Name == name {
+			return s
+		}
+	}
+	return Source{}
+}
+
+// Versions returns the union of versions from sources that implement prompty.Versioner, ascending.
+func (r *Registry) Versions(ctx context.Context, id string) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var all []string
+	for _, s := range r.sources {
+		versioner, ok := s.Registry.(prompty.Versioner)
+		if !ok {
+			continue
+		}
+		versions, err := versioner.Versions(ctx, id)
+		if err != nil {
+			if fatal(err) {
+				return nil, fmt.Errorf("compositeregistry: source %q: %w", s.Name, err)
+			}
+			continue
+		}
+		all = append(all, versions...)
+	}
+	return prompty.SortVersions(all), nil
+}
+
+// Sources returns the source names in precedence order.
+func (r *Registry) Sources() []string {
+	names := make([]string, len(r.sources))
+	for i, s := range r.sources {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Close calls Close on every source that supports it and joins the errors.
+func (r *Registry) Close() error {
+	var errs []error
+	for _, s := range r.sources {
+		if c, ok := s.Registry.(interface{ Close() error }); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("source %q: %w", s.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,216 @@
+package compositeregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/embedregistry"
+	"github.com/skosovsky/prompty/fileregistry"
+	"github.com/skosovsky/prompty/manifest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func jsonManifest(id, version, text string) string {
+	return `{"id":"` + id + `","version":"` + version + `","messages":[{"role":"system","content":[` +
+		`{"type":"text","text":"` + text + `"}]}]}`
+}
+
+// defaults is an embedregistry with greet@1.0.0 and farewell@1.0.0.
+func defaults(t *testing.T) *embedregistry.Registry {
+	t.Helper()
+	reg, err := embedregistry.New(fstest.MapFS{
+		"p/greet.json":    &fstest.MapFile{Data: []byte(jsonManifest("greet", "1.0.0", "default greet"))},
+		"p/farewell.json": &fstest.MapFile{Data: []byte(jsonManifest("farewell", "1.0.0", "default farewell"))},
+	}, "p", embedregistry.WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	return reg
+}
+
+// overrides is a fileregistry in a temp dir with the given id -> manifest files.
+func overrides(t *testing.T, files map[string]string) *fileregistry.Registry {
+	t.Helper()
+	dir := t.TempDir()
+	for id, body := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id+".json"), []byte(body), 0600))
+	}
+	reg, err := fileregistry.New(dir, fileregistry.WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	return reg
+}
+
+// failingRegistry fails every call with err.
+type failingRegistry struct {
+	err error
+}
+
+func (f failingRegistry) GetTemplate(context.Context, string) (*prompty.ChatPromptTemplate, error) {
+	return nil, f.err
+}
+
+func (f failingRegistry) List(context.Context) ([]string, error) {
+	return nil, f.err
+}
+
+// noStatRegistry serves templates but cannot stat them (like a cache over a registry without Stat).
+type noStatRegistry struct {
+	prompty.Registry
+}
+
+func (noStatRegistry) Stat(_ context.Context, id string) (prompty.TemplateInfo, error) {
+	return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+}
+
+func text(t *testing.T, tpl *prompty.ChatPromptTemplate) string {
+	t.Helper()
+	exec, err := tpl.Format(nil)
+	require.NoError(t, err)
+	part, ok := exec.Messages[0].Content[0].(prompty.TextPart)
+	require.True(t, ok)
+	return part.Text
+}
+
+func TestRegistry_FirstMatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reg, err := New([]Source{
+		{Name: "overrides", Registry: overrides(t, map[string]string{"greet": jsonManifest("greet", "0.9.0", "custom")})},
+		{Name: "defaults", Registry: defaults(t)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"overrides", "defaults"}, reg.Sources())
+
+	tpl, source, err := reg.Lookup(ctx, "greet")
+	require.NoError(t, err)
+	assert.Equal(t, "overrides", source)
+	assert.Equal(t, "custom", text(t, tpl))
+	assert.Equal(t, "overrides", tpl.Metadata.Extras[SourceKey])
+
+	tpl, err = reg.GetTemplate(ctx, "farewell")
+	require.NoError(t, err)
+	assert.Equal(t, "default farewell", text(t, tpl))
+	assert.Equal(t, "defaults", tpl.Metadata.Extras[SourceKey])
+
+	_, err = reg.GetTemplate(ctx, "missing")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"farewell", "greet"}, ids)
+
+	info, err := reg.Stat(ctx, "greet")
+	require.NoError(t, err)
+	assert.False(t, info.UpdatedAt.IsZero(), "stat delegated to the file source")
+	info, err = reg.Stat(ctx, "farewell")
+	require.NoError(t, err)
+	assert.True(t, info.UpdatedAt.IsZero(), "stat delegated to the embed source")
+}
+
+func TestRegistry_Stat_FollowsLookupSource(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reg, err := New([]Source{
+		{Name: "defaults", Registry: noStatRegistry{defaults(t)}},
+		{Name: "overrides", Registry: overrides(t, map[string]string{"greet": jsonManifest("greet", "0.9.0", "custom")})},
+	})
+	require.NoError(t, err)
+	_, source, err := reg.Lookup(ctx, "greet")
+	require.NoError(t, err)
+	require.Equal(t, "defaults", source)
+
+	info, err := reg.Stat(ctx, "greet")
+	require.NoError(t, err)
+	assert.Equal(t, prompty.TemplateInfo{ID: "greet", Version: "1.0.0"}, info, "not the skipped overrides file")
+	_, err = reg.Stat(ctx, "missing")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+}
+
+func TestRegistry_HighestVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reg, err := New([]Source{
+		{Name: "overrides", Registry: overrides(t, map[string]string{
+			"greet":    jsonManifest("greet", "0.9.0", "old override"),
+			"farewell": jsonManifest("farewell", "1.1.0", "new override"),
+		})},
+		{Name: "defaults", Registry: defaults(t)},
+	}, WithPrecedence(PrecedenceHighestVersion))
+	require.NoError(t, err)
+
+	tpl, source, err := reg.Lookup(ctx, "greet")
+	require.NoError(t, err)
+	assert.Equal(t, "defaults", source)
+	assert.Equal(t, "default greet", text(t, tpl))
+	tpl, source, err = reg.Lookup(ctx, "farewell")
+	require.NoError(t, err)
+	assert.Equal(t, "overrides", source)
+	assert.Equal(t, "new override", text(t, tpl))
+
+	info, err := reg.Stat(ctx, "greet")
+	require.NoError(t, err)
+	assert.True(t, info.UpdatedAt.IsZero(), "stat delegated to the source that won")
+
+	versions, err := reg.Versions(ctx, "farewell")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+}
+
+func TestRegistry_Fallback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	down := errors.New("connection refused")
+	reg, err := New([]Source{
+		{Name: "remote", Registry: failingRegistry{err: down}},
+		{Name: "defaults", Registry: defaults(t)},
+	})
+	require.NoError(t, err)
+	tpl, source, err := reg.Lookup(ctx, "greet")
+	require.NoError(t, err, "transient errors fall back to the next source")
+	assert.Equal(t, "defaults", source)
+	assert.Equal(t, "default greet", text(t, tpl))
+	ids, err := reg.List(ctx)
+	require.NoError(t, err, "a failing lister is skipped")
+	assert.Equal(t, []string{"farewell", "greet"}, ids)
+
+	_, err = reg.GetTemplate(ctx, "missing")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	require.ErrorIs(t, err, down, "skipped transient errors are reported")
+
+	broken, err := New([]Source{
+		{Name: "overrides", Registry: overrides(t, map[string]string{"greet": `{"id":"greet"}`})},
+		{Name: "defaults", Registry: defaults(t)},
+	})
+	require.NoError(t, err)
+	_, err = broken.GetTemplate(ctx, "greet")
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest, "a broken override is not masked by the default")
+	assert.Contains(t, err.Error(), `source "overrides"`)
+
+	allDown, err := New([]Source{{Name: "remote", Registry: failingRegistry{err: down}}})
+	require.NoError(t, err)
+	_, err = allDown.List(ctx)
+	require.ErrorIs(t, err, down)
+}
+
+func TestNew_InvalidSources(t *testing.T) {
+	t.Parallel()
+	_, err := New(nil)
+	require.Error(t, err)
+	_, err = New([]Source{{Name: "a", Registry: nil}})
+	require.Error(t, err)
+	_, err = New([]Source{{Name: "", Registry: failingRegistry{}}})
+	require.Error(t, err)
+	_, err = New([]Source{{Name: "a", Registry: failingRegistry{}}, {Name: "a", Registry: failingRegistry{}}})
+	require.Error(t, err)
+}
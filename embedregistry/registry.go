@@ -2,48 +2,77 @@ package embedregistry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/manifest"
 )
 
-// Ensures Registry implements prompty.Registry, Lister, and Statter.
+// Ensures Registry implements prompty.Registry, Lister, Statter, and Versioner.
 var (
-	_ prompty.Registry = (*Registry)(nil)
-	_ prompty.Lister   = (*Registry)(nil)
-	_ prompty.Statter  = (*Registry)(nil)
+	_ prompty.Registry  = (*Registry)(nil)
+	_ prompty.Lister    = (*Registry)(nil)
+	_ prompty.Statter   = (*Registry)(nil)
+	_ prompty.Versioner = (*Registry)(nil)
 )
 
 // Registry loads all manifests from an [fs.FS] at construction (eager). No mutex. Holds parsed templates by id.
-// WithEnvironment(env): GetTemplate tries id.env first, then id (e.g. internal/router.prod before internal/router).
+// WithEnvironment(env): GetTemplate tries id.env first, then id (e.g. internal/router.prod before internal/router);
+// WithEnvironmentChain sets several envs. Env files marked `overlay: true` patch the next layer instead of replacing it.
+// Versioned manifests ({id}/v{version}.yaml) are served for "id@constraint".
 // Parser is required; use WithParser when creating the registry.
 type Registry struct {
 	cache           map[string]*prompty.ChatPromptTemplate
-	ids             []string // ordered list of ids for List()
+	ids             []string            // ordered list of ids for List()
+	versions        map[string][]string // id -> versions from the {id}/v{version}.yaml layout (for env or no env)
 	root            string
-	env             string // e.g. "prod"; GetTemplate tries id.env first
-	partialsPattern string // e.g. "partials/*.tmpl"; relative to root
+	envs            []string // e.g. ["prod-eu", "prod"]; GetTemplate tries id.prod-eu, id.prod, then id
+	partialsPattern string   // e.g. "partials/*.tmpl"; relative to root
 	parser          manifest.Unmarshaler
 	version         string // optional build/git version from WithVersion
+	strict          bool   // WithStrict: templates are built with prompty.WithStrict
+	funcs           template.FuncMap
 }
 
 // baseIDFromPath converts a manifest path (slash, no ext) to base ID: drops env suffix from basename.
 // Example: "internal/router.prod" -> "internal/router".
 func baseIDFromPath(slashPath string) string {
+	id, _ := splitEnv(slashPath)
+	return id
+}
+
+// splitEnv splits a manifest path (slash, no ext) into its cache key and env suffix:
+// "internal/router.prod" -> ("internal/router", "prod"); "agent/v1.2.0.prod" -> ("agent/v1.2.0", "prod").
+func splitEnv(slashPath string) (key, env string) {
+	if id, version, env, ok := prompty.ParseVersionedPath(slashPath); ok {
+		return prompty.VersionedPath(id, version), env
+	}
 	base := filepath.Base(slashPath)
 	if idx := strings.Index(base, "."); idx > 0 {
-		base = base[:idx]
+		base, env = base[:idx], base[idx+1:]
 	}
 	dir := filepath.Dir(slashPath)
 	if dir == "." {
-		return base
+		return base, env
+	}
+	return filepath.ToSlash(filepath.Join(dir, base)), env
+}
+
+// extRank orders manifest extensions like fileregistry resolution: .yaml, .yml, .json.
+func extRank(path string) int {
+	for i, ext := range []string{".yaml", ".yml", ".json"} {
+		if strings.HasSuffix(path, ext) {
+			return i
+		}
 	}
-	return filepath.ToSlash(filepath.Join(dir, base))
+	return len(path)
 }
 
 // underPartialsDir reports whether relPath (relative to walk root) is under partials pattern dir.
@@ -60,16 +89,22 @@ func underPartialsDir(relPath, partialsPattern string) bool {
 }
 
 // New walks fsys, parses every .yaml/.yml/.json under root, and returns a Registry.
-// Cache keys are full id (agent, agent.prod); List returns base IDs only (agent).
+// Manifests with extends are resolved against other manifests in the same fsys.
+// Cache keys are base ids (agent, agent/v1.2.0) composed over the env chain; List returns base IDs only (agent).
 // Parser is required (use WithParser).
 func New(fsys fs.FS, root string, opts ...Option) (*Registry, error) {
-	r := &Registry{cache: make(map[string]*prompty.ChatPromptTemplate), root: root}
+	r := &Registry{
+		cache:    make(map[string]*prompty.ChatPromptTemplate),
+		versions: make(map[string][]string),
+		root:     root,
+	}
 	for _, opt := range opts {
 		opt(r)
 	}
 	if r.parser == nil {
 		return nil, prompty.ErrNoParser
 	}
+	l := &loader{r: r, fsys: fsys, layers: make(map[string]map[string]string)}
 	seenID := make(map[string]bool)
 	seenBaseID := make(map[string]bool)
 	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
@@ -89,26 +124,28 @@ func New(fsys fs.FS, root string, opts ...Option) (*Registry, error) {
 		if underPartialsDir(relPath, r.partialsPattern) {
 			return nil
 		}
-		var tpl *prompty.ChatPromptTemplate
-		if r.partialsPattern != "" {
-			partialsPath := filepath.Join(r.root, r.partialsPattern)
-			tpl, err = manifest.ParseFS(fsys, path, r.parser, manifest.WithPartialsFS(fsys, partialsPath))
-		} else {
-			tpl, err = manifest.ParseFS(fsys, path, r.parser)
-		}
-		if err != nil {
-			return fmt.Errorf("%s: %w", path, err)
-		}
 		slashPath := filepath.ToSlash(relPath)
 		id := slashPath
 		for _, ext := range []string{".yaml", ".yml", ".json"} {
 			id = strings.TrimSuffix(id, ext)
 		}
-		tpl.Metadata.Environment = ""
-		r.cache[id] = tpl
+		key, env := splitEnv(id)
+		if l.layers[key] == nil {
+			l.layers[key] = make(map[string]string)
+			l.order = append(l.order, key)
+		}
+		if prev, ok := l.layers[key][env]; !ok || extRank(path) < extRank(prev) {
+			l.layers[key][env] = path
+		}
 		if !seenID[id] {
 			seenID[id] = true
 			baseID := baseIDFromPath(id)
+			if versionedID, version, env, ok := prompty.ParseVersionedPath(id); ok {
+				baseID = versionedID
+				if (env == "" || slices.Contains(r.envs, env)) && !slices.Contains(r.versions[baseID], version) {
+					r.versions[baseID] = append(r.versions[baseID], version)
+				}
+			}
 			if !seenBaseID[baseID] {
 				seenBaseID[baseID] = true
 				r.ids = append(r.ids, baseID)
@@ -119,9 +156,88 @@ func New(fsys fs.FS, root string, opts ...Option) (*Registry, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Parse after the walk so extends can reference manifests found later in walk order.
+	for _, key := range l.order {
+		if !l.inChain(key) {
+			continue // only other environments' files: nothing to serve
+		}
+		if _, err := l.load(context.Background(), key); err != nil {
+			return nil, err
+		}
+	}
 	return r, nil
 }
 
+// loader parses manifests on demand during New, memoizing into r.cache so extends parents
+// are parsed once and before their children.
+type loader struct {
+	r      *Registry
+	fsys   fs.FS
+	layers map[string]map[string]string // key (agent, agent/v1.2.0) -> env ("" for base) -> path in fsys
+	order  []string                     // keys in walk order
+}
+
+// inChain reports whether key has a base manifest or a file for one of the registry envs.
+func (l *loader) inChain(key string) bool {
+	for env := range l.layers[key] {
+		if env == "" || slices.Contains(l.r.envs, env) {
+			return true
+		}
+	}
+	return false
+}
+
+// load composes the manifest for key over the registry env chain unless already cached.
+func (l *loader) load(ctx context.Context, key string) (*prompty.ChatPromptTemplate, error) {
+	if tpl, ok := l.r.cache[key]; ok {
+		return tpl, nil
+	}
+	layers := l.layers[key]
+	opts := []manifest.ParseOption{manifest.WithBaseRegistry(ctx, l)}
+	if l.r.partialsPattern != "" {
+		partialsPath := filepath.Join(l.r.root, l.r.partialsPattern)
+		opts = append(opts, manifest.WithPartialsFS(l.fsys, partialsPath))
+	}
+	if l.r.strict {
+		opts = append(opts, manifest.WithStrict())
+	}
+	if l.r.funcs != nil {
+		opts = append(opts, manifest.WithFuncs(l.r.funcs))
+	}
+	read := func(env string) ([]byte, bool, error) {
+		path, ok := layers[env]
+		if !ok {
+			return nil, false, nil
+		}
+		data, err := fs.ReadFile(l.fsys, path)
+		if err != nil {
+			return nil, false, fmt.Errorf("manifest: read fs: %w", err)
+		}
+		return data, true, nil
+	}
+	tpl, err := manifest.ParseEnvChain(read, l.r.envs, l.r.parser, opts...)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, key)
+		}
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	l.r.cache[key] = tpl
+	return tpl, nil
+}
+
+// GetTemplate implements prompty.Registry for extends resolution (same env fallback as Registry.GetTemplate).
+func (l *loader) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	if err := prompty.ValidateID(id); err != nil {
+		return nil, err
+	}
+	tpl, err := l.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return prompty.CloneTemplate(tpl), nil
+}
+
 // Option configures a Registry.
 type Option func(*Registry)
 
@@ -132,7 +248,16 @@ func WithPartials(pattern string) Option {
 
 // WithEnvironment sets env for fallback: GetTemplate tries id.env first, then id.
 func WithEnvironment(env string) Option {
-	return func(r *Registry) { r.env = env }
+	return WithEnvironmentChain(env)
+}
+
+// WithEnvironmentChain sets the env fallback chain, most specific first: WithEnvironmentChain("prod-eu", "prod")
+// tries id.prod-eu, then id.prod, then id. Overlays (`overlay: true`) found on the way are applied over the first
+// complete manifest (see manifest.ParseEnvChain); Metadata.Environment lists them.
+func WithEnvironmentChain(envs ...string) Option {
+	return func(r *Registry) {
+		r.envs = slices.DeleteFunc(slices.Clone(envs), func(env string) bool { return env == "" })
+	}
 }
 
 // WithParser sets the manifest parser (required). Use manifest.NewJSONParser() or parser from github.com/skosovsky/prompty/parser/yaml for YAML.
@@ -145,35 +270,61 @@ func WithVersion(version string) Option {
 	return func(r *Registry) { r.version = version }
 }
 
-// candidateIDs returns ids to try in order: with env first, then base id.
-func candidateIDs(id, env string) []string {
-	if env != "" {
-		return []string{id + "." + env, id}
-	}
-	return []string{id}
+// WithFuncs adds custom template functions to every template (see prompty.WithFuncs).
+// Loading fails with prompty.ErrFuncConflict if a name shadows a built-in.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Registry) { r.funcs = funcs }
+}
+
+// WithStrict builds every template with prompty.WithStrict, so rendering fails on unused payload fields
+// and "<no value>" output. Useful in CI to catch broken prompts.
+func WithStrict() Option {
+	return func(r *Registry) { r.strict = true }
 }
 
 // GetTemplate returns a template by id. O(1) map lookup. With env, tries id.env first. Enriches tpl.Metadata.Version from Stat if empty.
+// "id@constraint" (e.g. "agent@1.2.0", "agent@latest", "agent@^1.2") resolves against Versions.
 func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
-	if err := prompty.ValidateID(id); err != nil {
+	if err := prompty.ValidateVersionedID(id); err != nil {
 		return nil, err
 	}
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	for _, cid := range candidateIDs(id, r.env) {
-		if tpl, ok := r.cache[cid]; ok {
-			clone := prompty.CloneTemplate(tpl)
-			info, _ := r.Stat(ctx, cid)
-			if info.Version != "" && clone.Metadata.Version == "" {
-				clone.Metadata.Version = info.Version
-			}
-			return clone, nil
+	if base, constraint, ok := prompty.SplitVersionedID(id); ok {
+		key, version, err := r.resolveVersion(base, constraint)
+		if err != nil {
+			return nil, err
+		}
+		if key == base {
+			return r.GetTemplate(ctx, base)
+		}
+		tpl, _ := r.lookup(key)
+		clone := prompty.CloneTemplate(tpl)
+		if clone.Metadata.Version == "" {
+			clone.Metadata.Version = version
 		}
+		return clone, nil
+	}
+	if tpl, cid := r.lookup(id); tpl != nil {
+		clone := prompty.CloneTemplate(tpl)
+		info, _ := r.Stat(ctx, cid)
+		if info.Version != "" && clone.Metadata.Version == "" {
+			clone.Metadata.Version = info.Version
+		}
+		return clone, nil
 	}
 	return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
 }
 
+// lookup returns the cached template for id (composed over the env chain at New) and its cache key.
+func (r *Registry) lookup(id string) (*prompty.ChatPromptTemplate, string) {
+	if tpl, ok := r.cache[id]; ok {
+		return tpl, id
+	}
+	return nil, ""
+}
+
 // List returns all template ids (order from walk).
 func (r *Registry) List(ctx context.Context) ([]string, error) {
 	if ctx.Err() != nil {
@@ -183,19 +334,59 @@ func (r *Registry) List(ctx context.Context) ([]string, error) {
 }
 
 // Stat returns metadata for id without parsing. Uses same env fallback as GetTemplate (id.env -> id).
-// Version from WithVersion; UpdatedAt is zero for embed.
+// Version from WithVersion (the resolved version for "id@constraint"); UpdatedAt is zero for embed.
 func (r *Registry) Stat(_ context.Context, id string) (prompty.TemplateInfo, error) {
-	if err := prompty.ValidateID(id); err != nil {
+	if err := prompty.ValidateVersionedID(id); err != nil {
 		return prompty.TemplateInfo{}, err
 	}
-	for _, cid := range candidateIDs(id, r.env) {
-		if _, ok := r.cache[cid]; ok {
-			return prompty.TemplateInfo{
-				ID:        id,
-				Version:   r.version,
-				UpdatedAt: time.Time{},
-			}, nil
+	if base, constraint, ok := prompty.SplitVersionedID(id); ok {
+		_, version, err := r.resolveVersion(base, constraint)
+		if err != nil {
+			return prompty.TemplateInfo{}, err
 		}
+		return prompty.TemplateInfo{ID: id, Version: version, UpdatedAt: time.Time{}}, nil
+	}
+	if tpl, _ := r.lookup(id); tpl != nil {
+		return prompty.TemplateInfo{
+			ID:        id,
+			Version:   r.version,
+			UpdatedAt: time.Time{},
+		}, nil
 	}
 	return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
 }
+
+// Versions implements prompty.Versioner: versions from the {id}/v{version}.yaml layout plus the semantic
+// `version` declared by the plain {id}.yaml, canonical and ascending.
+func (r *Registry) Versions(ctx context.Context, id string) ([]string, error) {
+	if err := prompty.ValidateID(id); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return prompty.SortVersions(r.versionList(id)), nil
+}
+
+func (r *Registry) versionList(id string) []string {
+	versions := slices.Clone(r.versions[id])
+	if tpl, _ := r.lookup(id); tpl != nil {
+		if v, err := prompty.ParseVersion(tpl.Metadata.Version); err == nil {
+			versions = append(versions, v.String())
+		}
+	}
+	return versions
+}
+
+// resolveVersion picks the best version of id for constraint and returns its lookup key:
+// the versioned path ({id}/v{version}) or id itself when the plain manifest declares that version.
+func (r *Registry) resolveVersion(id, constraint string) (key, version string, err error) {
+	version, err = prompty.ResolveVersion(prompty.SortVersions(r.versionList(id)), constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("template %q: %w", id, err)
+	}
+	if slices.Contains(r.versions[id], version) {
+		return prompty.VersionedPath(id, version), version, nil
+	}
+	return id, version, nil
+}
@@ -276,3 +276,120 @@ func TestEmbedRegistry_GetTemplate_WithPartials(t *testing.T) {
 	assert.Contains(t, textPart.Text, "Never give medical diagnoses.", "partial 'safety' must be rendered into message")
 	assert.Contains(t, textPart.Text, "You are a doctor assistant.")
 }
+
+func TestEmbedRegistry_Extends(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		// "a_child" sorts before "z_base" so the parent is parsed on demand.
+		"p/a_child.json": &fstest.MapFile{
+			Data: []byte(`{"id":"a_child","extends":"z_base","model_config":{"max_tokens":10}}`),
+		},
+		"p/z_base.json": &fstest.MapFile{
+			Data: []byte(
+				`{"id":"z_base","model_config":{"model":"m1"},` +
+					`"messages":[{"role":"system","content":[{"type":"text","text":"Base"}]}]}`,
+			),
+		},
+	}
+	reg, err := New(fsys, "p", WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	tpl, err := reg.GetTemplate(context.Background(), "a_child")
+	require.NoError(t, err)
+	assert.Equal(t, "a_child", tpl.Metadata.ID)
+	require.Len(t, tpl.Messages, 1)
+	assert.Equal(t, "Base", tpl.Messages[0].Content[0].Text)
+	require.NotNil(t, tpl.ModelOptions)
+	assert.Equal(t, "m1", tpl.ModelOptions.Model)
+	require.NotNil(t, tpl.ModelOptions.MaxTokens)
+	assert.Equal(t, int64(10), *tpl.ModelOptions.MaxTokens)
+}
+
+func TestEmbedRegistry_ExtendsMissingParent(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"p/child.json": &fstest.MapFile{Data: []byte(`{"id":"child","extends":"missing"}`)},
+	}
+	_, err := New(fsys, "p", WithParser(manifest.NewJSONParser()))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+}
+
+func TestEmbedRegistry_Versions(t *testing.T) {
+	t.Parallel()
+	file := func(version, text string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`{"id":"agent","version":"` + version + `","messages":[{"role":"system",` +
+			`"content":[{"type":"text","text":"` + text + `"}]}]}`)}
+	}
+	fsys := fstest.MapFS{
+		"p/agent.json":             file("2.0.0", "working copy"),
+		"p/agent/v1.0.0.json":      file("", "one"),
+		"p/agent/v1.1.0.json":      file("", "one-one"),
+		"p/agent/v1.1.0.prod.json": file("", "one-one prod"),
+		"p/agent/v1.2.0-rc.1.json": file("", "rc"),
+	}
+	ctx := context.Background()
+	reg, err := New(fsys, "p", WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	versions, err := reg.Versions(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0", "1.2.0-rc.1", "2.0.0"}, versions)
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent"}, ids)
+
+	text := func(reg *Registry, id string) string {
+		t.Helper()
+		tpl, err := reg.GetTemplate(ctx, id)
+		require.NoError(t, err, id)
+		exec, err := tpl.Format(nil)
+		require.NoError(t, err)
+		part, ok := exec.Messages[0].Content[0].(prompty.TextPart)
+		require.True(t, ok)
+		return part.Text
+	}
+	assert.Equal(t, "one", text(reg, "agent@1.0.0"))
+	assert.Equal(t, "one-one", text(reg, "agent@^1"))
+	assert.Equal(t, "working copy", text(reg, "agent@latest"))
+	assert.Equal(t, "rc", text(reg, "agent@1.2.0-rc.1"))
+	tpl, err := reg.GetTemplate(ctx, "agent@~1.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", tpl.Metadata.Version)
+	info, err := reg.Stat(ctx, "agent@<2")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", info.Version)
+	_, err = reg.GetTemplate(ctx, "agent@^3")
+	require.ErrorIs(t, err, prompty.ErrVersionNotFound)
+
+	prod, err := New(fsys, "p", WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	assert.Equal(t, "one-one prod", text(prod, "agent@1.1.0"))
+}
+
+func TestEmbedRegistry_EnvironmentChainOverlays(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"p/agent.json": &fstest.MapFile{Data: []byte(`{"id":"agent","model_config":{"model":"m-base"},` +
+			`"messages":[{"role":"system","content":[{"type":"text","text":"Base"}]}]}`)},
+		"p/agent.prod.json":    &fstest.MapFile{Data: []byte(`{"overlay":true,"model_config":{"model":"m-prod"}}`)},
+		"p/agent.prod-eu.json": &fstest.MapFile{Data: []byte(`{"overlay":true,"messages":[{"index":0,"content":[{"type":"text","text":"EU"}]}]}`)},
+		"p/only.staging.json": &fstest.MapFile{Data: []byte(`{"id":"only","messages":[{"role":"system",` +
+			`"content":[{"type":"text","text":"Staging"}]}]}`)},
+	}
+	reg, err := New(fsys, "p", WithParser(manifest.NewJSONParser()), WithEnvironmentChain("prod-eu", "prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "prod,prod-eu", tpl.Metadata.Environment)
+	assert.Equal(t, "m-prod", tpl.ModelOptions.Model)
+	assert.Equal(t, "EU", tpl.Messages[0].Content[0].Text)
+	_, err = reg.GetTemplate(ctx, "only")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+
+	reg, err = New(fsys, "p", WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Empty(t, tpl.Metadata.Environment)
+	assert.Equal(t, "m-base", tpl.ModelOptions.Model)
+}
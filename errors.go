@@ -29,6 +29,22 @@ var (
 	ErrConflictingDirectives = errors.New(
 		"prompty: conflicting directives (e.g. Tools and ResponseFormat cannot be used together)",
 	)
+	// ErrExtendsCycle indicates a manifest extends chain that refers back to itself.
+	ErrExtendsCycle = errors.New("prompty: manifest extends cycle")
+	// ErrFuncConflict indicates a custom template function (WithFuncs) that shadows a prompty or text/template built-in.
+	ErrFuncConflict = errors.New("prompty: template function conflicts with a built-in")
+	// ErrStrictRender indicates that strict rendering (WithStrict) found unused payload fields or <no value> output.
+	ErrStrictRender = errors.New("prompty: strict rendering failed")
+	// ErrInvalidVersion indicates a malformed semantic version or version constraint (e.g. in "id@^1.2").
+	ErrInvalidVersion = errors.New("prompty: invalid version or version constraint")
+	// ErrVersionNotFound indicates that no version of a template satisfies the requested constraint.
+	// ResolveVersion returns it together with ErrTemplateNotFound, so both match with [errors.Is].
+	ErrVersionNotFound = errors.New("prompty: no template version satisfies the constraint")
+	// ErrSchemaViolation indicates JSON (model output or tool arguments) that does not match its JSON Schema.
+	ErrSchemaViolation = errors.New("prompty: JSON does not match schema")
+	// ErrInvalidSchema indicates a JSON Schema that cannot be applied (e.g. a pattern that is not valid RE2 or an
+	// unresolvable $ref). It is a defect of the schema, not of the validated JSON, so it is never retried.
+	ErrInvalidSchema = errors.New("prompty: invalid JSON Schema")
 )
 
 // VariableError wraps a sentinel error with variable and template context.
@@ -53,6 +69,20 @@ type ToolCallError struct {
 	Err                 error
 }
 
+// StrictError is returned by Format and FormatStruct of a template built WithStrict.
+// Use [errors.Is](err, ErrStrictRender) and [errors.As](err, &strictErr) to inspect.
+type StrictError struct {
+	Template     string
+	UnusedFields []string // payload fields no template references (sorted)
+	NoValue      []string // locations whose rendered output contains "<no value>" (e.g. "message 1 part 0")
+}
+
+// SchemaError is returned by ValidateJSON and the built-in output and tool argument validation.
+// Use [errors.Is](err, ErrSchemaViolation) and [errors.As](err, &schemaErr) to inspect.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
 // Error implements error.
 func (e *VariableError) Error() string {
 	return fmt.Sprintf("prompty: variable %q in template %q: %v", e.Variable, e.Template, e.Err)
@@ -93,10 +123,39 @@ func (e *ToolCallError) Unwrap() error {
 	return e.Err
 }
 
+// Error implements error.
+func (e *StrictError) Error() string {
+	var problems []string
+	if len(e.UnusedFields) > 0 {
+		problems = append(problems, "unused payload fields "+strings.Join(e.UnusedFields, ", "))
+	}
+	if len(e.NoValue) > 0 {
+		problems = append(problems, "<no value> in "+strings.Join(e.NoValue, ", "))
+	}
+	return fmt.Sprintf("prompty: strict rendering of template %q: %s", e.Template, strings.Join(problems, "; "))
+}
+
+// Unwrap returns ErrStrictRender for [errors.Is].
+func (e *StrictError) Unwrap() error { return ErrStrictRender }
+
+// Error implements error.
+func (e *SchemaError) Error() string {
+	problems := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		problems[i] = v.String()
+	}
+	return fmt.Sprintf("%v: %s", ErrSchemaViolation, strings.Join(problems, "; "))
+}
+
+// Unwrap returns ErrSchemaViolation for [errors.Is].
+func (e *SchemaError) Unwrap() error { return ErrSchemaViolation }
+
 // Compile-time check that VariableError implements error.
 var _ error = (*VariableError)(nil)
 var _ error = (*ValidationError)(nil)
 var _ error = (*ToolCallError)(nil)
+var _ error = (*StrictError)(nil)
+var _ error = (*SchemaError)(nil)
 
 // ValidateName checks that name and env are safe for use in paths and cache keys.
 // Rejects empty name and names containing '/', '\\', "..", or ':'. Call before registry GetTemplate or path resolution.
@@ -135,3 +194,20 @@ func ValidateID(id string) error {
 	}
 	return nil
 }
+
+// ValidateVersionedID checks "id@constraint" (e.g. "support_agent@^1.2", "support_agent@latest"):
+// id must pass [ValidateID] and constraint must parse with [ParseVersionConstraint].
+// A plain id without "@" is validated with ValidateID only.
+func ValidateVersionedID(s string) error {
+	id, constraint, ok := SplitVersionedID(s)
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if _, err := ParseVersionConstraint(constraint); err != nil {
+		return err
+	}
+	return nil
+}
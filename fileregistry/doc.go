@@ -2,4 +2,5 @@
 // YAML manifests on demand (lazy) and caches them. Use New to create a Registry;
 // GetTemplate resolves id to files {dir}/{id}.yaml or {dir}/{id}.yml
 // with fallback to {dir}/{name}.yaml.
+// Watch (or Poll) opts into polling hot reload; Subscribe receives change events.
 package fileregistry
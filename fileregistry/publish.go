@@ -0,0 +1,154 @@
+package fileregistry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+)
+
+// Ensures Registry implements prompty.Publisher.
+var _ prompty.Publisher = (*Registry)(nil)
+
+// Put validates data (manifest.ValidateLayer) and writes it as the manifest for ref: {id}.{env}.yaml, or
+// {id}/v{version}.{env}.yaml for a released version. An existing file keeps its extension; a new one gets .json
+// when data is a JSON object, else .yaml. The write is atomic (temp file in the same directory, then rename), and
+// cached templates that use the file are invalidated.
+func (r *Registry) Put(ctx context.Context, ref prompty.ManifestRef, data []byte) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.validateLocked(ctx, ref, data); err != nil {
+		return err
+	}
+	_, path, err := readLayer(r.dir, ref.Path(), "")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		ext := ".yaml"
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			ext = ".json"
+		}
+		path = filepath.Join(r.dir, filepath.FromSlash(ref.Path()+ext))
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("fileregistry put %s: %w", ref, err)
+	}
+	r.invalidateLocked(path)
+	return nil
+}
+
+// Delete removes the manifest files for ref (every extension) and invalidates cached templates that used them.
+func (r *Registry) Delete(ctx context.Context, ref prompty.ManifestRef) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	removed := false
+	for _, path := range layerPaths(r.dir, ref.Path(), "") {
+		err := os.Remove(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("fileregistry delete %s: %w", ref, err)
+		}
+		removed = true
+		r.invalidateLocked(path)
+	}
+	if !removed {
+		return fmt.Errorf("%w: %s", prompty.ErrTemplateNotFound, ref)
+	}
+	return nil
+}
+
+// Promote copies the manifest file for ref to env (see prompty.Publisher), validated like Put.
+func (r *Registry) Promote(ctx context.Context, ref prompty.ManifestRef, env string) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	data, path, err := readLayer(r.dir, ref.Path(), "")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("%w: %s", prompty.ErrTemplateNotFound, ref)
+	}
+	to := ref
+	to.Env = env
+	return r.Put(ctx, to, data)
+}
+
+// validateLocked builds data as the manifest for ref over the files below it; r.mu must be held for writing.
+func (r *Registry) validateLocked(
+	ctx context.Context,
+	ref prompty.ManifestRef,
+	data []byte,
+) (*prompty.ChatPromptTemplate, error) {
+	below := func(env string) ([]byte, bool, error) {
+		layer := ref
+		layer.Env = env
+		data, path, err := readLayer(r.dir, layer.Path(), "")
+		return data, path != "", err
+	}
+	base := ref
+	base.Env = ""
+	return manifest.ValidateLayer(ref, data, r.envs, below, r.parser, r.parseOptions(ctx, base.Path())...)
+}
+
+// invalidateLocked drops cached templates that use the manifest at path (and their extends children);
+// r.mu must be held for writing.
+func (r *Registry) invalidateLocked(path string) {
+	for id := range r.affected([]string{path}) {
+		delete(r.cache, id)
+		delete(r.paths, id)
+	}
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it over path, creating parent directories.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil { // #nosec G301 -- manifest directories are shared like the files
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	mode := fs.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
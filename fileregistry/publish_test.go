@@ -0,0 +1,92 @@
+package fileregistry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishManifest(version, text string) []byte {
+	return []byte(`{"id":"agent","version":"` + version + `","messages":[{"role":"system","content":[{"type":"text","text":"` +
+		text + `"}]}]}`)
+}
+
+func TestRegistry_Publisher(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "team/agent"}, publishManifest("1.0.0", "v1")))
+	assert.FileExists(t, filepath.Join(dir, "team", "agent.json"))
+	tpl, err := reg.GetTemplate(ctx, "team/agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", tpl.Messages[0].Content[0].Text)
+
+	// Put replaces the file and invalidates the cached template.
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "team/agent"}, publishManifest("1.1.0", "v1.1")))
+	tpl, err = reg.GetTemplate(ctx, "team/agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1", tpl.Messages[0].Content[0].Text)
+
+	// Released versions go to the versioned layout; an overlay is validated over the layer below it.
+	ref := prompty.ManifestRef{ID: "team/agent", Version: "v1.0.0"}
+	require.NoError(t, reg.Put(ctx, ref, publishManifest("1.0.0", "released")))
+	assert.FileExists(t, filepath.Join(dir, "team", "agent", "v1.0.0.json"))
+	staging := prompty.ManifestRef{ID: "team/agent", Version: "1.0.0", Env: "staging"}
+	require.NoError(t, reg.Put(ctx, staging, []byte(`{"overlay":true,"model_config":{"model":"m-new"}}`)))
+	require.NoError(t, reg.Promote(ctx, staging, "prod"))
+	tpl, err = reg.GetTemplate(ctx, "team/agent@1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "released", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, "m-new", tpl.ModelOptions.Model)
+	assert.Equal(t, "prod", tpl.Metadata.Environment)
+
+	require.NoError(t, reg.Delete(ctx, prompty.ManifestRef{ID: "team/agent", Version: "1.0.0", Env: "prod"}))
+	tpl, err = reg.GetTemplate(ctx, "team/agent@1.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, tpl.ModelOptions)
+	err = reg.Delete(ctx, prompty.ManifestRef{ID: "team/agent", Env: "prod"})
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	err = reg.Promote(ctx, prompty.ManifestRef{ID: "missing"}, "prod")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+}
+
+func TestRegistry_Put_Rejects(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, publishManifest("1.0.0", "ok")))
+
+	for name, tc := range map[string]struct {
+		ref  prompty.ManifestRef
+		data string
+	}{
+		"malformed":          {prompty.ManifestRef{ID: "agent"}, `{"id":"agent"`},
+		"no messages":        {prompty.ManifestRef{ID: "agent"}, `{"id":"agent","messages":[]}`},
+		"overlay on nothing": {prompty.ManifestRef{ID: "other", Env: "prod"}, `{"overlay":true}`},
+		"version mismatch":   {prompty.ManifestRef{ID: "agent", Version: "2.0.0"}, string(publishManifest("1.0.0", "x"))},
+	} {
+		err := reg.Put(ctx, tc.ref, []byte(tc.data))
+		require.ErrorIs(t, err, prompty.ErrInvalidManifest, name)
+	}
+	require.ErrorIs(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent", Env: "a.b"}, publishManifest("", "x")),
+		prompty.ErrInvalidName)
+
+	data, err := os.ReadFile(filepath.Join(dir, "agent.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"ok"`, "rejected manifests do not touch the file")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files left behind")
+}
@@ -5,42 +5,55 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/manifest"
 )
 
-// Ensures Registry implements prompty.Registry, Lister, and Statter.
+// Ensures Registry implements prompty.Registry, Lister, Statter, and Versioner.
 var (
-	_ prompty.Registry = (*Registry)(nil)
-	_ prompty.Lister   = (*Registry)(nil)
-	_ prompty.Statter  = (*Registry)(nil)
+	_ prompty.Registry  = (*Registry)(nil)
+	_ prompty.Lister    = (*Registry)(nil)
+	_ prompty.Statter   = (*Registry)(nil)
+	_ prompty.Versioner = (*Registry)(nil)
 )
 
 // Registry loads prompt templates from the filesystem (lazy, cached).
 // Resolves id to {dir}/{id}.yaml or {dir}/{id}.yml (id = basename without extension).
-// WithEnvironment(env): tries {dir}/{id}.{env}.yaml first, then {dir}/{id}.yaml.
+// WithEnvironment(env): tries {dir}/{id}.{env}.yaml first, then {dir}/{id}.yaml; WithEnvironmentChain sets several
+// envs (e.g. prod-eu, prod). Env files marked `overlay: true` patch the next layer instead of replacing it.
+// Versions live at {dir}/{id}/v{version}.yaml (e.g. support_agent/v1.2.0.yaml) and are requested as "id@constraint".
 // Parser is required; use WithParser when creating the registry.
 type Registry struct {
 	dir             string
-	env             string // e.g. "prod"; env inserted before extension: internal/router.prod.yaml
-	partialsPattern string // e.g. "_partials/*.tmpl"; resolved relative to manifest dir when loading
+	envs            []string // e.g. ["prod-eu", "prod"]; env inserted before extension: internal/router.prod.yaml
+	partialsPattern string   // e.g. "_partials/*.tmpl"; resolved relative to manifest dir when loading
 	parser          manifest.Unmarshaler
+	strict          bool // WithStrict: templates are built with prompty.WithStrict
+	funcs           template.FuncMap
 	mu              sync.RWMutex
 	cache           map[string]*prompty.ChatPromptTemplate
+	paths           map[string]string          // cached id -> manifest path it was loaded from
+	dependents      map[string]map[string]bool // extends parent id -> child ids loaded through it
+	loading         []string                   // ids being parsed (under mu) to record extends dependencies
+	watch           watchState
 }
 
 // New creates a Registry that reads manifests from dir. Parser is required (use WithParser).
 func New(dir string, opts ...Option) (*Registry, error) {
 	r := &Registry{
-		dir:   dir,
-		cache: make(map[string]*prompty.ChatPromptTemplate),
+		dir:        dir,
+		cache:      make(map[string]*prompty.ChatPromptTemplate),
+		paths:      make(map[string]string),
+		dependents: make(map[string]map[string]bool),
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -62,7 +75,16 @@ func WithPartials(relativePattern string) Option {
 // WithEnvironment sets env for fallback resolution: tries {id}.{env}.yaml first, then {id}.yaml.
 // Example: id "internal/router", env "prod" -> internal/router.prod.yaml, then internal/router.yaml.
 func WithEnvironment(env string) Option {
-	return func(r *Registry) { r.env = env }
+	return WithEnvironmentChain(env)
+}
+
+// WithEnvironmentChain sets the env fallback chain, most specific first: WithEnvironmentChain("prod-eu", "prod")
+// tries {id}.prod-eu.yaml, then {id}.prod.yaml, then {id}.yaml. Overlays (`overlay: true`) found on the way are
+// applied over the first complete manifest (see manifest.ParseEnvChain); Metadata.Environment lists them.
+func WithEnvironmentChain(envs ...string) Option {
+	return func(r *Registry) {
+		r.envs = slices.DeleteFunc(slices.Clone(envs), func(env string) bool { return env == "" })
+	}
 }
 
 // WithParser sets the manifest parser (required). Use manifest.NewJSONParser() or parser/yaml for YAML.
@@ -70,6 +92,18 @@ func WithParser(u manifest.Unmarshaler) Option {
 	return func(r *Registry) { r.parser = u }
 }
 
+// WithFuncs adds custom template functions to every template (see prompty.WithFuncs).
+// Loading fails with prompty.ErrFuncConflict if a name shadows a built-in.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Registry) { r.funcs = funcs }
+}
+
+// WithStrict builds every template with prompty.WithStrict, so rendering fails on unused payload fields
+// and "<no value>" output. Useful in CI to catch broken prompts.
+func WithStrict() Option {
+	return func(r *Registry) { r.strict = true }
+}
+
 // insertEnvBeforeExt returns base with env inserted before extension: "internal/router" + "prod" -> "internal/router.prod".
 func insertEnvBeforeExt(base, env string) string {
 	if env == "" {
@@ -78,31 +112,54 @@ func insertEnvBeforeExt(base, env string) string {
 	return base + "." + env
 }
 
-// idToPaths returns candidate paths for id in resolution order (io/fs slash-style id).
-// When env != "", tries {id}.{env}.yaml first, then base paths.
-// Uses [filepath.FromSlash] on id for Windows filesystem compatibility.
-func idToPaths(dir, id, env string) []string {
-	exts := []string{".yaml", ".yml", ".json"}
+// idToPaths returns candidate paths for id in resolution order (io/fs slash-style id):
+// {id}.{env}.yaml for each env in envs, then base paths.
+func idToPaths(dir, id string, envs []string) []string {
 	var out []string
-	base := insertEnvBeforeExt(id, env)
-	if env != "" {
-		for _, ext := range exts {
-			path := filepath.FromSlash(base + ext)
-			out = append(out, filepath.Join(dir, path))
-		}
+	for _, env := range envs {
+		out = append(out, layerPaths(dir, id, env)...)
 	}
-	for _, ext := range exts {
-		path := filepath.FromSlash(id + ext)
-		out = append(out, filepath.Join(dir, path))
+	return append(out, layerPaths(dir, id, "")...)
+}
+
+// layerPaths returns candidate paths of one env layer of id ("" for the base manifest).
+// Uses [filepath.FromSlash] on id for Windows filesystem compatibility.
+func layerPaths(dir, id, env string) []string {
+	base := insertEnvBeforeExt(id, env)
+	out := make([]string, 0, 3)
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		out = append(out, filepath.Join(dir, filepath.FromSlash(base+ext)))
 	}
 	return out
 }
 
+// manifestPath returns the manifest path (without extension) of a cache key: a plain id, or "id@version"
+// in the versioned layout.
+func manifestPath(key string) string {
+	if id, version, ok := prompty.SplitVersionedID(key); ok {
+		return prompty.VersionedPath(id, version)
+	}
+	return key
+}
+
+// manifestPaths returns candidate paths for a cache key across the env chain, most specific first.
+func (r *Registry) manifestPaths(key string) []string {
+	return idToPaths(r.dir, manifestPath(key), r.envs)
+}
+
 // GetTemplate returns a template by id. Lazy-loads and caches. After load, enriches tpl.Metadata.Version from Stat if empty.
+// "id@constraint" (e.g. "agent@1.2.0", "agent@latest", "agent@^1.2") resolves against Versions.
 func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
-	if err := prompty.ValidateID(id); err != nil {
+	if err := prompty.ValidateVersionedID(id); err != nil {
 		return nil, err
 	}
+	if base, constraint, ok := prompty.SplitVersionedID(id); ok {
+		key, err := r.resolveVersion(ctx, base, constraint)
+		if err != nil {
+			return nil, err
+		}
+		id = key
+	}
 	r.mu.RLock()
 	tpl, ok := r.cache[id]
 	r.mu.RUnlock()
@@ -111,36 +168,98 @@ func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPro
 	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	tpl, ok = r.cache[id]
+	return r.getLocked(ctx, id)
+}
+
+// getLocked loads id into the cache; r.mu must be held for writing.
+// Parents referenced via extends are resolved through lockedResolver, reusing the held lock.
+func (r *Registry) getLocked(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	tpl, ok := r.cache[id]
 	if ok {
 		return prompty.CloneTemplate(tpl), nil
 	}
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	parseFile := func(path string) (*prompty.ChatPromptTemplate, error) {
-		if r.partialsPattern != "" {
-			glob := filepath.Join(filepath.Dir(path), r.partialsPattern)
-			return manifest.ParseFile(path, r.parser, manifest.WithPartialsGlob(glob))
+	base := manifestPath(id)
+	var found string // most specific layer path, for watch
+	read := func(env string) ([]byte, bool, error) {
+		data, path, err := readLayer(r.dir, base, env)
+		if path != "" && found == "" {
+			found = path
 		}
-		return manifest.ParseFile(path, r.parser)
+		return data, path != "", err
+	}
+	r.loading = append(r.loading, id)
+	defer func() { r.loading = r.loading[:len(r.loading)-1] }()
+	tpl, err := manifest.ParseEnvChain(read, r.envs, r.parser, r.parseOptions(ctx, base)...)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && found == "" {
+			return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+		}
+		return nil, err
 	}
-	for _, path := range idToPaths(r.dir, id, r.env) {
-		tpl, err := parseFile(path)
+	r.paths[id] = found
+	if _, version, versioned := prompty.SplitVersionedID(id); versioned {
+		if tpl.Metadata.Version == "" {
+			tpl.Metadata.Version = version
+		}
+	} else if info, _ := r.Stat(ctx, id); info.Version != "" && tpl.Metadata.Version == "" {
+		tpl.Metadata.Version = info.Version
+	}
+	r.cache[id] = tpl
+	return prompty.CloneTemplate(tpl), nil
+}
+
+// parseOptions returns the options for building the manifest at base (path without env and extension);
+// r.mu must be held for writing (extends parents are loaded through lockedResolver).
+func (r *Registry) parseOptions(ctx context.Context, base string) []manifest.ParseOption {
+	opts := []manifest.ParseOption{manifest.WithBaseRegistry(ctx, lockedResolver{r: r})}
+	if r.partialsPattern != "" {
+		glob := filepath.Join(r.dir, filepath.Dir(filepath.FromSlash(base)), r.partialsPattern)
+		opts = append(opts, manifest.WithPartialsGlob(glob))
+	}
+	if r.strict {
+		opts = append(opts, manifest.WithStrict())
+	}
+	if r.funcs != nil {
+		opts = append(opts, manifest.WithFuncs(r.funcs))
+	}
+	return opts
+}
+
+// readLayer reads the first existing file of one env layer of base; path is "" when the layer does not exist.
+func readLayer(dir, base, env string) (data []byte, path string, err error) {
+	for _, path := range layerPaths(dir, base, env) {
+		data, err := os.ReadFile(path) // #nosec G304 -- path built from a validated id under dir
 		if err == nil {
-			info, _ := r.Stat(ctx, id)
-			if info.Version != "" && tpl.Metadata.Version == "" {
-				tpl.Metadata.Version = info.Version
-			}
-			tpl.Metadata.Environment = "" // id-based; env expressed via id (e.g. doctor.prod)
-			r.cache[id] = tpl
-			return prompty.CloneTemplate(tpl), nil
+			return data, path, nil
 		}
 		if !errors.Is(err, fs.ErrNotExist) {
-			return nil, err
+			return nil, "", fmt.Errorf("manifest: read file: %w", err)
 		}
 	}
-	return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	return nil, "", nil
+}
+
+// lockedResolver resolves extends parents while the registry write lock is already held.
+type lockedResolver struct {
+	r *Registry
+}
+
+// GetTemplate implements prompty.Registry.
+func (l lockedResolver) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	if err := prompty.ValidateID(id); err != nil {
+		return nil, err
+	}
+	if n := len(l.r.loading); n > 0 {
+		child := l.r.loading[n-1]
+		if l.r.dependents[id] == nil {
+			l.r.dependents[id] = make(map[string]bool)
+		}
+		l.r.dependents[id][child] = true
+	}
+	return l.r.getLocked(ctx, id)
 }
 
 // baseIDFromPath converts a manifest path to base ID (slash format, no env suffix).
@@ -176,7 +295,8 @@ func underPartialsDir(path, partialsPattern string) bool {
 }
 
 // List returns all template ids (base slash path, env suffix stripped) under r.dir, unique and sorted.
-// agent.prod.yaml and agent.yaml both yield "agent"; internal/router.prod.yaml yields "internal/router".
+// agent.prod.yaml and agent.yaml both yield "agent"; internal/router.prod.yaml yields "internal/router";
+// versioned manifests (agent/v1.2.0.yaml) yield "agent".
 // Paths under partials directory are excluded.
 func (r *Registry) List(ctx context.Context) ([]string, error) {
 	if ctx.Err() != nil {
@@ -206,6 +326,9 @@ func (r *Registry) List(ctx context.Context) ([]string, error) {
 			return nil
 		}
 		id := baseIDFromPath(path)
+		if versionedID, _, _, ok := prompty.ParseVersionedPath(trimManifestExt(slashPath)); ok {
+			id = versionedID
+		}
 		if !seen[id] {
 			seen[id] = true
 			ids = append(ids, id)
@@ -220,17 +343,37 @@ func (r *Registry) List(ctx context.Context) ([]string, error) {
 }
 
 // Stat returns metadata for id without parsing the manifest body. Version is file ModTime in RFC3339; UpdatedAt is file ModTime.
-func (r *Registry) Stat(_ context.Context, id string) (prompty.TemplateInfo, error) {
-	if err := prompty.ValidateID(id); err != nil {
+// For "id@constraint", Version is the resolved version.
+func (r *Registry) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	if err := prompty.ValidateVersionedID(id); err != nil {
 		return prompty.TemplateInfo{}, err
 	}
-	for _, path := range idToPaths(r.dir, id, r.env) {
+	key, version := id, ""
+	if base, constraint, ok := prompty.SplitVersionedID(id); ok {
+		var err error
+		if key, err = r.resolveVersion(ctx, base, constraint); err != nil {
+			return prompty.TemplateInfo{}, err
+		}
+		_, version, _ = prompty.SplitVersionedID(key)
+		if version == "" {
+			// Resolved to the plain manifest: its declared version.
+			tpl, err := r.GetTemplate(ctx, base)
+			if err != nil {
+				return prompty.TemplateInfo{}, err
+			}
+			version = tpl.Metadata.Version
+		}
+	}
+	for _, path := range r.manifestPaths(key) {
 		fi, err := os.Stat(path)
 		if err == nil {
 			mod := fi.ModTime()
+			if version == "" {
+				version = mod.Format(time.RFC3339)
+			}
 			return prompty.TemplateInfo{
 				ID:        id,
-				Version:   mod.Format(time.RFC3339),
+				Version:   version,
 				UpdatedAt: mod,
 			}, nil
 		}
@@ -241,9 +384,76 @@ func (r *Registry) Stat(_ context.Context, id string) (prompty.TemplateInfo, err
 	return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
 }
 
+// Versions implements prompty.Versioner: versions from the {id}/v{version}.yaml layout (including env overlays
+// for the registry env) plus the semantic `version` declared by {id}.yaml itself, canonical and ascending.
+func (r *Registry) Versions(ctx context.Context, id string) ([]string, error) {
+	if err := prompty.ValidateID(id); err != nil {
+		return nil, err
+	}
+	versions, _, err := r.versionIndex(ctx, id)
+	return versions, err
+}
+
+// versionIndex returns all versions of id and the set found in the versioned layout
+// (a version outside the set is the plain manifest's declared version).
+func (r *Registry) versionIndex(ctx context.Context, id string) ([]string, map[string]bool, error) {
+	layout := make(map[string]bool)
+	entries, err := os.ReadDir(filepath.Join(r.dir, filepath.FromSlash(id)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("fileregistry versions: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !isManifestPath(e.Name()) {
+			continue
+		}
+		_, version, env, ok := prompty.ParseVersionedPath(id + "/" + trimManifestExt(e.Name()))
+		if ok && (env == "" || slices.Contains(r.envs, env)) {
+			layout[version] = true
+		}
+	}
+	versions := slices.Collect(maps.Keys(layout))
+	tpl, err := r.GetTemplate(ctx, id)
+	switch {
+	case err == nil:
+		if v, parseErr := prompty.ParseVersion(tpl.Metadata.Version); parseErr == nil {
+			versions = append(versions, v.String())
+		}
+	case !errors.Is(err, prompty.ErrTemplateNotFound):
+		return nil, nil, err
+	}
+	return prompty.SortVersions(versions), layout, nil
+}
+
+// resolveVersion returns the cache key for the best version of id matching constraint:
+// "id@version" for the versioned layout, or id when the plain manifest declares the chosen version.
+func (r *Registry) resolveVersion(ctx context.Context, id, constraint string) (string, error) {
+	versions, layout, err := r.versionIndex(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	version, err := prompty.ResolveVersion(versions, constraint)
+	if err != nil {
+		return "", fmt.Errorf("template %q: %w", id, err)
+	}
+	if layout[version] {
+		return id + "@" + version, nil
+	}
+	return id, nil
+}
+
+// trimManifestExt strips a .yaml, .yml, or .json extension.
+func trimManifestExt(name string) string {
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
 // Reload clears the cache (for hot-reload in development).
 func (r *Registry) Reload() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.cache = make(map[string]*prompty.ChatPromptTemplate)
+	r.paths = make(map[string]string)
+	r.dependents = make(map[string]map[string]bool)
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/manifest"
@@ -601,3 +603,205 @@ func TestFileRegistry_ConcurrentReloadAndGet(t *testing.T) {
 		<-done
 	}
 }
+
+func TestFileRegistry_GetTemplate_Extends(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "shared"), 0750))
+	base := `{"id":"shared/base","version":"1","messages":[{"role":"system","content":[{"type":"text","text":"Policy"}]}],` +
+		`"tools":[{"name":"search","description":"base"}]}`
+	child := `{"id":"agent","extends":"shared/base","messages_merge":"append",` +
+		`"messages":[{"role":"user","content":[{"type":"text","text":"{{ .query }}"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared", "base.json"), []byte(base), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "agent.json"), []byte(child), 0600))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	require.Len(t, tpl.Messages, 2)
+	assert.Equal(t, "Policy", tpl.Messages[0].Content[0].Text)
+	require.Len(t, tpl.Tools, 1)
+	assert.Equal(t, "search", tpl.Tools[0].Name)
+
+	parent, err := reg.GetTemplate(ctx, "shared/base")
+	require.NoError(t, err)
+	assert.Equal(t, "shared/base", parent.Metadata.ID)
+}
+
+func TestFileRegistry_GetTemplate_ExtendsCycle(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id":"a","extends":"b"}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"id":"b","extends":"a"}`), 0600))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	_, err = reg.GetTemplate(context.Background(), "a")
+	require.ErrorIs(t, err, prompty.ErrExtendsCycle)
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+}
+
+func TestFileRegistry_GetTemplate_Strict(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	body := `{"id":"greet","messages":[{"role":"user","content":[{"type":"text","text":"Hi {{ .user.name }}"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "greet.json"), []byte(body), 0600))
+	vars := map[string]any{"user": map[string]any{"name": nil}, "extra": 1}
+
+	lax, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	tpl, err := lax.GetTemplate(context.Background(), "greet")
+	require.NoError(t, err)
+	_, err = tpl.Format(vars)
+	require.NoError(t, err)
+
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()), WithStrict())
+	require.NoError(t, err)
+	tpl, err = reg.GetTemplate(context.Background(), "greet")
+	require.NoError(t, err)
+	_, err = tpl.Format(vars)
+	var se *prompty.StrictError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, []string{"extra"}, se.UnusedFields)
+	assert.Equal(t, []string{"message 0 part 0"}, se.NoValue)
+}
+
+func TestFileRegistry_GetTemplate_WithFuncs(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	body := `{"id":"greet","messages":[{"role":"user","content":[{"type":"text","text":"{{ .name | shout }}"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "greet.json"), []byte(body), 0600))
+
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()), WithFuncs(template.FuncMap{"shout": strings.ToUpper}))
+	require.NoError(t, err)
+	tpl, err := reg.GetTemplate(context.Background(), "greet")
+	require.NoError(t, err)
+	exec, err := tpl.Format(map[string]any{"name": "ann"})
+	require.NoError(t, err)
+	assert.Equal(t, "ANN", exec.Messages[0].Content[0].(prompty.TextPart).Text)
+
+	reg, err = New(dir, WithParser(manifest.NewJSONParser()), WithFuncs(template.FuncMap{"trim": strings.ToUpper}))
+	require.NoError(t, err)
+	_, err = reg.GetTemplate(context.Background(), "greet")
+	require.ErrorIs(t, err, prompty.ErrFuncConflict)
+}
+
+func TestFileRegistry_Versions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	versioned := `{"id":"agent","version":"2.0.0","messages":[{"role":"system","content":[{"type":"text","text":"working copy"}]}]}`
+	writeFile(t, filepath.Join(dir, "agent.json"), versioned)
+	writeFile(t, filepath.Join(dir, "agent", "v1.0.0.json"), jsonManifest("agent", "one"))
+	writeFile(t, filepath.Join(dir, "agent", "v1.2.0.json"), jsonManifest("agent", "one-two"))
+	writeFile(t, filepath.Join(dir, "agent", "v1.3.0-rc.1.json"), jsonManifest("agent", "rc"))
+	writeFile(t, filepath.Join(dir, "agent", "v1.2.0.prod.json"), jsonManifest("agent", "one-two prod"))
+	writeFile(t, filepath.Join(dir, "agent", "v1.4.0.staging.json"), jsonManifest("agent", "staging only"))
+	writeFile(t, filepath.Join(dir, "agent", "notes.json"), jsonManifest("agent", "ignored"))
+	ctx := context.Background()
+
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	versions, err := reg.Versions(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.2.0", "1.3.0-rc.1", "2.0.0"}, versions)
+
+	for constraint, want := range map[string]string{
+		"1.0.0":        "one",
+		"v1.2.0":       "one-two",
+		"^1":           "one-two",
+		"~1.0":         "one",
+		"latest":       "working copy",
+		">=1 <2":       "one-two",
+		"1.3.0-rc.1":   "rc",
+		">=1.3.0-rc.0": "working copy",
+	} {
+		assert.Equal(t, want, systemText(t, reg, "agent@"+constraint), constraint)
+	}
+	tpl, err := reg.GetTemplate(ctx, "agent@^1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", tpl.Metadata.Version, "version taken from the file name")
+	info, err := reg.Stat(ctx, "agent@~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", info.Version)
+	info, err = reg.Stat(ctx, "agent@latest")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", info.Version)
+
+	_, err = reg.GetTemplate(ctx, "agent@^3")
+	require.ErrorIs(t, err, prompty.ErrVersionNotFound)
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	_, err = reg.GetTemplate(ctx, "agent@not-a-version")
+	require.ErrorIs(t, err, prompty.ErrInvalidVersion)
+	_, err = reg.GetTemplate(ctx, "agent/v1.0.0")
+	require.ErrorIs(t, err, prompty.ErrInvalidName, "versioned files are not plain ids")
+
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent", "agent/notes"}, ids)
+
+	prod, err := New(dir, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	assert.Equal(t, "one-two prod", systemText(t, prod, "agent@1.2.0"))
+	staging, err := New(dir, WithParser(manifest.NewJSONParser()), WithEnvironment("staging"))
+	require.NoError(t, err)
+	versions, err = staging.Versions(ctx, "agent")
+	require.NoError(t, err)
+	assert.Contains(t, versions, "1.4.0", "env-only versions count for their env")
+	assert.Equal(t, "staging only", systemText(t, staging, "agent@^1.4"))
+}
+
+func TestPoll_VersionedManifest(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent", "v1.0.0.json")
+	writeFile(t, path, jsonManifest("agent", "one"))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "one", systemText(t, reg, "agent@1.0.0"))
+
+	writeFile(t, path, jsonManifest("agent", "one fixed"))
+	writeFile(t, filepath.Join(dir, "agent", "v1.1.0.json"), jsonManifest("agent", "one-one"))
+	events, err := reg.Poll(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Event{
+		{ID: "agent@1.0.0", Kind: EventUpdated, Path: path, Err: nil},
+		{ID: "agent@1.1.0", Kind: EventAdded, Path: filepath.Join(dir, "agent", "v1.1.0.json"), Err: nil},
+	}, events)
+	assert.Equal(t, "one fixed", systemText(t, reg, "agent@1.0.0"))
+	assert.Equal(t, "one-one", systemText(t, reg, "agent@latest"))
+}
+
+func TestFileRegistry_GetTemplate_EnvironmentChainOverlays(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"agent.json": `{"id":"agent","model_config":{"model":"m-base","temperature":0.2},` +
+			`"messages":[{"role":"system","content":[{"type":"text","text":"Base"}]}]}`,
+		"agent.prod.json":    `{"overlay":true,"model_config":{"model":"m-prod"}}`,
+		"agent.prod-eu.json": `{"overlay":true,"messages":[{"index":0,"content":[{"type":"text","text":"EU"}]}]}`,
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0600))
+	}
+	ctx := context.Background()
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()), WithEnvironmentChain("prod-eu", "prod"))
+	require.NoError(t, err)
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "prod,prod-eu", tpl.Metadata.Environment)
+	assert.Equal(t, "m-prod", tpl.ModelOptions.Model)
+	require.NotNil(t, tpl.ModelOptions.Temperature)
+	assert.InDelta(t, 0.2, *tpl.ModelOptions.Temperature, 1e-9)
+	assert.Equal(t, "EU", tpl.Messages[0].Content[0].Text)
+
+	reg, err = New(dir, WithParser(manifest.NewJSONParser()), WithEnvironmentChain("prod-us", "prod"))
+	require.NoError(t, err)
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", tpl.Metadata.Environment)
+	assert.Equal(t, "Base", tpl.Messages[0].Content[0].Text)
+}
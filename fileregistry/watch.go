@@ -0,0 +1,333 @@
+package fileregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/skosovsky/prompty"
+)
+
+// DefaultWatchInterval is the polling interval used by Watch when interval <= 0.
+const DefaultWatchInterval = time.Second
+
+// EventKind describes what happened to a template detected by the watcher.
+type EventKind string
+
+const (
+	// EventAdded reports a new manifest file for an id that was not loaded yet.
+	EventAdded EventKind = "added"
+	// EventUpdated reports a changed manifest or partial; cached templates were re-parsed and swapped in.
+	EventUpdated EventKind = "updated"
+	// EventRemoved reports a deleted manifest; the id was dropped from the cache.
+	EventRemoved EventKind = "removed"
+	// EventInvalid reports a change that failed to parse or validate; the last good template is kept.
+	EventInvalid EventKind = "invalid"
+	// EventError reports a failure scanning the directory (ID is empty).
+	EventError EventKind = "error"
+)
+
+// Event is emitted to subscribers when Poll detects a change.
+type Event struct {
+	ID   string    // template id (base id, no env suffix; "id@version" for versioned manifests); empty for EventError
+	Kind EventKind // what happened
+	Path string    // changed file (manifest or partial) that triggered the event
+	Err  error     // parse/validation error for EventInvalid, scan error for EventError
+}
+
+// fileState is the polled state of one file: mtime and size gate hashing, sum decides whether it changed.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// watchState holds the last snapshot and subscribers. mu serializes Poll.
+type watchState struct {
+	mu       sync.Mutex
+	snapshot map[string]fileState // path (joined with dir) -> state; nil until the first Poll
+
+	subMu   sync.Mutex
+	subs    map[int]func(Event)
+	nextSub int
+}
+
+// Subscribe registers fn for change events and returns a function that removes it.
+// fn is called synchronously from Poll (and so from the Watch goroutine); it may call GetTemplate but not Poll.
+func (r *Registry) Subscribe(fn func(Event)) (unsubscribe func()) {
+	r.watch.subMu.Lock()
+	defer r.watch.subMu.Unlock()
+	if r.watch.subs == nil {
+		r.watch.subs = make(map[int]func(Event))
+	}
+	id := r.watch.nextSub
+	r.watch.nextSub++
+	r.watch.subs[id] = fn
+	return func() {
+		r.watch.subMu.Lock()
+		defer r.watch.subMu.Unlock()
+		delete(r.watch.subs, id)
+	}
+}
+
+func (r *Registry) emit(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	r.watch.subMu.Lock()
+	keys := slices.Sorted(maps.Keys(r.watch.subs))
+	subs := make([]func(Event), 0, len(keys))
+	for _, k := range keys {
+		subs = append(subs, r.watch.subs[k])
+	}
+	r.watch.subMu.Unlock()
+	for _, ev := range events {
+		for _, fn := range subs {
+			fn(ev)
+		}
+	}
+}
+
+// Watch polls dir every interval (DefaultWatchInterval if <= 0) until ctx is done and returns ctx.Err().
+// The first Poll takes a baseline snapshot; later changes invalidate only the affected ids (see Poll).
+// Opt-in: run it in a goroutine, e.g. go reg.Watch(ctx, time.Second).
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	_, _ = r.Poll(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, _ = r.Poll(ctx)
+		}
+	}
+}
+
+// Poll scans dir once (mtime/size, then SHA-256 of changed files) and compares it with the previous snapshot.
+// The first call only records the baseline. Changed manifests and partials invalidate the cached ids that use
+// them (including extends children); those are re-parsed before being swapped in, and a template that fails
+// to parse keeps its last good version (EventInvalid). Events are returned and sent to subscribers.
+func (r *Registry) Poll(ctx context.Context) ([]Event, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	r.watch.mu.Lock()
+	defer r.watch.mu.Unlock()
+	snapshot, err := r.scan(r.watch.snapshot)
+	if err != nil {
+		events := []Event{{ID: "", Kind: EventError, Path: r.dir, Err: err}}
+		r.emit(events)
+		return events, err
+	}
+	previous := r.watch.snapshot
+	r.watch.snapshot = snapshot
+	if previous == nil {
+		return nil, nil
+	}
+	changed := changedPaths(previous, snapshot)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	events := r.applyChanges(ctx, changed, previous, snapshot)
+	r.emit(events)
+	return events, nil
+}
+
+// scan returns the state of every regular file under dir; unchanged mtime and size reuse the previous hash.
+func (r *Registry) scan(previous map[string]fileState) (map[string]fileState, error) {
+	out := make(map[string]fileState)
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		st := fileState{modTime: info.ModTime(), size: info.Size(), sum: [sha256.Size]byte{}}
+		if prev, ok := previous[path]; ok && prev.modTime.Equal(st.modTime) && prev.size == st.size {
+			st.sum = prev.sum
+			out[path] = st
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		st.sum = sha256.Sum256(data)
+		out[path] = st
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fileregistry watch: %w", err)
+	}
+	return out, nil
+}
+
+// changedPaths returns sorted paths that were added, removed, or whose content hash differs.
+func changedPaths(previous, current map[string]fileState) []string {
+	var out []string
+	for path, st := range current {
+		if prev, ok := previous[path]; !ok || prev.sum != st.sum {
+			out = append(out, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			out = append(out, path)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// affected maps changed paths to ids: manifests by id resolution (env-aware), partials by the glob of cached ids.
+// Returns id -> triggering path, including extends children of affected ids.
+func (r *Registry) affected(changed []string) map[string]string {
+	out := make(map[string]string)
+	for _, path := range changed {
+		rel, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			continue
+		}
+		if isManifestPath(rel) && !underPartialsDir(rel, r.partialsPattern) {
+			if id, version, env, ok := prompty.ParseVersionedPath(trimManifestExt(filepath.ToSlash(rel))); ok {
+				if env == "" || slices.Contains(r.envs, env) {
+					out[id+"@"+version] = path
+				}
+				continue
+			}
+			id := baseIDFromPath(rel)
+			if slices.Contains(idToPaths(r.dir, id, r.envs), path) {
+				out[id] = path
+			}
+			continue
+		}
+		if r.partialsPattern == "" {
+			continue
+		}
+		for id, manifestPath := range r.paths {
+			glob := filepath.Join(filepath.Dir(manifestPath), r.partialsPattern)
+			if ok, _ := filepath.Match(glob, path); ok {
+				out[id] = path
+			}
+		}
+	}
+	queue := slices.Sorted(maps.Keys(out))
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for child := range r.dependents[parent] {
+			if _, ok := out[child]; !ok {
+				out[child] = out[parent]
+				queue = append(queue, child)
+			}
+		}
+	}
+	return out
+}
+
+// applyChanges re-parses affected cached ids under the write lock, restoring old templates that fail.
+// Ids that were never loaded stay lazy and only produce added/updated/removed events.
+func (r *Registry) applyChanges(
+	ctx context.Context,
+	changed []string,
+	previous, current map[string]fileState,
+) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	targets := r.affected(changed)
+	ids := slices.Sorted(maps.Keys(targets))
+	old := make(map[string]*prompty.ChatPromptTemplate, len(ids))
+	oldPaths := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if tpl, ok := r.cache[id]; ok {
+			old[id] = tpl
+			oldPaths[id] = r.paths[id]
+		}
+		delete(r.cache, id)
+		delete(r.paths, id)
+	}
+	events := make([]Event, 0, len(ids))
+	for _, id := range ids {
+		path := targets[id]
+		prev, wasCached := old[id]
+		if !wasCached {
+			events = append(events, Event{ID: id, Kind: uncachedKind(path, previous, current), Path: path, Err: nil})
+			continue
+		}
+		if _, cached := r.cache[id]; cached {
+			// Already reloaded as an extends parent of an earlier id.
+			events = append(events, Event{ID: id, Kind: EventUpdated, Path: path, Err: nil})
+			continue
+		}
+		_, err := r.getLocked(ctx, id)
+		switch {
+		case err == nil:
+			events = append(events, Event{ID: id, Kind: EventUpdated, Path: path, Err: nil})
+		case errors.Is(err, prompty.ErrTemplateNotFound) && r.missing(id):
+			events = append(events, Event{ID: id, Kind: EventRemoved, Path: path, Err: nil})
+		default:
+			r.cache[id] = prev
+			r.paths[id] = oldPaths[id]
+			events = append(events, Event{ID: id, Kind: EventInvalid, Path: path, Err: err})
+		}
+	}
+	return events
+}
+
+// uncachedKind classifies a change of a manifest that was never loaded by comparing snapshots.
+func uncachedKind(path string, previous, current map[string]fileState) EventKind {
+	_, before := previous[path]
+	_, after := current[path]
+	switch {
+	case !after:
+		return EventRemoved
+	case !before:
+		return EventAdded
+	default:
+		return EventUpdated
+	}
+}
+
+// missing reports whether no manifest file exists for id (so a failed load means removal, not a broken file).
+func (r *Registry) missing(id string) bool {
+	for _, path := range r.manifestPaths(id) {
+		if _, err := os.Stat(path); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isManifestPath reports whether path has a manifest extension (.yaml, .yml, .json).
+func isManifestPath(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,204 @@
+package fileregistry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonManifest(id, text string) string {
+	return `{"id":"` + id + `","messages":[{"role":"system","content":[{"type":"text","text":` +
+		`"` + text + `"}]}]}`
+}
+
+// writeFile writes data and moves mtime forward so polling sees the change even within one clock tick.
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	future := time.Now().Add(time.Duration(len(data)) * time.Second)
+	require.NoError(t, os.Chtimes(path, future, future))
+}
+
+func systemText(t *testing.T, reg *Registry, id string) string {
+	t.Helper()
+	tpl, err := reg.GetTemplate(context.Background(), id)
+	require.NoError(t, err)
+	exec, err := tpl.Format(nil)
+	require.NoError(t, err)
+	text, ok := exec.Messages[0].Content[0].(prompty.TextPart)
+	require.True(t, ok)
+	return text.Text
+}
+
+func TestPoll_UpdatesOnlyAffectedIDs(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "A1"))
+	writeFile(t, filepath.Join(dir, "b.json"), jsonManifest("b", "B1"))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	assert.Equal(t, "A1", systemText(t, reg, "a"))
+	assert.Equal(t, "B1", systemText(t, reg, "b"))
+
+	events, err := reg.Poll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, events, "first poll is the baseline")
+
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "A2 changed"))
+	events, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, Event{ID: "a", Kind: EventUpdated, Path: filepath.Join(dir, "a.json"), Err: nil}, events[0])
+	assert.Equal(t, "A2 changed", systemText(t, reg, "a"))
+	assert.Equal(t, "B1", systemText(t, reg, "b"))
+
+	events, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Touching without changing content is not a change.
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "b.json"), future, future))
+	events, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestPoll_InvalidKeepsLastGood(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "good"))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	assert.Equal(t, "good", systemText(t, reg, "a"))
+	_, err = reg.Poll(ctx)
+	require.NoError(t, err)
+
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "{{ .broken"))
+	events, err := reg.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventInvalid, events[0].Kind)
+	require.ErrorIs(t, events[0].Err, prompty.ErrTemplateParse)
+	assert.Equal(t, "good", systemText(t, reg, "a"), "last good version is served")
+
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "fixed"))
+	events, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventUpdated, events[0].Kind)
+	assert.Equal(t, "fixed", systemText(t, reg, "a"))
+}
+
+func TestPoll_PartialsAndExtends(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "_partials", "sig.tmpl"), `{{ define "sig" }}v1{{ end }}`)
+	writeFile(t, filepath.Join(dir, "base.json"), jsonManifest("base", `{{ template \"sig\" . }}`))
+	writeFile(t, filepath.Join(dir, "child.json"), `{"id":"child","extends":"base"}`)
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()), WithPartials("_partials/*.tmpl"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	assert.Equal(t, "v1", systemText(t, reg, "child"))
+	_, err = reg.Poll(ctx)
+	require.NoError(t, err)
+
+	writeFile(t, filepath.Join(dir, "_partials", "sig.tmpl"), `{{ define "sig" }}v2!{{ end }}`)
+	events, err := reg.Poll(ctx)
+	require.NoError(t, err)
+	kinds := make(map[string]EventKind)
+	for _, ev := range events {
+		kinds[ev.ID] = ev.Kind
+	}
+	assert.Equal(t, map[string]EventKind{"base": EventUpdated, "child": EventUpdated}, kinds)
+	assert.Equal(t, "v2!", systemText(t, reg, "child"))
+
+	writeFile(t, filepath.Join(dir, "base.json"), jsonManifest("base", "rewritten"))
+	events, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 2, "extends children are invalidated with their parent")
+	assert.Equal(t, "rewritten", systemText(t, reg, "child"))
+}
+
+func TestPoll_AddedAndRemoved(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "A"))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	assert.Equal(t, "A", systemText(t, reg, "a"))
+	_, err = reg.Poll(ctx)
+	require.NoError(t, err)
+
+	writeFile(t, filepath.Join(dir, "a.prod.json"), jsonManifest("a", "A prod"))
+	writeFile(t, filepath.Join(dir, "new.json"), jsonManifest("new", "N"))
+	writeFile(t, filepath.Join(dir, "a.dev.json"), jsonManifest("a", "ignored for prod"))
+	events, err := reg.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Event{
+		{ID: "a", Kind: EventUpdated, Path: filepath.Join(dir, "a.prod.json"), Err: nil},
+		{ID: "new", Kind: EventAdded, Path: filepath.Join(dir, "new.json"), Err: nil},
+	}, events)
+	assert.Equal(t, "A prod", systemText(t, reg, "a"))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.prod.json")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.json")))
+	events, err = reg.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventRemoved, events[0].Kind)
+	_, err = reg.GetTemplate(ctx, "a")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+}
+
+func TestWatch_Subscribe(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "A1"))
+	reg, err := New(dir, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	assert.Equal(t, "A1", systemText(t, reg, "a"))
+
+	got := make(chan Event, 4)
+	unsubscribe := reg.Subscribe(func(ev Event) { got <- ev })
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.ErrorIs(t, reg.Watch(ctx, 5*time.Millisecond), context.Canceled)
+	}()
+	// Wait for the baseline poll before changing the file.
+	require.Eventually(t, func() bool {
+		reg.watch.mu.Lock()
+		defer reg.watch.mu.Unlock()
+		return reg.watch.snapshot != nil
+	}, time.Second, time.Millisecond)
+	writeFile(t, filepath.Join(dir, "a.json"), jsonManifest("a", "A2 from watch"))
+
+	select {
+	case ev := <-got:
+		assert.Equal(t, "a", ev.ID)
+		assert.Equal(t, EventUpdated, ev.Kind)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event from Watch")
+	}
+	cancel()
+	wg.Wait()
+	assert.Equal(t, "A2 from watch", systemText(t, reg, "a"))
+}
@@ -0,0 +1,161 @@
+package prompty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textOf(t *testing.T, m ChatMessage) string {
+	t.Helper()
+	require.Len(t, m.Content, 1)
+	tp, ok := m.Content[0].(TextPart)
+	require.True(t, ok)
+	return tp.Text
+}
+
+func TestFormat_ForEachMessage(t *testing.T) {
+	t.Parallel()
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("Docs for {{ .topic }}:")},
+		{Role: RoleUser, ForEach: "docs", When: `item.score > 0.5`, Content: TextContent("[{{ .index }}] {{ .item.text }}")},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"topic", "docs"}, tpl.requiredFromAST)
+
+	exec, err := tpl.Format(map[string]any{
+		"topic": "go",
+		"docs": []map[string]any{
+			{"text": "a", "score": 0.9},
+			{"text": "b", "score": 0.1},
+			{"text": "c", "score": 0.7},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 3)
+	assert.Equal(t, "[0] a", textOf(t, exec.Messages[1]))
+	assert.Equal(t, "[2] c", textOf(t, exec.Messages[2]))
+
+	exec, err = tpl.Format(map[string]any{"topic": "go", "docs": nil})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 1)
+
+	_, err = tpl.Format(map[string]any{"topic": "go"})
+	require.ErrorIs(t, err, ErrMissingVariable)
+
+	_, err = tpl.Format(map[string]any{"topic": "go", "docs": "not a list"})
+	require.ErrorIs(t, err, ErrTemplateRender)
+	assert.Contains(t, err.Error(), "must be a list")
+}
+
+func TestFormat_ForEachTurns(t *testing.T) {
+	t.Parallel()
+	type example struct {
+		Q string
+		A string
+	}
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("Answer briefly.")},
+		{
+			ForEach: "examples",
+			Turns: []MessageTemplate{
+				{Role: RoleUser, Content: TextContent("{{ .item.Q }}")},
+				{Role: RoleAssistant, Content: TextContent("{{ .item.A }}")},
+			},
+		},
+		{Role: RoleUser, Content: TextContent("{{ .query }}")},
+	})
+	require.NoError(t, err)
+
+	exec, err := tpl.Format(map[string]any{
+		"examples": []example{{Q: "2+2?", A: "4"}, {Q: "Capital of France?", A: "Paris"}},
+		"query":    "3+3?",
+	})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 6)
+	roles := make([]Role, len(exec.Messages))
+	for i, m := range exec.Messages {
+		roles[i] = m.Role
+	}
+	assert.Equal(t, []Role{RoleSystem, RoleUser, RoleAssistant, RoleUser, RoleAssistant, RoleUser}, roles)
+	assert.Equal(t, "Paris", textOf(t, exec.Messages[4]))
+
+	require.NoError(t, tpl.ValidateVariables(map[string]any{
+		"examples": []example{{Q: "q", A: "a"}},
+		"query":    "x",
+	}))
+	err = tpl.ValidateVariables(map[string]any{"examples": []map[string]any{{"Q": "q"}}, "query": "x"})
+	require.ErrorIs(t, err, ErrTemplateRender)
+	assert.Contains(t, err.Error(), "message 1 item 0 turn 1")
+}
+
+func TestFormat_ForEachOptionalAndHistory(t *testing.T) {
+	t.Parallel()
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("S")},
+		{Role: RoleUser, ForEach: "shots", Optional: true, Content: TextContent("{{ .item }}")},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, tpl.requiredFromAST)
+	exec, err := tpl.Format(nil)
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 1)
+	exec, err = tpl.Format(map[string]any{"shots": []string{"x", "y"}})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 3)
+}
+
+func TestNewChatPromptTemplate_ForEachErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		msg  MessageTemplate
+		want string
+	}{
+		{"turns without for_each", MessageTemplate{Turns: []MessageTemplate{{Role: RoleUser}}}, "turns require for_each"},
+		{"content and turns", MessageTemplate{
+			ForEach: "xs",
+			Content: TextContent("x"),
+			Turns:   []MessageTemplate{{Role: RoleUser}},
+		}, "cannot be combined"},
+		{"not a variable", MessageTemplate{ForEach: `"xs"`, Content: TextContent("x")}, "must be a variable"},
+		{"nested", MessageTemplate{
+			ForEach: "xs",
+			Turns:   []MessageTemplate{{ForEach: "ys", Content: TextContent("y")}},
+		}, "nested for_each"},
+		{"list is a loop variable", MessageTemplate{ForEach: "item", Content: TextContent("x")}, "loop variable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewChatPromptTemplate([]MessageTemplate{tt.msg})
+			require.ErrorIs(t, err, ErrTemplateParse)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestForEach_LoopVarConflicts(t *testing.T) {
+	t.Parallel()
+	loop := MessageTemplate{Role: RoleUser, ForEach: "items", Content: TextContent("{{ .item }}")}
+
+	_, err := NewChatPromptTemplate([]MessageTemplate{loop}, WithInputSchema(&SchemaDefinition{
+		Schema: map[string]any{"type": "object", "properties": map[string]any{"index": map[string]any{}}},
+	}))
+	require.ErrorIs(t, err, ErrTemplateParse)
+	assert.Contains(t, err.Error(), `input variable "index" conflicts`)
+
+	_, err = NewChatPromptTemplate([]MessageTemplate{loop}, WithPartialVariables(map[string]any{"item": "x"}))
+	require.ErrorIs(t, err, ErrTemplateParse)
+
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("{{ .item }}")},
+		loop,
+	})
+	require.NoError(t, err)
+	_, err = tpl.Format(map[string]any{"item": "outer", "items": []string{"a"}})
+	require.ErrorIs(t, err, ErrTemplateRender)
+	assert.Contains(t, err.Error(), `input variable "item" conflicts`)
+	require.ErrorIs(t, tpl.ValidateVariables(map[string]any{"item": "outer", "items": []string{"a"}}), ErrTemplateRender)
+}
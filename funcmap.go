@@ -7,9 +7,16 @@ import (
 	"encoding/xml"
 	"fmt"
 	"html"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
 )
 
 // defaultFuncMap returns the [template.FuncMap] used for ChatPromptTemplate rendering.
@@ -24,7 +31,63 @@ func defaultFuncMap(tc TokenCounter) template.FuncMap {
 		"render_tools_as_json": renderToolsAsJSON,
 		"escapeXML":            escapeXML,
 		"randomHex":            randomHex,
+		"join":                 join,
+		"indent":               indent,
+		"trim":                 strings.TrimSpace,
+		"default":              defaultValue,
+		"toJSON":               toJSON,
+		"toYAML":               toYAML,
+		"numbered_list":        numberedList,
+		"bullet_list":          bulletList,
+		"markdown_table":       markdownTable,
+		"date":                 formatDate,
+		"wrap":                 wrap,
+		"xml_tag":              xmlTag,
+	}
+}
+
+// funcMapWith returns the built-in functions extended with custom ones.
+// Returns ErrFuncConflict if a custom name shadows a prompty or text/template built-in and ErrTemplateParse
+// if a value is not a valid function.
+func funcMapWith(tc TokenCounter, custom template.FuncMap) (template.FuncMap, error) {
+	funcMap := defaultFuncMap(tc)
+	for _, name := range slices.Sorted(maps.Keys(custom)) {
+		if _, ok := funcMap[name]; ok || isTemplateBuiltin(name) {
+			return nil, fmt.Errorf("%w: %q", ErrFuncConflict, name)
+		}
+		if err := checkTemplateFunc(name, custom[name]); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrTemplateParse, err)
+		}
+		funcMap[name] = custom[name]
 	}
+	return funcMap, nil
+}
+
+// isTemplateBuiltin reports whether name is a text/template predefined function, which Funcs would silently override.
+func isTemplateBuiltin(name string) bool {
+	switch name {
+	case "and", "or", "not", "len", "index", "slice", "print", "printf", "println",
+		"html", "js", "urlquery", "call", "eq", "ne", "lt", "le", "gt", "ge":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkTemplateFunc reports why text/template would reject fn (Funcs panics instead of returning an error).
+func checkTemplateFunc(name string, fn any) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("func %q: value is %T, not a function", name, fn)
+	}
+	errorType := reflect.TypeFor[error]()
+	switch t := v.Type(); {
+	case t.NumOut() == 1 && t.Out(0) != errorType:
+	case t.NumOut() == 2 && t.Out(1) == errorType: //nolint:mnd // value and error results.
+	default:
+		return fmt.Errorf("func %q: must return one value or a value and an error", name)
+	}
+	return nil
 }
 
 // escapeXML escapes text so that XML/HTML tags are not interpreted (e.g. for isolating user input in prompts).
@@ -147,6 +210,211 @@ func renderToolsAsJSON(tools any) (string, error) {
 	return string(b), nil
 }
 
+// listItems converts a slice or array to strings with fmt.Sprint; nil yields no items.
+func listItems(fn string, list any) ([]string, error) {
+	if list == nil {
+		return nil, nil
+	}
+	if ss, ok := list.([]string); ok {
+		return ss, nil
+	}
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%s: expected a list, got %T", fn, list)
+	}
+	out := make([]string, v.Len())
+	for i := range v.Len() {
+		out[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return out, nil
+}
+
+// join concatenates list elements with sep: {{ .tags | join ", " }}.
+func join(sep string, list any) (string, error) {
+	items, err := listItems("join", list)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(items, sep), nil
+}
+
+// indent prefixes every non-empty line of text with spaces: {{ .body | indent 4 }}.
+func indent(spaces int, text string) string {
+	if spaces <= 0 {
+		return text
+	}
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// defaultValue returns value unless it is empty (zero, nil, empty string/list/map), then def: {{ .tone | default "neutral" }}.
+func defaultValue(def, value any) any {
+	if truth, ok := template.IsTrue(value); !ok || !truth {
+		return def
+	}
+	return value
+}
+
+// toJSON returns the JSON encoding of v.
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJSON: %w", err)
+	}
+	return string(b), nil
+}
+
+// toYAML returns the YAML encoding of v without the trailing newline.
+func toYAML(v any) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toYAML: %w", err)
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// numberedList renders list elements as "1. a" lines.
+func numberedList(list any) (string, error) {
+	items, err := listItems("numbered_list", list)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, item)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// bulletList renders list elements as "- a" lines.
+func bulletList(list any) (string, error) {
+	items, err := listItems("bullet_list", list)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + item
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// markdownTable renders a slice of structs (or struct pointers) as a Markdown table.
+// Columns are exported fields in declaration order, named by the prompt tag when set; prompt:"-" skips a field.
+func markdownTable(rows any) (string, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("markdown_table: expected a slice of structs, got %T", rows)
+	}
+	elem := v.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return "", fmt.Errorf("markdown_table: expected a slice of structs, got %T", rows)
+	}
+	var headers []string
+	var fields []int
+	for i := range elem.NumField() {
+		f := elem.Field(i)
+		name := f.Tag.Get("prompt")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		headers = append(headers, markdownCell(name))
+		fields = append(fields, i)
+	}
+	var sb strings.Builder
+	sb.WriteString("| " + strings.Join(headers, " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat(" --- |", len(headers)))
+	for i := range v.Len() {
+		row := reflect.Indirect(v.Index(i))
+		cells := make([]string, len(fields))
+		if row.IsValid() {
+			for j, idx := range fields {
+				cells[j] = markdownCell(fmt.Sprint(row.Field(idx).Interface()))
+			}
+		}
+		sb.WriteString("\n| " + strings.Join(cells, " | ") + " |")
+	}
+	return sb.String(), nil
+}
+
+// markdownCell escapes pipes and flattens newlines so a value stays in one table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "\n", " ")), " ")
+}
+
+// formatDate formats t (time.Time, *time.Time or an RFC 3339 string) with a Go layout: {{ .created | date "2006-01-02" }}.
+func formatDate(layout string, t any) (string, error) {
+	switch v := t.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		return parsed.Format(layout), nil
+	default:
+		return "", fmt.Errorf("date: expected time.Time or RFC 3339 string, got %T", t)
+	}
+}
+
+// wrap word-wraps text to lines of at most width runes (longer words stay on their own line); paragraphs are kept.
+func wrap(width int, text string) string {
+	if width <= 0 {
+		return text
+	}
+	paragraphs := strings.Split(text, "\n")
+	for p, para := range paragraphs {
+		var sb strings.Builder
+		lineLen := 0
+		for _, word := range strings.Fields(para) {
+			n := utf8.RuneCountInString(word)
+			switch {
+			case lineLen == 0:
+			case lineLen+1+n > width:
+				sb.WriteString("\n")
+				lineLen = 0
+			default:
+				sb.WriteString(" ")
+				lineLen++
+			}
+			sb.WriteString(word)
+			lineLen += n
+		}
+		paragraphs[p] = sb.String()
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// xmlTagName matches tag names accepted by xml_tag (letters, digits, '_', '-', '.'; no leading digit or punctuation).
+var xmlTagName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// xmlTag wraps escaped content in <tag>…</tag> for isolating data in prompts: {{ .doc | xml_tag "document" }}.
+func xmlTag(tag string, content any) (string, error) {
+	if !xmlTagName.MatchString(tag) {
+		return "", fmt.Errorf("xml_tag: invalid tag name %q", tag)
+	}
+	return "<" + tag + ">" + escapeXML(fmt.Sprint(content)) + "</" + tag + ">", nil
+}
+
 func asToolSlice(tools any) ([]ToolDefinition, bool) {
 	if tools == nil {
 		return nil, true
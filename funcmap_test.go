@@ -7,6 +7,8 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -233,3 +235,105 @@ func TestFuncMap_RandomHex_Integration(t *testing.T) {
 	assert.Equal(t, delimOpen, delimClose, "same randomHex value must appear in both tags")
 	assert.Regexp(t, `^[0-9a-f]+$`, delimOpen)
 }
+
+func TestFuncMap_Library(t *testing.T) {
+	t.Parallel()
+	type row struct {
+		Name  string `prompt:"name"`
+		Score int
+		note  string
+		Skip  string `prompt:"-"`
+	}
+	created := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	vars := map[string]any{
+		"tags":    []string{"go", "llm"},
+		"nums":    []int{1, 2},
+		"body":    "a\n\nb",
+		"empty":   "",
+		"cfg":     map[string]any{"k": "v"},
+		"rows":    []row{{Name: "a|b", Score: 1, note: "x", Skip: "y"}, {Name: "c\nd", Score: 2}},
+		"created": created,
+		"stamp":   "2026-03-14T09:30:00Z",
+		"long":    "one two three four five",
+		"doc":     `x</doc><b>&`,
+	}
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"join", `{{ .tags | join ", " }}`, "go, llm"},
+		{"join ints", `{{ join "+" .nums }}`, "1+2"},
+		{"indent", `{{ .body | indent 2 }}`, "  a\n\n  b"},
+		{"trim", `{{ trim "  x " }}`, "x"},
+		{"default empty", `{{ .empty | default "n/a" }}`, "n/a"},
+		{"default set", `{{ .tags | join "," | default "n/a" }}`, "go,llm"},
+		{"toJSON", `{{ toJSON .cfg }}`, `{"k":"v"}`},
+		{"toYAML", `{{ toYAML .cfg }}`, "k: v"},
+		{"numbered_list", `{{ numbered_list .tags }}`, "1. go\n2. llm"},
+		{"bullet_list", `{{ bullet_list .tags }}`, "- go\n- llm"},
+		{"markdown_table", `{{ markdown_table .rows }}`, "| name | Score |\n| --- | --- |\n| a\\|b | 1 |\n| c d | 2 |"},
+		{"date", `{{ .created | date "2006-01-02 15:04" }}`, "2026-03-14 09:30"},
+		{"date string", `{{ .stamp | date "Jan 2" }}`, "Mar 14"},
+		{"wrap", `{{ .long | wrap 9 }}`, "one two\nthree\nfour five"},
+		{"xml_tag", `{{ .doc | xml_tag "doc" }}`, "<doc>x&lt;/doc&gt;&lt;b&gt;&amp;</doc>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tpl, err := NewChatPromptTemplate([]MessageTemplate{{Role: RoleUser, Content: TextContent(tt.src)}})
+			require.NoError(t, err)
+			exec, err := tpl.Format(vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, textOf(t, exec.Messages[0]))
+		})
+	}
+}
+
+func TestFuncMap_LibraryErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"join non-list", `{{ join "," 3 }}`},
+		{"markdown_table non-struct", `{{ markdown_table .items }}`},
+		{"date bad string", `{{ date "2006" "yesterday" }}`},
+		{"xml_tag bad name", `{{ xml_tag "a b" "x" }}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tpl, err := NewChatPromptTemplate([]MessageTemplate{{Role: RoleUser, Content: TextContent(tt.src)}})
+			require.NoError(t, err)
+			_, err = tpl.Format(map[string]any{"items": []string{"x"}})
+			require.ErrorIs(t, err, ErrTemplateRender)
+		})
+	}
+}
+
+func TestWithFuncs(t *testing.T) {
+	t.Parallel()
+	funcs := template.FuncMap{"shout": strings.ToUpper}
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleUser, Content: TextContent(`{{ .name | shout | xml_tag "name" }}`)},
+	}, WithFuncs(funcs))
+	require.NoError(t, err)
+	funcs["shout"] = strings.ToLower // options copy the map
+	exec, err := CloneTemplate(tpl).Format(map[string]any{"name": "ann"})
+	require.NoError(t, err)
+	assert.Equal(t, "<name>ANN</name>", textOf(t, exec.Messages[0]))
+
+	_, err = NewChatPromptTemplate(nil, WithFuncs(template.FuncMap{"join": strings.Join}))
+	require.ErrorIs(t, err, ErrFuncConflict)
+	assert.Contains(t, err.Error(), `"join"`)
+	for _, name := range []string{"len", "printf", "eq", "index"} {
+		_, err = NewChatPromptTemplate(nil, WithFuncs(template.FuncMap{name: strings.ToUpper}))
+		require.ErrorIs(t, err, ErrFuncConflict, name)
+	}
+
+	_, err = NewChatPromptTemplate(nil, WithFuncs(template.FuncMap{"bad": 42}))
+	require.ErrorIs(t, err, ErrTemplateParse)
+	_, err = NewChatPromptTemplate(nil, WithFuncs(template.FuncMap{"noResult": func() {}}))
+	require.ErrorIs(t, err, ErrTemplateParse)
+}
@@ -1,4 +1,6 @@
 // Package manifest parses YAML/JSON prompt manifests into prompty.ChatPromptTemplate.
 // Use ParseBytes, ParseFile, or ParseFS to load a manifest; the result is used with
 // fileregistry or embedregistry, or passed to NewChatPromptTemplate callers.
+// Manifests with `extends: <id>` are merged over a parent resolved via WithBaseRegistry.
+// Env overlays (`overlay: true`) patch a base manifest; ParseEnvChain composes an environment chain.
 package manifest
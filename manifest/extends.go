@@ -0,0 +1,250 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/internal/cast"
+)
+
+// Messages merge modes for RawManifest.MessagesMerge (manifest key messages_merge).
+const (
+	// MessagesReplace uses the child messages when present, otherwise the parent's (default).
+	MessagesReplace = "replace"
+	// MessagesPrepend renders child messages before the parent's.
+	MessagesPrepend = "prepend"
+	// MessagesAppend renders child messages after the parent's.
+	MessagesAppend = "append"
+)
+
+// maxExtendsDepth bounds the extends chain so misconfigured registries cannot recurse forever.
+const maxExtendsDepth = 16
+
+type extendsChainKey struct{}
+
+// WithBaseRegistry sets the registry used to resolve `extends: <id>` (usually the registry that is loading the manifest).
+// ctx is passed to reg.GetTemplate and carries the chain of ids being resolved for cycle detection.
+func WithBaseRegistry(ctx context.Context, reg prompty.Registry) ParseOption {
+	return func(o *parseOpts) {
+		o.baseCtx = ctx
+		o.baseRegistry = reg
+	}
+}
+
+// extendsChain returns ids already being resolved in ctx (outermost first).
+func extendsChain(ctx context.Context) []string {
+	chain, _ := ctx.Value(extendsChainKey{}).([]string)
+	return chain
+}
+
+// resolveParent loads the parent template of raw through the base registry, detecting cycles.
+func resolveParent(raw *RawManifest, po *parseOpts) (*prompty.ChatPromptTemplate, error) {
+	if po == nil || po.baseRegistry == nil {
+		return nil, fmt.Errorf(
+			"%w: extends %q requires a registry (manifest.WithBaseRegistry)",
+			prompty.ErrInvalidManifest,
+			raw.Extends,
+		)
+	}
+	ctx := po.baseCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	chain := extendsChain(ctx)
+	if len(chain) == 0 || chain[len(chain)-1] != raw.ID {
+		chain = append(slices.Clone(chain), raw.ID)
+	}
+	if slices.Contains(chain, raw.Extends) {
+		cycle := strings.Join(append(slices.Clone(chain), raw.Extends), " -> ")
+		return nil, fmt.Errorf("%w: %w: %s", prompty.ErrInvalidManifest, prompty.ErrExtendsCycle, cycle)
+	}
+	if len(chain) > maxExtendsDepth {
+		return nil, fmt.Errorf(
+			"%w: extends chain of %q exceeds %d levels",
+			prompty.ErrInvalidManifest,
+			raw.ID,
+			maxExtendsDepth,
+		)
+	}
+	next := append(slices.Clone(chain), raw.Extends)
+	parent, err := po.baseRegistry.GetTemplate(context.WithValue(ctx, extendsChainKey{}, next), raw.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q extends %q: %w", prompty.ErrInvalidManifest, raw.ID, raw.Extends, err)
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("%w: %q extends %q: nil template", prompty.ErrInvalidManifest, raw.ID, raw.Extends)
+	}
+	return parent, nil
+}
+
+// mergeWithParent returns a copy of raw with tools, model_config, input_schema and response_format
+// merged over the parent template. Messages are merged separately by mergeMessageTemplates.
+func mergeWithParent(raw *RawManifest, parent *prompty.ChatPromptTemplate) *RawManifest {
+	merged := *raw
+	merged.Tools = mergeTools(parent.Tools, raw.Tools)
+	merged.ModelOptions = mergeModelOptions(parent.ModelOptions, raw.ModelOptions)
+	merged.InputSchema = mergeInputSchema(parent.InputSchema, raw.InputSchema)
+	if merged.ResponseFormat == nil {
+		merged.ResponseFormat = parent.ResponseFormat
+	}
+	if merged.Description == "" {
+		merged.Description = parent.Metadata.Description
+	}
+	return &merged
+}
+
+// mergeMessageTemplates combines parent and child messages according to mode.
+func mergeMessageTemplates(parent, child []prompty.MessageTemplate, mode string) ([]prompty.MessageTemplate, error) {
+	switch mode {
+	case "", MessagesReplace:
+		if len(child) == 0 {
+			return parent, nil
+		}
+		return child, nil
+	case MessagesPrepend:
+		return slices.Concat(child, parent), nil
+	case MessagesAppend:
+		return slices.Concat(parent, child), nil
+	default:
+		return nil, fmt.Errorf(
+			"%w: messages_merge %q is invalid (use replace, prepend or append)",
+			prompty.ErrInvalidManifest,
+			mode,
+		)
+	}
+}
+
+// mergeTools merges tools by name: child definitions replace parent ones in place, new tools are appended.
+func mergeTools(parent, child []prompty.ToolDefinition) []prompty.ToolDefinition {
+	if len(child) == 0 {
+		return parent
+	}
+	out := slices.Clone(parent)
+	index := make(map[string]int, len(out))
+	for i, t := range out {
+		index[t.Name] = i
+	}
+	for _, t := range child {
+		if i, ok := index[t.Name]; ok {
+			out[i] = t
+			continue
+		}
+		index[t.Name] = len(out)
+		out = append(out, t)
+	}
+	return out
+}
+
+// mergeModelOptions deep-merges model_config: set child fields win, provider_settings are merged recursively.
+func mergeModelOptions(parent, child *prompty.ModelOptions) *prompty.ModelOptions {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	out := *parent
+	if child.Model != "" {
+		out.Model = child.Model
+	}
+	if child.Temperature != nil {
+		out.Temperature = child.Temperature
+	}
+	if child.MaxTokens != nil {
+		out.MaxTokens = child.MaxTokens
+	}
+	if child.TopP != nil {
+		out.TopP = child.TopP
+	}
+	if child.Stop != nil {
+		out.Stop = child.Stop
+	}
+	out.ProviderSettings = deepMergeMaps(parent.ProviderSettings, child.ProviderSettings)
+	return &out
+}
+
+// deepMergeMaps merges over into base; nested maps are merged, other values from over win.
+func deepMergeMaps(base, over map[string]any) map[string]any {
+	if base == nil {
+		return over
+	}
+	if over == nil {
+		return base
+	}
+	out := maps.Clone(base)
+	for k, v := range over {
+		baseMap, baseOK := out[k].(map[string]any)
+		overMap, overOK := v.(map[string]any)
+		if baseOK && overOK {
+			out[k] = deepMergeMaps(baseMap, overMap)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// mergeInputSchema merges input_schema: properties are merged (child wins per property),
+// required lists are unioned, other schema keys and name/description from the child win.
+func mergeInputSchema(parent, child *prompty.SchemaDefinition) *prompty.SchemaDefinition {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	out := &prompty.SchemaDefinition{
+		Name:        child.Name,
+		Description: child.Description,
+		Schema:      mergeSchemaObject(parent.Schema, child.Schema),
+	}
+	if out.Name == "" {
+		out.Name = parent.Name
+	}
+	if out.Description == "" {
+		out.Description = parent.Description
+	}
+	return out
+}
+
+// mergeSchemaObject merges two JSON Schema objects: properties per key, required as a union.
+func mergeSchemaObject(parent, child map[string]any) map[string]any {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	out := maps.Clone(parent)
+	for k, v := range child {
+		if k != "properties" && k != "required" {
+			out[k] = v
+		}
+	}
+	parentProps, _ := parent["properties"].(map[string]any)
+	childProps, _ := child["properties"].(map[string]any)
+	if parentProps != nil || childProps != nil {
+		props := maps.Clone(parentProps)
+		if props == nil {
+			props = make(map[string]any, len(childProps))
+		}
+		maps.Copy(props, childProps)
+		out["properties"] = props
+	}
+	parentReq, _ := cast.ToStringSlice(parent["required"])
+	childReq, _ := cast.ToStringSlice(child["required"])
+	if len(parentReq) > 0 || len(childReq) > 0 {
+		required := slices.Clone(parentReq)
+		for _, name := range childReq {
+			if !slices.Contains(required, name) {
+				required = append(required, name)
+			}
+		}
+		out["required"] = required
+	}
+	return out
+}
@@ -24,9 +24,12 @@ func (JSONParser) Unmarshal(in []byte, out any) error {
 		return errors.New("manifest: out must be *RawManifest")
 	}
 	var wire struct {
+		Overlay         bool                      `json:"overlay"`
 		ID              string                    `json:"id"`
 		Version         string                    `json:"version"`
 		Description     string                    `json:"description"`
+		Extends         string                    `json:"extends"`
+		MessagesMerge   string                    `json:"messages_merge"`
 		ModelOptionsRaw json.RawMessage           `json:"model_config"`
 		Metadata        map[string]any            `json:"metadata"`
 		InputSchema     *prompty.SchemaDefinition `json:"input_schema"`
@@ -48,9 +51,12 @@ func (JSONParser) Unmarshal(in []byte, out any) error {
 		return err
 	}
 
+	raw.Overlay = wire.Overlay
 	raw.ID = wire.ID
 	raw.Version = wire.Version
 	raw.Description = wire.Description
+	raw.Extends = wire.Extends
+	raw.MessagesMerge = wire.MessagesMerge
 	raw.Metadata = wire.Metadata
 	raw.InputSchema = wire.InputSchema
 	raw.Tools = wire.Tools
@@ -1,7 +1,9 @@
 package manifest
 
 import (
+	"context"
 	"embed"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -379,3 +381,136 @@ func TestParse_FuncMapHelpersManifestPath(t *testing.T) {
 	assert.Equal(t, delimOpen, delimClose, "same randomHex value must appear in both tags")
 	assert.Regexp(t, `^[0-9a-f]{16}$`, delimOpen)
 }
+
+// manifestRegistry resolves extends parents from in-memory JSON manifests.
+type manifestRegistry map[string]string
+
+func (m manifestRegistry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	data, ok := m[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	}
+	return Parse([]byte(data), jsonParser, WithBaseRegistry(ctx, m))
+}
+
+const extendsBase = `{"id":"base","version":"1","description":"Base",
+"messages":[{"role":"system","content":[{"type":"text","text":"Policy for {{ .user }}"}]}],
+"tools":[{"name":"search","description":"old"},{"name":"lookup","description":"lookup"}],
+"model_config":{"model":"gpt-4o","temperature":0.2,"provider_settings":{"a":{"x":1,"y":2}}},
+"input_schema":{"name":"base_input","schema":{"type":"object",
+"properties":{"user":{"type":"string"},"tone":{"type":"string","default":"calm"}},"required":["user"]}}}`
+
+func TestParse_Extends_MergesParent(t *testing.T) {
+	t.Parallel()
+	reg := manifestRegistry{"base": extendsBase}
+	child := []byte(`{"id":"child","version":"2","extends":"base","messages_merge":"append",
+"messages":[{"role":"user","content":[{"type":"text","text":"{{ .query }}"}]}],
+"tools":[{"name":"search","description":"new"},{"name":"extra","description":"extra"}],
+"model_config":{"max_tokens":100,"provider_settings":{"a":{"y":3}}},
+"input_schema":{"schema":{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}}}`)
+	tpl, err := Parse(child, jsonParser, WithBaseRegistry(context.Background(), reg))
+	require.NoError(t, err)
+
+	assert.Equal(t, "child", tpl.Metadata.ID)
+	assert.Equal(t, "2", tpl.Metadata.Version)
+	assert.Equal(t, "Base", tpl.Metadata.Description)
+	require.Len(t, tpl.Messages, 2)
+	assert.Equal(t, prompty.RoleSystem, tpl.Messages[0].Role)
+	assert.Equal(t, prompty.RoleUser, tpl.Messages[1].Role)
+
+	require.Len(t, tpl.Tools, 3)
+	assert.Equal(t, "search", tpl.Tools[0].Name)
+	assert.Equal(t, "new", tpl.Tools[0].Description)
+	assert.Equal(t, "lookup", tpl.Tools[1].Name)
+	assert.Equal(t, "extra", tpl.Tools[2].Name)
+
+	require.NotNil(t, tpl.ModelOptions)
+	assert.Equal(t, "gpt-4o", tpl.ModelOptions.Model)
+	require.NotNil(t, tpl.ModelOptions.Temperature)
+	require.NotNil(t, tpl.ModelOptions.MaxTokens)
+	assert.Equal(t, int64(100), *tpl.ModelOptions.MaxTokens)
+	assert.Equal(t, map[string]any{"a": map[string]any{"x": float64(1), "y": float64(3)}}, tpl.ModelOptions.ProviderSettings)
+
+	require.NotNil(t, tpl.InputSchema)
+	assert.Equal(t, "base_input", tpl.InputSchema.Name)
+	props, ok := tpl.InputSchema.Schema["properties"].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, props, "user")
+	assert.Contains(t, props, "query")
+	assert.ElementsMatch(t, []string{"user", "query"}, tpl.RequiredVars)
+	assert.Equal(t, "calm", tpl.PartialVariables["tone"])
+
+	exec, err := tpl.Format(map[string]any{"user": "Ann", "query": "hi"})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 2)
+	assert.Equal(t, "Policy for Ann", exec.Messages[0].Content[0].(prompty.TextPart).Text)
+	assert.Equal(t, "hi", exec.Messages[1].Content[0].(prompty.TextPart).Text)
+}
+
+func TestParse_Extends_MessagesMergeModes(t *testing.T) {
+	t.Parallel()
+	reg := manifestRegistry{"base": extendsBase}
+	tests := []struct {
+		name  string
+		merge string
+		msgs  string
+		roles []prompty.Role
+	}{
+		{"inherit", "", "", []prompty.Role{prompty.RoleSystem}},
+		{"replace", "replace", `,"messages":[{"role":"user","content":[{"type":"text","text":"x"}]}]`, []prompty.Role{prompty.RoleUser}},
+		{"prepend", "prepend", `,"messages":[{"role":"user","content":[{"type":"text","text":"x"}]}]`, []prompty.Role{prompty.RoleUser, prompty.RoleSystem}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := `{"id":"child","extends":"base"` + tt.msgs
+			if tt.merge != "" {
+				data += `,"messages_merge":"` + tt.merge + `"`
+			}
+			tpl, err := Parse([]byte(data+"}"), jsonParser, WithBaseRegistry(context.Background(), reg))
+			require.NoError(t, err)
+			roles := make([]prompty.Role, len(tpl.Messages))
+			for i, m := range tpl.Messages {
+				roles[i] = m.Role
+			}
+			assert.Equal(t, tt.roles, roles)
+		})
+	}
+}
+
+func TestParse_Extends_Errors(t *testing.T) {
+	t.Parallel()
+	reg := manifestRegistry{
+		"base": extendsBase,
+		"a":    `{"id":"a","extends":"b"}`,
+		"b":    `{"id":"b","extends":"a"}`,
+		"self": `{"id":"self","extends":"self"}`,
+	}
+	ctx := context.Background()
+
+	_, err := Parse([]byte(`{"id":"child","extends":"base"}`), jsonParser)
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	assert.Contains(t, err.Error(), "requires a registry")
+
+	_, err = Parse([]byte(`{"id":"child","extends":"missing"}`), jsonParser, WithBaseRegistry(ctx, reg))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	assert.Contains(t, err.Error(), `"child" extends "missing"`)
+
+	_, err = reg.GetTemplate(ctx, "a")
+	require.ErrorIs(t, err, prompty.ErrExtendsCycle)
+	assert.Contains(t, err.Error(), "a -> b -> a")
+
+	_, err = reg.GetTemplate(ctx, "self")
+	require.ErrorIs(t, err, prompty.ErrExtendsCycle)
+
+	_, err = Parse([]byte(`{"id":"child","extends":"base","messages_merge":"merge"}`), jsonParser,
+		WithBaseRegistry(ctx, reg))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	assert.Contains(t, err.Error(), "messages_merge")
+
+	_, err = Parse([]byte(`{"id":"x","messages_merge":"append",
+"messages":[{"role":"user","content":[{"type":"text","text":"x"}]}]}`), jsonParser)
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	assert.Contains(t, err.Error(), "requires extends")
+}
@@ -2,6 +2,8 @@ package manifest
 
 import (
 	"encoding/json"
+	"maps"
+	"slices"
 
 	"github.com/skosovsky/prompty"
 )
@@ -15,6 +17,12 @@ var knownModelOptionKeys = map[string]struct{}{
 	"provider_settings": {},
 }
 
+// ModelOptionKeys returns the model_config keys (sorted) that map to ModelOptions fields.
+// Other keys are accepted but moved into ProviderSettings (see DecodeModelOptions).
+func ModelOptionKeys() []string {
+	return slices.Sorted(maps.Keys(knownModelOptionKeys))
+}
+
 // DecodeModelOptions converts a normalized model_config block into typed ModelOptions.
 // Unknown top-level keys are preserved in ProviderSettings, while explicit provider_settings wins on conflicts.
 func DecodeModelOptions(raw map[string]any) (*prompty.ModelOptions, error) {
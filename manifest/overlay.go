@@ -0,0 +1,221 @@
+package manifest
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/skosovsky/prompty"
+)
+
+// LayerReader returns the manifest bytes of one layer of an environment chain: env is an environment
+// (e.g. "prod-eu") or "" for the base manifest. ok is false when the layer does not exist.
+type LayerReader func(env string) (data []byte, ok bool, err error)
+
+// FormatLayerReader is a LayerReader for chains whose layers differ in format: it also returns the parser of the
+// layer (e.g. from a per-row format column).
+type FormatLayerReader func(env string) (data []byte, u Unmarshaler, ok bool, err error)
+
+// ParseEnvChain builds a template from an environment chain: envs from most to least specific
+// (e.g. "prod-eu", "prod"), then the base manifest. Layers are read in that order down to the first complete
+// manifest; overlays found above it (`overlay: true`) are applied over it, least specific first.
+// An overlay patches only what it sets: model_config and input_schema are deep-merged, tools merge by name,
+// messages with `index` patch that message and other messages combine per messages_merge (default replace).
+//
+// Metadata.Environment lists the env layers applied, least specific first, comma-separated
+// (e.g. "prod,prod-eu"; "" when only the base manifest was used).
+// Returns an error matching [fs.ErrNotExist] when no layer exists.
+func ParseEnvChain(read LayerReader, envs []string, u Unmarshaler, opts ...ParseOption) (*prompty.ChatPromptTemplate, error) {
+	if u == nil {
+		return nil, prompty.ErrNoParser
+	}
+	return ParseEnvChainFormats(func(env string) ([]byte, Unmarshaler, bool, error) {
+		data, ok, err := read(env)
+		return data, u, ok, err
+	}, envs, opts...)
+}
+
+// ParseEnvChainFormats is ParseEnvChain with a parser per layer: each layer is decoded by the Unmarshaler read
+// returns with it. A layer without a parser fails with prompty.ErrNoParser.
+func ParseEnvChainFormats(
+	read FormatLayerReader,
+	envs []string,
+	opts ...ParseOption,
+) (*prompty.ChatPromptTemplate, error) {
+	type overlay struct {
+		env string
+		raw *RawManifest
+	}
+	var overlays []overlay // most specific first
+	for _, env := range append(slices.Clone(envs), "") {
+		data, u, ok, err := read(env)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if u == nil {
+			return nil, fmt.Errorf("%w: %s", prompty.ErrNoParser, layerName(env))
+		}
+		var raw RawManifest
+		if err := u.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", prompty.ErrInvalidManifest, layerName(env), err)
+		}
+		if !raw.Overlay {
+			tpl, err := Build(&raw, opts...)
+			if err != nil {
+				return nil, err
+			}
+			var applied []string
+			if env != "" {
+				applied = append(applied, env)
+			}
+			for i := len(overlays) - 1; i >= 0; i-- {
+				o := overlays[i]
+				if tpl, err = Build(o.raw, append(slices.Clone(opts), withOverlayBase(tpl))...); err != nil {
+					return nil, fmt.Errorf("%s: %w", layerName(o.env), err)
+				}
+				applied = append(applied, o.env)
+			}
+			tpl.Metadata.Environment = strings.Join(applied, ",")
+			return tpl, nil
+		}
+		if env == "" {
+			return nil, fmt.Errorf("%w: base manifest must not be an overlay", prompty.ErrInvalidManifest)
+		}
+		overlays = append(overlays, overlay{env: env, raw: &raw})
+	}
+	if len(overlays) > 0 {
+		return nil, fmt.Errorf("%w: %s has no base manifest to patch", prompty.ErrInvalidManifest, layerName(overlays[0].env))
+	}
+	return nil, fmt.Errorf("manifest: no layer in environment chain: %w", fs.ErrNotExist)
+}
+
+func layerName(env string) string {
+	if env == "" {
+		return "base manifest"
+	}
+	return fmt.Sprintf("overlay %q", env)
+}
+
+// withOverlayBase sets the template an overlay manifest patches.
+func withOverlayBase(base *prompty.ChatPromptTemplate) ParseOption {
+	return func(o *parseOpts) { o.overlayBase = base }
+}
+
+// buildOverlay patches po.overlayBase with the overlay raw.
+func buildOverlay(raw *RawManifest, po *parseOpts) (*prompty.ChatPromptTemplate, error) {
+	if po == nil || po.overlayBase == nil {
+		return nil, fmt.Errorf("%w: overlay manifest needs a base manifest (load it through an environment chain)",
+			prompty.ErrInvalidManifest)
+	}
+	if raw.Extends != "" {
+		return nil, fmt.Errorf("%w: overlay must not use extends", prompty.ErrInvalidManifest)
+	}
+	base := po.overlayBase
+	if raw.ID != "" && raw.ID != base.Metadata.ID {
+		return nil, fmt.Errorf("%w: overlay id %q does not match %q", prompty.ErrInvalidManifest, raw.ID, base.Metadata.ID)
+	}
+	patched, rest, err := patchMessages(base.Messages, raw.Messages)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := mergeMessageTemplates(patched, rawToMessageTemplates(rest), raw.MessagesMerge)
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeWithParent(raw, base)
+	merged.ID = base.Metadata.ID
+	if merged.Version == "" {
+		merged.Version = base.Metadata.Version
+	}
+	return buildTemplate(merged, overlayMetadata(base.Metadata, metadataToPromptMetadata(merged)), messages, po)
+}
+
+// patchMessages applies the indexed messages of patches to a copy of base and returns the rest unchanged.
+func patchMessages(
+	base []prompty.MessageTemplate,
+	patches []RawMessage,
+) ([]prompty.MessageTemplate, []RawMessage, error) {
+	out := slices.Clone(base)
+	var rest []RawMessage
+	for _, p := range patches {
+		if p.Index == nil {
+			rest = append(rest, p)
+			continue
+		}
+		i := *p.Index
+		if i < 0 {
+			i += len(out)
+		}
+		if i < 0 || i >= len(out) {
+			return nil, nil, fmt.Errorf("%w: overlay message index %d out of range (%d messages)",
+				prompty.ErrInvalidManifest, *p.Index, len(out))
+		}
+		out[i] = patchMessage(out[i], rawToMessageTemplates([]RawMessage{p})[0])
+	}
+	return out, rest, nil
+}
+
+// patchMessage returns m with the fields set in patch replaced; metadata keys are merged.
+func patchMessage(m, patch prompty.MessageTemplate) prompty.MessageTemplate {
+	if patch.Role != "" {
+		m.Role = patch.Role
+	}
+	if len(patch.Content) > 0 {
+		m.Content = patch.Content
+	}
+	if patch.Optional {
+		m.Optional = true
+	}
+	if patch.When != "" {
+		m.When = patch.When
+	}
+	if patch.ForEach != "" {
+		m.ForEach = patch.ForEach
+	}
+	if len(patch.Turns) > 0 {
+		m.Turns = patch.Turns
+	}
+	if patch.CacheControl != nil {
+		m.CacheControl = patch.CacheControl
+	}
+	if patch.Metadata != nil {
+		meta := maps.Clone(m.Metadata)
+		if meta == nil {
+			meta = make(map[string]any, len(patch.Metadata))
+		}
+		maps.Copy(meta, patch.Metadata)
+		m.Metadata = meta
+	}
+	return m
+}
+
+// overlayMetadata fills metadata the overlay does not set from the base template's.
+func overlayMetadata(base, over prompty.PromptMetadata) prompty.PromptMetadata {
+	if over.Tags == nil {
+		over.Tags = slices.Clone(base.Tags)
+	}
+	if over.Environment == "" {
+		over.Environment = base.Environment
+	}
+	if base.Extras != nil {
+		extras := maps.Clone(base.Extras)
+		maps.Copy(extras, over.Extras)
+		over.Extras = extras
+	}
+	return over
+}
+
+// checkNoMessageIndex rejects `index` outside overlays.
+func checkNoMessageIndex(messages []RawMessage) error {
+	for i, m := range messages {
+		if m.Index != nil {
+			return fmt.Errorf("%w: message %d: index is only allowed in overlay manifests", prompty.ErrInvalidManifest, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/skosovsky/prompty"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const overlayBaseJSON = `{"id":"agent","version":"1.0.0",
+	"model_config":{"model":"m-base","temperature":0.2,"max_tokens":100},
+	"metadata":{"tags":["support"],"team":"core"},
+	"messages":[
+		{"role":"system","content":[{"type":"text","text":"Base system"}]},
+		{"role":"user","content":[{"type":"text","text":"{{ .question }}"}]}
+	]}`
+
+func layers(files map[string]string) LayerReader {
+	return func(env string) ([]byte, bool, error) {
+		data, ok := files[env]
+		return []byte(data), ok, nil
+	}
+}
+
+func TestParseEnvChain_Overlays(t *testing.T) {
+	t.Parallel()
+	read := layers(map[string]string{
+		"": overlayBaseJSON,
+		"prod": `{"overlay":true,"model_config":{"model":"m-prod"},
+			"messages":[{"index":0,"content":[{"type":"text","text":"Prod system"}]}]}`,
+		"prod-eu": `{"overlay":true,"model_config":{"temperature":0},"metadata":{"region":"eu"}}`,
+	})
+	tpl, err := ParseEnvChain(read, []string{"prod-eu", "prod"}, jsonParser)
+	require.NoError(t, err)
+	assert.Equal(t, "agent", tpl.Metadata.ID)
+	assert.Equal(t, "1.0.0", tpl.Metadata.Version)
+	assert.Equal(t, "prod,prod-eu", tpl.Metadata.Environment)
+	assert.Equal(t, []string{"support"}, tpl.Metadata.Tags)
+	assert.Equal(t, "core", tpl.Metadata.Extras["team"])
+	assert.Equal(t, "eu", tpl.Metadata.Extras["region"])
+
+	require.NotNil(t, tpl.ModelOptions)
+	assert.Equal(t, "m-prod", tpl.ModelOptions.Model)
+	require.NotNil(t, tpl.ModelOptions.Temperature)
+	assert.InDelta(t, 0.0, *tpl.ModelOptions.Temperature, 1e-9)
+	require.NotNil(t, tpl.ModelOptions.MaxTokens)
+	assert.Equal(t, int64(100), *tpl.ModelOptions.MaxTokens)
+
+	require.Len(t, tpl.Messages, 2)
+	assert.Equal(t, prompty.RoleSystem, tpl.Messages[0].Role)
+	assert.Equal(t, "Prod system", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, "{{ .question }}", tpl.Messages[1].Content[0].Text)
+}
+
+func TestParseEnvChain_FullEnvManifestReplacesBase(t *testing.T) {
+	t.Parallel()
+	read := layers(map[string]string{
+		"":        overlayBaseJSON,
+		"prod":    `{"id":"agent","messages":[{"role":"system","content":[{"type":"text","text":"Prod only"}]}]}`,
+		"prod-eu": `{"overlay":true,"messages":[{"index":-1,"content":[{"type":"text","text":"EU"}]}]}`,
+	})
+	tpl, err := ParseEnvChain(read, []string{"prod-eu", "prod"}, jsonParser)
+	require.NoError(t, err)
+	assert.Equal(t, "prod,prod-eu", tpl.Metadata.Environment)
+	assert.Empty(t, tpl.Metadata.Version, "the base manifest below a full env manifest is not read")
+	require.Len(t, tpl.Messages, 1)
+	assert.Equal(t, "EU", tpl.Messages[0].Content[0].Text)
+
+	tpl, err = ParseEnvChain(read, nil, jsonParser)
+	require.NoError(t, err)
+	assert.Empty(t, tpl.Metadata.Environment)
+	assert.Equal(t, "Base system", tpl.Messages[0].Content[0].Text)
+}
+
+func TestParseEnvChain_AppendMessages(t *testing.T) {
+	t.Parallel()
+	read := layers(map[string]string{
+		"": overlayBaseJSON,
+		"prod": `{"overlay":true,"messages_merge":"append",
+			"messages":[{"role":"system","content":[{"type":"text","text":"Be brief."}]}]}`,
+	})
+	tpl, err := ParseEnvChain(read, []string{"prod"}, jsonParser)
+	require.NoError(t, err)
+	require.Len(t, tpl.Messages, 3)
+	assert.Equal(t, "Be brief.", tpl.Messages[2].Content[0].Text)
+}
+
+func TestParseEnvChain_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"no base", map[string]string{"prod": `{"overlay":true,"model_config":{"model":"m"}}`}},
+		{"base is overlay", map[string]string{"": `{"overlay":true,"id":"agent"}`}},
+		{"index out of range", map[string]string{
+			"":     overlayBaseJSON,
+			"prod": `{"overlay":true,"messages":[{"index":5,"content":[{"type":"text","text":"x"}]}]}`,
+		}},
+		{"id mismatch", map[string]string{"": overlayBaseJSON, "prod": `{"overlay":true,"id":"other"}`}},
+		{"overlay extends", map[string]string{"": overlayBaseJSON, "prod": `{"overlay":true,"extends":"base"}`}},
+		{"index outside overlay", map[string]string{
+			"": `{"id":"agent","messages":[{"index":0,"role":"system","content":[{"type":"text","text":"x"}]}]}`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseEnvChain(layers(tt.files), []string{"prod"}, jsonParser)
+			require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+		})
+	}
+
+	_, err := ParseEnvChain(layers(nil), []string{"prod"}, jsonParser)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = Parse([]byte(`{"overlay":true,"id":"agent"}`), jsonParser)
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest, "an overlay alone is not a template")
+}
+
+func TestParseEnvChainFormats(t *testing.T) {
+	t.Parallel()
+	files := map[string]string{"": overlayBaseJSON, "prod": `{"overlay":true,"model_config":{"model":"m-prod"}}`}
+	parsers := map[string]Unmarshaler{"": jsonParser, "prod": jsonParser}
+	read := func(env string) ([]byte, Unmarshaler, bool, error) {
+		data, ok := files[env]
+		return []byte(data), parsers[env], ok, nil
+	}
+	tpl, err := ParseEnvChainFormats(read, []string{"prod"})
+	require.NoError(t, err)
+	assert.Equal(t, "m-prod", tpl.ModelOptions.Model)
+
+	delete(parsers, "prod")
+	_, err = ParseEnvChainFormats(read, []string{"prod"})
+	require.ErrorIs(t, err, prompty.ErrNoParser)
+}
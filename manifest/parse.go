@@ -1,10 +1,12 @@
 package manifest
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"maps"
 	"os"
+	"text/template"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/internal/cast"
@@ -49,6 +51,11 @@ type parseOpts struct {
 	partialsGlob      string
 	partialsFS        fs.FS
 	partialsFSPattern string
+	baseCtx           context.Context //nolint:containedctx // carries the extends chain into the base registry.
+	baseRegistry      prompty.Registry
+	overlayBase       *prompty.ChatPromptTemplate // template an overlay manifest patches (ParseEnvChain)
+	strict            bool
+	funcs             template.FuncMap
 }
 
 // WithPartialsGlob sets a glob for partials when loading from file (e.g. "_partials/*.tmpl").
@@ -56,6 +63,16 @@ func WithPartialsGlob(glob string) ParseOption {
 	return func(o *parseOpts) { o.partialsGlob = glob }
 }
 
+// WithStrict builds templates with [prompty.WithStrict] (unused payload fields and <no value> output fail rendering).
+func WithStrict() ParseOption {
+	return func(o *parseOpts) { o.strict = true }
+}
+
+// WithFuncs adds custom template functions (see [prompty.WithFuncs]); names must not shadow built-ins.
+func WithFuncs(funcs template.FuncMap) ParseOption {
+	return func(o *parseOpts) { o.funcs = funcs }
+}
+
 // WithPartialsFS sets [fs.FS] and pattern for partials (e.g. embed and "partials/*.tmpl").
 func WithPartialsFS(fsys fs.FS, pattern string) ParseOption {
 	return func(o *parseOpts) {
@@ -73,45 +90,59 @@ func Parse(data []byte, u Unmarshaler, opts ...ParseOption) (*prompty.ChatPrompt
 	if err := u.Unmarshal(data, &raw); err != nil {
 		return nil, fmt.Errorf("%w: %w", prompty.ErrInvalidManifest, err)
 	}
+	return Build(&raw, opts...)
+}
+
+// Build builds ChatPromptTemplate from an already unmarshaled RawManifest with parse options
+// (e.g. WithBaseRegistry for extends).
+func Build(raw *RawManifest, opts ...ParseOption) (*prompty.ChatPromptTemplate, error) {
 	var po parseOpts
 	for _, opt := range opts {
 		opt(&po)
 	}
-	return BuildFromRaw(&raw, &po)
+	return BuildFromRaw(raw, &po)
 }
 
 // BuildFromRaw builds ChatPromptTemplate from RawManifest (used by parsers and tests).
 func BuildFromRaw(raw *RawManifest, po *parseOpts) (*prompty.ChatPromptTemplate, error) {
+	if raw.Overlay {
+		return buildOverlay(raw, po)
+	}
 	if raw.ID == "" {
 		return nil, fmt.Errorf("%w: missing id", prompty.ErrInvalidManifest)
 	}
-	if len(raw.Messages) == 0 {
+	if len(raw.Messages) == 0 && raw.Extends == "" {
 		return nil, fmt.Errorf("%w: missing messages", prompty.ErrInvalidManifest)
 	}
-	messages := make([]prompty.MessageTemplate, len(raw.Messages))
-	for i := range raw.Messages {
-		rm := &raw.Messages[i]
-		content := make([]prompty.TemplatePart, len(rm.Content))
-		for j, p := range rm.Content {
-			content[j] = prompty.TemplatePart{
-				Type:         p.Type,
-				Text:         p.Text,
-				MediaType:    p.MediaType,
-				MIMEType:     p.MIMEType,
-				URL:          p.URL,
-				CacheControl: copyCacheControl(p.CacheControl),
-			}
+	if raw.MessagesMerge != "" && raw.Extends == "" {
+		return nil, fmt.Errorf("%w: messages_merge requires extends", prompty.ErrInvalidManifest)
+	}
+	if err := checkNoMessageIndex(raw.Messages); err != nil {
+		return nil, err
+	}
+	messages := rawToMessageTemplates(raw.Messages)
+	if raw.Extends != "" {
+		parent, err := resolveParent(raw, po)
+		if err != nil {
+			return nil, err
 		}
-		messages[i] = prompty.MessageTemplate{
-			Role:         prompty.Role(rm.Role),
-			Content:      content,
-			Optional:     rm.Optional,
-			CacheControl: copyCacheControl(rm.CacheControl),
-			Metadata:     maps.Clone(rm.Metadata),
+		if messages, err = mergeMessageTemplates(parent.Messages, messages, raw.MessagesMerge); err != nil {
+			return nil, err
 		}
+		raw = mergeWithParent(raw, parent)
 	}
+	return buildTemplate(raw, metadataToPromptMetadata(raw), messages, po)
+}
+
+// buildTemplate builds the template from merged raw fields, metadata and message templates.
+func buildTemplate(
+	raw *RawManifest,
+	meta prompty.PromptMetadata,
+	messages []prompty.MessageTemplate,
+	po *parseOpts,
+) (*prompty.ChatPromptTemplate, error) {
 	opts := []prompty.ChatTemplateOption{
-		prompty.WithMetadata(metadataToPromptMetadata(raw)),
+		prompty.WithMetadata(meta),
 	}
 	if raw.InputSchema != nil {
 		opts = append(opts, prompty.WithInputSchema(raw.InputSchema))
@@ -149,9 +180,49 @@ func BuildFromRaw(raw *RawManifest, po *parseOpts) (*prompty.ChatPromptTemplate,
 	if po != nil && po.partialsFS != nil {
 		opts = append(opts, prompty.WithPartialsFS(po.partialsFS, po.partialsFSPattern))
 	}
+	if po != nil && po.funcs != nil {
+		opts = append(opts, prompty.WithFuncs(po.funcs))
+	}
+	if po != nil && po.strict {
+		opts = append(opts, prompty.WithStrict())
+	}
 	return prompty.NewChatPromptTemplate(messages, opts...)
 }
 
+// rawToMessageTemplates converts raw messages into prompty message templates.
+func rawToMessageTemplates(raw []RawMessage) []prompty.MessageTemplate {
+	if raw == nil {
+		return nil
+	}
+	messages := make([]prompty.MessageTemplate, len(raw))
+	for i := range raw {
+		rm := &raw[i]
+		content := make([]prompty.TemplatePart, len(rm.Content))
+		for j, p := range rm.Content {
+			content[j] = prompty.TemplatePart{
+				Type:         p.Type,
+				Text:         p.Text,
+				MediaType:    p.MediaType,
+				MIMEType:     p.MIMEType,
+				URL:          p.URL,
+				When:         p.When,
+				CacheControl: copyCacheControl(p.CacheControl),
+			}
+		}
+		messages[i] = prompty.MessageTemplate{
+			Role:         prompty.Role(rm.Role),
+			Content:      content,
+			Optional:     rm.Optional,
+			When:         rm.When,
+			ForEach:      rm.ForEach,
+			Turns:        rawToMessageTemplates(rm.Turns),
+			CacheControl: copyCacheControl(rm.CacheControl),
+			Metadata:     maps.Clone(rm.Metadata),
+		}
+	}
+	return messages
+}
+
 // ParseFile reads the file and calls Parse.
 func ParseFile(path string, u Unmarshaler, opts ...ParseOption) (*prompty.ChatPromptTemplate, error) {
 	data, err := os.ReadFile(path) // #nosec G304 -- path is validated by caller
@@ -15,14 +15,24 @@ type RawContentPart struct {
 	MediaType    string                `json:"media_type,omitempty"`
 	MIMEType     string                `json:"mime_type,omitempty"`
 	URL          string                `json:"url,omitempty"`
+	When         string                `json:"when,omitempty"`
 	CacheControl *prompty.CacheControl `json:"cache_control,omitempty"`
 }
 
 // RawMessage is the raw representation of a single message.
+// When (and RawContentPart.When) holds an optional condition such as `has_docs && tier == "pro"`.
+// ForEach repeats the message per list element (.item/.index in scope); Turns emits one message per turn
+// for each element instead (e.g. user/assistant few-shot pairs).
+// Index (overlays only) patches the base message at that position (negative counts from the end) instead of
+// adding a message: fields set in the patch replace the base message's.
 type RawMessage struct {
+	Index        *int                  `json:"index,omitempty"`
 	Role         string                `json:"role"`
 	Content      []RawContentPart      `json:"content"`
 	Optional     bool                  `json:"optional"`
+	When         string                `json:"when,omitempty"`
+	ForEach      string                `json:"for_each,omitempty"`
+	Turns        []RawMessage          `json:"turns,omitempty"`
 	CacheControl *prompty.CacheControl `json:"cache_control,omitempty"`
 	Metadata     map[string]any        `json:"metadata,omitempty"`
 }
@@ -31,10 +41,17 @@ type RawMessage struct {
 // Supports Unmarshaler (YAML, JSON, etc.).
 // InputSchema is the JSON Schema for input typing (prompty-gen, required/partial derivation).
 // Metadata is the full metadata block; BuildFromRaw extracts tags and puts the rest into Extras.
+// Extends names a parent template id resolved through the registry (see WithBaseRegistry);
+// MessagesMerge selects how child messages combine with the parent's (replace, prepend, append).
+// Overlay marks an environment overlay (e.g. router.prod.yaml) that patches the next manifest in the
+// environment chain instead of replacing it (see ParseEnvChain).
 type RawManifest struct {
+	Overlay        bool                      `json:"overlay,omitempty"`
 	ID             string                    `json:"id"`
 	Version        string                    `json:"version"`
 	Description    string                    `json:"description"`
+	Extends        string                    `json:"extends,omitempty"`
+	MessagesMerge  string                    `json:"messages_merge,omitempty"`
 	ModelOptions   *prompty.ModelOptions     `json:"model_config"`
 	Metadata       map[string]any            `json:"metadata"`
 	InputSchema    *prompty.SchemaDefinition `json:"input_schema"`
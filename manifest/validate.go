@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/skosovsky/prompty"
+)
+
+// ValidateLayer checks that data can be published as the manifest for ref (see prompty.Publisher): a full manifest
+// must build on its own like Parse; an overlay (`overlay: true`) must build over the layers below ref.Env, read via
+// below (ref.Env's successors in chain, e.g. prod for prod-eu in ["prod-eu", "prod"], then the base manifest).
+// A released ref.Version must not be contradicted by the manifest's own `version`.
+// Returns the composed template; a manifest that does not build fails with an error matching
+// prompty.ErrInvalidManifest, while errors from below are returned as is.
+func ValidateLayer(
+	ref prompty.ManifestRef,
+	data []byte,
+	chain []string,
+	below LayerReader,
+	u Unmarshaler,
+	opts ...ParseOption,
+) (*prompty.ChatPromptTemplate, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	var envs []string
+	if ref.Env != "" {
+		envs = []string{ref.Env}
+		if i := slices.Index(chain, ref.Env); i >= 0 {
+			envs = slices.Clone(chain[i:])
+		}
+	}
+	var readErr error
+	read := func(env string) ([]byte, bool, error) {
+		if env == ref.Env {
+			return data, true, nil
+		}
+		layer, ok, err := below(env)
+		if err != nil {
+			readErr = err
+		}
+		return layer, ok, err
+	}
+	tpl, err := ParseEnvChain(read, envs, u, opts...)
+	switch {
+	case err != nil && readErr != nil:
+		return nil, err
+	case err != nil && !errors.Is(err, prompty.ErrInvalidManifest):
+		return nil, fmt.Errorf("%w: %s: %w", prompty.ErrInvalidManifest, ref, err)
+	case err != nil:
+		return nil, err
+	}
+	if ref.Version != "" && tpl.Metadata.Version != "" {
+		want, _ := prompty.ParseVersion(ref.Version)
+		if got, parseErr := prompty.ParseVersion(tpl.Metadata.Version); parseErr != nil || got != want {
+			return nil, fmt.Errorf("%w: %s: manifest declares version %q", prompty.ErrInvalidManifest, ref, tpl.Metadata.Version)
+		}
+	}
+	return tpl, nil
+}
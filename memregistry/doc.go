@@ -0,0 +1,5 @@
+// Package memregistry provides an in-memory prompt registry written through prompty.Publisher: tests, previews in
+// prompt-ops tooling, or manifests assembled at runtime. Manifests are stored by path like files in fileregistry
+// ({id}, {id}.{env}, {id}/v{version}), validated on Put, and built on every GetTemplate; env chains, overlays,
+// extends and "id@constraint" work as in the other registries.
+package memregistry
@@ -0,0 +1,303 @@
+package memregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+)
+
+// Ensures Registry implements prompty.Registry, Lister, Statter, Versioner, and Publisher.
+var (
+	_ prompty.Registry  = (*Registry)(nil)
+	_ prompty.Lister    = (*Registry)(nil)
+	_ prompty.Statter   = (*Registry)(nil)
+	_ prompty.Versioner = (*Registry)(nil)
+	_ prompty.Publisher = (*Registry)(nil)
+)
+
+// Registry keeps manifests in memory, keyed by ManifestRef.Path. Safe for concurrent use.
+// WithEnvironment(env): GetTemplate tries {id}.{env} first, then {id}; WithEnvironmentChain sets several envs.
+// Parser is required; use WithParser when creating the registry.
+type Registry struct {
+	parser manifest.Unmarshaler
+	envs   []string // e.g. ["prod-eu", "prod"]
+	strict bool     // WithStrict: templates are built with prompty.WithStrict
+	funcs  template.FuncMap
+	mu     sync.RWMutex
+	files  map[string]file // ManifestRef.Path() -> stored manifest
+}
+
+// file is one stored manifest.
+type file struct {
+	data      []byte
+	updatedAt time.Time
+}
+
+// New creates an empty Registry. Returns prompty.ErrNoParser when no parser is set.
+func New(opts ...Option) (*Registry, error) {
+	r := &Registry{files: make(map[string]file)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.parser == nil {
+		return nil, prompty.ErrNoParser
+	}
+	return r, nil
+}
+
+// Option configures a Registry (functional options pattern).
+type Option func(*Registry)
+
+// WithParser sets the manifest parser (required). Use manifest.NewJSONParser() or parser/yaml for YAML.
+func WithParser(u manifest.Unmarshaler) Option {
+	return func(r *Registry) { r.parser = u }
+}
+
+// WithEnvironment sets env for fallback: GetTemplate tries {id}.{env} first, then {id}.
+func WithEnvironment(env string) Option {
+	return WithEnvironmentChain(env)
+}
+
+// WithEnvironmentChain sets the env fallback chain, most specific first (see manifest.ParseEnvChain).
+func WithEnvironmentChain(envs ...string) Option {
+	return func(r *Registry) {
+		r.envs = slices.DeleteFunc(slices.Clone(envs), func(env string) bool { return env == "" })
+	}
+}
+
+// WithStrict builds every template with prompty.WithStrict, so rendering fails on unused payload fields
+// and "<no value>" output. Useful in CI to catch broken prompts.
+func WithStrict() Option {
+	return func(r *Registry) { r.strict = true }
+}
+
+// WithFuncs adds custom template functions to every template (see prompty.WithFuncs).
+// Loading fails with prompty.ErrFuncConflict if a name shadows a built-in.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Registry) { r.funcs = funcs }
+}
+
+// read returns the stored manifest at path.
+func (r *Registry) read(path string) (file, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.files[path]
+	return f, ok
+}
+
+// layerPath returns the path of the env layer of ref ("" for the base manifest).
+func layerPath(ref prompty.ManifestRef, env string) string {
+	ref.Env = env
+	return ref.Path()
+}
+
+func (r *Registry) parseOptions(ctx context.Context) []manifest.ParseOption {
+	opts := []manifest.ParseOption{manifest.WithBaseRegistry(ctx, r)}
+	if r.strict {
+		opts = append(opts, manifest.WithStrict())
+	}
+	if r.funcs != nil {
+		opts = append(opts, manifest.WithFuncs(r.funcs))
+	}
+	return opts
+}
+
+// GetTemplate builds the template for id over the env chain.
+// "id@constraint" (e.g. "agent@1.2.0", "agent@latest", "agent@^1.2") resolves against Versions.
+func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	ref, err := r.resolve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	read := func(env string) ([]byte, bool, error) {
+		f, ok := r.read(layerPath(ref, env))
+		return f.data, ok, nil
+	}
+	tpl, err := manifest.ParseEnvChain(read, r.envs, r.parser, r.parseOptions(ctx)...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tpl.Metadata.Version == "" {
+		tpl.Metadata.Version = ref.Version
+	}
+	return tpl, nil
+}
+
+// resolve validates id and returns the ref to read: the released version for "id@constraint" when it is stored
+// in the versioned layout, else the working manifest.
+func (r *Registry) resolve(ctx context.Context, id string) (prompty.ManifestRef, error) {
+	if err := prompty.ValidateVersionedID(id); err != nil {
+		return prompty.ManifestRef{}, err
+	}
+	if ctx.Err() != nil {
+		return prompty.ManifestRef{}, ctx.Err()
+	}
+	base, constraint, ok := prompty.SplitVersionedID(id)
+	if !ok {
+		return prompty.ManifestRef{ID: id}, nil
+	}
+	versions, layout, err := r.versionIndex(ctx, base)
+	if err != nil {
+		return prompty.ManifestRef{}, err
+	}
+	version, err := prompty.ResolveVersion(versions, constraint)
+	if err != nil {
+		return prompty.ManifestRef{}, fmt.Errorf("template %q: %w", base, err)
+	}
+	if layout[version] {
+		return prompty.ManifestRef{ID: base, Version: version}, nil
+	}
+	return prompty.ManifestRef{ID: base}, nil
+}
+
+// Versions implements prompty.Versioner: versions stored in the versioned layout (base or a registry env) plus the
+// semantic `version` declared by the working manifest, ascending.
+func (r *Registry) Versions(ctx context.Context, id string) ([]string, error) {
+	if err := prompty.ValidateID(id); err != nil {
+		return nil, err
+	}
+	versions, _, err := r.versionIndex(ctx, id)
+	return versions, err
+}
+
+// versionIndex returns all versions of id and the set stored in the versioned layout.
+func (r *Registry) versionIndex(ctx context.Context, id string) ([]string, map[string]bool, error) {
+	layout := make(map[string]bool)
+	r.mu.RLock()
+	for path := range r.files {
+		vid, version, env, ok := prompty.ParseVersionedPath(path)
+		if ok && vid == id && (env == "" || slices.Contains(r.envs, env)) {
+			layout[version] = true
+		}
+	}
+	r.mu.RUnlock()
+	versions := slices.Collect(maps.Keys(layout))
+	tpl, err := r.GetTemplate(ctx, id)
+	switch {
+	case err == nil:
+		if v, parseErr := prompty.ParseVersion(tpl.Metadata.Version); parseErr == nil && !layout[v.String()] {
+			versions = append(versions, v.String())
+		}
+	case !errors.Is(err, prompty.ErrTemplateNotFound):
+		return nil, nil, err
+	}
+	return prompty.SortVersions(versions), layout, nil
+}
+
+// List returns the ids of all stored manifests (env suffix and versioned layout stripped), sorted.
+func (r *Registry) List(ctx context.Context) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ids []string
+	for path := range r.files {
+		id := baseID(path)
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// baseID returns the template id of a stored path: "agent.prod" -> "agent", "agent/v1.2.0.prod" -> "agent".
+func baseID(path string) string {
+	if id, _, _, ok := prompty.ParseVersionedPath(path); ok {
+		return id
+	}
+	dir, name := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		dir, name = path[:i+1], path[i+1:]
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return dir + name
+}
+
+// Stat builds the template to report its version (declared, or resolved for "id@constraint"); UpdatedAt is the
+// latest Put among the env layers read.
+func (r *Registry) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	tpl, err := r.GetTemplate(ctx, id)
+	if err != nil {
+		return prompty.TemplateInfo{}, err
+	}
+	ref, err := r.resolve(ctx, id)
+	if err != nil {
+		return prompty.TemplateInfo{}, err
+	}
+	info := prompty.TemplateInfo{ID: id, Version: tpl.Metadata.Version, UpdatedAt: time.Time{}}
+	for _, env := range append(slices.Clone(r.envs), "") {
+		if f, ok := r.read(layerPath(ref, env)); ok && f.updatedAt.After(info.UpdatedAt) {
+			info.UpdatedAt = f.updatedAt
+		}
+	}
+	return info, nil
+}
+
+// Put validates data (manifest.ValidateLayer) and stores it as the manifest for ref.
+func (r *Registry) Put(ctx context.Context, ref prompty.ManifestRef, data []byte) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	below := func(env string) ([]byte, bool, error) {
+		f, ok := r.read(layerPath(ref, env))
+		return f.data, ok, nil
+	}
+	if _, err := manifest.ValidateLayer(ref, data, r.envs, below, r.parser, r.parseOptions(ctx)...); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[ref.Path()] = file{data: slices.Clone(data), updatedAt: time.Now()}
+	return nil
+}
+
+// Delete removes the manifest for ref.
+func (r *Registry) Delete(ctx context.Context, ref prompty.ManifestRef) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.files[ref.Path()]; !ok {
+		return fmt.Errorf("%w: %s", prompty.ErrTemplateNotFound, ref)
+	}
+	delete(r.files, ref.Path())
+	return nil
+}
+
+// Promote copies the manifest for ref to env (see prompty.Publisher), validated like Put.
+func (r *Registry) Promote(ctx context.Context, ref prompty.ManifestRef, env string) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	f, ok := r.read(ref.Path())
+	if !ok {
+		return fmt.Errorf("%w: %s", prompty.ErrTemplateNotFound, ref)
+	}
+	to := ref
+	to.Env = env
+	return r.Put(ctx, to, f.data)
+}
@@ -0,0 +1,121 @@
+package memregistry
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/parser/yaml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func yamlManifest(id, version, text string) []byte {
+	return []byte("id: " + id + "\nversion: \"" + version + "\"\nmessages:\n  - role: system\n    content: " + text + "\n")
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	_, err := New()
+	require.ErrorIs(t, err, prompty.ErrNoParser)
+}
+
+func TestRegistry_PutGetTemplate(t *testing.T) {
+	t.Parallel()
+	reg, err := New(WithParser(yaml.New()), WithEnvironmentChain("prod-eu", "prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = reg.GetTemplate(ctx, "team/agent")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "team/agent"}, yamlManifest("team/agent", "1.1.0", "Working")))
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "team/agent", Version: "1.0.0"},
+		yamlManifest("team/agent", "1.0.0", "Released")))
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "team/agent", Env: "prod"},
+		[]byte("overlay: true\nmodel_config:\n  model: m-prod\n")))
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "base"}, yamlManifest("base", "", "Parent")))
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "child"}, []byte("id: child\nextends: base\n")))
+
+	tpl, err := reg.GetTemplate(ctx, "team/agent")
+	require.NoError(t, err)
+	assert.Equal(t, "Working", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, "m-prod", tpl.ModelOptions.Model)
+	assert.Equal(t, "prod", tpl.Metadata.Environment)
+
+	tpl, err = reg.GetTemplate(ctx, "team/agent@~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "Released", tpl.Messages[0].Content[0].Text)
+	tpl, err = reg.GetTemplate(ctx, "child")
+	require.NoError(t, err)
+	assert.Equal(t, "Parent", tpl.Messages[0].Content[0].Text)
+
+	versions, err := reg.Versions(ctx, "team/agent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "child", "team/agent"}, ids)
+	info, err := reg.Stat(ctx, "team/agent@latest")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", info.Version)
+	assert.False(t, info.UpdatedAt.IsZero())
+}
+
+func TestRegistry_PromoteDelete(t *testing.T) {
+	t.Parallel()
+	reg, err := New(WithParser(yaml.New()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, yamlManifest("agent", "1.0.0", "Base")))
+	staging := prompty.ManifestRef{ID: "agent", Env: "staging"}
+	require.NoError(t, reg.Put(ctx, staging, yamlManifest("agent", "1.1.0", "Candidate")))
+
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "Base", tpl.Messages[0].Content[0].Text, "staging is not in the prod chain")
+
+	require.NoError(t, reg.Promote(ctx, staging, "prod"))
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "Candidate", tpl.Messages[0].Content[0].Text)
+
+	require.NoError(t, reg.Delete(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}))
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "Base", tpl.Messages[0].Content[0].Text)
+	require.ErrorIs(t, reg.Delete(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}), prompty.ErrTemplateNotFound)
+	require.ErrorIs(t, reg.Promote(ctx, prompty.ManifestRef{ID: "agent", Env: "qa"}, "prod"), prompty.ErrTemplateNotFound)
+
+	err = reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, []byte("id: agent\nmessages: [\n"))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	err = reg.Put(ctx, prompty.ManifestRef{ID: "orphan", Env: "prod"}, []byte("overlay: true\n"))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "Base", tpl.Messages[0].Content[0].Text)
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	t.Parallel()
+	reg, err := New(WithParser(yaml.New()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, yamlManifest("agent", "1.0.0", "v")))
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			assert.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, yamlManifest("agent", "1.0.0", "v")))
+			_, err := reg.GetTemplate(ctx, "agent")
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+}
@@ -1,6 +1,10 @@
 package prompty
 
-import "io/fs"
+import (
+	"io/fs"
+	"maps"
+	"text/template"
+)
 
 // ChatTemplateOption configures ChatPromptTemplate (functional options pattern).
 type ChatTemplateOption func(*ChatPromptTemplate)
@@ -12,6 +16,23 @@ func WithPartialVariables(vars map[string]any) ChatTemplateOption {
 	}
 }
 
+// WithStrict enables strict rendering: Format and FormatStruct return a [StrictError] when the payload
+// has fields no template references or the rendered text contains "<no value>" (a nil value was printed).
+// Missing map keys already fail rendering (missingkey=error). Meant for CI and tests; see also UnusedVariables.
+func WithStrict() ChatTemplateOption {
+	return func(c *ChatPromptTemplate) {
+		c.strict = true
+	}
+}
+
+// WithFuncs adds custom template functions next to the built-ins (join, toJSON, xml_tag, ...).
+// NewChatPromptTemplate returns ErrFuncConflict if a name shadows a built-in, text/template ones (len, eq) included.
+func WithFuncs(funcs template.FuncMap) ChatTemplateOption {
+	return func(c *ChatPromptTemplate) {
+		c.funcs = maps.Clone(funcs)
+	}
+}
+
 // WithTools sets tool definitions available in templates as .Tools.
 func WithTools(tools []ToolDefinition) ChatTemplateOption {
 	return func(c *ChatPromptTemplate) {
@@ -19,6 +19,7 @@ type rawContentPart struct {
 	MediaType    string                `yaml:"media_type,omitempty"`
 	MIMEType     string                `yaml:"mime_type,omitempty"`
 	URL          string                `yaml:"url,omitempty"`
+	When         string                `yaml:"when,omitempty"`
 	CacheControl *prompty.CacheControl `yaml:"cache_control,omitempty"`
 }
 
@@ -30,6 +31,7 @@ var rawContentPartAllowedFields = map[string]struct{}{
 	"media_type":    {},
 	"mime_type":     {},
 	"url":           {},
+	"when":          {},
 	"cache_control": {},
 }
 
@@ -71,17 +73,24 @@ func validateRawContentPartFields(node *yaml.Node) error {
 }
 
 type rawMessage struct {
+	Index        *int                  `yaml:"index,omitempty"`
 	Role         string                `yaml:"role"`
 	Content      rawContentSlice       `yaml:"content"`
 	Optional     bool                  `yaml:"optional"`
+	When         string                `yaml:"when,omitempty"`
+	ForEach      string                `yaml:"for_each,omitempty"`
+	Turns        []rawMessage          `yaml:"turns,omitempty"`
 	CacheControl *prompty.CacheControl `yaml:"cache_control,omitempty"`
 	Metadata     map[string]any        `yaml:"metadata,omitempty"`
 }
 
 type fileManifest struct {
+	Overlay         bool                     `yaml:"overlay"`
 	ID              string                   `yaml:"id"`
 	Version         string                   `yaml:"version"`
 	Description     string                   `yaml:"description"`
+	Extends         string                   `yaml:"extends"`
+	MessagesMerge   string                   `yaml:"messages_merge"`
 	ModelOptionsRaw map[string]any           `yaml:"model_config"`
 	Metadata        map[string]any           `yaml:"metadata"`
 	InputSchema     map[string]any           `yaml:"input_schema"`
@@ -174,16 +183,16 @@ func (p *Parser) Unmarshal(in []byte, out any) error {
 	for i := range fm.Tools {
 		fm.Tools[i].Parameters = normalizeMap(fm.Tools[i].Parameters)
 	}
-	for i := range fm.Messages {
-		fm.Messages[i].Metadata = normalizeMap(fm.Messages[i].Metadata)
-	}
 	raw, ok := out.(*manifest.RawManifest)
 	if !ok {
 		return fmt.Errorf("%w: out must be *manifest.RawManifest", prompty.ErrInvalidManifest)
 	}
+	raw.Overlay = fm.Overlay
 	raw.ID = fm.ID
 	raw.Version = fm.Version
 	raw.Description = fm.Description
+	raw.Extends = fm.Extends
+	raw.MessagesMerge = fm.MessagesMerge
 	modelOptions, err := manifest.DecodeModelOptions(fm.ModelOptionsRaw)
 	if err != nil {
 		return fmt.Errorf("%w: model_config: %w", prompty.ErrInvalidManifest, err)
@@ -194,29 +203,43 @@ func (p *Parser) Unmarshal(in []byte, out any) error {
 	raw.InputSchema = rawToSchemaDefinition(fm.InputSchema)
 	raw.ResponseFormat = rawToSchemaDefinition(fm.ResponseFormat)
 	raw.Tools = fm.Tools
-	raw.Messages = make([]manifest.RawMessage, len(fm.Messages))
-	for i := range fm.Messages {
-		m := &fm.Messages[i]
-		raw.Messages[i] = manifest.RawMessage{
+	raw.Messages = toRawMessages(fm.Messages)
+	return nil
+}
+
+// toRawMessages converts YAML messages (including for_each turns) to manifest.RawMessage.
+func toRawMessages(msgs []rawMessage) []manifest.RawMessage {
+	if msgs == nil {
+		return nil
+	}
+	out := make([]manifest.RawMessage, len(msgs))
+	for i := range msgs {
+		m := &msgs[i]
+		out[i] = manifest.RawMessage{
+			Index:        m.Index,
 			Role:         m.Role,
 			Optional:     m.Optional,
+			When:         m.When,
+			ForEach:      m.ForEach,
+			Turns:        toRawMessages(m.Turns),
 			CacheControl: copyCacheControl(m.CacheControl),
-			Metadata:     m.Metadata,
+			Metadata:     normalizeMap(m.Metadata),
 		}
-		raw.Messages[i].Content = make([]manifest.RawContentPart, len(m.Content))
+		out[i].Content = make([]manifest.RawContentPart, len(m.Content))
 		for j := range m.Content {
 			c := &m.Content[j]
-			raw.Messages[i].Content[j] = manifest.RawContentPart{
+			out[i].Content[j] = manifest.RawContentPart{
 				Type:         c.Type,
 				Text:         c.Text,
 				MediaType:    c.MediaType,
 				MIMEType:     c.MIMEType,
 				URL:          c.URL,
+				When:         c.When,
 				CacheControl: copyCacheControl(c.CacheControl),
 			}
 		}
 	}
-	return nil
+	return out
 }
 
 func copyCacheControl(in *prompty.CacheControl) *prompty.CacheControl {
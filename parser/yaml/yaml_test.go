@@ -349,3 +349,79 @@ messages:
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "field foo not found")
 }
+
+func TestUnmarshal_WhenConditions(t *testing.T) {
+	t.Parallel()
+	yamlData := []byte(`
+id: yaml_when
+version: "1"
+messages:
+  - role: system
+    when: has_docs && tier == "pro"
+    content: "Docs: {{ .docs }}"
+  - role: user
+    content:
+      - type: text
+        text: "{{ .query }}"
+      - type: text
+        when: verbose
+        text: " Explain in detail."
+`)
+	tpl, err := manifest.Parse(yamlData, New())
+	require.NoError(t, err)
+	require.Len(t, tpl.Messages, 2)
+	assert.Equal(t, `has_docs && tier == "pro"`, tpl.Messages[0].When)
+	assert.Equal(t, "verbose", tpl.Messages[1].Content[1].When)
+
+	exec, err := tpl.Format(map[string]any{
+		"has_docs": false, "tier": "pro", "docs": "", "query": "Hi", "verbose": false,
+	})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 1)
+	require.Len(t, exec.Messages[0].Content, 1)
+	assert.Equal(t, "Hi", exec.Messages[0].Content[0].(prompty.TextPart).Text)
+}
+
+func TestUnmarshal_ForEachTurns(t *testing.T) {
+	t.Parallel()
+	yamlData := []byte(`
+id: yaml_few_shot
+version: "1"
+messages:
+  - role: system
+    content: "Classify sentiment."
+  - for_each: examples
+    turns:
+      - role: user
+        content: "{{ .item.text }}"
+      - role: assistant
+        content: "{{ .item.label }}"
+        metadata:
+          source: few_shot
+  - role: user
+    for_each: docs
+    content: "Doc {{ .index }}: {{ .item }}"
+  - role: user
+    content: "{{ .query }}"
+`)
+	tpl, err := manifest.Parse(yamlData, New())
+	require.NoError(t, err)
+	require.Len(t, tpl.Messages, 4)
+	assert.Equal(t, "examples", tpl.Messages[1].ForEach)
+	require.Len(t, tpl.Messages[1].Turns, 2)
+	assert.Equal(t, "few_shot", tpl.Messages[1].Turns[1].Metadata["source"])
+
+	exec, err := tpl.Format(map[string]any{
+		"examples": []any{
+			map[string]any{"text": "great!", "label": "positive"},
+			map[string]any{"text": "awful", "label": "negative"},
+		},
+		"docs":  []string{"d0"},
+		"query": "not bad",
+	})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 7)
+	assert.Equal(t, prompty.RoleAssistant, exec.Messages[4].Role)
+	assert.Equal(t, "negative", exec.Messages[4].Content[0].(prompty.TextPart).Text)
+	assert.Equal(t, "Doc 0: d0", exec.Messages[5].Content[0].(prompty.TextPart).Text)
+}
@@ -2,6 +2,7 @@ package prompty
 
 import (
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"text/template/parse"
@@ -163,6 +164,8 @@ func extractVarsFromTree(tree *parse.Tree) []string {
 	return out
 }
 
+// extractRequiredVarsFromParsed collects when-condition and template variables of non-optional messages
+// (loop item/index excluded, for_each list variables included).
 func extractRequiredVarsFromParsed(parsed []parsedMessage) []string {
 	seen := make(map[string]bool)
 	var out []string
@@ -170,17 +173,10 @@ func extractRequiredVarsFromParsed(parsed []parsedMessage) []string {
 		if pm.optional {
 			continue
 		}
-		for _, part := range pm.parts {
-			for _, tmpl := range part.templates() {
-				if tmpl == nil {
-					continue
-				}
-				for _, name := range extractVarsFromTree(tmpl.Tree) {
-					if !seen[name] {
-						seen[name] = true
-						out = append(out, name)
-					}
-				}
+		for _, name := range slices.Concat(pm.whenVars, pm.vars) {
+			if !seen[name] {
+				seen[name] = true
+				out = append(out, name)
 			}
 		}
 	}
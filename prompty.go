@@ -347,6 +347,7 @@ type TemplatePart struct {
 	MediaType    string        // Go text/template for type "media" (for example: image, audio, video, document)
 	MIMEType     string        // Optional Go text/template for type "media" (for example: image/png)
 	URL          string        // Optional Go text/template for type "media"
+	When         string        // Optional condition (e.g. `has_docs && tier == "pro"`); part is skipped when false
 	CacheControl *CacheControl `json:"cache_control,omitempty" yaml:"cache_control,omitempty"`
 }
 
@@ -360,9 +361,14 @@ func TextContent(text string) []TemplatePart {
 // Optional: true skips the message if all referenced variables are zero-value.
 // CacheControl applies message-level cache hint; parts may override with their own cache_control.
 type MessageTemplate struct {
-	Role         Role           // RoleSystem, RoleUser, RoleAssistant (and others; see Role* constants)
-	Content      []TemplatePart // Parts to render (text and/or media); each part is a Go text/template
-	Optional     bool           // true → skip if all referenced variables are zero-value
+	Role     Role           // RoleSystem, RoleUser, RoleAssistant (and others; see Role* constants)
+	Content  []TemplatePart // Parts to render (text and/or media); each part is a Go text/template
+	Optional bool           // true → skip if all referenced variables are zero-value
+	When     string         // Optional condition evaluated against merged vars; message is skipped when false
+	// ForEach names a list variable: the message is rendered once per element with .item and .index in scope.
+	// With Turns, each element emits one message per turn (e.g. user/assistant few-shot pairs) instead.
+	ForEach      string
+	Turns        []MessageTemplate
 	CacheControl *CacheControl  `json:"cache_control,omitempty" yaml:"cache_control,omitempty"`
 	Metadata     map[string]any `json:"metadata,omitempty"      yaml:"metadata,omitempty"`
 }
@@ -0,0 +1,72 @@
+package prompty
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Publisher is optional. When implemented by a registry, manifests can be written through it (e.g. by prompt-ops
+// tooling). Implementations validate manifests before accepting them (see manifest.ValidateLayer) and return an
+// error matching ErrInvalidManifest for ones that do not build; nothing is written then.
+type Publisher interface {
+	// Put stores data as the manifest for ref, replacing an existing one.
+	Put(ctx context.Context, ref ManifestRef, data []byte) error
+	// Delete removes the manifest for ref. Returns ErrTemplateNotFound when there is none.
+	Delete(ctx context.Context, ref ManifestRef) error
+	// Promote copies the manifest for ref as stored (an overlay stays an overlay) to environment env,
+	// e.g. staging to prod; env "" promotes to the base manifest.
+	Promote(ctx context.Context, ref ManifestRef, env string) error
+}
+
+// ManifestRef addresses one stored manifest: the working manifest of ID (Version "") or a released Version in the
+// versioned layout, for Env ("" for the base manifest).
+type ManifestRef struct {
+	ID      string
+	Version string
+	Env     string
+}
+
+// Validate checks ID, Version (a full semantic version when set) and Env (no '.', '/', '@' or ':').
+func (r ManifestRef) Validate() error {
+	if err := ValidateID(r.ID); err != nil {
+		return err
+	}
+	if r.Version != "" {
+		if _, err := ParseVersion(r.Version); err != nil {
+			return err
+		}
+	}
+	if strings.ContainsAny(r.Env, "./@:\\") {
+		return fmt.Errorf("%w: env %q must not contain '.', '/', '@' or ':'", ErrInvalidName, r.Env)
+	}
+	return nil
+}
+
+// Path returns the manifest path (slash, no extension) shared by the registries: "agent", "agent.prod",
+// "agent/v1.2.0" or "agent/v1.2.0.prod". Call Validate first.
+func (r ManifestRef) Path() string {
+	p := r.ID
+	if r.Version != "" {
+		v, err := ParseVersion(r.Version)
+		if err == nil {
+			p = VersionedPath(r.ID, v.String())
+		}
+	}
+	if r.Env != "" {
+		p += "." + r.Env
+	}
+	return p
+}
+
+// String returns "id" or "id@version", followed by " (env)" when Env is set (for messages).
+func (r ManifestRef) String() string {
+	s := r.ID
+	if r.Version != "" {
+		s += "@" + r.Version
+	}
+	if r.Env != "" {
+		s += " (" + r.Env + ")"
+	}
+	return s
+}
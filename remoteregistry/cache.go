@@ -2,8 +2,10 @@ package remoteregistry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/skosovsky/prompty"
@@ -18,45 +20,124 @@ type inflightFetch struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	waiters int
-	done    chan struct{}
-	tpl     *prompty.ChatPromptTemplate
-	err     error
+	// detached fetches (stale-while-revalidate refreshes) run to completion; waiters leaving do not cancel them.
+	detached bool
+	done     chan struct{}
+	tpl      *prompty.ChatPromptTemplate
+	err      error
+}
+
+// CacheStats is a snapshot of CachedRegistry counters (see CachedRegistry.Stats).
+type CacheStats struct {
+	Hits          uint64 // served from a fresh entry
+	StaleHits     uint64 // served from an expired entry while it is revalidated (WithStaleWhileRevalidate)
+	StaleIfError  uint64 // served from an expired entry because fetching failed (WithStaleIfError)
+	Misses        uint64 // had to wait for the base registry
+	Refreshes     uint64 // successful fetches stored in the cache (misses, revalidations, background refresh)
+	RefreshErrors uint64 // failed fetches
+}
+
+// cacheCounters holds CacheStats counters updated without the cache lock.
+type cacheCounters struct {
+	hits, staleHits, staleIfError, misses, refreshes, refreshErrors atomic.Uint64
 }
 
 // CachedRegistry decorates any prompty.Registry with TTL cache and request deduplication.
+// Optional stale-while-revalidate, stale-if-error and background refresh are enabled via CacheOption.
 type CachedRegistry struct {
 	base     prompty.Registry
 	ttl      time.Duration
 	mu       sync.RWMutex
 	cache    map[string]*cacheEntry
 	inflight map[string]*inflightFetch
+
+	staleWhileRevalidate time.Duration // serve expired entries this long past expiry while refreshing
+	staleIfError         time.Duration // serve expired entries this long past expiry when fetching fails
+	refreshInterval      time.Duration // background refresh of List ids; 0 disables
+	stats                cacheCounters
+
+	closeOnce sync.Once
+	stop      context.CancelFunc // stops the background refresh goroutine
+	stopped   chan struct{}      // closed when the background refresh goroutine exits
 }
 
 var (
-	_ prompty.Registry = (*CachedRegistry)(nil)
-	_ prompty.Lister   = (*CachedRegistry)(nil)
-	_ prompty.Statter  = (*CachedRegistry)(nil)
+	_ prompty.Registry  = (*CachedRegistry)(nil)
+	_ prompty.Lister    = (*CachedRegistry)(nil)
+	_ prompty.Statter   = (*CachedRegistry)(nil)
+	_ prompty.Versioner = (*CachedRegistry)(nil)
 )
 
+// CacheOption configures a CachedRegistry (functional options pattern).
+type CacheOption func(*CachedRegistry)
+
+// WithStaleWhileRevalidate serves an expired entry for up to window past its TTL and refreshes it
+// asynchronously (deduplicated), so callers do not block on the network.
+func WithStaleWhileRevalidate(window time.Duration) CacheOption {
+	return func(r *CachedRegistry) { r.staleWhileRevalidate = window }
+}
+
+// WithStaleIfError serves an expired entry for up to maxStale past its TTL when fetching it fails
+// (e.g. the remote is down). Not-found errors are returned as is.
+func WithStaleIfError(maxStale time.Duration) CacheOption {
+	return func(r *CachedRegistry) { r.staleIfError = maxStale }
+}
+
+// WithBackgroundRefresh refreshes every id returned by the base registry's List each interval
+// (base must implement prompty.Lister). The goroutine stops on Close.
+func WithBackgroundRefresh(interval time.Duration) CacheOption {
+	return func(r *CachedRegistry) { r.refreshInterval = interval }
+}
+
 // WithCache wraps base registry with cache + in-flight request dedupe.
 // TTL <= 0 means entries never expire.
-func WithCache(base prompty.Registry, ttl time.Duration) *CachedRegistry {
+// With WithBackgroundRefresh, call Close to stop the refresh goroutine.
+func WithCache(base prompty.Registry, ttl time.Duration, opts ...CacheOption) *CachedRegistry {
 	if base == nil {
 		panic("remoteregistry: base registry must not be nil")
 	}
-	return &CachedRegistry{
-		base:     base,
-		ttl:      ttl,
-		mu:       sync.RWMutex{},
-		cache:    make(map[string]*cacheEntry),
-		inflight: make(map[string]*inflightFetch),
+	r := &CachedRegistry{
+		base:                 base,
+		ttl:                  ttl,
+		mu:                   sync.RWMutex{},
+		cache:                make(map[string]*cacheEntry),
+		inflight:             make(map[string]*inflightFetch),
+		staleWhileRevalidate: 0,
+		staleIfError:         0,
+		refreshInterval:      0,
+		stats:                cacheCounters{},
+		closeOnce:            sync.Once{},
+		stop:                 nil,
+		stopped:              nil,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	if r.refreshInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.stop = cancel
+		r.stopped = make(chan struct{})
+		go r.refreshLoop(ctx)
+	}
+	return r
 }
 
 func (r *CachedRegistry) cacheEntryValid(ent *cacheEntry, now time.Time) bool {
 	return r.ttl <= 0 || now.Before(ent.expiresAt)
 }
 
+// withinStale reports whether ent expired no longer than window ago.
+func withinStale(ent *cacheEntry, now time.Time, window time.Duration) bool {
+	return window > 0 && !now.After(ent.expiresAt.Add(window))
+}
+
+func (r *CachedRegistry) expiry(now time.Time) time.Time {
+	if r.ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(r.ttl)
+}
+
 func (r *CachedRegistry) GetTemplate(
 	ctx context.Context,
 	id string,
@@ -71,6 +152,7 @@ func (r *CachedRegistry) GetTemplate(
 	if ok && r.cacheEntryValid(ent, now) {
 		tpl := prompty.CloneTemplate(ent.tpl)
 		r.mu.RUnlock()
+		r.stats.hits.Add(1)
 		return tpl, nil
 	}
 	r.mu.RUnlock()
@@ -81,30 +163,73 @@ func (r *CachedRegistry) GetTemplate(
 	if ok && r.cacheEntryValid(ent, now) {
 		tpl := prompty.CloneTemplate(ent.tpl)
 		r.mu.Unlock()
+		r.stats.hits.Add(1)
 		return tpl, nil
 	}
 
-	if inFlight, exists := r.inflight[id]; exists {
-		inFlight.waiters++
+	if ok && withinStale(ent, now, r.staleWhileRevalidate) {
+		tpl := prompty.CloneTemplate(ent.tpl)
+		if _, exists := r.inflight[id]; !exists {
+			// No waiters: the refresh is detached, so blocking callers that join and cancel do not abort it.
+			r.startInflightLocked(id, 0, true)
+		}
 		r.mu.Unlock()
-		return r.waitForInflight(ctx, id, inFlight)
+		r.stats.staleHits.Add(1)
+		return tpl, nil
 	}
 
+	r.stats.misses.Add(1)
+	inFlight, exists := r.inflight[id]
+	if exists {
+		inFlight.waiters++
+	} else {
+		inFlight = r.startInflightLocked(id, 1, false)
+	}
+	r.mu.Unlock()
+
+	tpl, err := r.waitForInflight(ctx, id, inFlight)
+	if err != nil && ctx.Err() == nil {
+		if stale := r.staleOnError(id, err); stale != nil {
+			return stale, nil
+		}
+	}
+	return tpl, err
+}
+
+// startInflightLocked registers and starts a shared fetch for id; r.mu must be held for writing.
+// A detached fetch is not canceled when its waiters leave.
+func (r *CachedRegistry) startInflightLocked(id string, waiters int, detached bool) *inflightFetch {
 	//nolint:gosec // cancel is stored in inflight and called by waiter lifecycle / fetch completion.
 	sharedCtx, cancel := context.WithCancel(context.Background())
 	inFlight := &inflightFetch{
-		ctx:     sharedCtx,
-		cancel:  cancel,
-		waiters: 1,
-		done:    make(chan struct{}),
-		tpl:     nil,
-		err:     nil,
+		ctx:      sharedCtx,
+		cancel:   cancel,
+		waiters:  waiters,
+		detached: detached,
+		done:     make(chan struct{}),
+		tpl:      nil,
+		err:      nil,
 	}
 	r.inflight[id] = inFlight
-	r.mu.Unlock()
-
 	go r.runInflightFetch(id, inFlight)
-	return r.waitForInflight(ctx, id, inFlight)
+	return inFlight
+}
+
+// staleOnError returns a clone of the expired entry for id if stale-if-error allows serving it after err.
+// Verification failures are never masked by a stale entry.
+func (r *CachedRegistry) staleOnError(id string, err error) *prompty.ChatPromptTemplate {
+	if r.staleIfError <= 0 || errors.Is(err, ErrNotFound) || errors.Is(err, prompty.ErrTemplateNotFound) ||
+		errors.Is(err, ErrVerificationFailed) {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ent, ok := r.cache[id]
+	if !ok || !withinStale(ent, time.Now(), r.staleIfError) {
+		return nil
+	}
+	r.stats.staleIfError.Add(1)
+	return prompty.CloneTemplate(ent.tpl)
 }
 
 func (r *CachedRegistry) runInflightFetch(id string, inFlight *inflightFetch) {
@@ -119,17 +244,17 @@ func (r *CachedRegistry) runInflightFetch(id string, inFlight *inflightFetch) {
 		}
 	}
 	inFlight.err = err
+	if err != nil {
+		r.stats.refreshErrors.Add(1)
+	}
 
 	r.mu.Lock()
 	if current, exists := r.inflight[id]; exists && current == inFlight {
 		delete(r.inflight, id)
 		if inFlight.err == nil {
 			stored := prompty.CloneTemplate(inFlight.tpl)
-			expiresAt := time.Now().Add(r.ttl)
-			if r.ttl <= 0 {
-				expiresAt = time.Time{}
-			}
-			r.cache[id] = &cacheEntry{tpl: stored, expiresAt: expiresAt}
+			r.cache[id] = &cacheEntry{tpl: stored, expiresAt: r.expiry(time.Now())}
+			r.stats.refreshes.Add(1)
 		}
 	}
 	r.mu.Unlock()
@@ -169,12 +294,73 @@ func (r *CachedRegistry) releaseInflightWaiter(id string, inFlight *inflightFetc
 	}
 
 	current.waiters--
-	if current.waiters <= 0 {
+	if current.waiters <= 0 && !current.detached {
 		delete(r.inflight, id)
 		current.cancel()
 	}
 }
 
+// Refresh fetches every id returned by the base registry's List and stores fresh entries.
+// Failed ids keep their cached entry; their errors are joined into the returned error.
+// Used by WithBackgroundRefresh; can also be called directly (e.g. on a deploy webhook).
+func (r *CachedRegistry) Refresh(ctx context.Context) error {
+	lister, ok := r.base.(prompty.Lister)
+	if !ok {
+		return errors.New("remoteregistry: refresh requires a base registry implementing prompty.Lister")
+	}
+	ids, err := lister.List(ctx)
+	if err != nil {
+		return fmt.Errorf("remoteregistry: refresh list: %w", err)
+	}
+	var errs []error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tpl, err := r.base.GetTemplate(ctx, id)
+		if err == nil && tpl == nil {
+			err = fmt.Errorf("remoteregistry: unexpected nil template for id %q", id)
+		}
+		if err != nil {
+			r.stats.refreshErrors.Add(1)
+			errs = append(errs, fmt.Errorf("%q: %w", id, err))
+			continue
+		}
+		r.mu.Lock()
+		r.cache[id] = &cacheEntry{tpl: prompty.CloneTemplate(tpl), expiresAt: r.expiry(time.Now())}
+		r.mu.Unlock()
+		r.stats.refreshes.Add(1)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *CachedRegistry) refreshLoop(ctx context.Context) {
+	defer close(r.stopped)
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Errors are reflected in Stats; failed ids keep serving their cached entry.
+			_ = r.Refresh(ctx)
+		}
+	}
+}
+
+// Stats returns a snapshot of hit/miss/refresh counters.
+func (r *CachedRegistry) Stats() CacheStats {
+	return CacheStats{
+		Hits:          r.stats.hits.Load(),
+		StaleHits:     r.stats.staleHits.Load(),
+		StaleIfError:  r.stats.staleIfError.Load(),
+		Misses:        r.stats.misses.Load(),
+		Refreshes:     r.stats.refreshes.Load(),
+		RefreshErrors: r.stats.refreshErrors.Load(),
+	}
+}
+
 // List proxies to base when supported.
 func (r *CachedRegistry) List(ctx context.Context) ([]string, error) {
 	if ctx.Err() != nil {
@@ -194,9 +380,18 @@ func (r *CachedRegistry) Stat(ctx context.Context, id string) (prompty.TemplateI
 	return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
 }
 
+// Versions proxies to base when supported. "id@constraint" lookups are cached per constraint string,
+// so "id@latest" moves to a new version only after the entry expires.
+func (r *CachedRegistry) Versions(ctx context.Context, id string) ([]string, error) {
+	if versioner, ok := r.base.(prompty.Versioner); ok {
+		return versioner.Versions(ctx, id)
+	}
+	return nil, nil
+}
+
 // Evict removes one entry from cache.
 func (r *CachedRegistry) Evict(id string) {
-	if err := ValidateID(id); err != nil {
+	if err := validateRevisionID(id); err != nil {
 		return
 	}
 	r.mu.Lock()
@@ -211,8 +406,14 @@ func (r *CachedRegistry) EvictAll() {
 	r.mu.Unlock()
 }
 
-// Close proxies to base when it supports Close.
+// Close stops background refresh (if enabled) and proxies to base when it supports Close.
 func (r *CachedRegistry) Close() error {
+	r.closeOnce.Do(func() {
+		if r.stop != nil {
+			r.stop()
+			<-r.stopped
+		}
+	})
 	if c, ok := r.base.(interface{ Close() error }); ok {
 		return c.Close()
 	}
@@ -1,4 +1,8 @@
 // Package remoteregistry provides remote template loading via Fetcher (HTTP or Git).
 // New creates a stateless Registry. Use WithCache to add explicit TTL cache and
-// in-flight request dedupe when needed.
+// in-flight request dedupe when needed; CacheOption adds stale-while-revalidate,
+// stale-if-error and background refresh.
+// WithVerifier checks manifests before parsing: NewSignatureVerifier (detached ed25519 signature per manifest)
+// or NewIndexVerifier (signed checksum index), with trusted keys in a Keyring. Sign and ChecksumIndex produce
+// the files (`prompty-gen sign`); fetchers expose them via FileFetcher.
 package remoteregistry
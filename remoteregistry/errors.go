@@ -1,14 +1,52 @@
 package remoteregistry
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skosovsky/prompty"
+)
 
 // Sentinel errors for remote registry operations.
 // Callers should use [errors.Is] to check.
 var (
 	// ErrFetchFailed indicates the Fetcher could not retrieve the manifest.
 	ErrFetchFailed = errors.New("remoteregistry: fetch failed")
+	// ErrWriteFailed indicates a manifest could not be published (see Writer).
+	ErrWriteFailed = errors.New("remoteregistry: write failed")
 	// ErrHTTPStatus indicates an unexpected HTTP status (e.g. 500) when using HTTPFetcher.
 	ErrHTTPStatus = errors.New("remoteregistry: unexpected HTTP status")
 	// ErrNotFound indicates no manifest was found for the given name/env; registry wraps it in prompty.ErrTemplateNotFound.
 	ErrNotFound = errors.New("remoteregistry: no manifest found")
+	// ErrVerificationFailed indicates a fetched manifest failed integrity verification (see Verifier).
+	ErrVerificationFailed = errors.New("remoteregistry: manifest verification failed")
+	// ErrUnsigned indicates the signature (or the checksum index entry) for a manifest is missing.
+	ErrUnsigned = errors.New("remoteregistry: manifest is not signed")
+	// ErrUnknownKey indicates no signature was made by a key in the keyring.
+	ErrUnknownKey = errors.New("remoteregistry: no signature by a trusted key")
+	// ErrSignatureMismatch indicates a signature by a trusted key does not match the signed content.
+	ErrSignatureMismatch = errors.New("remoteregistry: signature mismatch")
+	// ErrChecksumMismatch indicates the manifest does not match its SHA-256 in the signed checksum index.
+	ErrChecksumMismatch = errors.New("remoteregistry: checksum mismatch")
+	// ErrIDMismatch indicates a verified manifest declares an id other than the one requested.
+	ErrIDMismatch = errors.New("remoteregistry: manifest id does not match the requested id")
 )
+
+// VerificationError is returned when a fetched manifest fails verification. It matches ErrVerificationFailed,
+// prompty.ErrInvalidManifest (so registries never fall back past a tampered manifest) and Err
+// (ErrUnsigned, ErrUnknownKey, ErrSignatureMismatch, ErrChecksumMismatch, ErrIDMismatch).
+// Use [errors.As](err, &verifyErr) to read the id.
+type VerificationError struct {
+	ID  string // fetched id, e.g. "support/agent.prod" or "support/agent@rev:v1.4.0"
+	Err error
+}
+
+// Error implements error.
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("remoteregistry: verify %q: %v", e.ID, e.Err)
+}
+
+// Unwrap returns ErrVerificationFailed, prompty.ErrInvalidManifest and the cause for [errors.Is] and [errors.As].
+func (e *VerificationError) Unwrap() []error {
+	return []error{ErrVerificationFailed, prompty.ErrInvalidManifest, e.Err}
+}
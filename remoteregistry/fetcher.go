@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"strings"
 
 	"github.com/skosovsky/prompty"
 )
@@ -27,6 +28,45 @@ type Statter interface {
 	Stat(ctx context.Context, id string) (prompty.TemplateInfo, error)
 }
 
+// InfoFetcher is optional. When implemented by Fetcher, Registry.GetTemplate takes the version of a manifest
+// without one from the fetch response (e.g. HTTP headers) instead of a separate Statter call.
+type InfoFetcher interface {
+	FetchInfo(ctx context.Context, id string) ([]byte, prompty.TemplateInfo, error)
+}
+
+// Writer is optional. When implemented by Fetcher, Registry implements prompty.Publisher through it (the git
+// Fetcher commits and pushes). Ids are fetch ids with env suffix and versioned layout (prompty.ManifestRef.Path).
+type Writer interface {
+	// WriteManifest stores data as the manifest for id, replacing an existing file whatever its extension.
+	WriteManifest(ctx context.Context, id string, data []byte) error
+	// DeleteManifest removes the manifest for id. Returns ErrNotFound when there is none.
+	DeleteManifest(ctx context.Context, id string) error
+}
+
+// CheckedWriter is optional for a Writer whose store can change under it (e.g. a git clone behind its remote).
+// WriteManifestChecked writes like WriteManifest, but first calls check with a Fetcher over the manifests as the
+// write will see them: synced with the remote and under the writer's lock, so no other write lands in between.
+// The Fetcher is valid only during check. An error from check aborts the write and is returned as is.
+type CheckedWriter interface {
+	Writer
+	WriteManifestChecked(ctx context.Context, id string, data []byte, check func(tree Fetcher) error) error
+}
+
+// RevisionPrefix marks the "@" suffix of an id as a fetcher revision rather than a version constraint:
+// "support_agent@rev:v1.4.0" is the manifest at git tag v1.4.0, "support_agent@1.4.0" is semantic version 1.4.0.
+const RevisionPrefix = "rev:"
+
+// SplitRevision splits "id@rev:rev" into id and rev (e.g. "support_agent@rev:v1.4.0"). Ids without a revision,
+// including "id@constraint", are returned unchanged with an empty rev. Revision-aware fetchers (e.g. the git Fetcher)
+// read the manifest at rev; others treat the suffix as part of the id.
+func SplitRevision(id string) (base, rev string) {
+	base, suffix, ok := prompty.SplitVersionedID(id)
+	if !ok || !strings.HasPrefix(suffix, RevisionPrefix) {
+		return id, ""
+	}
+	return base, strings.TrimPrefix(suffix, RevisionPrefix)
+}
+
 // ValidateID checks that id is a valid user-facing prompt id (slash-only, no extension).
 // Delegates to prompty.ValidateID. Use for user input validation.
 func ValidateID(id string) error {
@@ -83,3 +83,21 @@ func TestCandidatePaths(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitRevision(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		id, base, rev string
+	}{
+		{"agent", "agent", ""},
+		{"agent@rev:v1.4.0", "agent", "v1.4.0"},
+		{"agent.prod@rev:3f2a9c1", "agent.prod", "3f2a9c1"},
+		{"agent@v1.4.0", "agent@v1.4.0", ""}, // a version constraint, not a revision
+		{"agent@latest", "agent@latest", ""},
+	}
+	for _, tt := range tests {
+		base, rev := SplitRevision(tt.id)
+		assert.Equal(t, tt.base, base, tt.id)
+		assert.Equal(t, tt.rev, rev, tt.id)
+	}
+}
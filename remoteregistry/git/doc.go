@@ -1,4 +1,8 @@
 // Package git provides a Fetcher that reads YAML manifests from a Git repository.
 // It clones (or pulls) the repo on first use and reads files from the working tree.
 // Use NewFetcher with the repo URL; the returned Fetcher implements remoteregistry.Fetcher for use with remoteregistry.New.
+// WithRevision pins a commit SHA or tag, Fetch("id@rev:v1.4.0") reads a single manifest at a revision, Stat reports the
+// last commit SHA and author time, and WithPullInterval limits how often the tracked branch is pulled.
+// WriteManifest and DeleteManifest (remoteregistry.Writer) commit and push to the tracked branch;
+// WriteManifestChecked (remoteregistry.CheckedWriter) validates against the pulled tree before writing.
 package git
@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -15,28 +16,36 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/remoteregistry"
 )
 
-// Fetcher fetches YAML manifests from a Git repository (clone on first use, then pull).
+// Fetcher fetches YAML manifests from a Git repository (clone on first use, then pull or pin to a revision).
 // Implements remoteregistry.Fetcher. Call Close to remove the local clone.
 var _ remoteregistry.Fetcher = (*Fetcher)(nil)
 
 // Fetcher holds repo URL, clone options, and local path.
+// mu guards the clone: working-tree reads share the read lock; clone, pull and object reads take the write lock.
 type Fetcher struct {
-	repoURL   string
-	branch    string
-	dir       string
-	depth     int
-	authToken string
-	cloneDir  string // if set, clone/open here and do not remove on Close
-	localDir  string
-	mu        sync.Mutex
-	repo      *git.Repository
+	repoURL      string
+	branch       string
+	revision     string // WithRevision: pinned commit SHA or tag; the worktree is checked out detached and never pulled
+	dir          string
+	depth        int
+	authToken    string
+	cloneDir     string        // if set, clone/open here and do not remove on Close
+	pullInterval time.Duration // WithPullInterval: minimum time between pulls; 0 pulls on every access
+	lastPull     time.Time
+	authorName   string // WithCommitAuthor: author of published commits
+	authorEmail  string
+	localDir     string
+	mu           sync.RWMutex
+	repo         *git.Repository
 }
 
 // NewFetcher creates a Fetcher. Repo is cloned on first Fetch. Use Close to cleanup.
@@ -46,9 +55,11 @@ func NewFetcher(repoURL string, opts ...Option) (*Fetcher, error) {
 		return nil, fmt.Errorf("remoteregistry/git: repo URL must not be empty")
 	}
 	g := &Fetcher{
-		repoURL: repoURL,
-		branch:  "main",
-		depth:   1,
+		repoURL:     repoURL,
+		branch:      "main",
+		depth:       1,
+		authorName:  "prompty",
+		authorEmail: "prompty@localhost",
 	}
 	for _, opt := range opts {
 		opt(g)
@@ -60,16 +71,42 @@ func NewFetcher(repoURL string, opts ...Option) (*Fetcher, error) {
 }
 
 // Fetch reads the manifest from the repo: {dir}/{id}.yaml or {dir}/{id}.yml.
+// "id@rev:rev" reads the manifest as of rev (commit SHA, short SHA, tag or branch), e.g. "support_agent@rev:v1.4.0".
 func (g *Fetcher) Fetch(ctx context.Context, id string) ([]byte, error) {
+	return g.read(ctx, id, remoteregistry.CandidatePaths)
+}
+
+var _ remoteregistry.FileFetcher = (*Fetcher)(nil)
+
+// FetchFile implements remoteregistry.FileFetcher: reads {dir}/{name} (e.g. a signature file), "name@rev:rev" at rev.
+func (g *Fetcher) FetchFile(ctx context.Context, name string) ([]byte, error) {
+	return g.read(ctx, name, func(name string) []string { return []string{name} })
+}
+
+// read reads the first existing file of paths(id) under the manifest dir, from the working tree or,
+// for "id@rev:rev", from the commit tree at rev.
+func (g *Fetcher) read(ctx context.Context, id string, paths func(string) []string) ([]byte, error) {
 	if err := remoteregistry.ValidatePathForFetch(id); err != nil {
 		return nil, err
 	}
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	if err := g.ensureClone(ctx); err != nil {
+	base, rev := remoteregistry.SplitRevision(id)
+	if rev != "" {
+		return g.fetchAt(ctx, paths(base), rev)
+	}
+	if err := g.sync(ctx); err != nil {
 		return nil, fmt.Errorf("%w: %w", remoteregistry.ErrFetchFailed, err)
 	}
-	absPath, _, err := g.resolvePath(id)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.repo == nil {
+		return nil, fmt.Errorf("%w: fetcher closed", remoteregistry.ErrFetchFailed)
+	}
+	return g.readFile(paths(base))
+}
+
+// readFile reads the first existing file of rels from the working tree. Caller must hold g.mu.
+func (g *Fetcher) readFile(rels []string) ([]byte, error) {
+	absPath, _, err := g.resolvePath(rels)
 	if err != nil {
 		if errors.Is(err, remoteregistry.ErrNotFound) {
 			return nil, err
@@ -83,11 +120,79 @@ func (g *Fetcher) Fetch(ctx context.Context, id string) ([]byte, error) {
 	return data, nil
 }
 
-// resolvePath finds the physical file for id (tries .yaml then .yml). Returns absolute path and path from repo root (forward slashes).
+// fetchAt reads the first existing file of rels from the commit tree at rev (not the working tree).
+func (g *Fetcher) fetchAt(ctx context.Context, rels []string, rev string) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.ensureClone(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %w", remoteregistry.ErrFetchFailed, err)
+	}
+	return g.readAt(ctx, rels, rev)
+}
+
+// readAt reads the first existing file of rels from the commit tree at rev. Caller must hold g.mu (write).
+func (g *Fetcher) readAt(ctx context.Context, rels []string, rev string) ([]byte, error) {
+	commit, err := g.commitAt(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	file, _, err := g.fileAt(commit, rels)
+	if err != nil {
+		return nil, err
+	}
+	data, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s@%s: %w", remoteregistry.ErrFetchFailed, file.Name, rev, err)
+	}
+	return []byte(data), nil
+}
+
+// commitAt resolves rev to a commit, fetching all branches and tags once when it is not known locally
+// (e.g. a tag created after the clone, or history missing from a shallow clone). Caller must hold g.mu.
+func (g *Fetcher) commitAt(ctx context.Context, rev string) (*object.Commit, error) {
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		if fetchErr := g.fetchAll(ctx); fetchErr != nil {
+			slog.Default().Warn("git fetch failed, resolving revision from cached clone", "rev", rev, "err", fetchErr)
+		}
+		hash, err = g.repo.ResolveRevision(plumbing.Revision(rev))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: revision %q: %w", remoteregistry.ErrNotFound, rev, err)
+	}
+	commit, err := g.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: commit %s: %w", remoteregistry.ErrFetchFailed, hash, err)
+	}
+	return commit, nil
+}
+
+// fileAt finds the first of rels (paths under the manifest dir, e.g. CandidatePaths(id)) in commit's tree and
+// returns it with its path from repo root.
+func (g *Fetcher) fileAt(commit *object.Commit, rels []string) (*object.File, string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: tree %s: %w", remoteregistry.ErrFetchFailed, commit.Hash, err)
+	}
+	for _, rel := range rels {
+		p := path.Join(filepath.ToSlash(g.dir), rel)
+		file, err := tree.File(p)
+		if err == nil {
+			return file, p, nil
+		}
+		if !errors.Is(err, object.ErrFileNotFound) && !errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, "", fmt.Errorf("%w: %s: %w", remoteregistry.ErrFetchFailed, p, err)
+		}
+	}
+	return nil, "", fmt.Errorf("%w: %q at %s", remoteregistry.ErrNotFound, rels[0], commit.Hash)
+}
+
+// resolvePath finds the first existing physical file of rels (e.g. CandidatePaths(id)). Returns absolute path and
+// path from repo root (forward slashes).
 // Caller must hold g.mu and have called ensureClone. Uses os.Stat only (no ReadFile).
-func (g *Fetcher) resolvePath(id string) (absPath, relPathFromRepoRoot string, err error) {
+func (g *Fetcher) resolvePath(rels []string) (absPath, relPathFromRepoRoot string, err error) {
 	baseDir := filepath.Clean(filepath.Join(g.localDir, g.dir))
-	for _, rel := range remoteregistry.CandidatePaths(id) {
+	for _, rel := range rels {
 		path := filepath.Join(g.localDir, g.dir, rel)
 		cleanPath := filepath.Clean(path)
 		relPath, relErr := filepath.Rel(baseDir, cleanPath)
@@ -103,7 +208,7 @@ func (g *Fetcher) resolvePath(id string) (absPath, relPathFromRepoRoot string, e
 		relPathFromRepoRoot = filepath.ToSlash(filepath.Join(g.dir, relPath))
 		return cleanPath, relPathFromRepoRoot, nil
 	}
-	return "", "", fmt.Errorf("%w: %q", remoteregistry.ErrNotFound, id)
+	return "", "", fmt.Errorf("%w: %q", remoteregistry.ErrNotFound, rels[0])
 }
 
 var _ remoteregistry.Lister = (*Fetcher)(nil)
@@ -117,11 +222,19 @@ func trimManifestExt(s string) string {
 
 // ListIDs returns all template ids (relative path without .yaml/.yml/.json, forward slashes) under the manifest dir.
 func (g *Fetcher) ListIDs(ctx context.Context) ([]string, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	if err := g.ensureClone(ctx); err != nil {
+	if err := g.sync(ctx); err != nil {
 		return nil, err
 	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.repo == nil {
+		return nil, fmt.Errorf("%w: fetcher closed", remoteregistry.ErrFetchFailed)
+	}
+	return g.listIDs()
+}
+
+// listIDs lists the manifest ids of the working tree. Caller must hold g.mu.
+func (g *Fetcher) listIDs() ([]string, error) {
 	baseDir := filepath.Clean(filepath.Join(g.localDir, g.dir))
 	seen := make(map[string]bool)
 	var ids []string
@@ -161,9 +274,12 @@ func (g *Fetcher) ListIDs(ctx context.Context) ([]string, error) {
 
 var _ remoteregistry.Statter = (*Fetcher)(nil)
 
-// Stat returns template metadata without parsing the manifest body. Version is the commit hash; UpdatedAt is commit time.
+// Stat returns template metadata without parsing the manifest body: Version is the SHA of the last commit
+// that touched the manifest and UpdatedAt is that commit's author time. The history is walked from the
+// checked-out commit (branch head or WithRevision), or from rev for "id@rev:rev".
 func (g *Fetcher) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
-	if err := remoteregistry.ValidateID(id); err != nil {
+	base, rev := remoteregistry.SplitRevision(id)
+	if err := remoteregistry.ValidateID(base); err != nil {
 		return prompty.TemplateInfo{}, err
 	}
 	g.mu.Lock()
@@ -171,19 +287,22 @@ func (g *Fetcher) Stat(ctx context.Context, id string) (prompty.TemplateInfo, er
 	if err := g.ensureClone(ctx); err != nil {
 		return prompty.TemplateInfo{}, err
 	}
-	_, relPathFromRepoRoot, err := g.resolvePath(id)
+	if rev == "" {
+		rev = plumbing.HEAD.String()
+	}
+	from, err := g.commitAt(ctx, rev)
+	if err != nil {
+		return prompty.TemplateInfo{}, err
+	}
+	_, relPathFromRepoRoot, err := g.fileAt(from, remoteregistry.CandidatePaths(base))
 	if err != nil {
 		if errors.Is(err, remoteregistry.ErrNotFound) {
 			return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
 		}
 		return prompty.TemplateInfo{}, err
 	}
-	ref, err := g.repo.Head()
-	if err != nil {
-		return prompty.TemplateInfo{}, fmt.Errorf("head: %w", err)
-	}
 	cIter, err := g.repo.Log(&git.LogOptions{
-		From: ref.Hash(),
+		From: from.Hash,
 		PathFilter: func(p string) bool {
 			return p == relPathFromRepoRoot
 		},
@@ -191,43 +310,49 @@ func (g *Fetcher) Stat(ctx context.Context, id string) (prompty.TemplateInfo, er
 	if err != nil {
 		return prompty.TemplateInfo{}, fmt.Errorf("log: %w", err)
 	}
+	defer cIter.Close()
 	commit, err := cIter.Next()
 	if err != nil {
-		cIter.Close()
 		if errors.Is(err, io.EOF) {
-			return prompty.TemplateInfo{ID: id, Version: "", UpdatedAt: time.Time{}}, nil
+			// Shallow clone: the file predates the fetched history; report the newest commit we have.
+			return prompty.TemplateInfo{ID: id, Version: from.Hash.String(), UpdatedAt: from.Author.When}, nil
 		}
 		return prompty.TemplateInfo{}, err
 	}
-	cIter.Close()
 	return prompty.TemplateInfo{
 		ID:        id,
 		Version:   commit.Hash.String(),
-		UpdatedAt: commit.Committer.When,
+		UpdatedAt: commit.Author.When,
 	}, nil
 }
 
+// sync makes sure the clone exists and is fresh, taking the write lock only when a clone or pull is due.
+func (g *Fetcher) sync(ctx context.Context) error {
+	g.mu.RLock()
+	ready := g.repo != nil && !g.pullDue()
+	g.mu.RUnlock()
+	if ready {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.ensureClone(ctx)
+}
+
+// pullDue reports whether the next access should pull: never for pinned revisions or file:// URLs
+// (file:// has no remote to pull), otherwise once pullInterval has passed since the last pull.
+func (g *Fetcher) pullDue() bool {
+	if g.revision != "" || strings.HasPrefix(g.repoURL, "file://") {
+		return false
+	}
+	return g.pullInterval <= 0 || time.Since(g.lastPull) >= g.pullInterval
+}
+
+// ensureClone clones or opens the repo on first use and pulls when due. Caller must hold g.mu (write).
 func (g *Fetcher) ensureClone(ctx context.Context) error {
 	if g.repo != nil {
-		// Pull to refresh only for remote URLs (file:// has no remote to pull).
-		if !strings.HasPrefix(g.repoURL, "file://") {
-			wt, err := g.repo.Worktree()
-			if err != nil {
-				return fmt.Errorf("worktree: %w", err)
-			}
-			pullOpts := &git.PullOptions{}
-			if g.authToken != "" {
-				pullOpts.Auth = &http.BasicAuth{
-					Username: "x-access-token",
-					Password: g.authToken,
-				}
-			}
-			err = wt.PullContext(ctx, pullOpts)
-			if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-				// Do not remove clone on transient errors; next Fetch will retry pull.
-				// Proceed to read from existing working tree (stale data).
-				slog.Default().Warn("git pull failed, using cached clone", "err", err)
-			}
+		if g.pullDue() {
+			g.pull(ctx)
 		}
 		return nil
 	}
@@ -270,26 +395,85 @@ func (g *Fetcher) ensureClone(ctx context.Context) error {
 		return fmt.Errorf("clone/open: %w", err)
 	}
 	g.repo = repo
-	// Run pull once after open or clone so persistent cache is refreshed.
-	if !strings.HasPrefix(g.repoURL, "file://") {
-		wt, wtErr := g.repo.Worktree()
-		if wtErr != nil {
-			return fmt.Errorf("worktree: %w", wtErr)
-		}
-		pullOpts := &git.PullOptions{}
-		if g.authToken != "" {
-			pullOpts.Auth = &http.BasicAuth{
-				Username: "x-access-token",
-				Password: g.authToken,
+	if g.revision != "" {
+		if checkoutErr := g.checkoutRevision(ctx); checkoutErr != nil {
+			g.repo = nil
+			if g.cloneDir == "" {
+				_ = os.RemoveAll(g.localDir)
 			}
+			g.localDir = ""
+			return checkoutErr
 		}
-		if pullErr := wt.PullContext(ctx, pullOpts); pullErr != nil && !errors.Is(pullErr, git.NoErrAlreadyUpToDate) {
-			slog.Default().Warn("git pull failed, using cached clone", "err", pullErr)
-		}
+		return nil
+	}
+	// Run pull once after open or clone so persistent cache is refreshed.
+	if !strings.HasPrefix(g.repoURL, "file://") {
+		g.pull(ctx)
 	}
 	return nil
 }
 
+// pull updates the working tree from the remote branch. Failures are logged and the cached clone is kept
+// (stale data); lastPull is updated either way so a failing remote is retried after pullInterval.
+func (g *Fetcher) pull(ctx context.Context) {
+	g.lastPull = time.Now()
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		slog.Default().Warn("git worktree failed, using cached clone", "err", err)
+		return
+	}
+	pullOpts := &git.PullOptions{}
+	if g.authToken != "" {
+		pullOpts.Auth = g.auth()
+	}
+	if err := wt.PullContext(ctx, pullOpts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		// Do not remove clone on transient errors; next access will retry pull.
+		slog.Default().Warn("git pull failed, using cached clone", "err", err)
+	}
+}
+
+// checkoutRevision checks out the pinned revision as a detached HEAD.
+func (g *Fetcher) checkoutRevision(ctx context.Context) error {
+	commit, err := g.commitAt(ctx, g.revision)
+	if err != nil {
+		return fmt.Errorf("pin: %w", err)
+	}
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("worktree: %w", err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Hash: commit.Hash, Force: true}); err != nil {
+		return fmt.Errorf("checkout %s: %w", g.revision, err)
+	}
+	return nil
+}
+
+// fetchAll fetches all branches and tags from the remote. Caller must hold g.mu.
+func (g *Fetcher) fetchAll(ctx context.Context) error {
+	fetchOpts := &git.FetchOptions{
+		RefSpecs: []config.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+		Tags: git.AllTags,
+	}
+	if g.authToken != "" {
+		fetchOpts.Auth = g.auth()
+	}
+	err := g.repo.FetchContext(ctx, fetchOpts)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("fetch: %w", err)
+	}
+	return nil
+}
+
+func (g *Fetcher) auth() *http.BasicAuth {
+	return &http.BasicAuth{
+		Username: "x-access-token",
+		Password: g.authToken,
+	}
+}
+
 func (g *Fetcher) buildCloneOptions() *git.CloneOptions {
 	opts := &git.CloneOptions{
 		URL:           g.repoURL,
@@ -297,14 +481,16 @@ func (g *Fetcher) buildCloneOptions() *git.CloneOptions {
 		SingleBranch:  true,
 		Progress:      nil,
 	}
-	if g.depth > 0 {
+	if g.revision != "" {
+		// A pinned SHA or tag may not be on the branch head: clone full history with all branches and tags.
+		opts.ReferenceName = ""
+		opts.SingleBranch = false
+		opts.Tags = git.AllTags
+	} else if g.depth > 0 {
 		opts.Depth = g.depth
 	}
 	if g.authToken != "" {
-		opts.Auth = &http.BasicAuth{
-			Username: "x-access-token",
-			Password: g.authToken,
-		}
+		opts.Auth = g.auth()
 	}
 	return opts
 }
@@ -317,6 +503,7 @@ func (g *Fetcher) Close() error {
 		return nil
 	}
 	g.repo = nil
+	g.lastPull = time.Time{}
 	dir := g.localDir
 	g.localDir = ""
 	if g.cloneDir != "" {
@@ -2,10 +2,13 @@ package git
 
 import (
 	"context"
+	"crypto/ed25519"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/manifest"
@@ -398,3 +401,189 @@ func TestFetcher_Stat_NotFound(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
 }
+
+// runGit runs shell commands in dir with the test git identity and returns the output of the last one.
+func runGit(t *testing.T, dir string, env []string, cmds ...string) string {
+	t.Helper()
+	var out []byte
+	for _, c := range cmds {
+		cmd := exec.Command("sh", "-c", c) // #nosec G204 -- test helper: c is from fixed list
+		cmd.Dir = dir
+		cmd.Env = append(gitTestEnv(), env...)
+		var err error
+		out, err = cmd.CombinedOutput()
+		require.NoError(t, err, "run %q: %s", c, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func manifestText(id, text string) string {
+	return "id: " + id + "\nmessages:\n  - role: system\n    content: " + text + "\n"
+}
+
+// initTaggedRepo creates a.yaml "v1" tagged v1.0.0, then a second commit with "v2"; returns both commit SHAs.
+func initTaggedRepo(t *testing.T, dir string) (v1, v2 string) {
+	t.Helper()
+	initRepo(t, dir, map[string]string{"a.yaml": manifestText("a", "v1")})
+	runGit(t, dir, nil, "git tag -a v1.0.0 -m release")
+	v1 = runGit(t, dir, nil, "git rev-parse HEAD")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(manifestText("a", "v2")), 0644))  // #nosec G306 -- test
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yaml"), []byte(manifestText("b", "new")), 0644)) // #nosec G306 -- test
+	runGit(t, dir, nil, "git add .", "git commit -m v2")
+	v2 = runGit(t, dir, nil, "git rev-parse HEAD")
+	return v1, v2
+}
+
+func TestFetcher_WithRevision(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	v1, _ := initTaggedRepo(t, dir)
+	ctx := context.Background()
+	for _, rev := range []string{"v1.0.0", v1, v1[:8]} {
+		g, err := NewFetcher("file://"+dir, WithRevision(rev))
+		require.NoError(t, err)
+		data, err := g.Fetch(ctx, "a")
+		require.NoError(t, err, rev)
+		require.Contains(t, string(data), "content: v1", rev)
+		ids, err := g.ListIDs(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, ids, "b.yaml was added after the pin")
+		info, err := g.Stat(ctx, "a")
+		require.NoError(t, err)
+		require.Equal(t, v1, info.Version)
+		require.NoError(t, g.Close())
+	}
+
+	g, err := NewFetcher("file://"+dir, WithRevision("v9.9.9"))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	_, err = g.Fetch(ctx, "a")
+	require.ErrorIs(t, err, remoteregistry.ErrFetchFailed)
+}
+
+func TestFetcher_Fetch_AtRevision(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	v1, v2 := initTaggedRepo(t, dir)
+	g, err := NewFetcher("file://" + dir) // shallow clone of main: the tag is fetched on demand
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	ctx := context.Background()
+
+	data, err := g.Fetch(ctx, "a")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "content: v2")
+	data, err = g.Fetch(ctx, "a@rev:v1.0.0")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "content: v1")
+	_, err = g.Fetch(ctx, "b@rev:v1.0.0")
+	require.ErrorIs(t, err, remoteregistry.ErrNotFound)
+	_, err = g.Fetch(ctx, "a@rev:no-such-tag")
+	require.ErrorIs(t, err, remoteregistry.ErrNotFound)
+
+	info, err := g.Stat(ctx, "a@rev:v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, v1, info.Version)
+	info, err = g.Stat(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, v2, info.Version)
+
+	reg, err := remoteregistry.New(g, remoteregistry.WithParser(yaml.New()), remoteregistry.WithEnvironment("prod"))
+	require.NoError(t, err)
+	tpl, err := reg.GetTemplate(ctx, "a@rev:v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, v1, tpl.Metadata.Version, "manifest without version takes the commit SHA")
+	exec, err := tpl.Format(nil)
+	require.NoError(t, err)
+	text, ok := exec.Messages[0].Content[0].(prompty.TextPart)
+	require.True(t, ok)
+	require.Equal(t, "v1", text.Text)
+	_, err = reg.GetTemplate(ctx, "a@v1.0.0")
+	require.ErrorIs(t, err, prompty.ErrVersionNotFound, "without rev: the suffix is a semantic version")
+	_, err = reg.GetTemplate(ctx, "a@")
+	require.ErrorIs(t, err, prompty.ErrInvalidName)
+	_, err = reg.GetTemplate(ctx, "a@rev:")
+	require.ErrorIs(t, err, prompty.ErrInvalidName)
+}
+
+func TestFetcher_Stat_AuthorTime(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	initRepo(t, dir, map[string]string{"a.yaml": manifestText("a", "v1"), "b.yaml": manifestText("b", "v1")})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(manifestText("a", "v2")), 0644)) // #nosec G306 -- test
+	runGit(t, dir, []string{"GIT_AUTHOR_DATE=2024-03-01T10:00:00Z", "GIT_COMMITTER_DATE=2024-05-01T10:00:00Z"},
+		"git commit -am edit")
+	sha := runGit(t, dir, nil, "git rev-parse HEAD")
+	g, err := NewFetcher("file://"+dir, WithDepth(0))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	ctx := context.Background()
+	info, err := g.Stat(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, sha, info.Version)
+	require.True(t, info.UpdatedAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)), info.UpdatedAt)
+	info, err = g.Stat(ctx, "b")
+	require.NoError(t, err)
+	require.NotEqual(t, sha, info.Version, "b was last touched by the initial commit")
+}
+
+func TestFetcher_WithPullInterval(t *testing.T) {
+	t.Parallel()
+	origin := t.TempDir()
+	initRepo(t, origin, map[string]string{"a.yaml": manifestText("a", "v1")})
+	ctx := context.Background()
+	// Plain paths (unlike file:// URLs) are pulled.
+	every, err := NewFetcher(origin)
+	require.NoError(t, err)
+	defer func() { _ = every.Close() }()
+	hourly, err := NewFetcher(origin, WithPullInterval(time.Hour))
+	require.NoError(t, err)
+	defer func() { _ = hourly.Close() }()
+	for _, g := range []*Fetcher{every, hourly} {
+		data, fetchErr := g.Fetch(ctx, "a")
+		require.NoError(t, fetchErr)
+		require.Contains(t, string(data), "content: v1")
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(origin, "a.yaml"), []byte(manifestText("a", "v2")), 0644)) // #nosec G306 -- test
+	runGit(t, origin, nil, "git commit -am v2")
+	data, err := every.Fetch(ctx, "a")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "content: v2")
+	data, err = hourly.Fetch(ctx, "a")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "content: v1", "no pull before the interval elapses")
+}
+
+func TestFetcher_FetchFile_SignedManifests(t *testing.T) {
+	t.Parallel()
+	key := ed25519.NewKeyFromSeed([]byte(strings.Repeat("k", ed25519.SeedSize)))
+	pub, _ := key.Public().(ed25519.PublicKey)
+	keyring, err := remoteregistry.NewKeyring(pub)
+	require.NoError(t, err)
+	v1 := manifestText("a", "v1")
+	dir := t.TempDir()
+	initRepo(t, dir, map[string]string{"prompts/a.yaml": v1, "prompts/a.sig": string(remoteregistry.SignManifest(key, "a", []byte(v1)))})
+	runGit(t, dir, nil, "git tag v1.0.0")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prompts", "a.yaml"), []byte(manifestText("a", "evil")), 0644)) // #nosec G306 -- test
+	runGit(t, dir, nil, "git commit -am tamper")
+
+	g, err := NewFetcher("file://"+dir, WithDir("prompts"))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	ctx := context.Background()
+	sig, err := g.FetchFile(ctx, "a.sig@rev:v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, string(remoteregistry.SignManifest(key, "a", []byte(v1))), string(sig))
+	_, err = g.FetchFile(ctx, "missing.sig")
+	require.ErrorIs(t, err, remoteregistry.ErrNotFound)
+
+	reg, err := remoteregistry.New(g, remoteregistry.WithParser(yaml.New()),
+		remoteregistry.WithVerifier(remoteregistry.NewSignatureVerifier(keyring)))
+	require.NoError(t, err)
+	_, err = reg.GetTemplate(ctx, "a")
+	require.ErrorIs(t, err, remoteregistry.ErrSignatureMismatch)
+	tpl, err := reg.GetTemplate(ctx, "a@rev:v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "a", tpl.Metadata.ID)
+}
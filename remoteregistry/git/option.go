@@ -1,5 +1,7 @@
 package git
 
+import "time"
+
 // Option configures Fetcher.
 type Option func(*Fetcher)
 
@@ -42,3 +44,28 @@ func WithCloneDir(dir string) Option {
 		g.cloneDir = dir
 	}
 }
+
+// WithRevision pins the fetcher to a commit SHA (full or short) or tag, e.g. "v1.4.0".
+// The repo is cloned with full history and all tags, the revision is checked out detached, and it is never pulled,
+// so every Fetch, ListIDs and Stat sees the same tree. WithBranch and WithDepth are ignored.
+func WithRevision(rev string) Option {
+	return func(g *Fetcher) {
+		g.revision = rev
+	}
+}
+
+// WithPullInterval sets the minimum time between pulls of the tracked branch. Default 0 pulls on every
+// Fetch/ListIDs; with an interval, accesses in between read the working tree concurrently without pulling.
+func WithPullInterval(d time.Duration) Option {
+	return func(g *Fetcher) {
+		g.pullInterval = d
+	}
+}
+
+// WithCommitAuthor sets the author of commits made by WriteManifest and DeleteManifest.
+// Default is "prompty" <prompty@localhost>.
+func WithCommitAuthor(name, email string) Option {
+	return func(g *Fetcher) {
+		g.authorName, g.authorEmail = name, email
+	}
+}
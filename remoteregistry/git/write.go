@@ -0,0 +1,170 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/skosovsky/prompty/remoteregistry"
+)
+
+var _ remoteregistry.CheckedWriter = (*Fetcher)(nil)
+
+// WriteManifest implements remoteregistry.Writer: writes {dir}/{id}.yaml (an existing file keeps its extension;
+// a new JSON object gets .json), commits it on the tracked branch and pushes. The branch is pulled first; when the
+// push fails the local commit is dropped so the clone matches the remote again.
+func (g *Fetcher) WriteManifest(ctx context.Context, id string, data []byte) error {
+	return g.WriteManifestChecked(ctx, id, data, nil)
+}
+
+// WriteManifestChecked implements remoteregistry.CheckedWriter: like WriteManifest, with check run on the pulled
+// working tree under the write lock, before anything is written.
+func (g *Fetcher) WriteManifestChecked(
+	ctx context.Context,
+	id string,
+	data []byte,
+	check func(tree remoteregistry.Fetcher) error,
+) error {
+	var checkTree func() error
+	if check != nil {
+		checkTree = func() error { return check(worktree{g: g}) }
+	}
+	return g.commitChange(ctx, id, "prompty: put "+id, checkTree, func(wt *git.Worktree) error {
+		abs, rel, err := g.resolvePath(remoteregistry.CandidatePaths(id))
+		if errors.Is(err, remoteregistry.ErrNotFound) {
+			ext := ".yaml"
+			if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+				ext = ".json"
+			}
+			rel = filepath.ToSlash(filepath.Join(g.dir, id+ext))
+			abs = filepath.Join(g.localDir, filepath.FromSlash(rel))
+		} else if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil { // #nosec G301 -- directory inside the clone
+			return err
+		}
+		if err := os.WriteFile(abs, data, 0644); err != nil { // #nosec G306 -- manifests are committed to the repo
+			return err
+		}
+		_, err = wt.Add(rel)
+		return err
+	})
+}
+
+// DeleteManifest implements remoteregistry.Writer: removes the manifest for id, commits and pushes like WriteManifest.
+// Returns ErrNotFound when there is none.
+func (g *Fetcher) DeleteManifest(ctx context.Context, id string) error {
+	return g.commitChange(ctx, id, "prompty: delete "+id, nil, func(wt *git.Worktree) error {
+		_, rel, err := g.resolvePath(remoteregistry.CandidatePaths(id))
+		if err != nil {
+			return err
+		}
+		_, err = wt.Remove(rel)
+		return err
+	})
+}
+
+// worktree is the Fetcher view handed to a WriteManifestChecked check: it reads the pulled working tree (and commit
+// trees for "id@rev:rev") while commitChange holds g.mu, without syncing or locking again.
+type worktree struct {
+	g *Fetcher
+}
+
+var _ remoteregistry.Lister = worktree{}
+
+func (w worktree) Fetch(ctx context.Context, id string) ([]byte, error) {
+	if err := remoteregistry.ValidatePathForFetch(id); err != nil {
+		return nil, err
+	}
+	base, rev := remoteregistry.SplitRevision(id)
+	if rev != "" {
+		return w.g.readAt(ctx, remoteregistry.CandidatePaths(base), rev)
+	}
+	return w.g.readFile(remoteregistry.CandidatePaths(base))
+}
+
+func (w worktree) ListIDs(context.Context) ([]string, error) {
+	return w.g.listIDs()
+}
+
+// commitChange applies change to the up-to-date working tree of the tracked branch, commits with message and
+// pushes. check, when set, runs after the pull and before change; its error is returned as is. Errors other than
+// ErrNotFound from change are wrapped in ErrWriteFailed.
+func (g *Fetcher) commitChange(
+	ctx context.Context,
+	id, message string,
+	check func() error,
+	change func(*git.Worktree) error,
+) error {
+	if err := remoteregistry.ValidatePathForFetch(id); err != nil {
+		return err
+	}
+	if strings.Contains(id, "@") {
+		return fmt.Errorf("%w: cannot write at a revision: %q", remoteregistry.ErrWriteFailed, id)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.revision != "" {
+		return fmt.Errorf("%w: fetcher is pinned to %q", remoteregistry.ErrWriteFailed, g.revision)
+	}
+	if err := g.ensureClone(ctx); err != nil {
+		return fmt.Errorf("%w: %w", remoteregistry.ErrWriteFailed, err)
+	}
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("%w: worktree: %w", remoteregistry.ErrWriteFailed, err)
+	}
+	pullOpts := &git.PullOptions{RemoteName: git.DefaultRemoteName}
+	if g.authToken != "" {
+		pullOpts.Auth = g.auth()
+	}
+	if err := wt.PullContext(ctx, pullOpts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("%w: pull: %w", remoteregistry.ErrWriteFailed, err)
+	}
+	g.lastPull = time.Now()
+	head, err := g.repo.Head()
+	if err != nil {
+		return fmt.Errorf("%w: head: %w", remoteregistry.ErrWriteFailed, err)
+	}
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	if err := change(wt); err != nil {
+		_ = wt.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset})
+		if errors.Is(err, remoteregistry.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("%w: %s: %w", remoteregistry.ErrWriteFailed, id, err)
+	}
+	author := &object.Signature{Name: g.authorName, Email: g.authorEmail, When: time.Now()}
+	if _, err := wt.Commit(message, &git.CommitOptions{Author: author}); err != nil {
+		_ = wt.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset})
+		return fmt.Errorf("%w: commit: %w", remoteregistry.ErrWriteFailed, err)
+	}
+	branch := plumbing.NewBranchReferenceName(g.branch)
+	pushOpts := &git.PushOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(branch + ":" + branch)},
+	}
+	if g.authToken != "" {
+		pushOpts.Auth = g.auth()
+	}
+	if err := g.repo.PushContext(ctx, pushOpts); err != nil {
+		_ = wt.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset})
+		return fmt.Errorf("%w: push: %w", remoteregistry.ErrWriteFailed, err)
+	}
+	return nil
+}
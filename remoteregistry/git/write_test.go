@@ -0,0 +1,128 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/parser/yaml"
+	"github.com/skosovsky/prompty/remoteregistry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initBareRepo creates a repo with files and returns the path of a bare clone of it (the "remote").
+func initBareRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	src := t.TempDir()
+	initRepo(t, src, files)
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	cmd := exec.Command("git", "clone", "--bare", src, bare) // #nosec G204 -- test helper: temp paths
+	cmd.Env = gitTestEnv()
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "clone --bare: %s", out)
+	return bare
+}
+
+// gitShow returns `git show main:path` in the bare repo ("" when missing) and the last commit subject.
+func gitShow(t *testing.T, bare, path string) (content, subject string) {
+	t.Helper()
+	out, err := exec.Command("git", "--git-dir", bare, "show", "main:"+path).Output() // #nosec G204 -- test helper
+	if err == nil {
+		content = string(out)
+	}
+	out, err = exec.Command("git", "--git-dir", bare, "log", "-1", "--format=%s <%ae>", "main").Output() // #nosec G204 -- test helper
+	require.NoError(t, err)
+	return content, strings.TrimSpace(string(out))
+}
+
+func TestFetcher_Publish(t *testing.T) {
+	t.Parallel()
+	bare := initBareRepo(t, map[string]string{
+		"prompts/agent.yaml": "id: agent\nversion: 1.0.0\nmessages:\n  - role: system\n    content: v1\n",
+	})
+	g, err := NewFetcher("file://"+bare, WithDir("prompts"), WithCommitAuthor("ops", "ops@example.com"))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	reg, err := remoteregistry.New(g, remoteregistry.WithParser(yaml.New()), remoteregistry.WithEnvironment("prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"},
+		[]byte("id: agent\nversion: 1.1.0\nmessages:\n  - role: system\n    content: v1.1\n")))
+	content, subject := gitShow(t, bare, "prompts/agent.yaml")
+	assert.Contains(t, content, "v1.1")
+	assert.Equal(t, "prompty: put agent <ops@example.com>", subject)
+
+	staging := prompty.ManifestRef{ID: "agent", Env: "staging"}
+	require.NoError(t, reg.Put(ctx, staging, []byte("overlay: true\nmodel_config:\n  model: m-new\n")))
+	require.NoError(t, reg.Promote(ctx, staging, "prod"))
+	content, _ = gitShow(t, bare, "prompts/agent.prod.yaml")
+	assert.Contains(t, content, "m-new")
+
+	// A fresh clone of the remote sees the published manifests.
+	other, err := NewFetcher("file://"+bare, WithDir("prompts"))
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+	otherReg, err := remoteregistry.New(other, remoteregistry.WithParser(yaml.New()), remoteregistry.WithEnvironment("prod"))
+	require.NoError(t, err)
+	tpl, err := otherReg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, "m-new", tpl.ModelOptions.Model)
+
+	require.NoError(t, reg.Delete(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}))
+	content, subject = gitShow(t, bare, "prompts/agent.prod.yaml")
+	assert.Empty(t, content)
+	assert.Equal(t, "prompty: delete agent.prod <ops@example.com>", subject)
+	require.ErrorIs(t, reg.Delete(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}), prompty.ErrTemplateNotFound)
+
+	// Invalid manifests are rejected before anything is committed.
+	err = reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, []byte("id: agent\nmessages: [\n"))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	_, after := gitShow(t, bare, "prompts/agent.yaml")
+	assert.Equal(t, subject, after)
+}
+
+func TestFetcher_Publish_Pinned(t *testing.T) {
+	t.Parallel()
+	bare := initBareRepo(t, map[string]string{"agent.yaml": "id: agent\nmessages:\n  - role: system\n    content: x\n"})
+	g, err := NewFetcher("file://"+bare, WithRevision("main"))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	err = g.WriteManifest(context.Background(), "agent", []byte("id: agent\n"))
+	require.ErrorIs(t, err, remoteregistry.ErrWriteFailed)
+}
+
+func TestFetcher_Publish_ValidatesAgainstRemote(t *testing.T) {
+	t.Parallel()
+	bare := initBareRepo(t, map[string]string{
+		"prompts/agent.yaml": "id: agent\nversion: 1.0.0\nmessages:\n  - role: system\n    content: v1\n",
+		"prompts/other.yaml": "id: other\nmessages:\n  - role: system\n    content: x\n",
+	})
+	g, err := NewFetcher("file://"+bare, WithDir("prompts"))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	reg, err := remoteregistry.New(g, remoteregistry.WithParser(yaml.New()), remoteregistry.WithEnvironment("prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = reg.GetTemplate(ctx, "agent") // clone; file:// clones are not pulled on read
+	require.NoError(t, err)
+
+	other, err := NewFetcher("file://"+bare, WithDir("prompts"))
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+	require.NoError(t, other.DeleteManifest(ctx, "agent"))
+	_, subject := gitShow(t, bare, "prompts/agent.yaml")
+
+	// The stale clone still has the base; the overlay is validated after the pull, where it is gone.
+	err = reg.Put(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}, []byte("overlay: true\nmodel_config:\n  model: m\n"))
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	content, after := gitShow(t, bare, "prompts/agent.prod.yaml")
+	assert.Empty(t, content)
+	assert.Equal(t, subject, after)
+}
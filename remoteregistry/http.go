@@ -1,14 +1,19 @@
 package remoteregistry
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/skosovsky/prompty"
 )
 
 // HTTPFetcher fetches YAML manifests over HTTP. URL resolution: {baseURL}/{name}.{env}.yaml (or .yml),
@@ -24,11 +29,31 @@ const defaultUserAgent = "prompty-remote-registry/1.0"
 // defaultHTTPClientTimeout is the default timeout for manifest fetches when no custom client is set.
 const defaultHTTPClientTimeout = 30 * time.Second
 
+// defaultMaxValidators bounds the validator cache (responses kept for conditional requests).
+const defaultMaxValidators = 256
+
 // HTTPFetcher holds base URL, client, and optional Bearer token.
+// Responses with ETag or Last-Modified are kept in a bounded LRU validator cache (see WithValidatorCacheSize);
+// later fetches of the same URL send If-None-Match / If-Modified-Since and reuse the cached body on 304.
 type HTTPFetcher struct {
 	baseURL    string
 	httpClient *http.Client
 	authToken  string
+	indexPath  string // index document for ListIDs/Stat relative to baseURL; empty unless WithIndex
+
+	mu            sync.Mutex
+	maxValidators int                        // validator cache capacity; <= 0 disables conditional requests
+	validators    map[string]*validatorEntry // URL -> last 200 response with validators
+	clock         uint64                     // use counter for LRU eviction
+}
+
+// validatorEntry is a cached response body with the headers needed for conditional requests.
+type validatorEntry struct {
+	used         uint64 // HTTPFetcher.clock at the last use
+	etag         string
+	lastModified string
+	body         []byte
+	header       http.Header
 }
 
 // HTTPOption configures HTTPFetcher.
@@ -50,6 +75,14 @@ func WithAuthToken(token string) HTTPOption {
 	}
 }
 
+// WithValidatorCacheSize sets how many responses (by URL) are kept for conditional requests; the least recently
+// used is evicted first. Default 256; n <= 0 disables the cache, so every fetch downloads the body.
+func WithValidatorCacheSize(n int) HTTPOption {
+	return func(h *HTTPFetcher) {
+		h.maxValidators = n
+	}
+}
+
 // NewHTTPFetcher creates an HTTPFetcher. baseURL must be a valid URL (e.g. https://api.example.com/prompts).
 func NewHTTPFetcher(baseURL string, opts ...HTTPOption) (*HTTPFetcher, error) {
 	baseURL = strings.TrimSuffix(baseURL, "/")
@@ -61,8 +94,13 @@ func NewHTTPFetcher(baseURL string, opts ...HTTPOption) (*HTTPFetcher, error) {
 		return nil, fmt.Errorf("remoteregistry: invalid base URL %q", baseURL)
 	}
 	h := &HTTPFetcher{
-		baseURL:    baseURL,
-		httpClient: &http.Client{Timeout: defaultHTTPClientTimeout},
+		baseURL:       baseURL,
+		httpClient:    &http.Client{Timeout: defaultHTTPClientTimeout},
+		indexPath:     "",
+		mu:            sync.Mutex{},
+		maxValidators: defaultMaxValidators,
+		validators:    make(map[string]*validatorEntry),
+		clock:         0,
 	}
 	for _, opt := range opts {
 		opt(h)
@@ -73,56 +111,161 @@ func NewHTTPFetcher(baseURL string, opts ...HTTPOption) (*HTTPFetcher, error) {
 // Fetch tries URLs in order: {base}/{id}.yaml, {base}/{id}.yml.
 // On 404 proceeds to next; on other non-2xx returns ErrHTTPStatus.
 func (h *HTTPFetcher) Fetch(ctx context.Context, id string) ([]byte, error) {
+	data, _, err := h.fetch(ctx, id)
+	return data, err
+}
+
+var _ InfoFetcher = (*HTTPFetcher)(nil)
+
+// FetchInfo implements InfoFetcher: Fetch plus the TemplateInfo Stat would return, read from the GET response
+// headers (or, with WithIndex, the index entry), so Registry needs no extra HEAD request.
+func (h *HTTPFetcher) FetchInfo(ctx context.Context, id string) ([]byte, prompty.TemplateInfo, error) {
+	data, header, err := h.fetch(ctx, id)
+	if err != nil {
+		return nil, prompty.TemplateInfo{}, err
+	}
+	info := infoFromHeader(id, header)
+	if h.indexPath != "" {
+		if indexed, err := h.statIndex(ctx, id); err == nil {
+			info = indexed
+		}
+	}
+	return data, info, nil
+}
+
+// fetch returns the body and response headers of the first candidate path of id that exists.
+func (h *HTTPFetcher) fetch(ctx context.Context, id string) ([]byte, http.Header, error) {
 	if err := ValidatePathForFetch(id); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, path := range CandidatePaths(id) {
-		data, err := h.fetchOne(ctx, path)
+		data, header, err := h.fetchOne(ctx, http.MethodGet, path)
 		if err != nil {
 			if errors.Is(err, errNotFound) {
 				continue
 			}
-			return nil, err
+			return nil, nil, err
 		}
-		return data, nil
+		return data, header, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+}
+
+var _ FileFetcher = (*HTTPFetcher)(nil)
+
+// FetchFile implements FileFetcher: GET {base}/{name}. 404 returns ErrNotFound.
+func (h *HTTPFetcher) FetchFile(ctx context.Context, name string) ([]byte, error) {
+	if err := ValidatePathForFetch(name); err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	data, _, err := h.fetchOne(ctx, http.MethodGet, name)
+	if errors.Is(err, errNotFound) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return data, err
 }
 
 var errNotFound = errors.New("not found")
 
-func (h *HTTPFetcher) fetchOne(ctx context.Context, pathSeg string) ([]byte, error) {
+// fetchOne requests pathSeg with method (GET or HEAD) and returns the body and response headers.
+// GET sends validators from a previous response and serves the cached body on 304 Not Modified.
+func (h *HTTPFetcher) fetchOne(ctx context.Context, method, pathSeg string) ([]byte, http.Header, error) {
 	u, err := url.JoinPath(h.baseURL, pathSeg)
 	if err != nil {
-		return nil, fmt.Errorf("%w: join path: %w", ErrFetchFailed, err)
+		return nil, nil, fmt.Errorf("%w: join path: %w", ErrFetchFailed, err)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrFetchFailed, err)
+		return nil, nil, fmt.Errorf("%w: %w", ErrFetchFailed, err)
 	}
 	req.Header.Set("User-Agent", defaultUserAgent)
 	if h.authToken != "" {
 		req.Header.Set("Authorization", "Bearer "+h.authToken)
 	}
+	cached := h.validator(u)
+	if method == http.MethodGet && cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
 	resp, err := h.httpClient.Do(req) // #nosec G704 -- URL is from config and path-escaped name
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrFetchFailed, err)
+		return nil, nil, fmt.Errorf("%w: %w", ErrFetchFailed, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotModified && method == http.MethodGet && cached != nil {
+		return bytes.Clone(cached.body), cached.header, nil
+	}
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, errNotFound
+		h.forget(u)
+		return nil, nil, errNotFound
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("%w: %w: %s %s", ErrFetchFailed, ErrHTTPStatus, resp.Status, u)
+		return nil, nil, fmt.Errorf("%w: %w: %s %s", ErrFetchFailed, ErrHTTPStatus, resp.Status, u)
+	}
+	if method == http.MethodHead {
+		return nil, resp.Header, nil
 	}
 	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
 	if err != nil {
-		return nil, fmt.Errorf("%w: read body: %w", ErrFetchFailed, err)
+		return nil, nil, fmt.Errorf("%w: read body: %w", ErrFetchFailed, err)
 	}
 	// Detect truncation: if more data is available, body exceeded maxBodySize.
 	probe := make([]byte, 1)
 	if n, _ := resp.Body.Read(probe); n > 0 {
-		return nil, fmt.Errorf("%w: response body exceeds %d bytes", ErrFetchFailed, maxBodySize)
+		return nil, nil, fmt.Errorf("%w: response body exceeds %d bytes", ErrFetchFailed, maxBodySize)
 	}
-	return data, nil
+	h.remember(u, data, resp.Header)
+	return data, resp.Header, nil
+}
+
+// validator returns the cached response for u, if any, and marks it recently used.
+func (h *HTTPFetcher) validator(u string) *validatorEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ent, ok := h.validators[u]
+	if ok {
+		h.clock++
+		ent.used = h.clock
+	}
+	return ent
+}
+
+// remember stores body and headers for u when the response carries ETag or Last-Modified,
+// evicting the least recently used entry beyond maxValidators.
+func (h *HTTPFetcher) remember(u string, body []byte, header http.Header) {
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if (etag == "" && lastModified == "") || h.maxValidators <= 0 {
+		delete(h.validators, u)
+		return
+	}
+	if _, ok := h.validators[u]; !ok && len(h.validators) >= h.maxValidators {
+		var oldest string
+		for key, ent := range h.validators {
+			if oldest == "" || ent.used < h.validators[oldest].used {
+				oldest = key
+			}
+		}
+		delete(h.validators, oldest)
+	}
+	h.clock++
+	h.validators[u] = &validatorEntry{
+		used:         h.clock,
+		etag:         etag,
+		lastModified: lastModified,
+		body:         bytes.Clone(body),
+		header:       maps.Clone(header),
+	}
+}
+
+// forget drops the cached response for u (e.g. after 404).
+func (h *HTTPFetcher) forget(u string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.validators, u)
 }
@@ -0,0 +1,149 @@
+package remoteregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skosovsky/prompty"
+)
+
+// defaultIndexPath is the index document requested by HTTPFetcher.ListIDs and Stat with WithIndex.
+const defaultIndexPath = "index.json"
+
+// versionHeader lets a server report a template version explicitly; otherwise Stat uses the ETag.
+const versionHeader = "X-Prompt-Version"
+
+var (
+	_ Lister  = (*HTTPFetcher)(nil)
+	_ Statter = (*HTTPFetcher)(nil)
+)
+
+// HTTPIndex is the document served at {baseURL}/index.json (see WithIndex) so HTTP registries can list and stat
+// templates without downloading every manifest:
+//
+//	{"templates": [{"id": "support_agent", "version": "1.2.0", "sha256": "…", "updated_at": "2026-01-02T15:04:05Z"}]}
+//
+// IDs are fetch ids as passed to Fetch (env variants such as "support_agent.prod" are listed separately).
+type HTTPIndex struct {
+	Templates []HTTPIndexEntry `json:"templates"`
+}
+
+// HTTPIndexEntry describes one manifest in an HTTPIndex.
+type HTTPIndexEntry struct {
+	ID        string    `json:"id"`
+	Version   string    `json:"version,omitempty"`
+	SHA256    string    `json:"sha256,omitempty"` // hex digest of the manifest body; Stat uses it when version is empty
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// WithIndex enables the index protocol at {baseURL}/index.json for ListIDs and Stat.
+func WithIndex() HTTPOption {
+	return WithIndexPath(defaultIndexPath)
+}
+
+// WithIndexPath enables the index protocol with a custom document path relative to the base URL.
+func WithIndexPath(path string) HTTPOption {
+	return func(h *HTTPFetcher) {
+		h.indexPath = strings.TrimPrefix(path, "/")
+	}
+}
+
+// index fetches and decodes the index document (conditional GET via the validator cache).
+// Returns errNotFound when the server has no index.
+func (h *HTTPFetcher) index(ctx context.Context) (*HTTPIndex, error) {
+	data, _, err := h.fetchOne(ctx, http.MethodGet, h.indexPath)
+	if err != nil {
+		return nil, err
+	}
+	var idx HTTPIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("%w: decode %s: %w", ErrFetchFailed, h.indexPath, err)
+	}
+	return &idx, nil
+}
+
+// ListIDs returns the ids from the index document in server order.
+// Without WithIndex it returns nil (listing is not supported); ErrNotFound when the server has no index.
+func (h *HTTPFetcher) ListIDs(ctx context.Context) ([]string, error) {
+	if h.indexPath == "" {
+		return nil, nil
+	}
+	idx, err := h.index(ctx)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, h.indexPath)
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(idx.Templates))
+	for _, e := range idx.Templates {
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
+
+// Stat returns template metadata without downloading the manifest. With WithIndex, the entry for id is used
+// (Version falls back to the SHA-256); otherwise (or if the server has no index) a HEAD request per candidate
+// path reads X-Prompt-Version or ETag as Version and Last-Modified as UpdatedAt.
+func (h *HTTPFetcher) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	if err := ValidatePathForFetch(id); err != nil {
+		return prompty.TemplateInfo{}, err
+	}
+	if h.indexPath == "" {
+		return h.statHead(ctx, id)
+	}
+	info, err := h.statIndex(ctx, id)
+	if errors.Is(err, errNotFound) {
+		return h.statHead(ctx, id)
+	}
+	return info, err
+}
+
+// statIndex returns the index entry for id (Version falls back to the SHA-256).
+// Returns errNotFound when the server has no index.
+func (h *HTTPFetcher) statIndex(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	idx, err := h.index(ctx)
+	if err != nil {
+		return prompty.TemplateInfo{}, err
+	}
+	for _, e := range idx.Templates {
+		if e.ID == id {
+			version := e.Version
+			if version == "" {
+				version = e.SHA256
+			}
+			return prompty.TemplateInfo{ID: id, Version: version, UpdatedAt: e.UpdatedAt}, nil
+		}
+	}
+	return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+}
+
+// statHead issues HEAD requests for the candidate paths of id and reads TemplateInfo from the headers.
+func (h *HTTPFetcher) statHead(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	for _, path := range CandidatePaths(id) {
+		_, header, err := h.fetchOne(ctx, http.MethodHead, path)
+		if err != nil {
+			if errors.Is(err, errNotFound) {
+				continue
+			}
+			return prompty.TemplateInfo{}, err
+		}
+		return infoFromHeader(id, header), nil
+	}
+	return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+}
+
+// infoFromHeader builds TemplateInfo from X-Prompt-Version (or the ETag without quotes) and Last-Modified.
+func infoFromHeader(id string, header http.Header) prompty.TemplateInfo {
+	version := header.Get(versionHeader)
+	if version == "" {
+		version = strings.Trim(strings.TrimPrefix(header.Get("ETag"), "W/"), `"`)
+	}
+	updated, _ := http.ParseTime(header.Get("Last-Modified"))
+	return prompty.TemplateInfo{ID: id, Version: version, UpdatedAt: updated}
+}
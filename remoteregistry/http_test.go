@@ -285,3 +285,217 @@ func TestHTTPFetcher_Fetch_SlashId(t *testing.T) {
 	require.Len(t, tpl.Messages[0].Content, 1)
 	assert.Contains(t, tpl.Messages[0].Content[0].Text, "Router prompt")
 }
+
+func TestRegistry_GetTemplate_VersionFromFetchHeaders(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path != "/router.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Prompt-Version", "2.0.0")
+		_, _ = w.Write([]byte(`{"id":"router","messages":[{"role":"system","content":[{"type":"text","text":"Route"}]}]}`))
+	}))
+	defer srv.Close()
+	h, err := NewHTTPFetcher(srv.URL)
+	require.NoError(t, err)
+	reg, err := New(h, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+
+	tpl, err := reg.GetTemplate(context.Background(), "router")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", tpl.Metadata.Version)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"GET /router.yaml"}, methods, "the version comes from the GET, no HEAD")
+}
+
+func TestHTTPFetcher_Fetch_ConditionalGET(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	body, etag := `{"id":"a"}`, `"v1"`
+	var full, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Fri, 02 Jan 2026 15:04:05 GMT")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTPFetcher(srv.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+	for range 2 {
+		data, err := h.Fetch(ctx, "a")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"a"}`, string(data))
+	}
+	mu.Lock()
+	assert.Equal(t, 1, full)
+	assert.Equal(t, 1, notModified)
+	body, etag = `{"id":"a","version":"2"}`, `"v2"`
+	mu.Unlock()
+
+	data, err := h.Fetch(ctx, "a")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a","version":"2"}`, string(data), "changed ETag downloads the new body")
+}
+
+func TestHTTPFetcher_ValidatorCacheIsBounded(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var conditional []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			mu.Lock()
+			conditional = append(conditional, r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"id":"x"}`))
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTPFetcher(srv.URL, WithValidatorCacheSize(2))
+	require.NoError(t, err)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := h.Fetch(ctx, id)
+		require.NoError(t, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// c evicts b (least recently used), b then evicts c: a keeps its validators throughout.
+	assert.Equal(t, []string{"/a.yaml", "/a.yaml"}, conditional)
+
+	off, err := NewHTTPFetcher(srv.URL, WithValidatorCacheSize(0))
+	require.NoError(t, err)
+	for range 2 {
+		_, err := off.Fetch(ctx, "d")
+		require.NoError(t, err)
+	}
+	assert.Len(t, conditional, 2, "a disabled cache never sends validators")
+}
+
+func TestHTTPFetcher_Fetch_IfModifiedSince(t *testing.T) {
+	t.Parallel()
+	const lastModified = "Fri, 02 Jan 2026 15:04:05 GMT"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(`{"id":"a"}`))
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTPFetcher(srv.URL)
+	require.NoError(t, err)
+	_, err = h.Fetch(context.Background(), "a")
+	require.NoError(t, err)
+	data, err := h.Fetch(context.Background(), "a")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a"}`, string(data))
+}
+
+func TestHTTPFetcher_Index(t *testing.T) {
+	t.Parallel()
+	updated := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	idx := `{"templates":[` +
+		`{"id":"support_agent","version":"1.2.0","updated_at":"2026-01-02T15:04:05Z"},` +
+		`{"id":"support_agent.prod","sha256":"abc123"}]}`
+	var indexRequests int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prompts/index.json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		indexRequests++
+		mu.Unlock()
+		if r.Header.Get("If-None-Match") == `"idx1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"idx1"`)
+		_, _ = w.Write([]byte(idx))
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTPFetcher(srv.URL+"/prompts", WithIndex())
+	require.NoError(t, err)
+	reg, err := New(h, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support_agent", "support_agent.prod"}, ids)
+
+	info, err := reg.Stat(ctx, "support_agent")
+	require.NoError(t, err)
+	assert.Equal(t, prompty.TemplateInfo{ID: "support_agent", Version: "1.2.0", UpdatedAt: updated}, info)
+	info, err = h.Stat(ctx, "support_agent.prod")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", info.Version, "sha256 is the version when none is set")
+	_, err = reg.Stat(ctx, "missing")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	mu.Lock()
+	assert.Equal(t, 4, indexRequests)
+	mu.Unlock()
+}
+
+func TestHTTPFetcher_Stat_FromHeaders(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		switch r.URL.Path {
+		case "/tagged.yaml":
+			w.Header().Set("X-Prompt-Version", "3.1.0")
+			w.Header().Set("ETag", `"ignored"`)
+		case "/etag.json":
+			w.Header().Set("ETag", `W/"e42"`)
+			w.Header().Set("Last-Modified", "Fri, 02 Jan 2026 15:04:05 GMT")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTPFetcher(srv.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+	info, err := h.Stat(ctx, "tagged")
+	require.NoError(t, err)
+	assert.Equal(t, "3.1.0", info.Version)
+
+	info, err = h.Stat(ctx, "etag")
+	require.NoError(t, err)
+	assert.Equal(t, "e42", info.Version)
+	assert.Equal(t, time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), info.UpdatedAt)
+
+	_, err = h.Stat(ctx, "missing")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+
+	ids, err := h.ListIDs(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, ids, "listing requires WithIndex")
+}
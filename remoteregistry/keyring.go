@@ -0,0 +1,216 @@
+package remoteregistry
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Keyring holds the ed25519 public keys trusted to sign manifests. Immutable; safe for concurrent use.
+type Keyring struct {
+	keys map[string]ed25519.PublicKey // KeyID -> key
+}
+
+// NewKeyring creates a Keyring trusting keys. Returns error if keys is empty or a key has the wrong size.
+func NewKeyring(keys ...ed25519.PublicKey) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("remoteregistry: keyring must contain at least one key")
+	}
+	k := &Keyring{keys: make(map[string]ed25519.PublicKey, len(keys))}
+	for i, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("remoteregistry: key %d: want %d bytes, got %d", i, ed25519.PublicKeySize, len(key))
+		}
+		k.keys[KeyID(key)] = slices.Clone(key)
+	}
+	return k, nil
+}
+
+// ParseKeyring reads public keys from data: PEM "PUBLIC KEY" blocks (e.g. `openssl pkey -pubout`) or, without PEM,
+// one base64-encoded raw key per line ('#' starts a comment line).
+func ParseKeyring(data []byte) (*Keyring, error) {
+	var keys []ed25519.PublicKey
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		for rest := data; ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			key, err := ParsePublicKey(pem.EncodeToMemory(block))
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		for line := range signatureLines(data) {
+			key, err := ParsePublicKey([]byte(line))
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	}
+	return NewKeyring(keys...)
+}
+
+// KeyIDs returns the ids of the trusted keys, sorted.
+func (k *Keyring) KeyIDs() []string {
+	return slices.Sorted(maps.Keys(k.keys))
+}
+
+// verify checks that signatures (a signature file, see Sign) hold a valid signature of message by a trusted key.
+func (k *Keyring) verify(message, signatures []byte) error {
+	var seen, mismatch bool
+	for line := range signatureLines(signatures) {
+		seen = true
+		keyID, sig, ok := strings.Cut(line, " ")
+		key, trusted := k.keys[keyID]
+		if !ok || !trusted {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+		if err == nil && ed25519.Verify(key, message, raw) {
+			return nil
+		}
+		mismatch = true
+	}
+	switch {
+	case mismatch:
+		return ErrSignatureMismatch
+	case !seen:
+		return ErrUnsigned
+	default:
+		return ErrUnknownKey
+	}
+}
+
+// KeyID returns the id of an ed25519 public key: the first 8 bytes of its SHA-256, hex-encoded.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePublicKey parses an ed25519 public key: PEM "PUBLIC KEY" (PKIX) or the base64-encoded raw 32 bytes.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("remoteregistry: parse public key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("remoteregistry: public key is %T, want ed25519", parsed)
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("remoteregistry: parse public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("remoteregistry: public key: want %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ParsePrivateKey parses an ed25519 private key: PEM "PRIVATE KEY" (PKCS #8, e.g. `openssl genpkey -algorithm
+// ed25519`) or a base64-encoded 32-byte seed or 64-byte key.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("remoteregistry: parse private key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("remoteregistry: private key is %T, want ed25519", parsed)
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("remoteregistry: parse private key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("remoteregistry: private key: want %d or %d bytes, got %d",
+			ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+}
+
+// Sign returns a signature file for data: one line "{KeyID} {base64 signature}". Signature files from several
+// keys can be concatenated; verification passes when one line is a valid signature by a trusted key.
+func Sign(key ed25519.PrivateKey, data []byte) []byte {
+	pub, _ := key.Public().(ed25519.PublicKey)
+	return fmt.Appendf(nil, "%s %s\n", KeyID(pub), base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)))
+}
+
+// SignManifest returns the detached signature file of the manifest published at fetch id (e.g. "support/agent.prod"):
+// Sign over ManifestPayload, so the signature is only valid for that id and cannot be copied to another path.
+func SignManifest(key ed25519.PrivateKey, id string, data []byte) []byte {
+	return Sign(key, ManifestPayload(id, data))
+}
+
+// ManifestPayload returns the message a manifest signature covers: "{id}\n{sha256 hex of data}".
+func ManifestPayload(id string, data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return []byte(id + "\n" + hex.EncodeToString(sum[:]))
+}
+
+// ChecksumIndex returns a checksum index for manifests (fetch id -> bytes, e.g. "support/agent.prod"):
+// one line "{sha256 hex}  {id}" per manifest, sorted by id. Sign it and publish both as
+// ChecksumIndexName and ChecksumIndexName+SignatureExt for IndexVerifier.
+func ChecksumIndex(manifests map[string][]byte) []byte {
+	var b bytes.Buffer
+	for _, id := range slices.Sorted(maps.Keys(manifests)) {
+		sum := sha256.Sum256(manifests[id])
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), id)
+	}
+	return b.Bytes()
+}
+
+// parseChecksumIndex parses ChecksumIndex output into id -> sha256 hex.
+func parseChecksumIndex(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	for line := range signatureLines(data) {
+		sum, id, ok := strings.Cut(line, "  ")
+		if !ok || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("malformed checksum line %q", line)
+		}
+		sums[id] = sum
+	}
+	return sums, nil
+}
+
+// signatureLines yields the trimmed non-empty lines of data that do not start with '#'.
+func signatureLines(data []byte) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !yield(line) {
+				return
+			}
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package remoteregistry
 
 import (
+	"slices"
+	"text/template"
+
 	"github.com/skosovsky/prompty/manifest"
 )
 
@@ -14,5 +17,34 @@ func WithParser(u manifest.Unmarshaler) Option {
 
 // WithEnvironment sets env for fallback: Fetch tries id.env first, then id.
 func WithEnvironment(env string) Option {
-	return func(r *Registry) { r.env = env }
+	return WithEnvironmentChain(env)
+}
+
+// WithEnvironmentChain sets the env fallback chain, most specific first: WithEnvironmentChain("prod-eu", "prod")
+// fetches id.prod-eu, then id.prod, then id, down to the first complete manifest. Overlays (`overlay: true`)
+// found on the way are applied over it (see manifest.ParseEnvChain); Metadata.Environment lists them.
+func WithEnvironmentChain(envs ...string) Option {
+	return func(r *Registry) {
+		r.envs = slices.DeleteFunc(slices.Clone(envs), func(env string) bool { return env == "" })
+	}
+}
+
+// WithStrict builds every template with prompty.WithStrict, so rendering fails on unused payload fields
+// and "<no value>" output. Useful in CI to catch broken prompts.
+func WithStrict() Option {
+	return func(r *Registry) { r.strict = true }
+}
+
+// WithFuncs adds custom template functions to every template (see prompty.WithFuncs).
+// Loading fails with prompty.ErrFuncConflict if a name shadows a built-in.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Registry) { r.funcs = funcs }
+}
+
+// WithVerifier checks every fetched manifest with v before it is parsed (e.g. NewSignatureVerifier or
+// NewIndexVerifier). A failed check returns *VerificationError; the registry never falls back to another
+// layer (base id after env) past it. A verified manifest whose id differs from the requested one fails with
+// ErrIDMismatch.
+func WithVerifier(v Verifier) Option {
+	return func(r *Registry) { r.verifier = v }
 }
@@ -0,0 +1,138 @@
+package remoteregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+)
+
+// Ensures Registry and CachedRegistry implement prompty.Publisher.
+var (
+	_ prompty.Publisher = (*Registry)(nil)
+	_ prompty.Publisher = (*CachedRegistry)(nil)
+)
+
+// Put validates data (manifest.ValidateLayer, overlays over the layers fetched below them) and writes it through
+// the Fetcher's Writer. A CheckedWriter validates against the layers it is about to write over, so a stale read
+// cannot let a broken overlay through. Fails with ErrWriteFailed when the Fetcher does not implement Writer.
+// With WithVerifier, re-sign after publishing (prompty-gen sign) or reads fail verification.
+func (r *Registry) Put(ctx context.Context, ref prompty.ManifestRef, data []byte) error {
+	w, err := r.writer()
+	if err != nil {
+		return err
+	}
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	check := func(tree Fetcher) error {
+		view := *r // extends parents and versions resolve against the same tree
+		view.fetcher = tree
+		below := func(env string) ([]byte, bool, error) {
+			layer := ref
+			layer.Env = env
+			data, err := tree.Fetch(ctx, layer.Path())
+			if errors.Is(err, ErrNotFound) || errors.Is(err, prompty.ErrTemplateNotFound) {
+				return nil, false, nil
+			}
+			return data, err == nil, err
+		}
+		_, err := manifest.ValidateLayer(ref, data, r.envs, below, r.parser, view.parseOptions(ctx)...)
+		return err
+	}
+	if cw, ok := w.(CheckedWriter); ok {
+		return cw.WriteManifestChecked(ctx, ref.Path(), data, check)
+	}
+	if err := check(r.fetcher); err != nil {
+		return err
+	}
+	return w.WriteManifest(ctx, ref.Path(), data)
+}
+
+// Delete removes the manifest for ref through the Fetcher's Writer.
+func (r *Registry) Delete(ctx context.Context, ref prompty.ManifestRef) error {
+	w, err := r.writer()
+	if err != nil {
+		return err
+	}
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if err := w.DeleteManifest(ctx, ref.Path()); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%w: %s: %w", prompty.ErrTemplateNotFound, ref, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Promote fetches the manifest for ref as stored and puts it for env (see prompty.Publisher).
+func (r *Registry) Promote(ctx context.Context, ref prompty.ManifestRef, env string) error {
+	if _, err := r.writer(); err != nil {
+		return err
+	}
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	data, err := r.fetcher.Fetch(ctx, ref.Path())
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%w: %s: %w", prompty.ErrTemplateNotFound, ref, err)
+		}
+		return err
+	}
+	to := ref
+	to.Env = env
+	return r.Put(ctx, to, data)
+}
+
+func (r *Registry) writer() (Writer, error) {
+	w, ok := r.fetcher.(Writer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T does not implement Writer", ErrWriteFailed, r.fetcher)
+	}
+	return w, nil
+}
+
+// Put delegates to the base registry (which must implement prompty.Publisher) and evicts the cache.
+func (r *CachedRegistry) Put(ctx context.Context, ref prompty.ManifestRef, data []byte) error {
+	p, err := r.publisher()
+	if err != nil {
+		return err
+	}
+	defer r.EvictAll()
+	return p.Put(ctx, ref, data)
+}
+
+// Delete delegates to the base registry (which must implement prompty.Publisher) and evicts the cache.
+func (r *CachedRegistry) Delete(ctx context.Context, ref prompty.ManifestRef) error {
+	p, err := r.publisher()
+	if err != nil {
+		return err
+	}
+	defer r.EvictAll()
+	return p.Delete(ctx, ref)
+}
+
+// Promote delegates to the base registry (which must implement prompty.Publisher) and evicts the cache.
+func (r *CachedRegistry) Promote(ctx context.Context, ref prompty.ManifestRef, env string) error {
+	p, err := r.publisher()
+	if err != nil {
+		return err
+	}
+	defer r.EvictAll()
+	return p.Promote(ctx, ref, env)
+}
+
+// publisher returns the base registry as a prompty.Publisher. The whole cache is evicted after a write because
+// env overlays, extends children and version constraints can depend on the written manifest.
+func (r *CachedRegistry) publisher() (prompty.Publisher, error) {
+	p, ok := r.base.(prompty.Publisher)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T does not implement prompty.Publisher", ErrWriteFailed, r.base)
+	}
+	return p, nil
+}
@@ -0,0 +1,80 @@
+package remoteregistry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writableFetcher is an in-memory Fetcher and Writer keyed by fetch id.
+type writableFetcher struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (w *writableFetcher) Fetch(_ context.Context, id string) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if d, ok := w.files[id]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+}
+
+func (w *writableFetcher) WriteManifest(_ context.Context, id string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files[id] = data
+	return nil
+}
+
+func (w *writableFetcher) DeleteManifest(_ context.Context, id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.files[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	delete(w.files, id)
+	return nil
+}
+
+func TestRegistry_Publisher(t *testing.T) {
+	t.Parallel()
+	f := &writableFetcher{files: make(map[string][]byte)}
+	base, err := New(f, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	reg := WithCache(base, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, signedManifest("agent", "v1")))
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", tpl.Messages[0].Content[0].Text)
+
+	staging := prompty.ManifestRef{ID: "agent", Env: "staging"}
+	require.NoError(t, reg.Put(ctx, staging, []byte(`{"overlay":true,"model_config":{"model":"m2"}}`)))
+	require.NoError(t, reg.Promote(ctx, staging, "prod"))
+	assert.Contains(t, f.files, "agent.prod")
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "m2", tpl.ModelOptions.Model, "writes evict the cache")
+
+	require.NoError(t, reg.Delete(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}))
+	require.ErrorIs(t, reg.Delete(ctx, prompty.ManifestRef{ID: "agent", Env: "prod"}), prompty.ErrTemplateNotFound)
+	require.ErrorIs(t, reg.Put(ctx, prompty.ManifestRef{ID: "agent"}, []byte(`{"id":`)), prompty.ErrInvalidManifest)
+	assert.Equal(t, signedManifest("agent", "v1"), f.files["agent"])
+
+	readOnly, err := New(&mockFetcher{}, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	require.ErrorIs(t, readOnly.Put(ctx, prompty.ManifestRef{ID: "agent"}, signedManifest("agent", "x")), ErrWriteFailed)
+	require.ErrorIs(t, WithCache(&mockRegistryWithExtras{}, time.Minute).Delete(ctx, prompty.ManifestRef{ID: "a"}),
+		ErrWriteFailed)
+}
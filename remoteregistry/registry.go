@@ -4,25 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/skosovsky/prompty"
 	"github.com/skosovsky/prompty/manifest"
 )
 
-// Ensures Registry implements prompty.Registry, Lister, and Statter.
+// Ensures Registry implements prompty.Registry, Lister, Statter, and Versioner.
 var (
-	_ prompty.Registry = (*Registry)(nil)
-	_ prompty.Lister   = (*Registry)(nil)
-	_ prompty.Statter  = (*Registry)(nil)
+	_ prompty.Registry  = (*Registry)(nil)
+	_ prompty.Lister    = (*Registry)(nil)
+	_ prompty.Statter   = (*Registry)(nil)
+	_ prompty.Versioner = (*Registry)(nil)
 )
 
 // Registry loads templates via Fetcher without internal cache/state.
-// WithEnvironment(env): fetch tries id.env first, then id.
+// WithEnvironment(env): fetch tries id.env first, then id; WithEnvironmentChain sets several envs.
+// Env manifests marked `overlay: true` patch the next layer instead of replacing it.
+// Versioned manifests live at {id}/v{version}.yaml; the version index comes from the Fetcher's Lister.
+// WithVerifier checks signatures or a signed checksum index before manifests are parsed.
 // Parser is required; use WithParser when creating the registry.
 type Registry struct {
-	fetcher Fetcher
-	env     string // e.g. "prod"; Fetch tries id.env first
-	parser  manifest.Unmarshaler
+	fetcher  Fetcher
+	envs     []string // e.g. ["prod-eu", "prod"]; Fetch tries id.prod-eu, id.prod, then id
+	parser   manifest.Unmarshaler
+	strict   bool // WithStrict: templates are built with prompty.WithStrict
+	funcs    template.FuncMap
+	verifier Verifier // WithVerifier: checks manifest bytes before parsing
 }
 
 // New creates a stateless Registry. Panics if fetcher is nil.
@@ -41,73 +54,233 @@ func New(fetcher Fetcher, opts ...Option) (*Registry, error) {
 	return r, nil
 }
 
-// fetchCandidateIDs returns ids to try in order: with env first, then base id.
-func fetchCandidateIDs(id, env string) []string {
-	if env != "" {
-		return []string{id + "." + env, id}
+// layerID returns the fetch id of the env layer of id ("" for the base manifest).
+// A revision suffix ("id@rev:rev") is kept: "id.env@rev:rev", "id@rev:rev".
+func layerID(id, env string) string {
+	if env == "" {
+		return id
 	}
-	return []string{id}
+	base, rev := SplitRevision(id)
+	return withRevision(base+"."+env, rev)
+}
+
+// manifestID returns the id the manifest fetched for id must declare: without revision and versioned layout
+// ("support/agent/v1.2.0@rev:main" -> "support/agent").
+func manifestID(id string) string {
+	base, _ := SplitRevision(id)
+	if vid, _, _, ok := prompty.ParseVersionedPath(base); ok {
+		return vid
+	}
+	return base
+}
+
+// validateRevisionID validates the id part of "id@constraint" or "id@rev:rev"; the suffix must not be empty.
+func validateRevisionID(id string) error {
+	base, suffix, ok := prompty.SplitVersionedID(id)
+	if ok && (suffix == "" || suffix == RevisionPrefix) {
+		return fmt.Errorf("%w: empty version or revision in %q", prompty.ErrInvalidName, id)
+	}
+	return ValidateID(base)
 }
 
 // GetTemplate returns a template by id.
-// With env, tries id.env first and then id.
+// With env, tries id.env first and then id. "id@constraint" (e.g. "agent@^1.2", "agent@latest") resolves against
+// Versions; "id@rev:rev" (e.g. "agent@rev:v1.4.0") is passed to the Fetcher as a revision (see SplitRevision).
 func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
-	if err := ValidateID(id); err != nil {
+	if err := validateRevisionID(id); err != nil {
 		return nil, err
 	}
-	candidates := fetchCandidateIDs(id, r.env)
-	for _, cid := range candidates {
-		tpl, err := r.getTemplateByID(ctx, cid)
-		if err == nil {
-			return tpl, nil
-		}
-		if !errors.Is(err, ErrNotFound) && !errors.Is(err, prompty.ErrTemplateNotFound) {
+	if _, rev := SplitRevision(id); rev != "" {
+		return r.getCandidates(ctx, id, "")
+	}
+	if base, constraint, ok := prompty.SplitVersionedID(id); ok {
+		path, version, err := r.resolveVersion(ctx, base, constraint)
+		switch {
+		case err != nil:
 			return nil, err
+		case path == base:
+			return r.GetTemplate(ctx, base)
+		default:
+			return r.getCandidates(ctx, path, version)
 		}
 	}
-	return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	return r.getCandidates(ctx, id, "")
 }
 
-func (r *Registry) getTemplateByID(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+// getCandidates composes id over the env chain; version, when set, fills a missing manifest version.
+func (r *Registry) getCandidates(ctx context.Context, id, version string) (*prompty.ChatPromptTemplate, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	data, err := r.fetcher.Fetch(ctx, id)
-	if err != nil {
-		return nil, err
+	var found string                   // most specific fetched id, for Stat
+	var foundInfo prompty.TemplateInfo // its fetch response metadata, when the fetcher is an InfoFetcher
+	infoFetcher, hasInfo := r.fetcher.(InfoFetcher)
+	read := func(env string) ([]byte, bool, error) {
+		lid := layerID(id, env)
+		var data []byte
+		var info prompty.TemplateInfo
+		var err error
+		if hasInfo {
+			data, info, err = infoFetcher.FetchInfo(ctx, lid)
+		} else {
+			data, err = r.fetcher.Fetch(ctx, lid)
+		}
+		if errors.Is(err, ErrNotFound) || errors.Is(err, prompty.ErrTemplateNotFound) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if r.verifier != nil {
+			if err := r.verifier.Verify(ctx, r.fetcher, lid, data); err != nil {
+				return nil, false, err
+			}
+		}
+		if found == "" {
+			found, foundInfo = lid, info
+		}
+		return data, true, nil
+	}
+	tpl, err := manifest.ParseEnvChain(read, r.envs, r.parser, r.parseOptions(ctx)...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
 	}
-	tpl, err := manifest.Parse(data, r.parser)
 	if err != nil {
 		return nil, err
 	}
-	tpl.Metadata.Environment = ""
-	if statter, ok := r.fetcher.(Statter); ok {
-		if info, statErr := statter.Stat(ctx, id); statErr == nil && info.Version != "" && tpl.Metadata.Version == "" {
-			tpl.Metadata.Version = info.Version
+	if r.verifier != nil {
+		if want := manifestID(id); tpl.Metadata.ID != want {
+			// A validly signed manifest served under another id (e.g. a.yaml copied to b.yaml).
+			return nil, &VerificationError{ID: found, Err: fmt.Errorf("%w: %q", ErrIDMismatch, tpl.Metadata.ID)}
+		}
+	}
+	switch {
+	case tpl.Metadata.Version != "":
+	case version != "":
+		tpl.Metadata.Version = version
+	case hasInfo:
+		tpl.Metadata.Version = foundInfo.Version
+	default:
+		if statter, ok := r.fetcher.(Statter); ok {
+			if info, statErr := statter.Stat(ctx, found); statErr == nil && info.Version != "" {
+				tpl.Metadata.Version = info.Version
+			}
 		}
 	}
 	return prompty.CloneTemplate(tpl), nil
 }
 
-// List returns ids from Fetcher if it implements Lister.
+func (r *Registry) parseOptions(ctx context.Context) []manifest.ParseOption {
+	opts := []manifest.ParseOption{manifest.WithBaseRegistry(ctx, r)}
+	if r.strict {
+		opts = append(opts, manifest.WithStrict())
+	}
+	if r.funcs != nil {
+		opts = append(opts, manifest.WithFuncs(r.funcs))
+	}
+	return opts
+}
+
+// Versions implements prompty.Versioner: versions from {id}/v{version} ids listed by the Fetcher (Lister; env
+// overlays count for the registry env) plus the semantic `version` declared by the plain manifest, ascending.
+func (r *Registry) Versions(ctx context.Context, id string) ([]string, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	versions, _, err := r.versionIndex(ctx, id)
+	return versions, err
+}
+
+// versionIndex returns all versions of id and the set found in the versioned layout.
+func (r *Registry) versionIndex(ctx context.Context, id string) ([]string, map[string]bool, error) {
+	layout := make(map[string]bool)
+	if lister, ok := r.fetcher.(Lister); ok {
+		ids, err := lister.ListIDs(ctx)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, nil, err
+		}
+		for _, listed := range ids {
+			vid, version, env, isVersion := prompty.ParseVersionedPath(listed)
+			if isVersion && vid == id && (env == "" || slices.Contains(r.envs, env)) {
+				layout[version] = true
+			}
+		}
+	}
+	versions := slices.Collect(maps.Keys(layout))
+	tpl, err := r.getCandidates(ctx, id, "")
+	switch {
+	case err == nil:
+		if v, parseErr := prompty.ParseVersion(tpl.Metadata.Version); parseErr == nil {
+			versions = append(versions, v.String())
+		}
+	case !errors.Is(err, prompty.ErrTemplateNotFound):
+		return nil, nil, err
+	}
+	return prompty.SortVersions(versions), layout, nil
+}
+
+// resolveVersion returns the path to fetch for the best version of id matching constraint:
+// {id}/v{version} for the versioned layout, or id when the plain manifest declares the chosen version.
+func (r *Registry) resolveVersion(ctx context.Context, id, constraint string) (path, version string, err error) {
+	versions, layout, err := r.versionIndex(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	version, err = prompty.ResolveVersion(versions, constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("template %q: %w", id, err)
+	}
+	if layout[version] {
+		return prompty.VersionedPath(id, version), version, nil
+	}
+	return id, version, nil
+}
+
+// List returns ids from Fetcher if it implements Lister. Versioned manifests ({id}/v{version}) are listed as id.
 func (r *Registry) List(ctx context.Context) ([]string, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	if lister, ok := r.fetcher.(Lister); ok {
-		return lister.ListIDs(ctx)
+	lister, ok := r.fetcher.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	ids, err := lister.ListIDs(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if vid, _, _, isVersion := prompty.ParseVersionedPath(id); isVersion {
+			id = vid
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
 }
 
-// Stat returns metadata from Fetcher if it implements Statter.
+// Stat returns metadata from Fetcher if it implements Statter. For "id@constraint", Version is the resolved version.
 func (r *Registry) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
-	if err := ValidateID(id); err != nil {
+	if err := validateRevisionID(id); err != nil {
 		return prompty.TemplateInfo{}, err
 	}
 	if ctx.Err() != nil {
 		return prompty.TemplateInfo{}, ctx.Err()
 	}
+	if base, constraint, ok := prompty.SplitVersionedID(id); ok && !strings.HasPrefix(constraint, RevisionPrefix) {
+		path, version, err := r.resolveVersion(ctx, base, constraint)
+		switch {
+		case err != nil:
+			return prompty.TemplateInfo{}, err
+		case path == base:
+			info, _ := r.Stat(ctx, base)
+			return prompty.TemplateInfo{ID: id, Version: version, UpdatedAt: info.UpdatedAt}, nil
+		default:
+			return prompty.TemplateInfo{ID: id, Version: version, UpdatedAt: time.Time{}}, nil
+		}
+	}
 	if statter, ok := r.fetcher.(Statter); ok {
 		return statter.Stat(ctx, id)
 	}
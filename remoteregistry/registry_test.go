@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -584,3 +587,273 @@ func TestCachedRegistry_Close_DelegatesToBase(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, base.closeCalled)
 }
+
+func TestRegistry_GetTemplate_Extends(t *testing.T) {
+	t.Parallel()
+	m := &mockFetcher{data: map[string][]byte{
+		"base": []byte(
+			`{"id":"base","messages":[{"role":"system","content":[{"type":"text","text":"Base"}]}]}`,
+		),
+		"child": []byte(
+			`{"id":"child","extends":"base","messages_merge":"prepend",` +
+				`"messages":[{"role":"user","content":[{"type":"text","text":"Child"}]}]}`,
+		),
+	}}
+	reg, err := New(m, WithParser(manifest.NewJSONParser()))
+	require.NoError(t, err)
+	tpl, err := reg.GetTemplate(context.Background(), "child")
+	require.NoError(t, err)
+	require.Len(t, tpl.Messages, 2)
+	assert.Equal(t, "Child", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, "Base", tpl.Messages[1].Content[0].Text)
+}
+
+func TestRegistry_GetTemplate_ExtendsMissingParentNoFallback(t *testing.T) {
+	t.Parallel()
+	m := &mockFetcher{data: map[string][]byte{
+		"child.prod": []byte(`{"id":"child","extends":"missing"}`),
+		"child":      []byte(`{"id":"child","messages":[{"role":"system","content":[{"type":"text","text":"Base"}]}]}`),
+	}}
+	reg, err := New(m, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	_, err = reg.GetTemplate(context.Background(), "child")
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+}
+
+// switchRegistry is a base registry whose text and error can be changed between calls.
+type switchRegistry struct {
+	mu   sync.Mutex
+	text string
+	err  error
+}
+
+func (s *switchRegistry) set(text string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.text, s.err = text, err
+}
+
+func (s *switchRegistry) GetTemplate(_ context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return prompty.NewChatPromptTemplate(
+		[]prompty.MessageTemplate{{Role: prompty.RoleSystem, Content: prompty.TextContent(s.text)}},
+		prompty.WithMetadata(prompty.PromptMetadata{ID: id}),
+	)
+}
+
+func (s *switchRegistry) List(_ context.Context) ([]string, error) {
+	return []string{"a", "b"}, nil
+}
+
+func cachedText(t *testing.T, reg *CachedRegistry, id string) string {
+	t.Helper()
+	tpl, err := reg.GetTemplate(context.Background(), id)
+	require.NoError(t, err)
+	return tpl.Messages[0].Content[0].Text
+}
+
+func TestCachedRegistry_StaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+	base := &switchRegistry{text: "v1"}
+	reg := WithCache(base, 10*time.Millisecond, WithStaleWhileRevalidate(time.Hour))
+	assert.Equal(t, "v1", cachedText(t, reg, "a"))
+	time.Sleep(20 * time.Millisecond)
+
+	base.set("v2", nil)
+	assert.Equal(t, "v1", cachedText(t, reg, "a"), "expired entry is served while revalidating")
+	require.Eventually(t, func() bool { return cachedText(t, reg, "a") == "v2" }, time.Second, time.Millisecond)
+
+	stats := reg.Stats()
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.GreaterOrEqual(t, stats.StaleHits, uint64(1))
+	assert.Equal(t, uint64(2), stats.Refreshes)
+	assert.GreaterOrEqual(t, stats.Hits, uint64(1))
+}
+
+func TestCachedRegistry_StaleWhileRevalidate_RefreshSurvivesCanceledWaiter(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	base := registryFunc(func(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+		text := "v1"
+		if calls.Add(1) > 1 {
+			text = "v2"
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return prompty.NewChatPromptTemplate(
+			[]prompty.MessageTemplate{{Role: prompty.RoleSystem, Content: prompty.TextContent(text)}},
+			prompty.WithMetadata(prompty.PromptMetadata{ID: id}),
+		)
+	})
+	reg := WithCache(base, 5*time.Millisecond, WithStaleWhileRevalidate(time.Hour))
+	assert.Equal(t, "v1", cachedText(t, reg, "a"))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, "v1", cachedText(t, reg, "a"), "stale hit starts a background refresh")
+
+	// A blocking caller joins the refresh and gives up; the refresh must keep going.
+	reg.Evict("a")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := reg.GetTemplate(ctx, "a")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	require.Eventually(t, func() bool { return reg.Stats().Refreshes == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, "v2", cachedText(t, reg, "a"))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestCachedRegistry_StaleIfError(t *testing.T) {
+	t.Parallel()
+	base := &switchRegistry{text: "v1"}
+	reg := WithCache(base, 5*time.Millisecond, WithStaleIfError(time.Hour))
+	assert.Equal(t, "v1", cachedText(t, reg, "a"))
+	time.Sleep(10 * time.Millisecond)
+
+	base.set("", fmt.Errorf("%w: 503", ErrFetchFailed))
+	assert.Equal(t, "v1", cachedText(t, reg, "a"), "stale entry is served when the remote fails")
+	stats := reg.Stats()
+	assert.Equal(t, uint64(1), stats.StaleIfError)
+	assert.Equal(t, uint64(1), stats.RefreshErrors)
+
+	base.set("", fmt.Errorf("%w: %q", ErrNotFound, "a"))
+	_, err := reg.GetTemplate(context.Background(), "a")
+	require.ErrorIs(t, err, ErrNotFound, "deleted templates are not served stale")
+
+	strict := WithCache(base, time.Millisecond, WithStaleIfError(time.Millisecond))
+	base.set("v1", nil)
+	assert.Equal(t, "v1", cachedText(t, strict, "a"))
+	time.Sleep(10 * time.Millisecond)
+	base.set("", ErrFetchFailed)
+	_, err = strict.GetTemplate(context.Background(), "a")
+	require.ErrorIs(t, err, ErrFetchFailed, "entries older than max staleness are not served")
+}
+
+func TestCachedRegistry_BackgroundRefresh(t *testing.T) {
+	t.Parallel()
+	base := &switchRegistry{text: "v1"}
+	reg := WithCache(base, 0, WithBackgroundRefresh(5*time.Millisecond))
+	assert.Equal(t, "v1", cachedText(t, reg, "a"))
+
+	base.set("v2", nil)
+	require.Eventually(t, func() bool { return cachedText(t, reg, "a") == "v2" }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return cachedText(t, reg, "b") == "v2" }, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(1), reg.Stats().Misses, "b was prefetched from List")
+	require.NoError(t, reg.Close())
+	require.NoError(t, reg.Close(), "Close is idempotent")
+}
+
+func TestCachedRegistry_Refresh(t *testing.T) {
+	t.Parallel()
+	base := &switchRegistry{text: "v1"}
+	reg := WithCache(base, time.Hour)
+	require.NoError(t, reg.Refresh(context.Background()))
+	assert.Equal(t, "v1", cachedText(t, reg, "b"))
+	assert.Equal(t, CacheStats{Hits: 1, Refreshes: 2}, reg.Stats())
+
+	base.set("", ErrFetchFailed)
+	err := reg.Refresh(context.Background())
+	require.ErrorIs(t, err, ErrFetchFailed)
+	assert.Equal(t, "v1", cachedText(t, reg, "a"), "failed refresh keeps the cached entry")
+
+	plain := WithCache(registryFunc(func(context.Context, string) (*prompty.ChatPromptTemplate, error) {
+		return nil, ErrNotFound
+	}), time.Hour)
+	require.Error(t, plain.Refresh(context.Background()))
+}
+
+// registryFunc adapts a function to prompty.Registry (no List or Stat).
+type registryFunc func(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error)
+
+func (f registryFunc) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	return f(ctx, id)
+}
+
+// listingFetcher is a mockFetcher that also lists its ids (sorted).
+type listingFetcher struct {
+	mockFetcher
+}
+
+func (l *listingFetcher) ListIDs(_ context.Context) ([]string, error) {
+	ids := slices.Collect(maps.Keys(l.data))
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func TestRegistry_Versions(t *testing.T) {
+	t.Parallel()
+	doc := func(version, text string) []byte {
+		return []byte(`{"id":"agent","version":"` + version + `","messages":[{"role":"system","content":` +
+			`[{"type":"text","text":"` + text + `"}]}]}`)
+	}
+	f := &listingFetcher{mockFetcher: mockFetcher{data: map[string][]byte{
+		"agent":             doc("2.0.0", "working copy"),
+		"agent/v1.0.0":      doc("", "one"),
+		"agent/v1.1.0":      doc("", "one-one"),
+		"agent/v1.1.0.prod": doc("", "one-one prod"),
+		"other":             doc("", "other"),
+	}}}
+	reg, err := New(f, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	versions, err := reg.Versions(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0", "2.0.0"}, versions)
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent", "other"}, ids)
+
+	text := func(id string) string {
+		t.Helper()
+		tpl, getErr := reg.GetTemplate(ctx, id)
+		require.NoError(t, getErr, id)
+		return tpl.Messages[0].Content[0].Text
+	}
+	assert.Equal(t, "one", text("agent@1.0.0"))
+	assert.Equal(t, "one-one prod", text("agent@^1"), "env overlay of the resolved version")
+	assert.Equal(t, "working copy", text("agent@latest"))
+	tpl, err := reg.GetTemplate(ctx, "agent@~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", tpl.Metadata.Version)
+	info, err := reg.Stat(ctx, "agent@^1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", info.Version)
+	_, err = reg.GetTemplate(ctx, "agent@^3")
+	require.ErrorIs(t, err, prompty.ErrVersionNotFound)
+
+	cached := WithCache(reg, time.Minute)
+	versions, err = cached.Versions(ctx, "agent")
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+	tpl, err = cached.GetTemplate(ctx, "agent@1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", tpl.Metadata.Version)
+}
+
+func TestRegistry_GetTemplate_EnvironmentChainOverlays(t *testing.T) {
+	t.Parallel()
+	m := &mockFetcher{data: map[string][]byte{
+		"agent": []byte(`{"id":"agent","version":"1.0.0","model_config":{"model":"m-base"},` +
+			`"messages":[{"role":"system","content":[{"type":"text","text":"Base"}]}]}`),
+		"agent.prod-eu": []byte(`{"overlay":true,"model_config":{"model":"m-eu"},` +
+			`"messages":[{"index":0,"content":[{"type":"text","text":"EU"}]}]}`),
+	}}
+	reg, err := New(m, WithParser(manifest.NewJSONParser()), WithEnvironmentChain("prod-eu", "prod"))
+	require.NoError(t, err)
+	tpl, err := reg.GetTemplate(context.Background(), "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "prod-eu", tpl.Metadata.Environment, "the missing prod layer is skipped")
+	assert.Equal(t, "1.0.0", tpl.Metadata.Version)
+	assert.Equal(t, "m-eu", tpl.ModelOptions.Model)
+	assert.Equal(t, "EU", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, 3, m.called)
+}
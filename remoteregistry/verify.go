@@ -0,0 +1,138 @@
+package remoteregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	// SignatureExt is appended to a fetch id for its detached signature file ("support/agent.sig") and to
+	// ChecksumIndexName for the index signature.
+	SignatureExt = ".sig"
+	// ChecksumIndexName is the checksum index file at the manifest root (see ChecksumIndex).
+	ChecksumIndexName = "prompty.sum"
+)
+
+// FileFetcher is optional. When implemented by Fetcher, verifiers read signature and checksum index files
+// through it. HTTPFetcher and the git Fetcher implement it.
+type FileFetcher interface {
+	// FetchFile returns the file at name (slash path relative to the manifest root, no extension added).
+	// "name@rev:rev" reads it at a revision, like Fetch. Return ErrNotFound when the file does not exist.
+	FetchFile(ctx context.Context, name string) ([]byte, error)
+}
+
+// Verifier checks fetched manifest bytes before they are parsed (see WithVerifier).
+// id is the fetched id (with env suffix and "@rev:", e.g. "support/agent.prod@rev:v1.4.0").
+// Return a *VerificationError on mismatch; other errors are treated as fetch errors.
+type Verifier interface {
+	Verify(ctx context.Context, fetcher Fetcher, id string, data []byte) error
+}
+
+// Ensures the verifiers implement Verifier.
+var (
+	_ Verifier = (*SignatureVerifier)(nil)
+	_ Verifier = (*IndexVerifier)(nil)
+)
+
+// SignatureVerifier checks a detached ed25519 signature per manifest: {id}.sig next to the manifest
+// (see SignManifest), read via FileFetcher. The signature covers the id, so a manifest and signature copied to
+// another path fail with ErrSignatureMismatch. A missing signature fails with ErrUnsigned.
+type SignatureVerifier struct {
+	keyring *Keyring
+}
+
+// NewSignatureVerifier creates a SignatureVerifier trusting keyring. Panics if keyring is nil.
+func NewSignatureVerifier(keyring *Keyring) *SignatureVerifier {
+	if keyring == nil {
+		panic("remoteregistry: Keyring must not be nil")
+	}
+	return &SignatureVerifier{keyring: keyring}
+}
+
+// Verify implements Verifier.
+func (v *SignatureVerifier) Verify(ctx context.Context, fetcher Fetcher, id string, data []byte) error {
+	base, rev := SplitRevision(id)
+	sig, err := fetchFile(ctx, fetcher, withRevision(base+SignatureExt, rev))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &VerificationError{ID: id, Err: ErrUnsigned}
+		}
+		return err
+	}
+	if err := v.keyring.verify(ManifestPayload(base, data), sig); err != nil {
+		return &VerificationError{ID: id, Err: err}
+	}
+	return nil
+}
+
+// IndexVerifier checks manifests against a signed checksum index: ChecksumIndexName at the manifest root,
+// signed by ChecksumIndexName+SignatureExt (see ChecksumIndex and Sign). The index is fetched and its
+// signature checked on every Verify; wrap the registry in WithCache to avoid refetching per template.
+// A manifest missing from the index fails with ErrUnsigned.
+type IndexVerifier struct {
+	keyring *Keyring
+}
+
+// NewIndexVerifier creates an IndexVerifier trusting keyring. Panics if keyring is nil.
+func NewIndexVerifier(keyring *Keyring) *IndexVerifier {
+	if keyring == nil {
+		panic("remoteregistry: Keyring must not be nil")
+	}
+	return &IndexVerifier{keyring: keyring}
+}
+
+// Verify implements Verifier.
+func (v *IndexVerifier) Verify(ctx context.Context, fetcher Fetcher, id string, data []byte) error {
+	base, rev := SplitRevision(id)
+	index, err := fetchFile(ctx, fetcher, withRevision(ChecksumIndexName, rev))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &VerificationError{ID: id, Err: fmt.Errorf("%w: no %s", ErrUnsigned, ChecksumIndexName)}
+		}
+		return err
+	}
+	sig, err := fetchFile(ctx, fetcher, withRevision(ChecksumIndexName+SignatureExt, rev))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &VerificationError{ID: id, Err: fmt.Errorf("%w: no %s", ErrUnsigned, ChecksumIndexName+SignatureExt)}
+		}
+		return err
+	}
+	if err := v.keyring.verify(index, sig); err != nil {
+		return &VerificationError{ID: id, Err: fmt.Errorf("%s: %w", ChecksumIndexName, err)}
+	}
+	sums, err := parseChecksumIndex(index)
+	if err != nil {
+		return &VerificationError{ID: id, Err: fmt.Errorf("%w: %s: %w", ErrChecksumMismatch, ChecksumIndexName, err)}
+	}
+	want, ok := sums[base]
+	if !ok {
+		return &VerificationError{ID: id, Err: fmt.Errorf("%w: not in %s", ErrUnsigned, ChecksumIndexName)}
+	}
+	sum := sha256.Sum256(data)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(want)) != 1 {
+		return &VerificationError{ID: id, Err: ErrChecksumMismatch}
+	}
+	return nil
+}
+
+// fetchFile reads name through fetcher's FileFetcher.
+func fetchFile(ctx context.Context, fetcher Fetcher, name string) ([]byte, error) {
+	ff, ok := fetcher.(FileFetcher)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T does not implement FileFetcher", ErrFetchFailed, fetcher)
+	}
+	return ff.FetchFile(ctx, name)
+}
+
+// withRevision appends "@rev:rev" to name when rev is set.
+func withRevision(name, rev string) string {
+	if rev == "" {
+		return name
+	}
+	return name + "@" + RevisionPrefix + rev
+}
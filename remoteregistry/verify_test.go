@@ -0,0 +1,238 @@
+package remoteregistry
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileFetcher serves manifests (Fetch) and raw files (FetchFile) from one map keyed by path.
+type fileFetcher struct {
+	files map[string][]byte
+}
+
+func (f *fileFetcher) Fetch(_ context.Context, id string) ([]byte, error) {
+	for _, p := range CandidatePaths(id) {
+		if d, ok := f.files[p]; ok {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+}
+
+func (f *fileFetcher) FetchFile(_ context.Context, name string) ([]byte, error) {
+	if d, ok := f.files[name]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+func testKey(t *testing.T, seed byte) (ed25519.PrivateKey, *Keyring) {
+	t.Helper()
+	key := ed25519.NewKeyFromSeed([]byte(strings.Repeat(string(rune('a'+seed)), ed25519.SeedSize)))
+	pub, _ := key.Public().(ed25519.PublicKey)
+	keyring, err := NewKeyring(pub)
+	require.NoError(t, err)
+	return key, keyring
+}
+
+func signedManifest(id, text string) []byte {
+	return []byte(`{"id":"` + id + `","messages":[{"role":"system","content":[{"type":"text","text":"` + text + `"}]}]}`)
+}
+
+func TestKeyring_Parse(t *testing.T) {
+	t.Parallel()
+	key, _ := testKey(t, 0)
+	pub, _ := key.Public().(ed25519.PublicKey)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	other, _ := testKey(t, 1)
+	otherPub, _ := other.Public().(ed25519.PublicKey)
+
+	pemRing := append(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustPKIX(t, otherPub)})...)
+	kr, err := ParseKeyring(pemRing)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{KeyID(pub), KeyID(otherPub)}, kr.KeyIDs())
+
+	kr, err = ParseKeyring([]byte("# ops key\n" + base64.StdEncoding.EncodeToString(pub) + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{KeyID(pub)}, kr.KeyIDs())
+
+	der, err = x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.Equal(parsed))
+	parsed, err = ParsePrivateKey([]byte(base64.StdEncoding.EncodeToString(key.Seed())))
+	require.NoError(t, err)
+	assert.True(t, key.Equal(parsed))
+
+	_, err = ParseKeyring(nil)
+	require.Error(t, err)
+	_, err = ParsePublicKey([]byte("c2hvcnQ="))
+	require.Error(t, err)
+}
+
+func mustPKIX(t *testing.T, pub ed25519.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return der
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key, keyring := testKey(t, 0)
+	untrusted, _ := testKey(t, 1)
+	base := signedManifest("agent", "base")
+	prod := signedManifest("agent", "prod")
+	f := &fileFetcher{files: map[string][]byte{
+		"agent.json":      base,
+		"agent.sig":       SignManifest(key, "agent", base),
+		"agent.prod.json": prod,
+		"agent.prod.sig":  SignManifest(key, "agent.prod", prod),
+		"other.json":      signedManifest("other", "x"),
+		"other.sig":       SignManifest(untrusted, "other", signedManifest("other", "x")),
+		"unsigned.json":   signedManifest("unsigned", "x"),
+	}}
+	reg, err := New(f, WithParser(manifest.NewJSONParser()), WithEnvironment("prod"),
+		WithVerifier(NewSignatureVerifier(keyring)))
+	require.NoError(t, err)
+
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "agent", tpl.Metadata.ID)
+
+	var verifyErr *VerificationError
+	_, err = reg.GetTemplate(ctx, "other")
+	require.ErrorIs(t, err, ErrUnknownKey)
+	require.ErrorAs(t, err, &verifyErr)
+	assert.Equal(t, "other", verifyErr.ID)
+	_, err = reg.GetTemplate(ctx, "unsigned")
+	require.ErrorIs(t, err, ErrUnsigned)
+
+	f.files["agent.prod.json"] = signedManifest("agent", "tampered")
+	_, err = reg.GetTemplate(ctx, "agent")
+	require.ErrorIs(t, err, ErrSignatureMismatch, "no fallback from a tampered env overlay to the base manifest")
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	require.ErrorIs(t, err, prompty.ErrInvalidManifest)
+	require.ErrorAs(t, err, &verifyErr)
+	assert.Equal(t, "agent.prod", verifyErr.ID)
+
+	// A second signer line from a trusted key is enough.
+	f.files["other.sig"] = append(f.files["other.sig"], SignManifest(key, "other", signedManifest("other", "x"))...)
+	_, err = reg.GetTemplate(ctx, "other")
+	require.NoError(t, err)
+
+	// A signed manifest and its signature copied to another path do not verify there.
+	f.files["copy.json"], f.files["copy.sig"] = base, f.files["agent.sig"]
+	_, err = reg.GetTemplate(ctx, "copy")
+	require.ErrorIs(t, err, ErrSignatureMismatch)
+	require.ErrorAs(t, err, &verifyErr)
+	assert.Equal(t, "copy", verifyErr.ID)
+
+	// Even signed for its path, a manifest must declare the requested id.
+	f.files["copy.sig"] = SignManifest(key, "copy", base)
+	_, err = reg.GetTemplate(ctx, "copy")
+	require.ErrorIs(t, err, ErrIDMismatch)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+}
+
+func TestIndexVerifier(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key, keyring := testKey(t, 0)
+	manifests := map[string][]byte{"agent": signedManifest("agent", "a"), "team/b": signedManifest("team/b", "b")}
+	index := ChecksumIndex(manifests)
+	f := &fileFetcher{files: map[string][]byte{
+		"agent.json":                     manifests["agent"],
+		"team/b.json":                    manifests["team/b"],
+		"new.json":                       signedManifest("new", "n"),
+		ChecksumIndexName:                index,
+		ChecksumIndexName + SignatureExt: Sign(key, index),
+	}}
+	reg, err := New(f, WithParser(manifest.NewJSONParser()), WithVerifier(NewIndexVerifier(keyring)))
+	require.NoError(t, err)
+
+	_, err = reg.GetTemplate(ctx, "team/b")
+	require.NoError(t, err)
+	_, err = reg.GetTemplate(ctx, "new")
+	require.ErrorIs(t, err, ErrUnsigned)
+
+	f.files["agent.json"] = signedManifest("agent", "tampered")
+	_, err = reg.GetTemplate(ctx, "agent")
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	f.files[ChecksumIndexName] = ChecksumIndex(map[string][]byte{"agent": f.files["agent.json"]})
+	_, err = reg.GetTemplate(ctx, "agent")
+	require.ErrorIs(t, err, ErrSignatureMismatch, "a rewritten index does not match its signature")
+
+	delete(f.files, ChecksumIndexName+SignatureExt)
+	_, err = reg.GetTemplate(ctx, "agent")
+	require.ErrorIs(t, err, ErrUnsigned)
+}
+
+func TestVerifier_RequiresFileFetcher(t *testing.T) {
+	t.Parallel()
+	_, keyring := testKey(t, 0)
+	m := &mockFetcher{data: map[string][]byte{"agent": signedManifest("agent", "a")}}
+	reg, err := New(m, WithParser(manifest.NewJSONParser()), WithVerifier(NewSignatureVerifier(keyring)))
+	require.NoError(t, err)
+	_, err = reg.GetTemplate(context.Background(), "agent")
+	require.ErrorIs(t, err, ErrFetchFailed)
+	require.NotErrorIs(t, err, ErrVerificationFailed)
+}
+
+func TestHTTPFetcher_FetchFile_SignedManifest(t *testing.T) {
+	t.Parallel()
+	key, keyring := testKey(t, 0)
+	body := signedManifest("agent", "hi")
+	var mu sync.Mutex
+	files := map[string][]byte{"/agent.yaml": body, "/agent.sig": SignManifest(key, "agent", body)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		data, ok := files[r.URL.Path]
+		mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTPFetcher(srv.URL)
+	require.NoError(t, err)
+	_, err = h.FetchFile(context.Background(), "missing.sig")
+	require.ErrorIs(t, err, ErrNotFound)
+	reg, err := New(h, WithParser(manifest.NewJSONParser()), WithVerifier(NewSignatureVerifier(keyring)))
+	require.NoError(t, err)
+	cached := WithCache(reg, time.Millisecond, WithStaleIfError(time.Hour))
+	_, err = cached.GetTemplate(context.Background(), "agent")
+	require.NoError(t, err)
+
+	mu.Lock()
+	files["/agent.yaml"] = signedManifest("agent", "tampered")
+	mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	_, err = cached.GetTemplate(context.Background(), "agent")
+	require.True(t, errors.Is(err, ErrSignatureMismatch), "stale-if-error does not mask verification failures: %v", err)
+}
@@ -1,11 +1,19 @@
 package prompty
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
 	"reflect"
+	"time"
 )
 
-var schemaProviderType = reflect.TypeFor[SchemaProvider]()
+var (
+	schemaProviderType = reflect.TypeFor[SchemaProvider]()
+	timeType           = reflect.TypeFor[time.Time]()
+	rawMessageType     = reflect.TypeFor[json.RawMessage]()
+	textMarshalerType  = reflect.TypeFor[encoding.TextMarshaler]()
+)
 
 // SchemaProvider allows caller-owned types to provide a JSON Schema without reflection.
 type SchemaProvider interface {
@@ -15,7 +23,22 @@ type SchemaProvider interface {
 // ExtractSchema returns a JSON Schema for v using SchemaProvider when available,
 // otherwise falling back to the built-in reflect generator.
 //
-// On unsupported input, it returns nil.
+// The reflect generator follows encoding/json: field names and omitempty come from the `json` tag; time.Time is a
+// "date-time" string, json.RawMessage and interface fields accept any value, maps with string, integer or
+// encoding.TextMarshaler keys become objects with additionalProperties, and other TextMarshaler types are strings.
+// Named struct types that are recursive or used more than once are emitted once under "$defs" and referenced with
+// "$ref" ("#" for the root type); others are inlined.
+// A `jsonschema` tag annotates the field's schema node (on slices, value keywords apply to the items):
+//
+//	Status string    `json:"status" jsonschema:"description=Ticket state,enum=open|closed,default=open"`
+//	Score  int       `json:"score" jsonschema:"minimum=0,maximum=100"`
+//	Due    time.Time `json:"due" jsonschema:"description=Deadline\\, UTC"`
+//
+// Supported keys: title, description, enum, default, format, pattern, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, multipleOf, minLength, maxLength, minItems, maxItems, minProperties, maxProperties, and the
+// flags uniqueItems, deprecated, readOnly, writeOnly. Escape a literal comma as `\\,`.
+//
+// On unsupported input (including a malformed `jsonschema` tag), it returns nil.
 func ExtractSchema(v any) map[string]any {
 	schema, err := extractSchema(v)
 	if err != nil {
@@ -40,7 +63,7 @@ func extractSchemaFromType(t reflect.Type) (map[string]any, error) {
 	if schema, ok := schemaFromProvider(base); ok {
 		return schema, nil
 	}
-	return newSchemaBuilder().schemaForType(base, 0)
+	return newSchemaBuilder().build(base)
 }
 
 func normalizedSchemaTypeFromValue(v any) (reflect.Type, error) {
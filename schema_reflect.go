@@ -4,14 +4,23 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 )
 
 const maxSchemaDepth = 32
 
+// schemaBuilder generates a JSON Schema by reflection. Named struct types are built once and referenced with
+// "$ref": the root type as "#", others as "#/$defs/{name}"; finish inlines definitions used once that are not
+// recursive, so only recursive and shared types stay under "$defs".
 type schemaBuilder struct {
-	active map[reflect.Type]int
-	order  int
+	active      map[reflect.Type]int
+	order       int
+	root        reflect.Type
+	rootStarted bool
+	refs        map[reflect.Type]string   // named struct type -> "$ref" value
+	defs        map[string]map[string]any // $defs name -> schema
+	defTypes    map[string]reflect.Type   // $defs name -> type, for unique names
 }
 
 type schemaFieldCandidate struct {
@@ -25,10 +34,23 @@ type schemaFieldCandidate struct {
 
 func newSchemaBuilder() *schemaBuilder {
 	return &schemaBuilder{
-		active: make(map[reflect.Type]int),
+		active:   make(map[reflect.Type]int),
+		refs:     make(map[reflect.Type]string),
+		defs:     make(map[string]map[string]any),
+		defTypes: make(map[string]reflect.Type),
 	}
 }
 
+// build returns the schema for t with shared and recursive named types under "$defs".
+func (b *schemaBuilder) build(t reflect.Type) (map[string]any, error) {
+	b.root = t
+	schema, err := b.schemaForType(t, 0)
+	if err != nil {
+		return nil, err
+	}
+	return b.finish(schema), nil
+}
+
 func (b *schemaBuilder) schemaForType(t reflect.Type, depth int) (map[string]any, error) {
 	base, err := normalizedSchemaType(t)
 	if err != nil {
@@ -37,6 +59,14 @@ func (b *schemaBuilder) schemaForType(t reflect.Type, depth int) (map[string]any
 	if schema, ok := schemaFromProvider(base); ok {
 		return schema, nil
 	}
+	switch {
+	case base == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case base == rawMessageType:
+		return map[string]any{}, nil // any JSON value
+	case isTextMarshaler(base):
+		return map[string]any{"type": "string"}, nil
+	}
 
 	switch base.Kind() {
 	case reflect.Struct:
@@ -62,6 +92,10 @@ func (b *schemaBuilder) schemaForType(t reflect.Type, depth int) (map[string]any
 			"type":  "array",
 			"items": items,
 		}, nil
+	case reflect.Map:
+		return b.schemaForMapType(base, depth)
+	case reflect.Interface:
+		return map[string]any{}, nil // any JSON value
 	case reflect.String:
 		return map[string]any{"type": "string"}, nil
 	case reflect.Bool:
@@ -76,7 +110,81 @@ func (b *schemaBuilder) schemaForType(t reflect.Type, depth int) (map[string]any
 	}
 }
 
+// schemaForMapType maps a map with string, integer or encoding.TextMarshaler keys (the keys encoding/json
+// supports) to an object whose additionalProperties describe the values.
+func (b *schemaBuilder) schemaForMapType(t reflect.Type, depth int) (map[string]any, error) {
+	key := t.Key()
+	var propertyNames map[string]any
+	switch {
+	case key.Kind() == reflect.String, isTextMarshaler(key):
+	case isIntegerKind(key.Kind()):
+		propertyNames = map[string]any{"pattern": "^-?[0-9]+$"}
+	default:
+		return nil, fmt.Errorf("schema: unsupported map key type %s", key)
+	}
+	values, err := b.schemaForType(t.Elem(), depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("additionalProperties: %w", err)
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"additionalProperties": values,
+	}
+	if propertyNames != nil {
+		schema["propertyNames"] = propertyNames
+	}
+	return schema, nil
+}
+
+// schemaForStructType returns the inline schema of an anonymous struct or of the root type, and a "$ref" node for
+// other named structs (building the definition on first use).
 func (b *schemaBuilder) schemaForStructType(t reflect.Type, depth int) (map[string]any, error) {
+	switch {
+	case t.Name() == "":
+		return b.structSchema(t, depth)
+	case t == b.root:
+		if b.rootStarted {
+			return map[string]any{"$ref": "#"}, nil
+		}
+		b.rootStarted = true
+		return b.structSchema(t, depth)
+	}
+	if ref, ok := b.refs[t]; ok {
+		return map[string]any{"$ref": ref}, nil
+	}
+	name := b.defName(t)
+	ref := "#/$defs/" + name
+	b.refs[t] = ref
+	b.defTypes[name] = t
+	schema, err := b.structSchema(t, 0)
+	if err != nil {
+		return nil, err
+	}
+	b.defs[name] = schema
+	return map[string]any{"$ref": ref}, nil
+}
+
+// defName returns a unique $defs name for t: the type name with characters outside [A-Za-z0-9_.-] replaced
+// (generic instantiations), suffixed with a number when another type already uses it.
+func (b *schemaBuilder) defName(t reflect.Type) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, t.Name())
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := b.defTypes[name]; !taken {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type, depth int) (map[string]any, error) {
 	if depth >= maxSchemaDepth || b.active[t] > 0 {
 		return terminalObjectSchema(), nil
 	}
@@ -163,6 +271,11 @@ func (b *schemaBuilder) collectStructFields(
 		if err != nil {
 			return nil, fmt.Errorf("schema: field %s.%s: %w", t, field.Name, err)
 		}
+		if tag, ok := field.Tag.Lookup("jsonschema"); ok {
+			if err := applySchemaTag(schema, tag); err != nil {
+				return nil, fmt.Errorf("schema: field %s.%s: %w", t, field.Name, err)
+			}
+		}
 
 		candidates = append(candidates, schemaFieldCandidate{
 			name:     fieldName,
@@ -176,6 +289,104 @@ func (b *schemaBuilder) collectStructFields(
 	return candidates, nil
 }
 
+// finish inlines definitions referenced once that are not recursive and attaches the rest to schema as "$defs".
+func (b *schemaBuilder) finish(schema map[string]any) map[string]any {
+	if len(b.defs) == 0 {
+		return schema
+	}
+	counts := make(map[string]int)
+	edges := make(map[string][]string)
+	walkSchemaDefRefs(schema, func(name string) { counts[name]++ })
+	for from, def := range b.defs {
+		walkSchemaDefRefs(def, func(name string) {
+			counts[name]++
+			edges[from] = append(edges[from], name)
+		})
+	}
+	keep := make(map[string]bool)
+	for name := range b.defs {
+		if counts[name] > 1 || reachesSchemaDef(edges, name, name, make(map[string]bool)) {
+			keep[name] = true
+		}
+	}
+	schema = b.inlineDefs(schema, keep).(map[string]any)
+	if len(keep) > 0 {
+		defs := make(map[string]any, len(keep))
+		for name := range keep {
+			defs[name] = b.inlineDefs(b.defs[name], keep)
+		}
+		schema["$defs"] = defs
+	}
+	return schema
+}
+
+// inlineDefs replaces "$ref" nodes that point to definitions not in keep with the definition (annotations on the
+// "$ref" node win).
+func (b *schemaBuilder) inlineDefs(node any, keep map[string]bool) any {
+	switch x := node.(type) {
+	case map[string]any:
+		if name, ok := schemaDefRef(x); ok && !keep[name] {
+			merged := cloneMapAny(b.defs[name])
+			for key, value := range x {
+				if key != "$ref" {
+					merged[key] = value
+				}
+			}
+			x = merged
+		}
+		for key, value := range x {
+			x[key] = b.inlineDefs(value, keep)
+		}
+		return x
+	case []any:
+		for i, item := range x {
+			x[i] = b.inlineDefs(item, keep)
+		}
+	}
+	return node
+}
+
+// walkSchemaDefRefs calls fn with the definition name of every "#/$defs/{name}" reference in node.
+func walkSchemaDefRefs(node any, fn func(name string)) {
+	switch x := node.(type) {
+	case map[string]any:
+		if name, ok := schemaDefRef(x); ok {
+			fn(name)
+		}
+		for _, value := range x {
+			walkSchemaDefRefs(value, fn)
+		}
+	case []any:
+		for _, item := range x {
+			walkSchemaDefRefs(item, fn)
+		}
+	}
+}
+
+func schemaDefRef(node map[string]any) (string, bool) {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return "", false
+	}
+	return strings.CutPrefix(ref, "#/$defs/")
+}
+
+// reachesSchemaDef reports whether target is reachable from the definition from.
+func reachesSchemaDef(edges map[string][]string, from, target string, seen map[string]bool) bool {
+	for _, next := range edges[from] {
+		if next == target {
+			return true
+		}
+		if !seen[next] {
+			seen[next] = true
+			if reachesSchemaDef(edges, next, target, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (b *schemaBuilder) nextOrder() int {
 	order := b.order
 	b.order++
@@ -296,6 +507,21 @@ func indirectSchemaType(t reflect.Type) reflect.Type {
 	return t
 }
 
+// isTextMarshaler reports whether t or *t implements encoding.TextMarshaler (encoded as a JSON string).
+func isTextMarshaler(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
 func isPointerType(t reflect.Type) bool {
 	return t != nil && t.Kind() == reflect.Pointer
 }
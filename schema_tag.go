@@ -0,0 +1,132 @@
+package prompty
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isSchemaTagItemKey reports jsonschema tag keys that describe array elements: on a slice or array field they
+// apply to "items" instead of the array node.
+func isSchemaTagItemKey(key string) bool {
+	switch key {
+	case "enum", "default", "format", "pattern", "minLength", "maxLength",
+		"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+		return true
+	default:
+		return false
+	}
+}
+
+// applySchemaTag annotates schema with a `jsonschema:"..."` struct tag: comma-separated key=value pairs
+// (escape a literal comma as `\,`), enum values separated by "|". Enum and default values are typed after the
+// node's "type" (integer, number, boolean, else string).
+func applySchemaTag(schema map[string]any, tag string) error {
+	for _, part := range splitSchemaTag(tag) {
+		key, value, hasValue := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		node := schema
+		if items, ok := schema["items"].(map[string]any); ok && schema["type"] == "array" && isSchemaTagItemKey(key) {
+			node = items
+		}
+		if err := applySchemaTagKey(node, key, value, hasValue); err != nil {
+			return fmt.Errorf("jsonschema tag %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func applySchemaTagKey(node map[string]any, key, value string, hasValue bool) error {
+	switch key {
+	case "uniqueItems", "deprecated", "readOnly", "writeOnly":
+		flag := true
+		if hasValue {
+			var err error
+			if flag, err = strconv.ParseBool(value); err != nil {
+				return err
+			}
+		}
+		node[key] = flag
+		return nil
+	}
+	if !hasValue {
+		return errors.New("missing value")
+	}
+	switch key {
+	case "title", "description", "format", "pattern":
+		node[key] = value
+	case "enum":
+		values := make([]any, 0, strings.Count(value, "|")+1)
+		for item := range strings.SplitSeq(value, "|") {
+			v, err := schemaTagValue(node, item)
+			if err != nil {
+				return err
+			}
+			values = append(values, v)
+		}
+		node[key] = values
+	case "default":
+		v, err := schemaTagValue(node, value)
+		if err != nil {
+			return err
+		}
+		node[key] = v
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		node[key] = f
+	case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return errors.New("must not be negative")
+		}
+		node[key] = n
+	default:
+		return errors.New("unknown key")
+	}
+	return nil
+}
+
+// schemaTagValue converts an enum or default value to the JSON type of node.
+func schemaTagValue(node map[string]any, value string) (any, error) {
+	switch node["type"] {
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
+
+// splitSchemaTag splits a jsonschema tag on commas, honoring `\,` escapes.
+func splitSchemaTag(tag string) []string {
+	var (
+		parts []string
+		cur   strings.Builder
+	)
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(tag[i])
+		}
+	}
+	return append(parts, cur.String())
+}
@@ -2,9 +2,13 @@ package prompty
 
 import (
 	"encoding/json"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -119,21 +123,83 @@ func TestExtractSchema_NilPointerSchemaProviderDoesNotPanic(t *testing.T) {
 	})
 }
 
-func TestExtractSchema_RecursiveStructTerminates(t *testing.T) {
+func TestExtractSchema_RecursiveRootUsesSelfRef(t *testing.T) {
 	t.Parallel()
 
 	type node struct {
-		Value int   `json:"value"`
-		Next  *node `json:"next,omitempty"`
+		Value    int    `json:"value"`
+		Next     *node  `json:"next,omitempty"`
+		Children []node `json:"children"`
 	}
 
 	schema := ExtractSchema(node{})
 	require.NotNil(t, schema)
 	props := schema["properties"].(map[string]any)
-	next := props["next"].(map[string]any)
-	assert.Equal(t, "object", next["type"])
-	assert.Equal(t, false, next["additionalProperties"])
-	assert.Empty(t, next["properties"])
+	assert.Equal(t, map[string]any{"$ref": "#"}, props["next"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#"}}, props["children"])
+	assert.NotContains(t, schema, "$defs")
+}
+
+type schemaTreeNode struct {
+	Label    string            `json:"label"`
+	Children []*schemaTreeNode `json:"children,omitempty"`
+}
+
+type schemaAddress struct {
+	City string `json:"city"`
+}
+
+type schemaPage[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestExtractSchema_DefsForRecursiveAndSharedTypes(t *testing.T) {
+	t.Parallel()
+
+	type order struct {
+		Tree     schemaTreeNode             `json:"tree"`
+		Billing  schemaAddress              `json:"billing"`
+		Shipping *schemaAddress             `json:"shipping,omitempty" jsonschema:"description=Defaults to billing"`
+		Page     schemaPage[schemaTreeNode] `json:"page"`
+		Once     struct {
+			N int `json:"n"`
+		} `json:"once"`
+	}
+
+	schema := ExtractSchema(order{})
+	require.NotNil(t, schema)
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/schemaTreeNode"}, props["tree"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/schemaAddress"}, props["billing"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/schemaAddress", "description": "Defaults to billing"},
+		props["shipping"])
+	page := props["page"].(map[string]any)
+	assert.Equal(t, "object", page["type"], "types used once are inlined")
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/schemaTreeNode"},
+		page["properties"].(map[string]any)["items"].(map[string]any)["items"])
+	assert.Equal(t, "object", props["once"].(map[string]any)["type"])
+
+	defs := schema["$defs"].(map[string]any)
+	require.Len(t, defs, 2)
+	tree := defs["schemaTreeNode"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/schemaTreeNode"}},
+		tree["properties"].(map[string]any)["children"])
+	assert.Equal(t, []string{"city"}, defs["schemaAddress"].(map[string]any)["required"])
+
+	list := ExtractSchema([]schemaTreeNode{})
+	require.NotNil(t, list)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/schemaTreeNode"}, list["items"])
+	assert.Contains(t, list["$defs"], "schemaTreeNode")
+}
+
+func TestSchemaBuilder_DefNameIsUniqueAndSafe(t *testing.T) {
+	t.Parallel()
+
+	b := newSchemaBuilder()
+	name := b.defName(reflect.TypeFor[schemaPage[schemaAddress]]())
+	assert.Regexp(t, `^[A-Za-z0-9_.-]+$`, name)
+	b.defTypes[name] = reflect.TypeFor[schemaPage[schemaAddress]]()
+	assert.Equal(t, name+"2", b.defName(reflect.TypeFor[schemaPage[schemaAddress]]()))
 }
 
 func TestExtractSchema_JSONTagDashCommaUsesLiteralDashName(t *testing.T) {
@@ -165,6 +231,116 @@ func TestExtractSchema_ByteSliceUsesStringSchema(t *testing.T) {
 	assert.Equal(t, "string", props["data"].(map[string]any)["type"])
 }
 
+type schemaTestLevel int
+
+func (l schemaTestLevel) MarshalText() ([]byte, error) { return []byte(strconv.Itoa(int(l))), nil }
+
+func TestExtractSchema_JSONSchemaTags(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Status string   `json:"status" jsonschema:"title=Status,description=Ticket state\\, lowercase,enum=open|closed,default=open"`
+		Score  int      `json:"score" jsonschema:"minimum=0,maximum=100,enum=1|2|3"`
+		Ratio  *float64 `json:"ratio,omitempty" jsonschema:"exclusiveMinimum=0,multipleOf=0.5,default=1.5"`
+		Email  string   `json:"email" jsonschema:"format=email,pattern=^[^@]+@[^@]+$,minLength=3,maxLength=200"`
+		Tags   []string `json:"tags" jsonschema:"description=Labels,minItems=1,maxItems=5,uniqueItems,enum=a|b"`
+		Legacy bool     `json:"legacy" jsonschema:"deprecated,default=false"`
+	}
+
+	schema := ExtractSchema(payload{})
+	require.NotNil(t, schema)
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"type": "string", "title": "Status", "description": "Ticket state, lowercase",
+		"enum": []any{"open", "closed"}, "default": "open",
+	}, props["status"])
+	assert.Equal(t, map[string]any{
+		"type": "integer", "minimum": 0.0, "maximum": 100.0, "enum": []any{int64(1), int64(2), int64(3)},
+	}, props["score"])
+	assert.Equal(t, map[string]any{
+		"type": "number", "exclusiveMinimum": 0.0, "multipleOf": 0.5, "default": 1.5,
+	}, props["ratio"])
+	assert.Equal(t, map[string]any{
+		"type": "string", "format": "email", "pattern": "^[^@]+@[^@]+$", "minLength": 3, "maxLength": 200,
+	}, props["email"])
+	assert.Equal(t, map[string]any{
+		"type": "array", "description": "Labels", "minItems": 1, "maxItems": 5, "uniqueItems": true,
+		"items": map[string]any{"type": "string", "enum": []any{"a", "b"}},
+	}, props["tags"])
+	assert.Equal(t, map[string]any{"type": "boolean", "deprecated": true, "default": false}, props["legacy"])
+
+	out, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"enum":[1,2,3]`)
+}
+
+func TestExtractSchema_JSONSchemaTagErrors(t *testing.T) {
+	t.Parallel()
+
+	type unknownKey struct {
+		V string `json:"v" jsonschema:"colour=red"`
+	}
+	type badNumber struct {
+		V int `json:"v" jsonschema:"minimum=low"`
+	}
+	type badEnum struct {
+		V int `json:"v" jsonschema:"enum=1|two"`
+	}
+	type missingValue struct {
+		V string `json:"v" jsonschema:"description"`
+	}
+	for name, v := range map[string]any{
+		"unknown key": unknownKey{}, "bad number": badNumber{}, "bad enum": badEnum{}, "missing value": missingValue{},
+	} {
+		assert.Nil(t, ExtractSchema(v), name)
+	}
+	_, err := extractSchema(badEnum{})
+	require.ErrorContains(t, err, `field prompty.badEnum.V: jsonschema tag "enum"`)
+}
+
+func TestExtractSchema_WellKnownTypes(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		N int `json:"n"`
+	}
+	type payload struct {
+		At      time.Time               `json:"at"`
+		Raw     json.RawMessage         `json:"raw"`
+		Any     any                     `json:"any"`
+		Labels  map[string]string       `json:"labels"`
+		Items   map[string]item         `json:"items"`
+		ByID    map[int64]bool          `json:"by_id"`
+		ByLevel map[schemaTestLevel]int `json:"by_level"`
+		Level   schemaTestLevel         `json:"level" jsonschema:"enum=1|2"`
+		IP      *net.IP                 `json:"ip,omitempty" jsonschema:"format=ipv4"`
+	}
+
+	schema := ExtractSchema(payload{})
+	require.NotNil(t, schema)
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, props["at"])
+	assert.Equal(t, map[string]any{}, props["raw"])
+	assert.Equal(t, map[string]any{}, props["any"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+		props["labels"])
+	items := props["items"].(map[string]any)["additionalProperties"].(map[string]any)
+	assert.Equal(t, []string{"n"}, items["required"])
+	assert.Equal(t, map[string]any{
+		"type": "object", "additionalProperties": map[string]any{"type": "boolean"},
+		"propertyNames": map[string]any{"pattern": "^-?[0-9]+$"},
+	}, props["by_id"])
+	assert.NotContains(t, props["by_level"], "propertyNames")
+	assert.Equal(t, map[string]any{"type": "string", "enum": []any{"1", "2"}}, props["level"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "ipv4"}, props["ip"])
+
+	type badKey struct {
+		M map[float64]string `json:"m"`
+	}
+	_, err := extractSchema(badKey{})
+	require.ErrorContains(t, err, "unsupported map key type float64")
+}
+
 func loadDualSchemaFixture(t *testing.T) map[string]any {
 	t.Helper()
 
@@ -0,0 +1,586 @@
+package prompty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaRefDepth bounds nested "$ref" resolution without consuming input (e.g. a schema {"$ref": "#"}).
+const maxSchemaRefDepth = 64
+
+// SchemaViolation is one JSON Schema validation failure.
+type SchemaViolation struct {
+	Path    string // JSON path of the offending value, e.g. "$.items[2].price"
+	Message string // e.g. "must be >= 0, got -1"
+}
+
+func violationf(path, format string, args ...any) SchemaViolation {
+	return SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// String returns "path: message".
+func (v SchemaViolation) String() string { return v.Path + ": " + v.Message }
+
+// ValidateJSON validates the JSON document data against schema (e.g. ResponseFormat.Schema or
+// ToolDefinition.Parameters). It returns the json syntax error when data is not valid JSON, and a *SchemaError
+// listing every violation with its JSON path when it does not match. A schema that cannot be applied (a pattern
+// that is not valid RE2, an unresolved $ref) fails with an error matching ErrInvalidSchema instead.
+//
+// Supported keywords (draft 2020-12 subset): type, enum, const, required, properties, patternProperties,
+// additionalProperties, propertyNames, minProperties, maxProperties, items, prefixItems, minItems, maxItems,
+// uniqueItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf,
+// allOf, anyOf, oneOf, not, and "$ref" to a JSON pointer in the same schema ("#", "#/$defs/{name}").
+// Annotations and other keywords (format, description, default, ...) are ignored.
+func ValidateJSON(schema map[string]any, data []byte) error {
+	doc, err := decodeJSONDocument(data)
+	if err != nil {
+		return err
+	}
+	return validateJSONValue(schema, doc)
+}
+
+// decodeJSONDocument decodes data with json.Number for numbers (exact integer and multipleOf checks).
+func decodeJSONDocument(data []byte) (any, error) {
+	var doc any
+	if !json.Valid(data) {
+		return nil, json.Unmarshal(data, &doc)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// validateJSONValue validates a decoded document; nil when it matches (or schema is nil).
+func validateJSONValue(schema map[string]any, doc any) error {
+	return validateJSONSubschema(schema, schema, doc, "$")
+}
+
+// validateJSONSubschema validates doc, found at path, against schema, a subschema of root ("$ref"s resolve
+// against root); nil when it matches (or root is nil).
+func validateJSONSubschema(root map[string]any, schema any, doc any, path string) error {
+	if root == nil {
+		return nil
+	}
+	v := &schemaValidator{root: root, patterns: make(map[string]*regexp.Regexp), invalid: nil}
+	violations := v.validate(schema, doc, path, 0)
+	if v.invalid != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSchema, v.invalid)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &SchemaError{Violations: violations}
+}
+
+type schemaValidator struct {
+	root     map[string]any
+	patterns map[string]*regexp.Regexp
+	invalid  error // first schema defect found; reported instead of the violations
+}
+
+// schemaDefect records a defect of the schema itself (kept once: the first one found).
+func (v *schemaValidator) schemaDefect(err error) {
+	if v.invalid == nil {
+		v.invalid = err
+	}
+}
+
+func (v *schemaValidator) validate(schema any, value any, path string, refDepth int) []SchemaViolation {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []SchemaViolation{{Path: path, Message: "no value is allowed here"}}
+		}
+		return nil
+	case map[string]any:
+		return v.validateNode(s, value, path, refDepth)
+	default:
+		return nil
+	}
+}
+
+func (v *schemaValidator) validateNode(s map[string]any, value any, path string, refDepth int) []SchemaViolation {
+	var out []SchemaViolation
+	if ref, ok := s["$ref"].(string); ok {
+		target, found := resolveSchemaPointer(v.root, ref)
+		switch {
+		case !found:
+			v.schemaDefect(fmt.Errorf("%s: unresolved $ref %q", path, ref))
+			return nil
+		case refDepth >= maxSchemaRefDepth:
+			v.schemaDefect(fmt.Errorf("%s: $ref %q nests too deeply", path, ref))
+			return nil
+		}
+		out = append(out, v.validate(target, value, path, refDepth+1)...)
+	}
+	if t, ok := s["type"]; ok && !matchesSchemaType(t, value) {
+		return append(out, SchemaViolation{
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", describeSchemaType(t), jsonTypeName(value)),
+		})
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, value) }) {
+		out = append(out, SchemaViolation{
+			Path:    path,
+			Message: fmt.Sprintf("must be one of %s, got %s", formatJSONList(enum), formatJSONValue(value)),
+		})
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, value) {
+		out = append(out, SchemaViolation{
+			Path:    path,
+			Message: fmt.Sprintf("must be %s, got %s", formatJSONValue(c), formatJSONValue(value)),
+		})
+	}
+	switch x := value.(type) {
+	case map[string]any:
+		out = append(out, v.validateObject(s, x, path)...)
+	case []any:
+		out = append(out, v.validateArray(s, x, path)...)
+	case string:
+		out = append(out, v.validateString(s, x, path)...)
+	case json.Number, float64:
+		out = append(out, validateNumber(s, value, path)...)
+	}
+	return append(out, v.validateCombinators(s, value, path, refDepth)...)
+}
+
+// validateObject checks obj and its properties; children start a new $ref chain (they consume input).
+func (v *schemaValidator) validateObject(s map[string]any, obj map[string]any, path string) []SchemaViolation {
+	var out []SchemaViolation
+	for _, name := range schemaStrings(s["required"]) {
+		if _, ok := obj[name]; !ok {
+			out = append(out, violationf(path, "missing required property %q", name))
+		}
+	}
+	props, _ := s["properties"].(map[string]any)
+	patternProps, _ := s["patternProperties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		child := childJSONPath(path, key)
+		matched := false
+		if sub, ok := props[key]; ok {
+			matched = true
+			out = append(out, v.validate(sub, obj[key], child, 0)...)
+		}
+		for pattern, sub := range patternProps {
+			re, err := v.pattern(pattern)
+			if err != nil {
+				v.schemaDefect(err)
+				continue
+			}
+			if re.MatchString(key) {
+				matched = true
+				out = append(out, v.validate(sub, obj[key], child, 0)...)
+			}
+		}
+		if names, ok := s["propertyNames"]; ok {
+			for _, violation := range v.validate(names, key, child, 0) {
+				violation.Message = "property name: " + violation.Message
+				out = append(out, violation)
+			}
+		}
+		if additional, ok := s["additionalProperties"]; ok && !matched {
+			if allowed, isBool := additional.(bool); isBool && !allowed {
+				out = append(out, violationf(path, "unexpected property %q", key))
+				continue
+			}
+			out = append(out, v.validate(additional, obj[key], child, 0)...)
+		}
+	}
+	if n, ok := schemaInt(s["minProperties"]); ok && len(obj) < n {
+		out = append(out, violationf(path, "must have at least %d properties, got %d", n, len(obj)))
+	}
+	if n, ok := schemaInt(s["maxProperties"]); ok && len(obj) > n {
+		out = append(out, violationf(path, "must have at most %d properties, got %d", n, len(obj)))
+	}
+	return out
+}
+
+// validateArray checks arr and its items; like properties, items start a new $ref chain.
+func (v *schemaValidator) validateArray(s map[string]any, arr []any, path string) []SchemaViolation {
+	var out []SchemaViolation
+	prefix, _ := s["prefixItems"].([]any)
+	for i, item := range arr {
+		child := path + "[" + strconv.Itoa(i) + "]"
+		if i < len(prefix) {
+			out = append(out, v.validate(prefix[i], item, child, 0)...)
+		} else if items, ok := s["items"]; ok {
+			out = append(out, v.validate(items, item, child, 0)...)
+		}
+	}
+	if n, ok := schemaInt(s["minItems"]); ok && len(arr) < n {
+		out = append(out, violationf(path, "must have at least %d items, got %d", n, len(arr)))
+	}
+	if n, ok := schemaInt(s["maxItems"]); ok && len(arr) > n {
+		out = append(out, violationf(path, "must have at most %d items, got %d", n, len(arr)))
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	duplicates:
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					out = append(out, SchemaViolation{
+						Path:    path,
+						Message: fmt.Sprintf("items must be unique, [%d] and [%d] are equal", i, j),
+					})
+					break duplicates
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (v *schemaValidator) validateString(s map[string]any, str string, path string) []SchemaViolation {
+	var out []SchemaViolation
+	length := utf8.RuneCountInString(str)
+	if n, ok := schemaInt(s["minLength"]); ok && length < n {
+		out = append(out, violationf(path, "must be at least %d characters, got %d", n, length))
+	}
+	if n, ok := schemaInt(s["maxLength"]); ok && length > n {
+		out = append(out, violationf(path, "must be at most %d characters, got %d", n, length))
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := v.pattern(pattern)
+		switch {
+		case err != nil:
+			v.schemaDefect(err)
+		case !re.MatchString(str):
+			out = append(out, violationf(path, "must match pattern %q", pattern))
+		}
+	}
+	return out
+}
+
+func validateNumber(s map[string]any, value any, path string) []SchemaViolation {
+	var out []SchemaViolation
+	n, _ := schemaNumber(value)
+	got := formatJSONValue(value)
+	if limit, ok := schemaNumber(s["minimum"]); ok && n < limit {
+		out = append(out, violationf(path, "must be >= %s, got %s", formatNumber(limit), got))
+	}
+	if limit, ok := schemaNumber(s["maximum"]); ok && n > limit {
+		out = append(out, violationf(path, "must be <= %s, got %s", formatNumber(limit), got))
+	}
+	if limit, ok := schemaNumber(s["exclusiveMinimum"]); ok && n <= limit {
+		out = append(out, violationf(path, "must be > %s, got %s", formatNumber(limit), got))
+	}
+	if limit, ok := schemaNumber(s["exclusiveMaximum"]); ok && n >= limit {
+		out = append(out, violationf(path, "must be < %s, got %s", formatNumber(limit), got))
+	}
+	if m, ok := schemaNumber(s["multipleOf"]); ok && m > 0 {
+		q, okValue := jsonRat(value)
+		d, okDivisor := jsonRat(m)
+		if okValue && okDivisor && !new(big.Rat).Quo(q, d).IsInt() {
+			out = append(out, violationf(path, "must be a multiple of %s, got %s", formatNumber(m), got))
+		}
+	}
+	return out
+}
+
+func (v *schemaValidator) validateCombinators(
+	s map[string]any,
+	value any,
+	path string,
+	refDepth int,
+) []SchemaViolation {
+	var out []SchemaViolation
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			out = append(out, v.validate(sub, value, path, refDepth)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		if failures, matched := v.branches(anyOf, value, path, refDepth); matched == 0 {
+			out = append(out, noBranchMatched("anyOf", failures, path)...)
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		failures, matched := v.branches(oneOf, value, path, refDepth)
+		switch {
+		case matched == 0:
+			out = append(out, noBranchMatched("oneOf", failures, path)...)
+		case matched > 1:
+			out = append(out, SchemaViolation{
+				Path:    path,
+				Message: fmt.Sprintf("must match exactly one schema in oneOf, matched %d", matched),
+			})
+		}
+	}
+	if not, ok := s["not"]; ok && len(v.validate(not, value, path, refDepth)) == 0 {
+		out = append(out, SchemaViolation{Path: path, Message: "must not match the schema in not"})
+	}
+	return out
+}
+
+// branches validates value against each subschema; it returns the violations of the failing ones and the number
+// that matched.
+func (v *schemaValidator) branches(subs []any, value any, path string, refDepth int) ([][]SchemaViolation, int) {
+	var failures [][]SchemaViolation
+	matched := 0
+	for _, sub := range subs {
+		if violations := v.validate(sub, value, path, refDepth); len(violations) > 0 {
+			failures = append(failures, violations)
+		} else {
+			matched++
+		}
+	}
+	return failures, matched
+}
+
+// noBranchMatched reports a failed anyOf/oneOf. When exactly one branch accepts the value's type (e.g. the
+// non-null side of a nullable field), its violations are reported instead, as they say what to fix.
+func noBranchMatched(keyword string, failures [][]SchemaViolation, path string) []SchemaViolation {
+	var typed [][]SchemaViolation
+	for _, violations := range failures {
+		first := violations[0]
+		if len(violations) == 1 && first.Path == path && strings.HasPrefix(first.Message, "expected ") {
+			continue
+		}
+		typed = append(typed, violations)
+	}
+	if len(typed) == 1 {
+		return typed[0]
+	}
+	return []SchemaViolation{{Path: path, Message: "must match at least one schema in " + keyword}}
+}
+
+func (v *schemaValidator) pattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	v.patterns[pattern] = re
+	return re, nil
+}
+
+// resolveSchemaPointer resolves a "$ref" JSON pointer ("#", "#/$defs/node") against root.
+func resolveSchemaPointer(root map[string]any, ref string) (any, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	var node any = root
+	if pointer == "" {
+		return node, true
+	}
+	for token := range strings.SplitSeq(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch x := node.(type) {
+		case map[string]any:
+			if node, ok = x[token]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			node = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+func childJSONPath(path, key string) string {
+	for i, r := range key {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (i == 0 || r < '0' || r > '9') {
+			return path + "[" + strconv.Quote(key) + "]"
+		}
+	}
+	if key == "" {
+		return path + `[""]`
+	}
+	return path + "." + key
+}
+
+// matchesSchemaType reports whether value has the JSON type t (a name or a list of names).
+func matchesSchemaType(t any, value any) bool {
+	names := schemaStrings(t)
+	if name, ok := t.(string); ok {
+		names = []string{name}
+	}
+	for _, name := range names {
+		switch name {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := schemaNumber(value); ok {
+				return true
+			}
+		case "integer":
+			if r, ok := jsonRat(value); ok && r.IsInt() {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		}
+	}
+	return len(names) == 0
+}
+
+func describeSchemaType(t any) string {
+	if name, ok := t.(string); ok {
+		return name
+	}
+	return strings.Join(schemaStrings(t), " or ")
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	if r, ok := jsonRat(value); ok && r.IsInt() {
+		return "integer"
+	}
+	return "number"
+}
+
+// jsonEqual compares decoded JSON values; numbers compare by value (1 == 1.0).
+func jsonEqual(a, b any) bool {
+	if ra, ok := jsonRat(a); ok {
+		rb, ok := jsonRat(b)
+		return ok && ra.Cmp(rb) == 0
+	}
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, jsonEqual)
+	case []string:
+		y, ok := b.([]string)
+		return ok && slices.Equal(x, y)
+	default:
+		return a == b
+	}
+}
+
+// jsonRat returns a number from a decoded document or a schema as an exact rational.
+func jsonRat(value any) (*big.Rat, bool) {
+	var s string
+	switch x := value.(type) {
+	case json.Number:
+		s = x.String()
+	case float64:
+		s = strconv.FormatFloat(x, 'g', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s = fmt.Sprint(x)
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+func schemaNumber(value any) (float64, bool) {
+	r, ok := jsonRat(value)
+	if !ok {
+		return 0, false
+	}
+	f, _ := r.Float64()
+	return f, true
+}
+
+func schemaInt(value any) (int, bool) {
+	r, ok := jsonRat(value)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return int(r.Num().Int64()), true
+}
+
+// schemaStrings returns a []string or []any of strings (e.g. "required", a type list).
+func schemaStrings(value any) []string {
+	switch x := value.(type) {
+	case []string:
+		return x
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func formatNumber(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+func formatJSONValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	const maxLen = 80
+	if len(data) > maxLen {
+		return strings.ToValidUTF8(string(data[:maxLen]), "") + "…"
+	}
+	return string(data)
+}
+
+func formatJSONList(values []any) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = formatJSONValue(value)
+	}
+	return strings.Join(parts, ", ")
+}
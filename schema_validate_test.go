@@ -0,0 +1,157 @@
+package prompty
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validationMessages(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.ErrorIs(t, err, ErrSchemaViolation)
+	out := make([]string, len(schemaErr.Violations))
+	for i, v := range schemaErr.Violations {
+		out[i] = v.String()
+	}
+	return out
+}
+
+func TestValidateJSON(t *testing.T) {
+	t.Parallel()
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"status": {"type": "string", "enum": ["open", "closed"]},
+			"email": {"type": "string", "pattern": "^[^@]+@[^@]+$", "maxLength": 20},
+			"price": {"type": "number", "exclusiveMinimum": 0, "multipleOf": 0.01},
+			"tags": {"type": "array", "items": {"type": "string", "minLength": 2}, "maxItems": 3, "uniqueItems": true},
+			"note": {"type": ["string", "null"]},
+			"owner": {"anyOf": [{"$ref": "#/$defs/user"}, {"type": "null"}]},
+			"contact": {"oneOf": [
+				{"type": "object", "properties": {"phone": {"type": "string"}}, "required": ["phone"]},
+				{"type": "object", "properties": {"mail": {"type": "string"}}, "required": ["mail"]}
+			]},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}, "propertyNames": {"pattern": "^[a-z]+$"}}
+		},
+		"required": ["id", "status"],
+		"additionalProperties": false,
+		"$defs": {
+			"user": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}
+		}
+	}`), &schema))
+
+	for name, tc := range map[string]struct {
+		doc  string
+		want []string
+	}{
+		"valid": {
+			doc: `{"id": 1, "status": "open", "email": "a@b.c", "price": 19.99, "tags": ["ab", "cd"], "note": null,
+				"owner": {"name": "Ann"}, "contact": {"phone": "1"}, "labels": {"env": "prod"}}`,
+		},
+		"required and additional": {
+			doc:  `{"extra": true}`,
+			want: []string{`$: missing required property "id"`, `$: missing required property "status"`, `$: unexpected property "extra"`},
+		},
+		"types": {
+			doc:  `{"id": 1.5, "status": 3, "note": 1}`,
+			want: []string{"$.id: expected integer, got number", "$.note: expected string or null, got integer", "$.status: expected string, got integer"},
+		},
+		"enum and bounds": {
+			doc:  `{"id": 0, "status": "done", "price": 0.005, "email": "nope"}`,
+			want: []string{`$.email: must match pattern "^[^@]+@[^@]+$"`, "$.id: must be >= 1, got 0", "$.price: must be a multiple of 0.01, got 0.005", `$.status: must be one of "open", "closed", got "done"`},
+		},
+		"arrays": {
+			doc:  `{"id": 1, "status": "open", "tags": ["a", "bb", "bb", "cc"]}`,
+			want: []string{"$.tags[0]: must be at least 2 characters, got 1", "$.tags: must have at most 3 items, got 4", "$.tags: items must be unique, [1] and [2] are equal"},
+		},
+		"ref through nullable anyOf": {
+			doc:  `{"id": 1, "status": "open", "owner": {}}`,
+			want: []string{`$.owner: missing required property "name"`},
+		},
+		"oneOf": {
+			doc:  `{"id": 1, "status": "open", "contact": {"phone": "1", "mail": "x"}}`,
+			want: []string{"$.contact: must match exactly one schema in oneOf, matched 2"},
+		},
+		"map values and names": {
+			doc:  `{"id": 1, "status": "open", "labels": {"Env": "prod", "team a": 1}}`,
+			want: []string{`$.labels.Env: property name: must match pattern "^[a-z]+$"`, `$.labels["team a"]: expected string, got integer`, `$.labels["team a"]: property name: must match pattern "^[a-z]+$"`},
+		},
+	} {
+		err := ValidateJSON(schema, []byte(tc.doc))
+		got := validationMessages(t, err)
+		// ElementsMatch: properties are checked in key order, required first
+		assert.ElementsMatch(t, tc.want, got, name)
+	}
+
+	var syntaxErr *json.SyntaxError
+	require.ErrorAs(t, ValidateJSON(schema, []byte(`{"id":`)), &syntaxErr)
+	assert.NoError(t, ValidateJSON(nil, []byte(`[1]`)))
+}
+
+func TestValidateJSON_ReflectedSchemas(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		SKU string  `json:"sku" jsonschema:"pattern=^[A-Z]{3}-[0-9]+$"`
+		Qty int     `json:"qty" jsonschema:"minimum=1"`
+		Sub []*item `json:"sub,omitempty"`
+	}
+	type order struct {
+		Items []item `json:"items" jsonschema:"minItems=1"`
+	}
+	schema := ExtractSchema(order{})
+	require.NoError(t, ValidateJSON(schema, []byte(`{"items":[{"sku":"ABC-1","qty":2,"sub":[{"sku":"XYZ-2","qty":1}]}]}`)))
+	err := ValidateJSON(schema, []byte(`{"items":[{"sku":"ABC-1","qty":2,"sub":[{"sku":"bad","qty":0}]}]}`))
+	assert.Equal(t, []string{
+		`$.items[0].sub[0].qty: must be >= 1, got 0`,
+		`$.items[0].sub[0].sku: must match pattern "^[A-Z]{3}-[0-9]+$"`,
+	}, validationMessages(t, err))
+	assert.EqualError(t, err, `prompty: JSON does not match schema: $.items[0].sub[0].qty: must be >= 1, got 0; `+
+		`$.items[0].sub[0].sku: must match pattern "^[A-Z]{3}-[0-9]+$"`)
+}
+
+func TestValidateJSON_SchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	// Schema defects are reported once as ErrInvalidSchema, never as violations of the document.
+	err := ValidateJSON(map[string]any{"$ref": "#/$defs/missing"}, []byte(`1`))
+	require.ErrorIs(t, err, ErrInvalidSchema)
+	assert.EqualError(t, err, `prompty: invalid JSON Schema: $: unresolved $ref "#/$defs/missing"`)
+	err = ValidateJSON(map[string]any{"$ref": "#"}, []byte(`1`))
+	require.ErrorIs(t, err, ErrInvalidSchema)
+	assert.Contains(t, err.Error(), `$ref "#" nests too deeply`)
+	schema := map[string]any{
+		"type":              "object",
+		"properties":        map[string]any{"a": map[string]any{"pattern": "("}, "b": map[string]any{"pattern": "(?=x)"}},
+		"patternProperties": map[string]any{"[": true},
+		"required":          []any{"c"},
+	}
+	err = ValidateJSON(schema, []byte(`{"a": "x", "b": "y"}`))
+	require.ErrorIs(t, err, ErrInvalidSchema)
+	require.NotErrorIs(t, err, ErrSchemaViolation)
+	assert.Contains(t, err.Error(), "invalid pattern")
+
+	// A recursive schema may nest $ref as deep as the document does.
+	tree := map[string]any{
+		"$defs": map[string]any{"node": map[string]any{
+			"type": "object", "properties": map[string]any{"child": map[string]any{"$ref": "#/$defs/node"}},
+		}},
+		"$ref": "#/$defs/node",
+	}
+	deep := strings.Repeat(`{"child":`, maxSchemaRefDepth+1) + "{}" + strings.Repeat("}", maxSchemaRefDepth+1)
+	require.NoError(t, ValidateJSON(tree, []byte(deep)))
+	err = ValidateJSON(map[string]any{"not": map[string]any{"type": "string"}, "properties": map[string]any{"a": false}},
+		[]byte(`{"a": 1}`))
+	assert.Equal(t, []string{"$.a: no value is allowed here"}, validationMessages(t, err))
+}
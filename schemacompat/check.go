@@ -0,0 +1,287 @@
+package schemacompat
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Issue is one schema feature a profile does not accept.
+type Issue struct {
+	Path    string // JSON pointer of the schema node, e.g. "#/properties/status"
+	Keyword string // offending keyword, e.g. "oneOf"
+	Message string
+	Fixable bool // Downgrade rewrites it
+}
+
+// String returns "path: message", marking issues Downgrade cannot fix.
+func (i Issue) String() string {
+	if i.Fixable {
+		return i.Path + ": " + i.Message
+	}
+	return i.Path + ": " + i.Message + " (not fixable)"
+}
+
+// Check reports the features of schema that p does not accept, walking the schema in key order.
+func Check(p Profile, schema map[string]any) []Issue {
+	if schema == nil {
+		return nil
+	}
+	c := &checker{p: p, recursive: recursiveRefs(schema)}
+	if p.RootObject && schema["type"] != "object" {
+		c.add("#", "type", false, "%s requires the root schema to be an object", p.Name)
+	}
+	c.node(schema, "#", 0)
+	return c.issues
+}
+
+type checker struct {
+	p         Profile
+	recursive map[string]bool // "$ref" values that recurse
+	issues    []Issue
+}
+
+func (c *checker) add(path, keyword string, fixable bool, format string, args ...any) {
+	issue := Issue{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...), Fixable: fixable}
+	c.issues = append(c.issues, issue)
+}
+
+func (c *checker) node(n map[string]any, path string, depth int) {
+	if c.p.MaxDepth > 0 && depth == c.p.MaxDepth+1 {
+		c.add(path, "properties", false, "%s allows at most %d levels of object nesting", c.p.Name, c.p.MaxDepth)
+	}
+	for _, key := range slices.Sorted(maps.Keys(n)) {
+		c.keyword(n, key, path)
+		forEachSubschema(key, n[key], path, func(child map[string]any, childPath string) {
+			next := depth
+			if key == "properties" {
+				next++
+			}
+			c.node(child, childPath, next)
+		})
+	}
+}
+
+func (c *checker) keyword(n map[string]any, key, path string) {
+	p, value := c.p, n[key]
+	if !p.Keywords[key] {
+		fixable, how := c.fix(key, value)
+		c.add(path, key, fixable, "%s is not supported by %s%s", key, p.Name, how)
+		return
+	}
+	switch key {
+	case "format":
+		if f, ok := value.(string); ok && p.Formats != nil && !p.Formats[f] {
+			c.add(path, key, true, "format %q is not supported by %s; moved to the description", f, p.Name)
+		}
+	case "type":
+		if types := stringList(value); types != nil && !p.TypeArrays {
+			_, nullable := nullableType(types)
+			c.add(path, key, nullable && p.NullableKeyword, "%s does not accept a list of types%s", p.Name,
+				fixNote(nullable && p.NullableKeyword, "; rewritten with nullable"))
+		}
+	case "enum":
+		if values, ok := value.([]any); ok && p.StringEnumsOnly && slices.ContainsFunc(values, isNotString) {
+			c.add(path, key, true, "%s accepts only string enum values; moved to the description", p.Name)
+		}
+	case "minItems":
+		if n, ok := number(value); ok && p.MaxMinItems > 0 && n > float64(p.MaxMinItems) {
+			c.add(path, key, true, "%s accepts minItems up to %d; moved to the description", p.Name, p.MaxMinItems)
+		}
+	case "additionalProperties":
+		if allowed, ok := value.(bool); p.ClosedObjects && (!ok || allowed) {
+			c.add(path, key, false, "%s requires additionalProperties: false (no maps or open objects)", p.Name)
+		}
+	case "$ref":
+		if ref, _ := value.(string); !p.RecursiveRefs && c.recursive[ref] {
+			c.add(path, key, true, "%s does not accept recursive $ref %q; inlined %d levels deep", p.Name, ref,
+				maxRefDepth)
+		}
+	}
+}
+
+// fix reports whether Downgrade rewrites the unsupported keyword key, and how.
+func (c *checker) fix(key string, value any) (bool, string) {
+	p := c.p
+	switch key {
+	case "$ref", "$defs", "definitions":
+		return true, "; inlined"
+	case "oneOf":
+		if p.Keywords["anyOf"] {
+			return true, "; rewritten as anyOf"
+		}
+		if _, ok := nullableBranch(value); ok {
+			return true, "; rewritten as a nullable type"
+		}
+		return false, ""
+	case "allOf":
+		return true, "; merged into the schema"
+	case "const":
+		return p.Keywords["enum"], fixNote(p.Keywords["enum"], "; rewritten as a single-value enum")
+	case "nullable":
+		return p.TypeArrays, fixNote(p.TypeArrays, "; rewritten as a type list with null")
+	}
+	if hintKeyword(key) && p.Keywords["description"] {
+		return true, "; moved to the description"
+	}
+	return true, "; dropped"
+}
+
+func fixNote(ok bool, note string) string {
+	if ok {
+		return note
+	}
+	return ""
+}
+
+// forEachSubschema calls fn for each subschema held by keyword key.
+func forEachSubschema(key string, value any, path string, fn func(child map[string]any, childPath string)) {
+	switch key {
+	case "properties", "patternProperties", "$defs", "definitions", "dependentSchemas":
+		if m, ok := value.(map[string]any); ok {
+			for _, name := range slices.Sorted(maps.Keys(m)) {
+				if child, ok := m[name].(map[string]any); ok {
+					fn(child, path+"/"+key+"/"+escapePointer(name))
+				}
+			}
+		}
+	case "items", "additionalProperties", "not", "contains", "propertyNames", "if", "then", "else",
+		"unevaluatedProperties", "unevaluatedItems":
+		if child, ok := value.(map[string]any); ok {
+			fn(child, path+"/"+key)
+		}
+	case "anyOf", "oneOf", "allOf", "prefixItems":
+		if list, ok := value.([]any); ok {
+			for i, item := range list {
+				if child, ok := item.(map[string]any); ok {
+					fn(child, fmt.Sprintf("%s/%s/%d", path, key, i))
+				}
+			}
+		}
+	}
+}
+
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// recursiveRefs returns the "$ref" values in schema that lead back to themselves: "#" and definitions that
+// reach their own definition.
+func recursiveRefs(schema map[string]any) map[string]bool {
+	edges := make(map[string][]string)
+	out := make(map[string]bool)
+	var collect func(n map[string]any, from string)
+	collect = func(n map[string]any, from string) {
+		if ref, ok := n["$ref"].(string); ok {
+			if ref == "#" {
+				out[ref] = true
+			}
+			if from != "" {
+				edges[from] = append(edges[from], ref)
+			}
+		}
+		for key, value := range n {
+			if key == "$defs" || key == "definitions" {
+				continue
+			}
+			forEachSubschema(key, value, "", func(child map[string]any, _ string) { collect(child, from) })
+		}
+	}
+	collect(schema, "")
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, _ := schema[key].(map[string]any)
+		for name, def := range defs {
+			if m, ok := def.(map[string]any); ok {
+				collect(m, "#/"+key+"/"+name)
+			}
+		}
+	}
+	for from := range edges {
+		if reaches(edges, from, from, make(map[string]bool)) {
+			out[from] = true
+		}
+	}
+	return out
+}
+
+func reaches(edges map[string][]string, from, target string, seen map[string]bool) bool {
+	for _, next := range edges[from] {
+		if next == target {
+			return true
+		}
+		if !seen[next] {
+			seen[next] = true
+			if reaches(edges, next, target, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isNotString(v any) bool {
+	_, ok := v.(string)
+	return !ok
+}
+
+func stringList(value any) []string {
+	switch x := value.(type) {
+	case []string:
+		return x
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// nullableType splits ["T", "null"] into T.
+func nullableType(types []string) (string, bool) {
+	if len(types) != 2 || !slices.Contains(types, "null") {
+		return "", false
+	}
+	if types[0] == "null" {
+		return types[1], true
+	}
+	return types[0], true
+}
+
+// nullableBranch returns X for a two-branch union [X, {"type": "null"}] (either order).
+func nullableBranch(value any) (map[string]any, bool) {
+	list, ok := value.([]any)
+	if !ok || len(list) != 2 {
+		return nil, false
+	}
+	for i, item := range list {
+		m, ok := item.(map[string]any)
+		if ok && len(m) == 1 && m["type"] == "null" {
+			other, ok := list[1-i].(map[string]any)
+			return other, ok
+		}
+	}
+	return nil, false
+}
+
+func number(value any) (float64, bool) {
+	switch x := value.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,13 @@
+// Package schemacompat checks JSON Schemas (SchemaDefinition.Schema, ToolDefinition.Parameters) against the
+// subset each provider accepts and rewrites them into a compatible form, so incompatibilities surface at build
+// time instead of as request errors.
+//
+// Check reports every unsupported keyword with its location in the schema; Downgrade returns a rewritten copy
+// (refs inlined, unsupported constraints moved into descriptions, oneOf turned into anyOf or a nullable type)
+// plus the issues it could not fix. Profiles describe OpenAI strict structured outputs, Gemini's genai.Schema
+// and Anthropic strict tool input schemas; adapters apply Downgrade with their WithSchemaDowngrade option, and
+// `prompty-gen compat` runs Check over manifests.
+//
+// Downgraded schemas are looser than the originals; prompty still validates model output against the original
+// ResponseFormat.Schema (see prompty.ValidateJSON), so dropped constraints are enforced after the call.
+package schemacompat
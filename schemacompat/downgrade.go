@@ -0,0 +1,366 @@
+package schemacompat
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// maxRefDepth is how many times Downgrade expands a recursive $ref on one path before cutting the branch.
+const maxRefDepth = 3
+
+// Downgrade returns a copy of schema rewritten for p, and the issues it could not fix (Check on the result).
+// The input is not modified. Rewrites:
+//   - $ref is inlined when p has no refs or no recursive refs; recursion is cut after a few levels by
+//     dropping the property (and its required entry) or array that recurses;
+//   - allOf is merged into the schema; oneOf becomes anyOf, or a nullable type for [X, null];
+//   - anyOf [X, null] and type [T, "null"] become X / T with nullable: true where p uses the nullable keyword;
+//   - const becomes a single-value enum;
+//   - unsupported formats, bounds and enums move into the description as a hint, e.g. "(format: email)";
+//   - other unsupported keywords are dropped.
+func Downgrade(p Profile, schema map[string]any) (map[string]any, []Issue) {
+	if schema == nil {
+		return nil, nil
+	}
+	out := cloneSchema(schema)
+	if hasRef(out) && (!p.Refs() || !p.RecursiveRefs && len(recursiveRefs(out)) > 0) {
+		out = inlineRefs(out)
+	}
+	out = (&downgrader{p: p}).rewrite(out)
+	return out, Check(p, out)
+}
+
+type downgrader struct {
+	p Profile
+}
+
+func (d *downgrader) rewrite(n map[string]any) map[string]any {
+	for key, value := range n {
+		switch child := value.(type) {
+		case map[string]any:
+			switch key {
+			case "properties", "patternProperties", "$defs", "definitions", "dependentSchemas":
+				for name, sub := range child {
+					if m, ok := sub.(map[string]any); ok {
+						child[name] = d.rewrite(m)
+					}
+				}
+			default:
+				forEachSubschema(key, child, "", func(m map[string]any, _ string) { n[key] = d.rewrite(m) })
+			}
+		case []any:
+			forEachSubschema(key, child, "", func(m map[string]any, _ string) { d.rewrite(m) })
+		}
+	}
+	p := d.p
+	if _, ok := n["allOf"]; ok && !p.Keywords["allOf"] {
+		mergeAllOf(n)
+	}
+	if oneOf, ok := n["oneOf"]; ok && !p.Keywords["oneOf"] {
+		if p.Keywords["anyOf"] && n["anyOf"] == nil {
+			n["anyOf"] = oneOf
+			delete(n, "oneOf")
+		} else if x, ok := nullableBranch(oneOf); ok {
+			delete(n, "oneOf")
+			d.mergeNullable(n, x)
+		}
+	}
+	if anyOf, ok := n["anyOf"]; ok && (p.NullableKeyword || !p.Keywords["anyOf"]) {
+		if x, ok := nullableBranch(anyOf); ok {
+			delete(n, "anyOf")
+			d.mergeNullable(n, x)
+		}
+	}
+	if c, ok := n["const"]; ok && !p.Keywords["const"] && p.Keywords["enum"] {
+		n["enum"] = []any{c}
+		delete(n, "const")
+	}
+	if t, ok := nullableType(stringList(n["type"])); ok && !p.TypeArrays && p.NullableKeyword {
+		n["type"], n["nullable"] = t, true
+	}
+	if nullable, _ := n["nullable"].(bool); !p.Keywords["nullable"] {
+		delete(n, "nullable")
+		if t, ok := n["type"].(string); ok && nullable && p.TypeArrays {
+			n["type"] = []any{t, "null"}
+		}
+	}
+	d.hint(n)
+	return n
+}
+
+// mergeNullable merges branch x into n and marks n nullable the way p spells it.
+func (d *downgrader) mergeNullable(n, x map[string]any) {
+	for k, v := range x {
+		if _, exists := n[k]; !exists {
+			n[k] = v
+		}
+	}
+	switch t, _ := n["type"].(string); {
+	case d.p.NullableKeyword:
+		n["nullable"] = true
+	case d.p.TypeArrays && t != "":
+		n["type"] = []any{t, "null"}
+	}
+}
+
+// hint moves unsupported constraints of n into its description and drops the remaining unsupported keywords.
+func (d *downgrader) hint(n map[string]any) {
+	p := d.p
+	var hints []string
+	add := func(key string) {
+		if p.Keywords["description"] {
+			hints = append(hints, key+": "+hintValue(n[key]))
+		}
+		delete(n, key)
+	}
+	for _, key := range slices.Sorted(maps.Keys(n)) {
+		switch {
+		case !p.Keywords[key] && hintKeyword(key):
+			add(key)
+		case !p.Keywords[key]:
+			delete(n, key)
+		case key == "format":
+			if f, ok := n[key].(string); ok && p.Formats != nil && !p.Formats[f] {
+				add(key)
+			}
+		case key == "enum":
+			if values, ok := n[key].([]any); ok && p.StringEnumsOnly && slices.ContainsFunc(values, isNotString) {
+				add(key)
+			}
+		case key == "minItems":
+			if v, ok := number(n[key]); ok && p.MaxMinItems > 0 && v > float64(p.MaxMinItems) {
+				add(key)
+			}
+		}
+	}
+	if len(hints) == 0 {
+		return
+	}
+	text := strings.Join(hints, "; ")
+	if desc, _ := n["description"].(string); desc != "" {
+		text = desc + " (" + text + ")"
+	}
+	n["description"] = text
+}
+
+// hintKeyword reports whether an unsupported keyword is worth keeping as a description hint.
+func hintKeyword(key string) bool {
+	switch key {
+	case "format", "pattern", "enum", "const", "default", "examples",
+		"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+		"minLength", "maxLength", "minItems", "maxItems", "uniqueItems", "minProperties", "maxProperties":
+		return true
+	default:
+		return false
+	}
+}
+
+// hintValue formats a keyword value for a description: strings bare, lists comma-separated, the rest as JSON.
+func hintValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []any:
+		parts := make([]string, len(x))
+		for i, item := range x {
+			parts[i] = hintValue(item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		data, err := json.Marshal(x)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// mergeAllOf folds the allOf branches of n into n: properties and required are unioned, other keywords are
+// taken from the first branch that sets them unless n sets them itself.
+func mergeAllOf(n map[string]any) {
+	branches, _ := n["allOf"].([]any)
+	delete(n, "allOf")
+	for _, b := range branches {
+		branch, ok := b.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, value := range branch {
+			switch key {
+			case "properties":
+				props, _ := n[key].(map[string]any)
+				if props == nil {
+					props = make(map[string]any)
+					n[key] = props
+				}
+				if from, ok := value.(map[string]any); ok {
+					for name, schema := range from {
+						if _, exists := props[name]; !exists {
+							props[name] = schema
+						}
+					}
+				}
+			case "required":
+				required := stringList(n[key])
+				for _, name := range stringList(value) {
+					if !slices.Contains(required, name) {
+						required = append(required, name)
+					}
+				}
+				n[key] = required
+			default:
+				if _, exists := n[key]; !exists {
+					n[key] = value
+				}
+			}
+		}
+	}
+}
+
+func hasRef(n map[string]any) bool {
+	if _, ok := n["$ref"]; ok {
+		return true
+	}
+	found := false
+	for key, value := range n {
+		forEachSubschema(key, value, "", func(child map[string]any, _ string) { found = found || hasRef(child) })
+	}
+	return found
+}
+
+// inlineRefs replaces every resolvable $ref in root with its target and removes $defs/definitions.
+func inlineRefs(root map[string]any) map[string]any {
+	in := &inliner{root: cloneSchema(root), expanding: map[string]int{"#": 1}}
+	out, _ := in.inline(root)
+	delete(out, "$defs")
+	delete(out, "definitions")
+	return out
+}
+
+type inliner struct {
+	root      map[string]any // unmodified copy that refs resolve against
+	expanding map[string]int // ref -> expansions on the current path
+}
+
+func (in *inliner) resolve(ref string) (map[string]any, bool) {
+	if ref == "#" {
+		return in.root, true
+	}
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			defs, _ := in.root[prefix[2:len(prefix)-1]].(map[string]any)
+			target, ok := defs[strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")].(map[string]any)
+			return target, ok
+		}
+	}
+	return nil, false
+}
+
+// inline returns n with refs expanded; false means the branch recursed too deep and must be cut.
+func (in *inliner) inline(n map[string]any) (map[string]any, bool) {
+	if ref, ok := n["$ref"].(string); ok {
+		target, ok := in.resolve(ref)
+		if !ok {
+			return n, true
+		}
+		if in.expanding[ref] >= maxRefDepth {
+			return nil, false
+		}
+		in.expanding[ref]++
+		defer func() { in.expanding[ref]-- }()
+		merged := cloneSchema(target)
+		delete(merged, "$defs")
+		delete(merged, "definitions")
+		for k, v := range n {
+			if k != "$ref" {
+				merged[k] = v
+			}
+		}
+		return in.inline(merged)
+	}
+	for key, value := range n {
+		switch child := value.(type) {
+		case map[string]any:
+			switch key {
+			case "properties", "patternProperties", "dependentSchemas":
+				for name, sub := range child {
+					m, ok := sub.(map[string]any)
+					if !ok {
+						continue
+					}
+					if r, keep := in.inline(m); keep {
+						child[name] = r
+					} else {
+						delete(child, name)
+						if key == "properties" {
+							n["required"] = slices.DeleteFunc(stringList(n["required"]),
+								func(s string) bool { return s == name })
+						}
+					}
+				}
+			case "$defs", "definitions":
+			default:
+				r, keep := in.inline(child)
+				switch {
+				case keep:
+					n[key] = r
+				case key == "items":
+					return nil, false
+				default:
+					delete(n, key)
+				}
+			}
+		case []any:
+			if key != "anyOf" && key != "oneOf" && key != "allOf" && key != "prefixItems" {
+				continue
+			}
+			kept := child[:0]
+			for _, item := range child {
+				m, ok := item.(map[string]any)
+				if !ok {
+					kept = append(kept, item)
+					continue
+				}
+				r, keep := in.inline(m)
+				if !keep && (key == "allOf" || key == "prefixItems") {
+					return nil, false
+				}
+				if keep {
+					kept = append(kept, r)
+				}
+			}
+			if len(kept) == 0 {
+				return nil, false
+			}
+			n[key] = kept
+		}
+	}
+	return n, true
+}
+
+// cloneSchema deep-copies maps and slices of a decoded JSON schema.
+func cloneSchema(n map[string]any) map[string]any {
+	out := make(map[string]any, len(n))
+	for k, v := range n {
+		out[k] = cloneValue(v)
+	}
+	return out
+}
+
+func cloneValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		return cloneSchema(x)
+	case []any:
+		out := make([]any, len(x))
+		for i, item := range x {
+			out[i] = cloneValue(item)
+		}
+		return out
+	case []string:
+		return slices.Clone(x)
+	default:
+		return v
+	}
+}
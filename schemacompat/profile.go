@@ -0,0 +1,107 @@
+package schemacompat
+
+// Profile describes the JSON Schema subset a provider accepts.
+type Profile struct {
+	Name     string
+	Keywords map[string]bool // accepted keywords; others are reported
+	Formats  map[string]bool // accepted "format" values when "format" is a keyword
+
+	TypeArrays      bool // "type": ["string", "null"] is accepted
+	NullableKeyword bool // nullability is expressed as "nullable": true (Gemini)
+	RecursiveRefs   bool // "$ref" may be recursive (when "$ref" is a keyword)
+	StringEnumsOnly bool // "enum" values must be strings
+	RootObject      bool // the root schema must be {"type": "object"}
+	ClosedObjects   bool // additionalProperties may only be false (no maps)
+	MaxMinItems     int  // largest accepted "minItems" (0: any)
+	MaxDepth        int  // maximum object nesting (0: unlimited)
+}
+
+// Refs reports whether the profile accepts "$ref" and "$defs".
+func (p Profile) Refs() bool { return p.Keywords["$ref"] }
+
+func keywordSet(keywords ...string) map[string]bool {
+	set := make(map[string]bool, len(keywords))
+	for _, k := range keywords {
+		set[k] = true
+	}
+	return set
+}
+
+// OpenAI returns the profile of OpenAI strict structured outputs (response_format json_schema with strict: true).
+// The openai adapter closes objects and marks optional properties nullable itself, so those are not reported.
+func OpenAI() Profile {
+	return Profile{
+		Name: "openai",
+		Keywords: keywordSet("type", "properties", "required", "additionalProperties", "items", "enum", "const",
+			"anyOf", "$ref", "$defs", "description", "title", "pattern", "format", "minimum", "maximum",
+			"exclusiveMinimum", "exclusiveMaximum", "multipleOf", "minItems", "maxItems"),
+		Formats: keywordSet("date-time", "time", "date", "duration", "email", "hostname", "ipv4", "ipv6",
+			"uuid"),
+		TypeArrays:      true,
+		NullableKeyword: false,
+		RecursiveRefs:   true,
+		StringEnumsOnly: false,
+		RootObject:      true,
+		ClosedObjects:   true,
+		MaxMinItems:     0,
+		MaxDepth:        10,
+	}
+}
+
+// Gemini returns the profile of Gemini's response schema (genai.Schema, see adapter/gemini). It has no
+// references and expresses null with "nullable".
+func Gemini() Profile {
+	return Profile{
+		Name: "gemini",
+		Keywords: keywordSet("type", "properties", "required", "items", "enum", "description", "title", "format",
+			"pattern", "minimum", "maximum", "minLength", "maxLength", "minItems", "maxItems", "minProperties",
+			"maxProperties", "nullable", "anyOf", "default", "example", "propertyOrdering"),
+		Formats:         keywordSet("date-time", "enum", "int32", "int64", "float", "double"),
+		TypeArrays:      false,
+		NullableKeyword: true,
+		RecursiveRefs:   false,
+		StringEnumsOnly: true,
+		RootObject:      false,
+		ClosedObjects:   false,
+		MaxMinItems:     0,
+		MaxDepth:        0,
+	}
+}
+
+// Anthropic returns the profile of Anthropic strict tool input schemas (also used for structured output, which
+// the anthropic adapter sends as a tool): no recursion, no numeric or length bounds, minItems 0 or 1.
+func Anthropic() Profile {
+	return Profile{
+		Name: "anthropic",
+		Keywords: keywordSet("type", "properties", "required", "additionalProperties", "items", "enum", "const",
+			"anyOf", "allOf", "$ref", "$defs", "description", "title", "format", "default", "examples", "pattern",
+			"minItems"),
+		Formats: keywordSet("date-time", "time", "date", "duration", "email", "hostname", "uri", "ipv4", "ipv6",
+			"uuid"),
+		TypeArrays:      true,
+		NullableKeyword: false,
+		RecursiveRefs:   false,
+		StringEnumsOnly: false,
+		RootObject:      true,
+		ClosedObjects:   true,
+		MaxMinItems:     1,
+		MaxDepth:        0,
+	}
+}
+
+// Lookup returns the profile for a provider name ("openai", "gemini", "anthropic").
+func Lookup(name string) (Profile, bool) {
+	switch name {
+	case "openai":
+		return OpenAI(), true
+	case "gemini":
+		return Gemini(), true
+	case "anthropic":
+		return Anthropic(), true
+	default:
+		return Profile{}, false
+	}
+}
+
+// Names lists the providers Lookup knows.
+func Names() []string { return []string{"anthropic", "gemini", "openai"} }
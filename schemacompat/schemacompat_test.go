@@ -0,0 +1,187 @@
+package schemacompat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	return m
+}
+
+func issueKeys(issues []Issue) []string {
+	keys := make([]string, len(issues))
+	for i, issue := range issues {
+		keys[i] = issue.Path + " " + issue.Keyword
+	}
+	return keys
+}
+
+const ticketSchema = `{
+	"type": "object",
+	"properties": {
+		"email": {"type": "string", "format": "email", "description": "Reporter"},
+		"priority": {"type": "integer", "enum": [1, 2, 3]},
+		"assignee": {"oneOf": [{"type": "string", "minLength": 3}, {"type": "null"}]},
+		"tags": {"type": "array", "items": {"type": "string"}, "minItems": 2, "uniqueItems": true},
+		"parent": {"$ref": "#/$defs/Ticket"}
+	},
+	"required": ["email", "parent"],
+	"$defs": {
+		"Ticket": {
+			"type": "object",
+			"properties": {"id": {"type": "string"}, "parent": {"$ref": "#/$defs/Ticket"}},
+			"required": ["id", "parent"]
+		}
+	}
+}`
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	schema := decode(t, ticketSchema)
+
+	assert.Equal(t, []string{
+		"# $defs",
+		"#/$defs/Ticket/properties/parent $ref",
+		"#/properties/assignee oneOf",
+		"#/properties/email format",
+		"#/properties/parent $ref",
+		"#/properties/priority enum",
+		"#/properties/tags uniqueItems",
+	}, issueKeys(Check(Gemini(), schema)))
+
+	assert.Equal(t, []string{
+		"#/properties/assignee oneOf",
+		"#/properties/assignee/oneOf/0 minLength",
+		"#/properties/tags uniqueItems",
+	}, issueKeys(Check(OpenAI(), schema)))
+
+	issues := Check(Anthropic(), schema)
+	assert.Equal(t, []string{
+		"#/$defs/Ticket/properties/parent $ref",
+		"#/properties/assignee oneOf",
+		"#/properties/assignee/oneOf/0 minLength",
+		"#/properties/parent $ref",
+		"#/properties/tags minItems",
+		"#/properties/tags uniqueItems",
+	}, issueKeys(issues))
+	for _, issue := range issues {
+		assert.True(t, issue.Fixable, issue.String())
+	}
+	assert.Equal(t, `#/$defs/Ticket/properties/parent: anthropic does not accept recursive $ref "#/$defs/Ticket"; `+
+		"inlined 3 levels deep", issues[0].String())
+}
+
+func TestCheck_Unfixable(t *testing.T) {
+	t.Parallel()
+	issues := Check(OpenAI(), decode(t, `{"type": "array", "items": {"type": "object", "additionalProperties": {}}}`))
+	require.Len(t, issues, 2)
+	assert.Equal(t, "#: openai requires the root schema to be an object (not fixable)", issues[0].String())
+	assert.Equal(t, "#/items additionalProperties", issueKeys(issues)[1])
+	assert.False(t, issues[1].Fixable)
+
+	deep := `{"type": "string"}`
+	for range 12 {
+		deep = `{"type": "object", "properties": {"x": ` + deep + `}}`
+	}
+	issues = Check(OpenAI(), decode(t, deep))
+	require.Len(t, issues, 1)
+	assert.Equal(t, "openai allows at most 10 levels of object nesting", issues[0].Message)
+
+	issues = Check(Gemini(), decode(t, `{"type": ["string", "integer"]}`))
+	require.Len(t, issues, 1)
+	assert.Equal(t, "#: gemini does not accept a list of types (not fixable)", issues[0].String())
+	assert.Nil(t, Check(Gemini(), nil))
+}
+
+func TestDowngrade_Gemini(t *testing.T) {
+	t.Parallel()
+	schema := decode(t, ticketSchema)
+	before, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	out, issues := Downgrade(Gemini(), schema)
+	assert.Empty(t, issues)
+	after, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(before), string(after), "the input is not modified")
+
+	props := out["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "description": "Reporter (format: email)"}, props["email"])
+	assert.Equal(t, map[string]any{"type": "integer", "description": "enum: 1, 2, 3"}, props["priority"])
+	assert.Equal(t, map[string]any{"type": "string", "nullable": true, "minLength": 3.0},
+		props["assignee"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 2.0,
+		"description": "uniqueItems: true"}, props["tags"])
+	assert.NotContains(t, out, "$defs")
+
+	// The recursive parent chain is inlined three levels deep, then cut along with its required entry.
+	depth := 0
+	for node := props["parent"].(map[string]any); node != nil; depth++ {
+		next, _ := node["properties"].(map[string]any)["parent"].(map[string]any)
+		if next == nil {
+			assert.Equal(t, []string{"id"}, node["required"])
+		}
+		node = next
+	}
+	assert.Equal(t, 3, depth)
+}
+
+func TestDowngrade_OpenAI(t *testing.T) {
+	t.Parallel()
+	out, issues := Downgrade(OpenAI(), decode(t, ticketSchema))
+	assert.Empty(t, issues)
+	props := out["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/Ticket"}, props["parent"], "recursive refs are kept")
+	assert.Equal(t, []any{
+		map[string]any{"type": "string", "description": "minLength: 3"},
+		map[string]any{"type": "null"},
+	}, props["assignee"].(map[string]any)["anyOf"])
+
+	out, issues = Downgrade(OpenAI(), decode(t, `{
+		"type": "object",
+		"allOf": [
+			{"properties": {"a": {"type": "string", "nullable": true}}, "required": ["a"]},
+			{"properties": {"b": {"const": "x"}}, "required": ["b"]}
+		]
+	}`))
+	assert.Empty(t, issues)
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"a": map[string]any{"type": []any{"string", "null"}},
+			"b": map[string]any{"const": "x"},
+		},
+		"required": []string{"a", "b"},
+	}, out)
+}
+
+func TestDowngrade_Anthropic(t *testing.T) {
+	t.Parallel()
+	out, issues := Downgrade(Anthropic(), decode(t, ticketSchema))
+	assert.Empty(t, issues)
+	assert.NotContains(t, out, "$defs")
+	props := out["properties"].(map[string]any)
+	assert.Equal(t, "minItems: 2; uniqueItems: true", props["tags"].(map[string]any)["description"])
+
+	_, issues = Downgrade(Anthropic(), decode(t, `{"type": "object", "additionalProperties": {"type": "string"}}`))
+	require.Len(t, issues, 1)
+	assert.False(t, issues[0].Fixable)
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+	for _, name := range Names() {
+		p, ok := Lookup(name)
+		require.True(t, ok)
+		assert.Equal(t, name, p.Name)
+	}
+	_, ok := Lookup("cohere")
+	assert.False(t, ok)
+}
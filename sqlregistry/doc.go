@@ -0,0 +1,27 @@
+// Package sqlregistry provides a prompt registry over database/sql, e.g. for manifests edited in an admin UI
+// and stored in Postgres. Bring your own driver; the registry only issues the queries below.
+//
+// Schema (Postgres; see Schema, WithTable for another table name):
+//
+//	CREATE TABLE prompty_templates (
+//	    id         TEXT        NOT NULL,              -- template id, e.g. "support/agent"
+//	    env        TEXT        NOT NULL DEFAULT '',   -- "" for the base manifest, else an environment
+//	    version    TEXT        NOT NULL DEFAULT '',   -- semantic version ("1.2.0") or ""
+//	    body       TEXT        NOT NULL,              -- manifest source
+//	    format     TEXT        NOT NULL DEFAULT '',   -- parser key ("yaml", "json"; see WithFormatParser)
+//	    updated_at TIMESTAMPTZ NOT NULL,
+//	    active     BOOLEAN     NOT NULL DEFAULT FALSE,
+//	    PRIMARY KEY (id, env, version)
+//	);
+//	CREATE UNIQUE INDEX prompty_templates_active ON prompty_templates (id, env) WHERE active;
+//
+// One row per (id, env) is active: GetTemplate serves it, composed over the environment chain like the other
+// registries (WithEnvironmentChain; rows with `overlay: true` patch the layer below). "id@constraint" resolves
+// against the versions stored for id and serves those rows whether active or not.
+// Publish validates a manifest and, in one transaction, deactivates the current row and inserts the new active one.
+// The partial unique index makes concurrent publishes safe: the transaction that commits second fails on it.
+// Without the index such a race leaves two active rows, and GetTemplate fails with ErrMultipleActive.
+//
+// The registry reads the database on every call; wrap it in remoteregistry.WithCache for a TTL cache.
+// Use WithPlaceholders(PlaceholderQuestion) for drivers that bind "?" instead of "$1" (SQLite, MySQL).
+package sqlregistry
@@ -0,0 +1,326 @@
+package sqlregistry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an in-memory database/sql driver that understands the statements the registry issues:
+// SELECT [DISTINCT] cols FROM t WHERE a = $n AND ..., UPDATE t SET a = $n, ... WHERE ...,
+// and INSERT INTO t (cols) VALUES ($1, ...) with (id, env, version) unique and, like the partial index of Schema,
+// one active row per (id, env).
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+type fakeDB struct {
+	rows    []map[string]any
+	failOn  string // statement prefix that fails (e.g. "INSERT"), for rollback tests
+	queries int    // SELECTs served
+	// noActiveIndex drops the one-active-row constraint, like a table created without the index of Schema.
+	noActiveIndex bool
+	// race is a row a concurrent Publish commits right before the next INSERT; a rollback keeps it.
+	race map[string]any
+}
+
+var testDriver = &fakeDriver{dbs: make(map[string]*fakeDB)}
+
+func init() {
+	sql.Register("prompty-fake", testDriver)
+}
+
+// openFakeDB opens a fresh in-memory database named after the test.
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	fdb := &fakeDB{}
+	testDriver.mu.Lock()
+	testDriver.dbs[t.Name()] = fdb
+	testDriver.mu.Unlock()
+	db, err := sql.Open("prompty-fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, fdb
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fdb, ok := d.dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("fake: unknown database %q", name)
+	}
+	return &fakeConn{d: d, db: fdb}, nil
+}
+
+type fakeConn struct {
+	d        *fakeDriver
+	db       *fakeDB
+	snapshot []map[string]any // rows at BEGIN; nil outside a transaction
+	inTx     bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c: c, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.snapshot = make([]map[string]any, len(c.db.rows))
+	for i, r := range c.db.rows {
+		c.snapshot[i] = maps.Clone(r)
+	}
+	c.inTx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.snapshot, c.inTx = nil, false
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.db.rows, c.snapshot, c.inTx = c.snapshot, nil, false
+	return nil
+}
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+var (
+	selectRe = regexp.MustCompile(`^SELECT (DISTINCT )?(.+?) FROM \w+ WHERE (.+)$`)
+	updateRe = regexp.MustCompile(`^UPDATE \w+ SET (.+?) WHERE (.+)$`)
+	insertRe = regexp.MustCompile(`^INSERT INTO \w+ \((.+?)\) VALUES \((.+?)\)$`)
+	assignRe = regexp.MustCompile(`^(\w+) = \$(\d+)$`)
+)
+
+// bind parses "a = $1 AND b = $2" (sep " AND ") or "a = $1, b = $2" (sep ", ") into column -> value.
+func bind(clause, sep string, args []driver.Value) (map[string]any, error) {
+	out := make(map[string]any)
+	for _, part := range strings.Split(clause, sep) {
+		m := assignRe.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("fake: unsupported clause %q", part)
+		}
+		n, _ := strconv.Atoi(m[2])
+		out[m[1]] = args[n-1]
+	}
+	return out, nil
+}
+
+func matches(r, where map[string]any) bool {
+	for k, v := range where {
+		if r[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *fakeStmt) fail() error {
+	if s.c.db.failOn != "" && strings.HasPrefix(s.query, s.c.db.failOn) {
+		return errors.New("fake: injected failure")
+	}
+	return nil
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	if m := updateRe.FindStringSubmatch(s.query); m != nil {
+		set, err := bind(m[1], ", ", args)
+		if err != nil {
+			return nil, err
+		}
+		where, err := bind(m[2], " AND ", args)
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		for _, r := range s.c.db.rows {
+			if !matches(r, where) {
+				continue
+			}
+			updated := maps.Clone(r)
+			maps.Copy(updated, set)
+			if err := s.c.db.checkActive(updated, r); err != nil {
+				return nil, err
+			}
+			maps.Copy(r, set)
+			n++
+		}
+		return driver.RowsAffected(n), nil
+	}
+	if m := insertRe.FindStringSubmatch(s.query); m != nil {
+		cols := strings.Split(m[1], ", ")
+		r := make(map[string]any, len(cols))
+		for i, col := range cols {
+			r[col] = args[i]
+		}
+		if race := s.c.db.race; race != nil {
+			// The concurrent Publish deactivated the row it replaced and committed; a rollback keeps its writes.
+			for _, rows := range [][]map[string]any{s.c.db.rows, s.c.snapshot} {
+				for _, existing := range rows {
+					if existing["id"] == race["id"] && existing["env"] == race["env"] {
+						existing["active"] = false
+					}
+				}
+			}
+			s.c.db.rows = append(s.c.db.rows, race)
+			if s.c.inTx {
+				s.c.snapshot = append(s.c.snapshot, maps.Clone(race))
+			}
+			s.c.db.race = nil
+		}
+		for _, existing := range s.c.db.rows {
+			if existing["id"] == r["id"] && existing["env"] == r["env"] && existing["version"] == r["version"] {
+				return nil, errors.New("fake: duplicate key (id, env, version)")
+			}
+		}
+		if err := s.c.db.checkActive(r, nil); err != nil {
+			return nil, err
+		}
+		s.c.db.rows = append(s.c.db.rows, r)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("fake: unsupported statement %q", s.query)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	s.c.db.queries++
+	m := selectRe.FindStringSubmatch(s.query)
+	if m == nil {
+		return nil, fmt.Errorf("fake: unsupported query %q", s.query)
+	}
+	cols := strings.Split(m[2], ", ")
+	where, err := bind(m[3], " AND ", args)
+	if err != nil {
+		return nil, err
+	}
+	var matched []map[string]any
+	for _, r := range s.c.db.rows {
+		if matches(r, where) {
+			matched = append(matched, r)
+		}
+	}
+	out := &fakeRows{cols: cols}
+	seen := make(map[string]bool)
+	for _, r := range matched {
+		vals := make([]driver.Value, len(cols))
+		for i, col := range cols {
+			vals[i] = r[col]
+		}
+		if key := fmt.Sprint(vals); m[1] != "" {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		out.rows = append(out.rows, vals)
+	}
+	return out, nil
+}
+
+// checkActive enforces one active row per (id, env) for r, ignoring self (the row r updates).
+func (db *fakeDB) checkActive(r, self map[string]any) error {
+	if db.noActiveIndex || r["active"] != true {
+		return nil
+	}
+	for _, existing := range db.rows {
+		if !isSameRow(existing, self) && existing["active"] == true &&
+			existing["id"] == r["id"] && existing["env"] == r["env"] {
+			return errors.New("fake: duplicate key (id, env) where active")
+		}
+	}
+	return nil
+}
+
+// isSameRow reports whether a and b have the same primary key.
+func isSameRow(a, b map[string]any) bool {
+	return b != nil && a["id"] == b["id"] && a["env"] == b["env"] && a["version"] == b["version"]
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// queryCount returns the number of SELECTs fdb served so far.
+func queryCount(fdb *fakeDB) int {
+	testDriver.mu.Lock()
+	defer testDriver.mu.Unlock()
+	return fdb.queries
+}
+
+// rowsOf returns a copy of the stored rows.
+func rowsOf(fdb *fakeDB) []map[string]any {
+	testDriver.mu.Lock()
+	defer testDriver.mu.Unlock()
+	out := make([]map[string]any, len(fdb.rows))
+	for i, r := range fdb.rows {
+		out[i] = maps.Clone(r)
+	}
+	return out
+}
+
+// insertRow stores a row directly, bypassing Publish.
+func insertRow(t *testing.T, db *sql.DB, id, env, version, body string, active bool) {
+	t.Helper()
+	execInsert(t, db, id, env, version, body, "json", active)
+}
+
+// insertRowFormat stores an active unversioned row with format, bypassing Publish.
+func insertRowFormat(t *testing.T, db *sql.DB, id, env, body, format string) {
+	t.Helper()
+	execInsert(t, db, id, env, "", body, format, true)
+}
+
+func execInsert(t *testing.T, db *sql.DB, id, env, version, body, format string, active bool) {
+	t.Helper()
+	_, err := db.ExecContext(context.Background(),
+		`INSERT INTO prompty_templates (id, env, version, body, format, updated_at, active) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		id, env, version, body, format, time.Now().UTC(), active)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
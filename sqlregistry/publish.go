@@ -0,0 +1,133 @@
+package sqlregistry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skosovsky/prompty"
+)
+
+// Manifest is a manifest to publish: one row of the table.
+type Manifest struct {
+	ID      string
+	Env     string // "" for the base manifest
+	Version string // semantic version; "" takes the manifest's own `version`
+	Format  string // parser key stored in the format column (see WithFormatParser)
+	Body    []byte
+}
+
+// Publish validates m and makes it the active row for (m.ID, m.Env) in one transaction: the current active row is
+// deactivated and m is inserted with updated_at set to now. Earlier rows stay readable as "id@version".
+// m.Body must build: a full manifest on its own, an overlay over the active rows of the envs below m.Env.
+// Versioned rows are immutable: publishing an existing (id, env, version) fails with the driver's unique-constraint
+// error and changes nothing. The unversioned row (no version anywhere) is overwritten in place.
+// Of two concurrent calls for the same (m.ID, m.Env), the one committing second fails on the active-row index
+// of Schema.
+func (r *Registry) Publish(ctx context.Context, m Manifest) error {
+	if err := prompty.ValidateID(m.ID); err != nil {
+		return err
+	}
+	if strings.ContainsAny(m.Env, "./@") {
+		return fmt.Errorf("%w: env %q must not contain '.', '/' or '@'", prompty.ErrInvalidName, m.Env)
+	}
+	version, err := r.validate(ctx, m)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlregistry: publish %q: begin: %w", m.ID, err)
+	}
+	if err := r.publishTx(ctx, tx, m, version); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			err = errors.Join(err, rbErr)
+		}
+		return fmt.Errorf("sqlregistry: publish %q: %w", m.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlregistry: publish %q: commit: %w", m.ID, err)
+	}
+	return nil
+}
+
+// validate builds m over the active rows below it and returns the version to store.
+func (r *Registry) validate(ctx context.Context, m Manifest) (string, error) {
+	if _, err := r.unmarshaler(m.Format); err != nil {
+		return "", err
+	}
+	version, err := formatVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	envs := r.envsBelow(m.Env)
+	tpl, _, err := r.compose(ctx, m.ID, envs, func(env string) (row, bool, error) {
+		if env == m.Env {
+			return row{body: m.Body, format: m.Format, version: version, updatedAt: time.Time{}}, true, nil
+		}
+		return r.layer(ctx, m.ID, env, "")
+	})
+	if err != nil {
+		return "", err
+	}
+	if version == "" && m.Env == "" {
+		// A full base manifest declares its own version; overlays inherit the base version and store none.
+		return formatVersion(tpl.Metadata.Version)
+	}
+	return version, nil
+}
+
+func (r *Registry) publishTx(ctx context.Context, tx *sql.Tx, m Manifest, version string) error {
+	_, err := tx.ExecContext(ctx,
+		r.rebind(`UPDATE {table} SET active = $1 WHERE id = $2 AND env = $3 AND active = $4`),
+		false, m.ID, m.Env, true)
+	if err != nil {
+		return fmt.Errorf("deactivate: %w", err)
+	}
+	now := time.Now().UTC()
+	if version == "" {
+		// The unversioned row is the working copy: overwrite it in place.
+		res, err := tx.ExecContext(ctx,
+			r.rebind(`UPDATE {table} SET body = $1, format = $2, updated_at = $3, active = $4
+WHERE id = $5 AND env = $6 AND version = $7`),
+			string(m.Body), m.Format, now, true, m.ID, m.Env, version)
+		if err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			return nil
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		r.rebind(`INSERT INTO {table} (id, env, version, body, format, updated_at, active) VALUES (`+placeholders(7)+`)`),
+		m.ID, m.Env, version, string(m.Body), m.Format, now, true)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	return nil
+}
+
+// formatVersion normalizes a semantic version; "" stays "".
+func formatVersion(version string) (string, error) {
+	if version == "" {
+		return "", nil
+	}
+	v, err := prompty.ParseVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// placeholders returns "$1, $2, ..., $n".
+func placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = "$" + strconv.Itoa(i+1)
+	}
+	return strings.Join(ps, ", ")
+}
@@ -0,0 +1,399 @@
+package sqlregistry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+)
+
+// DefaultTable is the table name used unless WithTable is set.
+const DefaultTable = "prompty_templates"
+
+// Schema is the Postgres DDL for DefaultTable (see the package documentation). The partial unique index keeps
+// one active row per (id, env): of two concurrent Publish calls, the one that commits second fails.
+const Schema = `CREATE TABLE IF NOT EXISTS prompty_templates (
+    id         TEXT        NOT NULL,
+    env        TEXT        NOT NULL DEFAULT '',
+    version    TEXT        NOT NULL DEFAULT '',
+    body       TEXT        NOT NULL,
+    format     TEXT        NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL,
+    active     BOOLEAN     NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id, env, version)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS prompty_templates_active ON prompty_templates (id, env) WHERE active`
+
+// ErrMultipleActive is returned when (id, env) has more than one active row: the table lacks the unique index
+// of Schema and concurrent publishes both committed.
+var ErrMultipleActive = errors.New("sqlregistry: more than one active row")
+
+// Ensures Registry implements prompty.Registry, Lister, Statter, and Versioner.
+var (
+	_ prompty.Registry  = (*Registry)(nil)
+	_ prompty.Lister    = (*Registry)(nil)
+	_ prompty.Statter   = (*Registry)(nil)
+	_ prompty.Versioner = (*Registry)(nil)
+)
+
+// PlaceholderStyle selects how query arguments are bound.
+type PlaceholderStyle int
+
+const (
+	// PlaceholderDollar binds "$1", "$2", ... (Postgres). Default.
+	PlaceholderDollar PlaceholderStyle = iota
+	// PlaceholderQuestion binds "?" (SQLite, MySQL).
+	PlaceholderQuestion
+)
+
+var ( //nolint:gochecknoglobals // compiled once, read-only
+	// tableName matches a (schema-qualified) SQL identifier accepted by WithTable.
+	tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	// dollarPlaceholder matches "$n" placeholders rewritten for PlaceholderQuestion.
+	dollarPlaceholder = regexp.MustCompile(`\$\d+`)
+)
+
+// Registry loads templates from a SQL table on every call (no internal cache). Safe for concurrent use.
+// WithEnvironment(env): GetTemplate reads the env row first, then the base row; WithEnvironmentChain sets several.
+// At least one parser is required; use WithParser or WithFormatParser when creating the registry.
+type Registry struct {
+	db          *sql.DB
+	table       string
+	placeholder PlaceholderStyle
+	envs        []string                        // e.g. ["prod-eu", "prod"]; rows for these envs, then env ""
+	parser      manifest.Unmarshaler            // WithParser: for formats without their own parser
+	formats     map[string]manifest.Unmarshaler // WithFormatParser: format column value -> parser
+	strict      bool                            // WithStrict: templates are built with prompty.WithStrict
+	funcs       template.FuncMap
+}
+
+// New creates a Registry over db. Panics if db is nil.
+// Returns prompty.ErrNoParser when no parser is set and an error for an invalid WithTable name.
+func New(db *sql.DB, opts ...Option) (*Registry, error) {
+	if db == nil {
+		panic("sqlregistry: DB must not be nil")
+	}
+	r := &Registry{db: db, table: DefaultTable}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.parser == nil && len(r.formats) == 0 {
+		return nil, prompty.ErrNoParser
+	}
+	if !tableName.MatchString(r.table) {
+		return nil, fmt.Errorf("sqlregistry: invalid table name %q", r.table)
+	}
+	return r, nil
+}
+
+// Option configures a Registry (functional options pattern).
+type Option func(*Registry)
+
+// WithParser sets the parser for rows whose format has no WithFormatParser entry (e.g. all rows are YAML).
+func WithParser(u manifest.Unmarshaler) Option {
+	return func(r *Registry) { r.parser = u }
+}
+
+// WithFormatParser sets the parser for rows with format (e.g. "yaml" -> parser/yaml, "json" -> manifest.NewJSONParser()).
+func WithFormatParser(format string, u manifest.Unmarshaler) Option {
+	return func(r *Registry) {
+		if r.formats == nil {
+			r.formats = make(map[string]manifest.Unmarshaler)
+		}
+		r.formats[format] = u
+	}
+}
+
+// WithTable sets the table name (default DefaultTable); "schema.table" is allowed.
+func WithTable(name string) Option {
+	return func(r *Registry) { r.table = name }
+}
+
+// WithPlaceholders sets the argument placeholder style of the driver (default PlaceholderDollar).
+func WithPlaceholders(style PlaceholderStyle) Option {
+	return func(r *Registry) { r.placeholder = style }
+}
+
+// WithEnvironment sets env for fallback: GetTemplate reads the row with env first, then the base row (env "").
+func WithEnvironment(env string) Option {
+	return WithEnvironmentChain(env)
+}
+
+// WithEnvironmentChain sets the env fallback chain, most specific first: WithEnvironmentChain("prod-eu", "prod")
+// reads the prod-eu row, then prod, then the base row. Overlays (`overlay: true`) found on the way are applied over
+// the first complete manifest (see manifest.ParseEnvChain); Metadata.Environment lists them.
+func WithEnvironmentChain(envs ...string) Option {
+	return func(r *Registry) {
+		r.envs = slices.DeleteFunc(slices.Clone(envs), func(env string) bool { return env == "" })
+	}
+}
+
+// WithStrict builds every template with prompty.WithStrict, so rendering fails on unused payload fields
+// and "<no value>" output. Useful in CI to catch broken prompts.
+func WithStrict() Option {
+	return func(r *Registry) { r.strict = true }
+}
+
+// WithFuncs adds custom template functions to every template (see prompty.WithFuncs).
+// Loading fails with prompty.ErrFuncConflict if a name shadows a built-in.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Registry) { r.funcs = funcs }
+}
+
+// row is one stored manifest layer.
+type row struct {
+	body      []byte
+	format    string
+	version   string
+	updatedAt time.Time
+}
+
+// rebind rewrites "$n" placeholders for the registry's PlaceholderStyle.
+func (r *Registry) rebind(query string) string {
+	query = strings.ReplaceAll(query, "{table}", r.table)
+	if r.placeholder != PlaceholderQuestion {
+		return query
+	}
+	return dollarPlaceholder.ReplaceAllString(query, "?")
+}
+
+// layer returns the active row of id for env, or the row with version when version is set.
+// More than one active row (a table without the unique index of Schema) is an error, not a pick.
+func (r *Registry) layer(ctx context.Context, id, env, version string) (row, bool, error) {
+	query := `SELECT body, format, version, updated_at FROM {table} WHERE id = $1 AND env = $2 AND active = $3`
+	args := []any{id, env, true}
+	if version != "" {
+		query = `SELECT body, format, version, updated_at FROM {table} WHERE id = $1 AND env = $2 AND version = $3`
+		args = []any{id, env, version}
+	}
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return row{}, false, fmt.Errorf("sqlregistry: query %q: %w", id, err)
+	}
+	defer rows.Close()
+	var (
+		lr    row
+		body  string
+		found bool
+	)
+	for rows.Next() {
+		if found {
+			return row{}, false, fmt.Errorf("sqlregistry: %q env %q: %w", id, env, ErrMultipleActive)
+		}
+		if err := rows.Scan(&body, &lr.format, &lr.version, &lr.updatedAt); err != nil {
+			return row{}, false, fmt.Errorf("sqlregistry: query %q: %w", id, err)
+		}
+		found = true
+	}
+	if err := rows.Err(); err != nil {
+		return row{}, false, fmt.Errorf("sqlregistry: query %q: %w", id, err)
+	}
+	if !found {
+		return row{}, false, nil
+	}
+	lr.body = []byte(body)
+	return lr, true, nil
+}
+
+// unmarshaler returns the parser for a format column value.
+func (r *Registry) unmarshaler(format string) (manifest.Unmarshaler, error) {
+	if u, ok := r.formats[format]; ok {
+		return u, nil
+	}
+	if r.parser != nil {
+		return r.parser, nil
+	}
+	return nil, fmt.Errorf("%w: format %q", prompty.ErrNoParser, format)
+}
+
+// compose builds id over the env chain from rows read by read; it returns the most specific row found.
+func (r *Registry) compose(
+	ctx context.Context,
+	id string,
+	envs []string,
+	read func(env string) (row, bool, error),
+) (*prompty.ChatPromptTemplate, row, error) {
+	var top row
+	found := false
+	layers := func(env string) ([]byte, manifest.Unmarshaler, bool, error) {
+		lr, ok, err := read(env)
+		if err != nil || !ok {
+			return nil, nil, ok, err
+		}
+		u, err := r.unmarshaler(lr.format)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if !found {
+			top, found = lr, true
+		}
+		return lr.body, u, true, nil
+	}
+	opts := []manifest.ParseOption{manifest.WithBaseRegistry(ctx, r)}
+	if r.strict {
+		opts = append(opts, manifest.WithStrict())
+	}
+	if r.funcs != nil {
+		opts = append(opts, manifest.WithFuncs(r.funcs))
+	}
+	tpl, err := manifest.ParseEnvChainFormats(layers, envs, opts...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, row{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	}
+	if err != nil {
+		return nil, row{}, fmt.Errorf("sqlregistry: %q: %w", id, err)
+	}
+	if tpl.Metadata.Version == "" {
+		tpl.Metadata.Version = top.version
+	}
+	return tpl, top, nil
+}
+
+// GetTemplate returns the template composed from the active rows of id over the env chain.
+// "id@constraint" (e.g. "agent@^1.2", "agent@latest") resolves against Versions and reads the rows of that version.
+func (r *Registry) GetTemplate(ctx context.Context, id string) (*prompty.ChatPromptTemplate, error) {
+	base, version, err := r.resolve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tpl, _, err := r.compose(ctx, base, r.envs, func(env string) (row, bool, error) {
+		return r.layer(ctx, base, env, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// resolve validates id and splits "id@constraint" into the base id and the resolved version ("" without constraint).
+func (r *Registry) resolve(ctx context.Context, id string) (base, version string, err error) {
+	if ctx.Err() != nil {
+		return "", "", ctx.Err()
+	}
+	base, constraint, versioned := prompty.SplitVersionedID(id)
+	if !versioned {
+		base = id
+	}
+	if err := prompty.ValidateID(base); err != nil {
+		return "", "", err
+	}
+	if !versioned {
+		return base, "", nil
+	}
+	versions, err := r.Versions(ctx, base)
+	if err != nil {
+		return "", "", err
+	}
+	version, err = prompty.ResolveVersion(versions, constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("template %q: %w", base, err)
+	}
+	return base, version, nil
+}
+
+// Versions implements prompty.Versioner: the semantic versions stored for id (base rows and rows of the registry
+// envs, active or not), ascending.
+func (r *Registry) Versions(ctx context.Context, id string) ([]string, error) {
+	if err := prompty.ValidateID(id); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT DISTINCT version, env FROM {table} WHERE id = $1`), id)
+	if err != nil {
+		return nil, fmt.Errorf("sqlregistry: versions %q: %w", id, err)
+	}
+	defer rows.Close()
+	var versions []string
+	for rows.Next() {
+		var version, env string
+		if err := rows.Scan(&version, &env); err != nil {
+			return nil, fmt.Errorf("sqlregistry: versions %q: %w", id, err)
+		}
+		v, parseErr := prompty.ParseVersion(version)
+		if parseErr != nil || (env != "" && !slices.Contains(r.envs, env)) || slices.Contains(versions, v.String()) {
+			continue
+		}
+		versions = append(versions, v.String())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlregistry: versions %q: %w", id, err)
+	}
+	return prompty.SortVersions(versions), nil
+}
+
+// List returns the ids with an active base row or an active row for one of the registry envs, sorted.
+func (r *Registry) List(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT DISTINCT id, env FROM {table} WHERE active = $1`), true)
+	if err != nil {
+		return nil, fmt.Errorf("sqlregistry: list: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id, env string
+		if err := rows.Scan(&id, &env); err != nil {
+			return nil, fmt.Errorf("sqlregistry: list: %w", err)
+		}
+		if (env == "" || slices.Contains(r.envs, env)) && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlregistry: list: %w", err)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// Stat returns the version and update time of the rows GetTemplate would read, without parsing them:
+// Version from the most specific row (or the resolved version for "id@constraint"), UpdatedAt the latest
+// updated_at among the chain rows.
+func (r *Registry) Stat(ctx context.Context, id string) (prompty.TemplateInfo, error) {
+	base, version, err := r.resolve(ctx, id)
+	if err != nil {
+		return prompty.TemplateInfo{}, err
+	}
+	info := prompty.TemplateInfo{ID: id, Version: version, UpdatedAt: time.Time{}}
+	found := false
+	for _, env := range append(slices.Clone(r.envs), "") {
+		lr, ok, err := r.layer(ctx, base, env, version)
+		if err != nil {
+			return prompty.TemplateInfo{}, err
+		}
+		if !ok {
+			continue
+		}
+		if !found {
+			found = true
+			if info.Version == "" {
+				info.Version = lr.version
+			}
+		}
+		if lr.updatedAt.After(info.UpdatedAt) {
+			info.UpdatedAt = lr.updatedAt
+		}
+	}
+	if !found {
+		return prompty.TemplateInfo{}, fmt.Errorf("%w: %q", prompty.ErrTemplateNotFound, id)
+	}
+	return info, nil
+}
+
+// envsBelow returns env and the registry envs less specific than it: the chain a row for env is composed over.
+func (r *Registry) envsBelow(env string) []string {
+	if env == "" {
+		return nil
+	}
+	if i := slices.Index(r.envs, env); i >= 0 {
+		return slices.Clone(r.envs[i:])
+	}
+	return []string{env}
+}
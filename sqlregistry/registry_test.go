@@ -0,0 +1,306 @@
+package sqlregistry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skosovsky/prompty"
+	"github.com/skosovsky/prompty/manifest"
+	"github.com/skosovsky/prompty/parser/yaml"
+	"github.com/skosovsky/prompty/remoteregistry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func jsonManifest(id, version, text string) string {
+	return `{"id":"` + id + `","version":"` + version + `","messages":[{"role":"system","content":[{"type":"text","text":"` +
+		text + `"}]}]}`
+}
+
+func newRegistry(t *testing.T, opts ...Option) (*Registry, *fakeDB) {
+	t.Helper()
+	db, fdb := openFakeDB(t)
+	reg, err := New(db, append([]Option{
+		WithFormatParser("json", manifest.NewJSONParser()),
+		WithFormatParser("yaml", yaml.New()),
+	}, opts...)...)
+	require.NoError(t, err)
+	return reg, fdb
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	db, _ := openFakeDB(t)
+	_, err := New(db)
+	require.ErrorIs(t, err, prompty.ErrNoParser)
+	_, err = New(db, WithParser(manifest.NewJSONParser()), WithTable("prompts; DROP TABLE x"))
+	require.Error(t, err)
+	_, err = New(db, WithParser(manifest.NewJSONParser()), WithTable("app.prompts"))
+	require.NoError(t, err)
+	assert.Panics(t, func() { _, _ = New(nil) })
+}
+
+func TestRegistry_GetTemplate(t *testing.T) {
+	t.Parallel()
+	reg, fdb := newRegistry(t, WithEnvironmentChain("prod-eu", "prod"))
+	db := reg.db
+	insertRow(t, db, "support/agent", "", "1.0.0", jsonManifest("support/agent", "1.0.0", "Old"), false)
+	insertRow(t, db, "support/agent", "", "1.1.0", jsonManifest("support/agent", "1.1.0", "Base"), true)
+	insertRow(t, db, "support/agent", "prod-eu", "",
+		`{"overlay":true,"messages":[{"index":0,"content":[{"type":"text","text":"EU"}]}]}`, true)
+	insertRow(t, db, "support/agent", "staging", "", jsonManifest("support/agent", "", "Staging"), true)
+	insertRow(t, db, "other", "staging", "", jsonManifest("other", "", "Staging only"), true)
+	ctx := context.Background()
+
+	tpl, err := reg.GetTemplate(ctx, "support/agent")
+	require.NoError(t, err)
+	assert.Equal(t, "EU", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, "prod-eu", tpl.Metadata.Environment)
+	assert.Equal(t, "1.1.0", tpl.Metadata.Version)
+
+	tpl, err = reg.GetTemplate(ctx, "support/agent@~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "Old", tpl.Messages[0].Content[0].Text, "versioned reads ignore active")
+	assert.Equal(t, "1.0.0", tpl.Metadata.Version)
+
+	_, err = reg.GetTemplate(ctx, "support/agent@2")
+	require.ErrorIs(t, err, prompty.ErrVersionNotFound)
+	_, err = reg.GetTemplate(ctx, "other")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	_, err = reg.GetTemplate(ctx, "../x")
+	require.ErrorIs(t, err, prompty.ErrInvalidName)
+
+	versions, err := reg.Versions(ctx, "support/agent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+
+	ids, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support/agent"}, ids)
+
+	info, err := reg.Stat(ctx, "support/agent")
+	require.NoError(t, err)
+	assert.Equal(t, "support/agent", info.ID)
+	assert.Empty(t, info.Version, "the most specific row (prod-eu overlay) has no version")
+	assert.False(t, info.UpdatedAt.IsZero())
+	_, err = reg.Stat(ctx, "missing")
+	require.ErrorIs(t, err, prompty.ErrTemplateNotFound)
+	assert.Positive(t, queryCount(fdb))
+}
+
+func TestRegistry_Publish(t *testing.T) {
+	t.Parallel()
+	reg, fdb := newRegistry(t, WithEnvironment("prod"))
+	ctx := context.Background()
+
+	require.NoError(t, reg.Publish(ctx, Manifest{
+		ID:     "agent",
+		Format: "yaml",
+		Body:   []byte("id: agent\nversion: 1.0.0\nmessages:\n  - role: system\n    content: v1\n"),
+	}))
+	require.NoError(t, reg.Publish(ctx, Manifest{
+		ID: "agent", Version: "v1.1.0", Format: "json", Body: []byte(jsonManifest("agent", "1.1.0", "v1.1")),
+	}))
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1", tpl.Messages[0].Content[0].Text)
+	tpl, err = reg.GetTemplate(ctx, "agent@1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", tpl.Messages[0].Content[0].Text)
+
+	// Overlays are validated over the active base row and inherit its version.
+	require.NoError(t, reg.Publish(ctx, Manifest{
+		ID: "agent", Env: "prod", Format: "json", Body: []byte(`{"overlay":true,"model_config":{"model":"m-prod"}}`),
+	}))
+	require.NoError(t, reg.Publish(ctx, Manifest{
+		ID: "agent", Env: "prod", Format: "json", Body: []byte(`{"overlay":true,"model_config":{"model":"m-prod-2"}}`),
+	}), "the unversioned row is overwritten in place")
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "m-prod-2", tpl.ModelOptions.Model)
+	assert.Equal(t, "1.1.0", tpl.Metadata.Version)
+
+	active := 0
+	for _, r := range rowsOf(fdb) {
+		if r["active"] == true {
+			active++
+		}
+	}
+	assert.Equal(t, 2, active, "one active row per (id, env)")
+	assert.Len(t, rowsOf(fdb), 3)
+
+	for name, m := range map[string]Manifest{
+		"invalid manifest": {ID: "agent", Format: "json", Body: []byte(`{"id":"agent"`)},
+		"overlay without base": {
+			ID: "new", Env: "prod", Format: "json", Body: []byte(`{"overlay":true,"model_config":{"model":"m"}}`),
+		},
+		"unknown format": {ID: "agent", Format: "toml", Body: []byte(`id = "agent"`)},
+		"bad version":    {ID: "agent", Version: "1.x", Format: "json", Body: []byte(jsonManifest("agent", "", "x"))},
+		"bad env":        {ID: "agent", Env: "a.b", Format: "json", Body: []byte(jsonManifest("agent", "", "x"))},
+	} {
+		require.Error(t, reg.Publish(ctx, m), name)
+	}
+	assert.Len(t, rowsOf(fdb), 3, "rejected manifests are not stored")
+}
+
+func TestRegistry_Publish_RollsBack(t *testing.T) {
+	t.Parallel()
+	reg, fdb := newRegistry(t)
+	ctx := context.Background()
+	require.NoError(t, reg.Publish(ctx, Manifest{
+		ID: "agent", Version: "1.0.0", Format: "json", Body: []byte(jsonManifest("agent", "1.0.0", "v1")),
+	}))
+
+	// Duplicate version: the insert fails after the current row was deactivated.
+	err := reg.Publish(ctx, Manifest{
+		ID: "agent", Version: "1.0.0", Format: "json", Body: []byte(jsonManifest("agent", "1.0.0", "again")),
+	})
+	require.Error(t, err)
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", tpl.Messages[0].Content[0].Text)
+
+	testDriver.mu.Lock()
+	fdb.failOn = "INSERT"
+	testDriver.mu.Unlock()
+	err = reg.Publish(ctx, Manifest{
+		ID: "agent", Version: "2.0.0", Format: "json", Body: []byte(jsonManifest("agent", "2.0.0", "v2")),
+	})
+	require.ErrorContains(t, err, "injected failure")
+	tpl, err = reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", tpl.Messages[0].Content[0].Text)
+	assert.Len(t, rowsOf(fdb), 1)
+}
+
+func TestRegistry_Publish_ConcurrentActivation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	raced := func(fdb *fakeDB) {
+		testDriver.mu.Lock()
+		fdb.race = map[string]any{
+			"id": "agent", "env": "", "version": "1.1.0", "body": jsonManifest("agent", "1.1.0", "other"),
+			"format": "json", "updated_at": time.Now().UTC(), "active": true,
+		}
+		testDriver.mu.Unlock()
+	}
+	publish := func(reg *Registry) error {
+		return reg.Publish(ctx, Manifest{
+			ID: "agent", Version: "1.2.0", Format: "json", Body: []byte(jsonManifest("agent", "1.2.0", "mine")),
+		})
+	}
+
+	t.Run("unique index", func(t *testing.T) {
+		t.Parallel()
+		reg, fdb := newRegistry(t)
+		require.NoError(t, reg.Publish(ctx, Manifest{
+			ID: "agent", Version: "1.0.0", Format: "json", Body: []byte(jsonManifest("agent", "1.0.0", "v1")),
+		}))
+		raced(fdb)
+		require.ErrorContains(t, publish(reg), "where active", "the publish committing second fails")
+		tpl, err := reg.GetTemplate(ctx, "agent")
+		require.NoError(t, err)
+		assert.Equal(t, "other", tpl.Messages[0].Content[0].Text)
+		active := 0
+		for _, r := range rowsOf(fdb) {
+			if r["active"] == true {
+				active++
+			}
+		}
+		assert.Equal(t, 1, active)
+	})
+
+	t.Run("no index", func(t *testing.T) {
+		t.Parallel()
+		reg, fdb := newRegistry(t)
+		testDriver.mu.Lock()
+		fdb.noActiveIndex = true
+		testDriver.mu.Unlock()
+		require.NoError(t, reg.Publish(ctx, Manifest{
+			ID: "agent", Version: "1.0.0", Format: "json", Body: []byte(jsonManifest("agent", "1.0.0", "v1")),
+		}))
+		raced(fdb)
+		require.NoError(t, publish(reg))
+		_, err := reg.GetTemplate(ctx, "agent")
+		require.ErrorIs(t, err, ErrMultipleActive, "two active rows are reported, not picked from")
+		_, err = reg.Stat(ctx, "agent")
+		require.ErrorIs(t, err, ErrMultipleActive)
+	})
+}
+
+// recordingParser decodes JSON and records its name on every call.
+type recordingParser struct {
+	name  string
+	calls *[]string
+}
+
+func (p recordingParser) Unmarshal(data []byte, v any) error {
+	*p.calls = append(*p.calls, p.name)
+	return manifest.NewJSONParser().Unmarshal(data, v)
+}
+
+func TestRegistry_GetTemplate_ParserPerRow(t *testing.T) {
+	t.Parallel()
+	var calls []string
+	reg, _ := newRegistry(t,
+		WithEnvironmentChain("prod-eu", "prod"),
+		WithFormatParser("a", recordingParser{name: "a", calls: &calls}),
+		WithFormatParser("b", recordingParser{name: "b", calls: &calls}),
+	)
+	db := reg.db
+	ctx := context.Background()
+	overlay := `{"overlay":true,"model_config":{"model":"m"}}`
+	insertRow(t, db, "agent", "", "", jsonManifest("agent", "1.0.0", "base"), true)
+	insertRowFormat(t, db, "agent", "prod", overlay, "a")
+	insertRowFormat(t, db, "agent", "prod-eu", overlay, "b")
+
+	tpl, err := reg.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "prod,prod-eu", tpl.Metadata.Environment)
+	assert.Equal(t, []string{"b", "a"}, calls, "identical bodies are decoded by the parser of their own row")
+
+	insertRowFormat(t, db, "other", "", jsonManifest("other", "", "x"), "toml")
+	_, err = reg.GetTemplate(ctx, "other")
+	require.ErrorIs(t, err, prompty.ErrNoParser)
+}
+
+func TestRegistry_WithCache(t *testing.T) {
+	t.Parallel()
+	reg, fdb := newRegistry(t)
+	ctx := context.Background()
+	require.NoError(t, reg.Publish(ctx, Manifest{
+		ID: "agent", Version: "1.0.0", Format: "json", Body: []byte(jsonManifest("agent", "1.0.0", "v1")),
+	}))
+	cached := remoteregistry.WithCache(reg, time.Hour)
+	t.Cleanup(func() { _ = cached.Close() })
+
+	_, err := cached.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	before := queryCount(fdb)
+	tpl, err := cached.GetTemplate(ctx, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", tpl.Messages[0].Content[0].Text)
+	assert.Equal(t, before, queryCount(fdb), "second read is served from the cache")
+
+	ids, err := cached.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent"}, ids)
+	info, err := cached.Stat(ctx, "agent@latest")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", info.Version)
+}
+
+func TestRegistry_QuestionPlaceholders(t *testing.T) {
+	t.Parallel()
+	reg, _ := newRegistry(t, WithPlaceholders(PlaceholderQuestion), WithTable("prompts"))
+	assert.Equal(t,
+		"SELECT x FROM prompts WHERE id = ? AND env = ?",
+		reg.rebind("SELECT x FROM {table} WHERE id = $1 AND env = $2"))
+}
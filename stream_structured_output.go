@@ -21,7 +21,9 @@ const (
 	streamJSONModeArray
 )
 
-// StreamStructuredOutput streams structured JSON objects from ExecuteStream.
+// StreamStructuredOutput streams structured JSON objects from ExecuteStream. Each object is validated against
+// ResponseFormat.Schema (its items schema when the model streams an array) before it is decoded into T;
+// a violation is yielded as a *ValidationError, like ExecuteWithStructuredOutput returns it.
 func StreamStructuredOutput[T any](ctx context.Context, invoker Invoker, exec *PromptExecution) iter.Seq2[T, error] {
 	return func(yield func(T, error) bool) {
 		var zero T
@@ -40,7 +42,7 @@ func StreamStructuredOutput[T any](ctx context.Context, invoker Invoker, exec *P
 		streamCtx, cancel := context.WithCancel(ctx)
 		defer cancel()
 
-		parser := newStructuredStreamParser[T]()
+		parser := newStructuredStreamParser[T](workExec.ResponseFormat)
 		for chunk, err := range invoker.ExecuteStream(streamCtx, workExec) {
 			if err != nil {
 				cancel()
@@ -53,17 +55,17 @@ func StreamStructuredOutput[T any](ctx context.Context, invoker Invoker, exec *P
 
 			for _, text := range textPartsFromContent(chunk.Content) {
 				items, parseErr := parser.feed(text)
-				if parseErr != nil {
-					cancel()
-					yield(zero, parseErr)
-					return
-				}
 				for _, item := range items {
 					if !yield(item, nil) {
 						cancel()
 						return
 					}
 				}
+				if parseErr != nil {
+					cancel()
+					yield(zero, parseErr)
+					return
+				}
 			}
 		}
 
@@ -102,12 +104,19 @@ type structuredStreamParser[T any] struct {
 	escape        bool
 	current       bytes.Buffer
 	preview       bytes.Buffer
+	schema        map[string]any // response schema the streamed objects are validated against (nil: none)
+	items         int            // objects decoded so far (array index of the next one)
 }
 
-func newStructuredStreamParser[T any]() *structuredStreamParser[T] {
-	return &structuredStreamParser[T]{}
+func newStructuredStreamParser[T any](format *SchemaDefinition) *structuredStreamParser[T] {
+	p := &structuredStreamParser[T]{}
+	if format != nil && len(format.Schema) > 0 {
+		p.schema = format.Schema
+	}
+	return p
 }
 
+// feed consumes text and returns the objects it completes; on error, with the objects completed before it.
 func (p *structuredStreamParser[T]) feed(text string) ([]T, error) {
 	var out []T
 	for i := range len(text) {
@@ -117,7 +126,7 @@ func (p *structuredStreamParser[T]) feed(text string) ([]T, error) {
 			if p.consumeFenceOrWhitespace(ch) {
 				continue
 			}
-			return nil, p.errorf("unexpected trailing data after structured output")
+			return out, p.errorf("unexpected trailing data after structured output")
 		}
 
 		if !p.started {
@@ -134,7 +143,7 @@ func (p *structuredStreamParser[T]) feed(text string) ([]T, error) {
 				p.mode = streamJSONModeArray
 				p.appendPreviewByte(ch)
 			default:
-				return nil, p.errorf("unexpected prefix byte %q before JSON payload", ch)
+				return out, p.errorf("unexpected prefix byte %q before JSON payload", ch)
 			}
 			continue
 		}
@@ -143,7 +152,7 @@ func (p *structuredStreamParser[T]) feed(text string) ([]T, error) {
 		case streamJSONModeObject:
 			item, completed, err := p.consumeObjectByte(ch)
 			if err != nil {
-				return nil, err
+				return out, err
 			}
 			if completed {
 				out = append(out, item)
@@ -151,11 +160,11 @@ func (p *structuredStreamParser[T]) feed(text string) ([]T, error) {
 		case streamJSONModeArray:
 			items, err := p.consumeArrayByte(ch)
 			if err != nil {
-				return nil, err
+				return out, err
 			}
 			out = append(out, items...)
 		default:
-			return nil, p.errorf("unknown JSON stream mode")
+			return out, p.errorf("unknown JSON stream mode")
 		}
 	}
 	return out, nil
@@ -294,6 +303,10 @@ func (p *structuredStreamParser[T]) decodeCurrent() (T, error) {
 	var item T
 
 	raw := p.current.String()
+	if err := p.validateSchema(raw); err != nil {
+		return item, err
+	}
+	p.items++
 	if err := json.Unmarshal([]byte(raw), &item); err != nil {
 		return item, p.errorf("failed to decode streamed JSON object: %v", err)
 	}
@@ -306,6 +319,37 @@ func (p *structuredStreamParser[T]) decodeCurrent() (T, error) {
 	return item, nil
 }
 
+// validateSchema checks the captured object against the response schema: the items schema (path "$[i]") when
+// the stream is an array and the schema describes one, otherwise the whole schema.
+func (p *structuredStreamParser[T]) validateSchema(raw string) error {
+	if p.schema == nil {
+		return nil
+	}
+	doc, err := decodeJSONDocument([]byte(raw))
+	if err != nil {
+		return nil // syntax errors are reported by decoding into T
+	}
+	var schema any = p.schema
+	path := "$"
+	if items, ok := p.schema["items"]; ok && p.mode == streamJSONModeArray {
+		schema = items
+		path = fmt.Sprintf("$[%d]", p.items)
+	}
+	err = validateJSONSubschema(p.schema, schema, doc, path)
+	if errors.Is(err, ErrInvalidSchema) {
+		return fmt.Errorf("stream structured output: %w", err)
+	}
+	if err != nil {
+		msg := newAssistantMessageWithContent([]ContentPart{TextPart{Text: raw}})
+		return &ValidationError{
+			RawAssistantMessage: &msg,
+			FeedbackPrompt:      validationFeedbackText(err),
+			Err:                 err,
+		}
+	}
+	return nil
+}
+
 func (p *structuredStreamParser[T]) appendPreviewByte(ch byte) {
 	p.preview.WriteByte(ch)
 	if p.preview.Len() <= streamBufferPreviewLimit {
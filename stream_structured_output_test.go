@@ -236,6 +236,55 @@ func TestStreamStructuredOutput_SemanticValidationCancelsContext(t *testing.T) {
 	}
 }
 
+func TestStreamStructuredOutput_SchemaViolation(t *testing.T) {
+	t.Parallel()
+
+	streamText := func(text string) *scriptedInvoker {
+		return &scriptedInvoker{
+			generateStream: func(context.Context, *PromptExecution) iter.Seq2[*ResponseChunk, error] {
+				return func(yield func(*ResponseChunk, error) bool) {
+					yield(&ResponseChunk{Content: []ContentPart{TextPart{Text: text}}, IsFinished: true}, nil)
+				}
+			},
+		}
+	}
+	answer := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"answer": map[string]any{"type": "string", "enum": []any{"a", "b"}}},
+		"required":   []any{"answer"},
+	}
+
+	exec := SimplePrompt("hi")
+	exec.ResponseFormat = &SchemaDefinition{Name: "answer", Schema: answer}
+	items, err := collectSeq(
+		StreamStructuredOutput[valueSchemaResult](context.Background(), streamText(`{"answer":"c"}`), exec),
+	)
+	assert.Empty(t, items)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.ErrorIs(t, err, ErrSchemaViolation)
+	assert.Contains(t, validationErr.FeedbackPrompt, "$.answer")
+	require.NotNil(t, validationErr.RawAssistantMessage)
+	raw, ok := validationErr.RawAssistantMessage.Content[0].(TextPart)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"answer":"c"}`, raw.Text)
+
+	// Array streams validate each object against the items schema; earlier objects are still yielded.
+	exec = SimplePrompt("hi")
+	exec.ResponseFormat = &SchemaDefinition{
+		Name:   "answers",
+		Schema: map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/answer"}},
+	}
+	exec.ResponseFormat.Schema["$defs"] = map[string]any{"answer": answer}
+	items, err = collectSeq(
+		StreamStructuredOutput[valueSchemaResult](context.Background(), streamText(`[{"answer":"a"},{}]`), exec),
+	)
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.FeedbackPrompt, "$[1]")
+	require.Len(t, items, 1)
+	assert.Equal(t, "a", items[0].Answer)
+}
+
 func TestStreamStructuredOutput_EarlyStopCancelsContext(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,211 @@
+package prompty
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// noValueMarker is what text/template prints for nil values; strict rendering treats it as a broken prompt.
+const noValueMarker = "<no value>"
+
+// referenceSet holds payload fields referenced by the message templates, partials, when and for_each.
+type referenceSet struct {
+	names   map[string]bool
+	payload map[string]bool // names read from the payload itself, not from a range/with dot
+	all     bool            // the whole payload (dot or $) is passed somewhere, so every field counts as used
+	loop    bool            // some message has for_each, so item/index are loop scope variables
+}
+
+// collectReferences walks all parsed messages; partials invoked with {{ template "x" . }} are followed through root.
+func collectReferences(parsed []parsedMessage, root *template.Template) referenceSet {
+	rs := referenceSet{names: make(map[string]bool), payload: make(map[string]bool), all: false, loop: false}
+	followed := make(map[string]bool)
+	var visit func(pms []parsedMessage)
+	visit = func(pms []parsedMessage) {
+		for _, pm := range pms {
+			for _, name := range pm.whenVars {
+				rs.add(name, false)
+			}
+			for _, name := range pm.vars {
+				rs.add(name, true) // extracted without scope; the part trees below record payload reads
+			}
+			if len(pm.forEach) > 0 {
+				rs.add(pm.forEach[0], false)
+				rs.loop = true
+			}
+			for _, part := range pm.parts {
+				for _, tmpl := range part.templates() {
+					if tmpl != nil && tmpl.Tree != nil {
+						rs.walk(tmpl.Tree.Root, root, false, followed)
+					}
+				}
+			}
+			visit(pm.turns)
+		}
+	}
+	visit(parsed)
+	return rs
+}
+
+// walk records field references under node. scoped is true inside range/with bodies, where dot is not the payload.
+func (rs *referenceSet) walk(node parse.Node, root *template.Template, scoped bool, followed map[string]bool) {
+	if isNilNode(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		rs.add(n.Ident[0], scoped)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			if len(n.Ident) == 1 {
+				rs.all = true
+				return
+			}
+			rs.add(n.Ident[1], false)
+		}
+	case *parse.DotNode:
+		if !scoped {
+			rs.all = true
+		}
+	case *parse.ChainNode:
+		rs.walk(n.Node, root, scoped, followed)
+	case *parse.ListNode:
+		for _, c := range n.Nodes {
+			rs.walk(c, root, scoped, followed)
+		}
+	case *parse.ActionNode:
+		rs.walk(n.Pipe, root, scoped, followed)
+	case *parse.PipeNode:
+		for _, c := range n.Cmds {
+			rs.walk(c, root, scoped, followed)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			rs.walk(a, root, scoped, followed)
+		}
+	case *parse.IfNode:
+		rs.walk(n.Pipe, root, scoped, followed)
+		rs.walk(n.List, root, scoped, followed)
+		rs.walk(n.ElseList, root, scoped, followed)
+	case *parse.RangeNode:
+		rs.walk(n.Pipe, root, scoped, followed)
+		rs.walk(n.List, root, true, followed)
+		rs.walk(n.ElseList, root, scoped, followed)
+	case *parse.WithNode:
+		rs.walk(n.Pipe, root, scoped, followed)
+		rs.walk(n.List, root, true, followed)
+		rs.walk(n.ElseList, root, scoped, followed)
+	case *parse.TemplateNode:
+		rs.walkTemplateCall(n, root, scoped, followed)
+	}
+}
+
+// add records a reference to name; scoped references may be fields of a range/with dot rather than the payload.
+func (rs *referenceSet) add(name string, scoped bool) {
+	rs.names[name] = true
+	if !scoped {
+		rs.payload[name] = true
+	}
+}
+
+// walkTemplateCall follows a partial invoked with the payload as dot; other arguments are walked as expressions.
+func (rs *referenceSet) walkTemplateCall(
+	n *parse.TemplateNode,
+	root *template.Template,
+	scoped bool,
+	followed map[string]bool,
+) {
+	if scoped || !isDotPipe(n.Pipe) {
+		rs.walk(n.Pipe, root, scoped, followed)
+		return
+	}
+	if followed[n.Name] {
+		return
+	}
+	followed[n.Name] = true
+	partial := root.Lookup(n.Name)
+	if partial == nil || partial.Tree == nil {
+		rs.all = true
+		return
+	}
+	rs.walk(partial.Tree.Root, root, false, followed)
+}
+
+// isDotPipe reports whether pipe is exactly "." (the payload itself).
+func isDotPipe(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	_, ok := pipe.Cmds[0].Args[0].(*parse.DotNode)
+	return ok
+}
+
+// UnusedVariables returns payload fields (sorted) that no message template, partial, when condition
+// or for_each references. Tools is ignored. Fields read dynamically (e.g. via index or a bare dot
+// passed to a function) cannot be tracked; in that case nothing is reported.
+func (c *ChatPromptTemplate) UnusedVariables(vars map[string]any) []string {
+	if c.references.all {
+		return nil
+	}
+	var out []string
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		if name != "Tools" && !c.references.names[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// ReferencedVariables returns payload fields (sorted) that message templates, partials, when conditions
+// and for_each read directly: fields read inside range/with blocks, Tools and, when a message has for_each,
+// the loop variables item and index are left out. complete is false when the whole payload is passed somewhere
+// (e.g. a bare dot to a function) or a partial cannot be resolved, so other fields may be read as well.
+func (c *ChatPromptTemplate) ReferencedVariables() (names []string, complete bool) {
+	for _, name := range slices.Sorted(maps.Keys(c.references.payload)) {
+		if name == "Tools" || (c.references.loop && (name == loopItemVar || name == loopIndexVar)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, !c.references.all
+}
+
+// checkStrict builds a StrictError from unused input fields and locations of leftover <no value> markers.
+func (c *ChatPromptTemplate) checkStrict(input map[string]any, noValue []string) error {
+	unused := c.UnusedVariables(input)
+	if len(unused) == 0 && len(noValue) == 0 {
+		return nil
+	}
+	return &StrictError{Template: c.Metadata.ID, UnusedFields: unused, NoValue: noValue}
+}
+
+// findNoValue returns locations (label part N [field]) of rendered parts that contain the <no value> marker.
+func findNoValue(label string, msgs []ChatMessage) []string {
+	var out []string
+	for m, msg := range msgs {
+		loc := label
+		if len(msgs) > 1 {
+			loc = fmt.Sprintf("%s output %d", label, m)
+		}
+		for j, part := range msg.Content {
+			switch p := part.(type) {
+			case TextPart:
+				if strings.Contains(p.Text, noValueMarker) {
+					out = append(out, fmt.Sprintf("%s part %d", loc, j))
+				}
+			case MediaPart:
+				fields := [][2]string{{"media_type", p.MediaType}, {"mime_type", p.MIMEType}, {"url", p.URL}}
+				for _, f := range fields {
+					if strings.Contains(f[1], noValueMarker) {
+						out = append(out, fmt.Sprintf("%s part %d %s", loc, j, f[0]))
+					}
+				}
+			}
+		}
+	}
+	return out
+}
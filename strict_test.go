@@ -0,0 +1,145 @@
+package prompty
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat_StrictUnusedFields(t *testing.T) {
+	t.Parallel()
+	msgs := []MessageTemplate{{Role: RoleUser, Content: TextContent("Hi {{ .name }}")}}
+	lax, err := NewChatPromptTemplate(msgs, WithPartialVariables(map[string]any{"tone": "warm"}))
+	require.NoError(t, err)
+	_, err = lax.Format(map[string]any{"name": "Ann", "nmae": "typo"})
+	require.NoError(t, err)
+
+	strict, err := NewChatPromptTemplate(msgs,
+		WithStrict(),
+		WithPartialVariables(map[string]any{"tone": "warm"}),
+		WithMetadata(PromptMetadata{ID: "greet"}),
+	)
+	require.NoError(t, err)
+	exec, err := strict.Format(map[string]any{"name": "Ann"})
+	require.NoError(t, err, "partial variables are not reported")
+	assert.Equal(t, "Hi Ann", textOf(t, exec.Messages[0]))
+
+	_, err = strict.Format(map[string]any{"name": "Ann", "nmae": "typo", "age": 3})
+	require.ErrorIs(t, err, ErrStrictRender)
+	var se *StrictError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, "greet", se.Template)
+	assert.Equal(t, []string{"age", "nmae"}, se.UnusedFields)
+	assert.Empty(t, se.NoValue)
+	assert.Contains(t, err.Error(), "unused payload fields age, nmae")
+
+	type payload struct {
+		Name  string `prompt:"name"`
+		Extra string `prompt:"extra"`
+	}
+	_, err = strict.FormatStruct(&payload{Name: "Ann", Extra: "x"})
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, []string{"extra"}, se.UnusedFields)
+	_, err = CloneTemplate(strict).Format(map[string]any{"name": "Ann", "extra": 1})
+	require.ErrorIs(t, err, ErrStrictRender, "clone keeps strict mode")
+}
+
+func TestFormat_StrictNoValue(t *testing.T) {
+	t.Parallel()
+	msgs := []MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("ok")},
+		{Role: RoleUser, Content: []TemplatePart{
+			{Type: "text", Text: "User {{ .user.name }}"},
+			{Type: "media", URL: "{{ .user.avatar }}", MIMEType: "image/png"},
+		}},
+	}
+	vars := map[string]any{"user": map[string]any{"name": nil, "avatar": nil}}
+	lax, err := NewChatPromptTemplate(msgs)
+	require.NoError(t, err)
+	exec, err := lax.Format(vars)
+	require.NoError(t, err)
+	text, ok := exec.Messages[1].Content[0].(TextPart)
+	require.True(t, ok)
+	assert.Equal(t, "User <no value>", text.Text)
+
+	strict, err := NewChatPromptTemplate(msgs, WithStrict())
+	require.NoError(t, err)
+	_, err = strict.Format(vars)
+	var se *StrictError
+	require.ErrorAs(t, err, &se)
+	assert.Empty(t, se.UnusedFields)
+	assert.Equal(t, []string{"message 1 part 0", "message 1 part 1 url"}, se.NoValue)
+
+	_, err = strict.Format(map[string]any{"user": map[string]any{"nmae": "Ann"}})
+	require.ErrorIs(t, err, ErrTemplateRender, "missing nested keys fail regardless of strict mode")
+	assert.False(t, errors.Is(err, ErrStrictRender))
+}
+
+func TestFormat_StrictForEachNoValue(t *testing.T) {
+	t.Parallel()
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleUser, ForEach: "docs", Content: TextContent("{{ .item.text }}")},
+	}, WithStrict())
+	require.NoError(t, err)
+	_, err = tpl.Format(map[string]any{"docs": []map[string]any{{"text": "a"}, {"text": nil}}})
+	var se *StrictError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, []string{"message 0 output 1 part 0"}, se.NoValue)
+}
+
+func TestUnusedVariables(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	sig := `{{ define "sig" }}-- {{ .author }}{{ end }}`
+	row := `{{ define "row" }}{{ .title }}{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sig.tmpl"), []byte(sig), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "row.tmpl"), []byte(row), 0o600))
+
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, When: "mode == 'expert'", Content: TextContent(
+			`{{ range .items }}{{ template "row" . }} {{ $.suffix }}{{ end }}{{ template "sig" . }}`,
+		)},
+		{Role: RoleUser, ForEach: "shots", Content: TextContent("{{ .item }}")},
+		{Role: RoleUser, Optional: true, Content: TextContent("{{ with .ctx }}{{ .title }}{{ end }}")},
+	}, WithPartialsGlob(filepath.Join(dir, "*.tmpl")))
+	require.NoError(t, err)
+	vars := map[string]any{
+		"mode": "x", "items": nil, "suffix": "", "author": "", "shots": nil, "ctx": nil,
+		"title": "", "unused": 1, "Tools": nil,
+	}
+	// title is only read relative to range/with dot, but extraction is conservative and counts it.
+	assert.Equal(t, []string{"unused"}, tpl.UnusedVariables(vars))
+
+	dynamic, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleUser, Content: TextContent("{{ render_tools_as_json . }}")},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, dynamic.UnusedVariables(map[string]any{"anything": 1}), "bare dot uses the whole payload")
+}
+
+func TestReferencedVariables(t *testing.T) {
+	t.Parallel()
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, When: "mode == 'expert'", Content: TextContent(
+			`{{ range .items }}{{ .title }} {{ $.suffix }}{{ end }}{{ len .Tools }}`,
+		)},
+		{Role: RoleUser, ForEach: "shots", Content: TextContent("{{ .item }} {{ .index }}")},
+		{Role: RoleUser, Content: TextContent("{{ with .ctx }}{{ .name }}{{ end }}")},
+	})
+	require.NoError(t, err)
+	names, complete := tpl.ReferencedVariables()
+	assert.True(t, complete)
+	assert.Equal(t, []string{"ctx", "items", "mode", "shots", "suffix"}, names)
+
+	dynamic, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleUser, Content: TextContent("{{ .q }} {{ render_tools_as_json . }}")},
+	})
+	require.NoError(t, err)
+	names, complete = dynamic.ReferencedVariables()
+	assert.False(t, complete, "bare dot uses the whole payload")
+	assert.Equal(t, []string{"q"}, names)
+}
@@ -72,6 +72,8 @@ func findClosingFenceOffset(s string) (int, bool) {
 }
 
 // ExecuteWithStructuredOutput performs a single request to the LLM and parses the response as JSON into type T.
+// The output is validated against exec.ResponseFormat.Schema (the schema of T when unset, see ValidateJSON) and
+// then by Validatable; failures return *ValidationError whose FeedbackPrompt lists each violation by JSON path.
 func ExecuteWithStructuredOutput[T any](
 	ctx context.Context,
 	invoker Invoker,
@@ -94,6 +96,15 @@ func ExecuteWithStructuredOutput[T any](
 	}
 
 	assistantMsg := newAssistantMessageWithContent(resp.Content)
+	if err := validateStructuredSchema(workExec.ResponseFormat, resp.Text()); errors.Is(err, ErrInvalidSchema) {
+		return nil, fmt.Errorf("structured output: %w", err) // another attempt cannot fix the schema
+	} else if err != nil {
+		return nil, &ValidationError{
+			RawAssistantMessage: &assistantMsg,
+			FeedbackPrompt:      validationFeedbackText(err),
+			Err:                 err,
+		}
+	}
 	result, err := decodeStructuredOutput[T](resp.Text())
 	if err != nil {
 		return nil, &ValidationError{
@@ -136,6 +147,19 @@ func schemaForStructuredType[T any]() (map[string]any, error) {
 	return extractSchemaFromType(reflect.TypeFor[T]())
 }
 
+// validateStructuredSchema checks raw model output against the response schema before it is decoded into T, so
+// missing fields and type mismatches are reported with their JSON paths. Syntax errors are left to decoding.
+func validateStructuredSchema(format *SchemaDefinition, raw string) error {
+	if format == nil || len(format.Schema) == 0 {
+		return nil
+	}
+	doc, err := decodeJSONDocument([]byte(stripMarkdownJSON(raw)))
+	if err != nil {
+		return nil
+	}
+	return validateJSONValue(format.Schema, doc)
+}
+
 func decodeStructuredOutput[T any](raw string) (*T, error) {
 	var result T
 	rawText := stripMarkdownJSON(raw)
@@ -196,6 +220,16 @@ func validatableFromValue(value reflect.Value) (Validatable, bool) {
 }
 
 func validationFeedbackText(validationError error) string {
+	var schemaErr *SchemaError
+	if errors.As(validationError, &schemaErr) {
+		var b strings.Builder
+		b.WriteString("JSON does not match the required schema:\n")
+		for _, v := range schemaErr.Violations {
+			b.WriteString("- " + v.String() + "\n")
+		}
+		b.WriteString("Please fix your output.")
+		return b.String()
+	}
 	return fmt.Sprintf("JSON validation failed: %v. Please fix your output.", validationError)
 }
 
@@ -76,6 +76,58 @@ func TestExecuteWithStructuredOutput_SemanticValidationReturnsValidationError(t
 	)
 }
 
+func TestExecuteWithStructuredOutput_SchemaViolationReturnsPathFeedback(t *testing.T) {
+	t.Parallel()
+
+	type line struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty" jsonschema:"minimum=1"`
+	}
+	type order struct {
+		Status string `json:"status" jsonschema:"enum=open|closed"`
+		Lines  []line `json:"lines"`
+	}
+	invoker := &scriptedInvoker{
+		generate: func(context.Context, *PromptExecution) (*Response, error) {
+			return NewResponse([]ContentPart{TextPart{Text: `{"status":"done","lines":[{"sku":"a","qty":"2"},{"qty":0}]}`}}), nil
+		},
+	}
+
+	result, err := ExecuteWithStructuredOutput[order](context.Background(), invoker, SimplePrompt("hi"))
+	require.Nil(t, result)
+	require.ErrorIs(t, err, ErrSchemaViolation)
+	var valErr *ValidationError
+	require.ErrorAs(t, err, &valErr)
+	assert.Equal(t, "JSON does not match the required schema:\n"+
+		"- $.lines[0].qty: expected integer, got string\n"+
+		"- $.lines[1]: missing required property \"sku\"\n"+
+		"- $.lines[1].qty: must be >= 1, got 0\n"+
+		"- $.status: must be one of \"open\", \"closed\", got \"done\"\n"+
+		"Please fix your output.", valErr.FeedbackPrompt)
+}
+
+func TestExecuteWithStructuredOutput_InvalidSchemaIsNotRetried(t *testing.T) {
+	t.Parallel()
+
+	invoker := &scriptedInvoker{
+		generate: func(context.Context, *PromptExecution) (*Response, error) {
+			return NewResponse([]ContentPart{TextPart{Text: `{"code":"A1"}`}}), nil
+		},
+	}
+	exec := SimplePrompt("hi")
+	exec.ResponseFormat = &SchemaDefinition{Schema: map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"code": map[string]any{"type": "string", "pattern": `^(?!X)`}},
+	}}
+
+	result, err := ExecuteWithStructuredOutput[map[string]any](context.Background(), invoker, exec)
+	require.Nil(t, result)
+	require.ErrorIs(t, err, ErrInvalidSchema)
+	require.NotErrorIs(t, err, ErrSchemaViolation)
+	var valErr *ValidationError
+	assert.NotErrorAs(t, err, &valErr, "no feedback prompt: another attempt cannot fix the schema")
+}
+
 func TestExecuteWithStructuredOutput_AutoSchemaValueReceiver(t *testing.T) {
 	t.Parallel()
 
@@ -241,7 +293,7 @@ func TestExecuteWithStructuredOutput_UnsupportedTypeFailsPreflight(t *testing.T)
 	t.Parallel()
 
 	type unsupported struct {
-		Values map[string]string `json:"values"`
+		Values chan string `json:"values"`
 	}
 
 	callNum := 0
@@ -41,9 +41,12 @@ type ChatPromptTemplate struct {
 	RequiredVars     []string          // explicit required vars from manifest; merged with template-derived in FormatStruct
 	requiredFromAST  []string          // pre-computed in constructor from non-optional message templates
 	tokenCounter     TokenCounter
+	funcs            template.FuncMap // custom functions from WithFuncs
 	parsedTemplates  []parsedMessage
-	partialsGlob     string   // e.g. "_partials/*.tmpl" for ParseGlob
-	partialsFS       struct { // for ParseFS (e.g. embed)
+	references       referenceSet // payload fields referenced by templates, for UnusedVariables
+	strict           bool         // WithStrict: fail on unused payload fields and <no value> output
+	partialsGlob     string       // e.g. "_partials/*.tmpl" for ParseGlob
+	partialsFS       struct {     // for ParseFS (e.g. embed)
 		fsys    fs.FS
 		pattern string
 	}
@@ -55,6 +58,7 @@ type parsedPart struct {
 	mediaTypeTpl *template.Template
 	mimeTypeTpl  *template.Template
 	urlTpl       *template.Template
+	when         *whenExpr
 	cacheControl *CacheControl
 }
 
@@ -62,9 +66,13 @@ type parsedMessage struct {
 	parts        []parsedPart
 	role         Role
 	optional     bool
+	when         *whenExpr
 	cacheControl *CacheControl
 	metadata     map[string]any // provider-specific; copied to ChatMessage on render
 	vars         []string       // pre-computed from all parts for optional-skip check
+	whenVars     []string       // variables of message and part when conditions (required unless optional)
+	forEach      []string       // for_each list variable path; nil for plain messages
+	turns        []parsedMessage
 }
 
 // NewChatPromptTemplate builds a template with defensive copies and applies options.
@@ -96,7 +104,10 @@ func NewChatPromptTemplate(
 	if tc == nil {
 		tc = &CharFallbackCounter{}
 	}
-	funcMap := defaultFuncMap(tc)
+	funcMap, err := funcMapWith(tc, tpl.funcs)
+	if err != nil {
+		return nil, err
+	}
 	root, err := template.New("root").Funcs(funcMap).Parse("")
 	if err != nil {
 		return nil, fmt.Errorf("%w: root: %w", ErrTemplateParse, err)
@@ -125,101 +136,17 @@ func NewChatPromptTemplate(
 	}
 	tpl.parsedTemplates = make([]parsedMessage, 0, len(tpl.Messages))
 	for i, m := range tpl.Messages {
-		var allVars []string
-		parsedParts := make([]parsedPart, 0, len(m.Content))
-		for j, part := range m.Content {
-			namePrefix := fmt.Sprintf("msg_%d_part_%d", i, j)
-			switch part.Type {
-			case partKindText:
-				textTpl, err := parsePartTemplate(root, namePrefix, part.Text)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d: %w",
-						ErrTemplateParse,
-						i,
-						j,
-						err,
-					)
-				}
-				parsedParts = append(parsedParts, parsedPart{
-					kind:         partKindText,
-					textTpl:      textTpl,
-					mediaTypeTpl: nil,
-					mimeTypeTpl:  nil,
-					urlTpl:       nil,
-					cacheControl: cloneCacheControl(part.CacheControl),
-				})
-				allVars = append(allVars, extractVarsFromTree(textTpl.Tree)...)
-			case partKindMedia:
-				mediaTypeTpl, err := parsePartTemplate(
-					root,
-					namePrefix+"_media_type",
-					part.MediaType,
-				)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d media_type: %w",
-						ErrTemplateParse,
-						i,
-						j,
-						err,
-					)
-				}
-				mimeTypeTpl, err := parsePartTemplate(root, namePrefix+"_mime_type", part.MIMEType)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d mime_type: %w",
-						ErrTemplateParse,
-						i,
-						j,
-						err,
-					)
-				}
-				urlTpl, err := parsePartTemplate(root, namePrefix+"_url", part.URL)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d url: %w",
-						ErrTemplateParse,
-						i,
-						j,
-						err,
-					)
-				}
-				parsedParts = append(parsedParts, parsedPart{
-					kind:         partKindMedia,
-					textTpl:      nil,
-					mediaTypeTpl: mediaTypeTpl,
-					mimeTypeTpl:  mimeTypeTpl,
-					urlTpl:       urlTpl,
-					cacheControl: cloneCacheControl(part.CacheControl),
-				})
-				allVars = append(allVars, extractVarsFromTree(mediaTypeTpl.Tree)...)
-				allVars = append(allVars, extractVarsFromTree(mimeTypeTpl.Tree)...)
-				allVars = append(allVars, extractVarsFromTree(urlTpl.Tree)...)
-			default:
-				return nil, fmt.Errorf(
-					"%w: message %d part %d: unknown type %q",
-					ErrTemplateParse,
-					i,
-					j,
-					part.Type,
-				)
-			}
+		pm, err := parseMessageTemplate(root, m, fmt.Sprintf("message %d", i), fmt.Sprintf("msg_%d", i))
+		if err != nil {
+			return nil, err
 		}
-		var meta map[string]any
-		if len(m.Metadata) > 0 {
-			meta = maps.Clone(m.Metadata)
-		}
-		tpl.parsedTemplates = append(tpl.parsedTemplates, parsedMessage{
-			parts:        parsedParts,
-			role:         m.Role,
-			optional:     m.Optional,
-			cacheControl: cloneCacheControl(m.CacheControl),
-			metadata:     meta,
-			vars:         allVars,
-		})
+		tpl.parsedTemplates = append(tpl.parsedTemplates, pm)
+	}
+	if err := checkLoopVarConflicts(tpl); err != nil {
+		return nil, err
 	}
 	tpl.requiredFromAST = extractRequiredVarsFromParsed(tpl.parsedTemplates)
+	tpl.references = collectReferences(tpl.parsedTemplates, root)
 	return tpl, nil
 }
 
@@ -236,7 +163,10 @@ func CloneTemplate(c *ChatPromptTemplate) *ChatPromptTemplate {
 		requiredFromAST: c.requiredFromAST,
 		Metadata:        clonePromptMetadata(c.Metadata),
 		tokenCounter:    c.tokenCounter,
+		funcs:           c.funcs,
 		parsedTemplates: c.parsedTemplates,
+		references:      c.references,
+		strict:          c.strict,
 		partialsGlob:    c.partialsGlob,
 		partialsFS:      c.partialsFS,
 	}
@@ -251,97 +181,47 @@ func CloneTemplate(c *ChatPromptTemplate) *ChatPromptTemplate {
 	return out
 }
 
+// renderTemplates renders all messages with mergedVars and splices history.
+// In strict mode, input (the caller's payload without defaults) is checked for unused fields.
 func (c *ChatPromptTemplate) renderTemplates(
 	mergedVars map[string]any,
+	input map[string]any,
 	history []ChatMessage,
 ) (*PromptExecution, error) {
 	var out []ChatMessage
+	var noValue []string
 	for i, pm := range c.parsedTemplates {
 		optionalSkip := pm.optional && allVarsZeroForMessage(mergedVars, pm.vars)
 		if optionalSkip {
 			continue
 		}
-		var contentParts []ContentPart
-		for j, part := range pm.parts {
-			switch part.kind {
-			case partKindText:
-				rendered, err := executeTemplateString(part.textTpl, mergedVars)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d: %w",
-						ErrTemplateRender,
-						i,
-						j,
-						err,
-					)
-				}
-				contentParts = append(contentParts, TextPart{
-					Text:         rendered,
-					CacheControl: cloneCacheControl(part.cacheControl),
-				})
-			case partKindMedia:
-				mediaType, err := executeTemplateString(part.mediaTypeTpl, mergedVars)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d media_type: %w",
-						ErrTemplateRender,
-						i,
-						j,
-						err,
-					)
-				}
-				mimeType, err := executeTemplateString(part.mimeTypeTpl, mergedVars)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d mime_type: %w",
-						ErrTemplateRender,
-						i,
-						j,
-						err,
-					)
-				}
-				url, err := executeTemplateString(part.urlTpl, mergedVars)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d url: %w",
-						ErrTemplateRender,
-						i,
-						j,
-						err,
-					)
-				}
-				mediaType, err = normalizeMediaType(mediaType, mimeType)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"%w: message %d part %d media_type: %w",
-						ErrTemplateRender,
-						i,
-						j,
-						err,
-					)
+		label := fmt.Sprintf("message %d", i)
+		if pm.forEach == nil {
+			msg, err := pm.render(mergedVars, label)
+			if err != nil {
+				return nil, err
+			}
+			if msg != nil {
+				out = append(out, *msg)
+				if c.strict {
+					noValue = append(noValue, findNoValue(label, []ChatMessage{*msg})...)
 				}
-				contentParts = append(contentParts, MediaPart{
-					MediaType:    mediaType,
-					MIMEType:     mimeType,
-					URL:          url,
-					CacheControl: cloneCacheControl(part.cacheControl),
-				})
-			default:
-				return nil, fmt.Errorf(
-					"%w: message %d part %d: unknown type %q",
-					ErrTemplateRender,
-					i,
-					j,
-					part.kind,
-				)
 			}
+			continue
+		}
+		msgs, err := pm.renderLoop(mergedVars, label)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msgs...)
+		if c.strict {
+			noValue = append(noValue, findNoValue(label, msgs)...)
+		}
+	}
+	if c.strict {
+		if err := c.checkStrict(input, noValue); err != nil {
+			return nil, err
 		}
-		out = append(out, ChatMessage{
-			Role:         pm.role,
-			Content:      contentParts,
-			CacheControl: cloneCacheControl(pm.cacheControl),
-			Metadata:     maps.Clone(pm.metadata),
-		})
 	}
 	out = spliceHistory(out, cloneMessages(history))
 	return &PromptExecution{
@@ -354,8 +234,14 @@ func (c *ChatPromptTemplate) renderTemplates(
 }
 
 // Format renders the template using the given input map (reflection-free).
-// Same merge and validation as FormatStruct. History is not supported.
+// Same merge and validation as FormatStruct. History is not supported; see FormatWithHistory.
 func (c *ChatPromptTemplate) Format(vars map[string]any) (*PromptExecution, error) {
+	return c.FormatWithHistory(vars, nil)
+}
+
+// FormatWithHistory is Format with chat history spliced in like the []ChatMessage field of a FormatStruct
+// payload: after the leading system/developer messages.
+func (c *ChatPromptTemplate) FormatWithHistory(vars map[string]any, history []ChatMessage) (*PromptExecution, error) {
 	if vars == nil {
 		vars = make(map[string]any)
 	}
@@ -375,7 +261,7 @@ func (c *ChatPromptTemplate) Format(vars map[string]any) (*PromptExecution, erro
 			}
 		}
 	}
-	return c.renderTemplates(merged, nil)
+	return c.renderTemplates(merged, vars, history)
 }
 
 // FormatStruct renders the template using payload struct (prompt tags), merges input fields, validates, splices history.
@@ -400,7 +286,7 @@ func (c *ChatPromptTemplate) FormatStruct(payload any) (*PromptExecution, error)
 			}
 		}
 	}
-	return c.renderTemplates(merged, history)
+	return c.renderTemplates(merged, vars, history)
 }
 
 // ValidateVariables runs a dry-run execute with the given data (same merge as FormatStruct: PartialVariables + data + Tools).
@@ -415,58 +301,378 @@ func (c *ChatPromptTemplate) ValidateVariables(data map[string]any) error {
 	}
 	merged["Tools"] = c.Tools
 	for i, pm := range c.parsedTemplates {
-		for j, part := range pm.parts {
-			for _, tmpl := range part.templates() {
-				if tmpl == nil {
-					continue
-				}
-				if err := tmpl.Execute(io.Discard, merged); err != nil {
-					return fmt.Errorf(
-						"%w: message %d part %d (role %s): %w",
-						ErrTemplateRender,
-						i,
-						j,
-						pm.role,
-						err,
-					)
+		label := fmt.Sprintf("message %d", i)
+		if pm.forEach == nil {
+			if err := pm.validate(merged, label); err != nil {
+				return err
+			}
+			continue
+		}
+		// Loop messages are checked once per element of the list present in data.
+		err := pm.eachItem(merged, label, func(scope map[string]any, itemLabel string) error {
+			if len(pm.turns) == 0 {
+				return pm.validate(scope, itemLabel)
+			}
+			for t := range pm.turns {
+				if err := pm.turns[t].validate(scope, fmt.Sprintf("%s turn %d", itemLabel, t)); err != nil {
+					return err
 				}
 			}
-			if part.kind != partKindMedia {
-				continue
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Loop scope variables available inside for_each messages.
+const (
+	loopItemVar  = "item"
+	loopIndexVar = "index"
+)
+
+// parseMessageTemplate parses one message (and its for_each turns). label ("message 2", "message 2 turn 0")
+// prefixes errors; prefix ("msg_2") names the part templates inside root.
+func parseMessageTemplate(root *template.Template, m MessageTemplate, label, prefix string) (parsedMessage, error) {
+	msgWhen, err := parseWhen(m.When)
+	if err != nil {
+		return parsedMessage{}, fmt.Errorf("%w: %s when: %w", ErrTemplateParse, label, err)
+	}
+	pm := parsedMessage{
+		parts:        make([]parsedPart, 0, len(m.Content)),
+		role:         m.Role,
+		optional:     m.Optional,
+		when:         msgWhen,
+		cacheControl: cloneCacheControl(m.CacheControl),
+		whenVars:     msgWhen.variables(),
+	}
+	if len(m.Metadata) > 0 {
+		pm.metadata = maps.Clone(m.Metadata)
+	}
+	for j, part := range m.Content {
+		pp, vars, err := parsePart(root, part, fmt.Sprintf("%s part %d", label, j), fmt.Sprintf("%s_part_%d", prefix, j))
+		if err != nil {
+			return parsedMessage{}, err
+		}
+		pm.parts = append(pm.parts, pp)
+		pm.vars = append(pm.vars, vars...)
+		pm.whenVars = append(pm.whenVars, pp.when.variables()...)
+	}
+	if len(m.Turns) > 0 && m.ForEach == "" {
+		return parsedMessage{}, fmt.Errorf("%w: %s: turns require for_each", ErrTemplateParse, label)
+	}
+	if m.ForEach == "" {
+		return pm, nil
+	}
+	if len(m.Turns) > 0 && len(m.Content) > 0 {
+		return parsedMessage{}, fmt.Errorf("%w: %s: content and turns cannot be combined", ErrTemplateParse, label)
+	}
+	list, err := parseWhen(m.ForEach)
+	if err != nil {
+		return parsedMessage{}, fmt.Errorf("%w: %s for_each: %w", ErrTemplateParse, label, err)
+	}
+	path, ok := list.root.(whenPath)
+	if !ok {
+		return parsedMessage{}, fmt.Errorf("%w: %s for_each %q must be a variable", ErrTemplateParse, label, m.ForEach)
+	}
+	if isLoopVar(path.path[0]) {
+		return parsedMessage{}, fmt.Errorf(
+			"%w: %s for_each %q: list conflicts with the loop variable %q",
+			ErrTemplateParse,
+			label,
+			m.ForEach,
+			path.path[0],
+		)
+	}
+	pm.forEach = path.path
+	for t, turn := range m.Turns {
+		tpm, err := parseMessageTemplate(
+			root,
+			turn,
+			fmt.Sprintf("%s turn %d", label, t),
+			fmt.Sprintf("%s_turn_%d", prefix, t),
+		)
+		if err != nil {
+			return parsedMessage{}, err
+		}
+		if tpm.forEach != nil {
+			return parsedMessage{}, fmt.Errorf("%w: %s turn %d: nested for_each", ErrTemplateParse, label, t)
+		}
+		pm.turns = append(pm.turns, tpm)
+		pm.vars = append(pm.vars, tpm.vars...)
+		pm.whenVars = append(pm.whenVars, tpm.whenVars...)
+	}
+	// item/index come from the loop, the list itself comes from input. Input variables with these names
+	// are rejected (checkLoopVarConflicts, eachItem), so nothing the caller passes is dropped here.
+	pm.vars = append([]string{path.path[0]}, withoutLoopVars(pm.vars)...)
+	pm.whenVars = withoutLoopVars(pm.whenVars)
+	return pm, nil
+}
+
+func withoutLoopVars(vars []string) []string {
+	return slices.DeleteFunc(slices.Clone(vars), isLoopVar)
+}
+
+func isLoopVar(name string) bool {
+	return name == loopItemVar || name == loopIndexVar
+}
+
+// checkLoopVarConflicts rejects templates with for_each messages whose input_schema or partial variables
+// declare item or index: the loop scope would shadow them inside the loop body.
+func checkLoopVarConflicts(tpl *ChatPromptTemplate) error {
+	i := slices.IndexFunc(tpl.parsedTemplates, func(pm parsedMessage) bool { return pm.forEach != nil })
+	if i < 0 {
+		return nil
+	}
+	var props map[string]any
+	if tpl.InputSchema != nil {
+		props, _ = tpl.InputSchema.Schema["properties"].(map[string]any)
+	}
+	for _, name := range []string{loopItemVar, loopIndexVar} {
+		_, inSchema := props[name]
+		_, inPartials := tpl.PartialVariables[name]
+		if inSchema || inPartials {
+			return fmt.Errorf(
+				"%w: message %d for_each: input variable %q conflicts with the loop variable",
+				ErrTemplateParse,
+				i,
+				name,
+			)
+		}
+	}
+	return nil
+}
+
+// parsePart parses one content part and returns it with the variables its templates reference.
+func parsePart(root *template.Template, part TemplatePart, label, name string) (parsedPart, []string, error) {
+	partWhen, err := parseWhen(part.When)
+	if err != nil {
+		return parsedPart{}, nil, fmt.Errorf("%w: %s when: %w", ErrTemplateParse, label, err)
+	}
+	switch part.Type {
+	case partKindText:
+		textTpl, err := parsePartTemplate(root, name, part.Text)
+		if err != nil {
+			return parsedPart{}, nil, fmt.Errorf("%w: %s: %w", ErrTemplateParse, label, err)
+		}
+		return parsedPart{
+			kind:         partKindText,
+			textTpl:      textTpl,
+			mediaTypeTpl: nil,
+			mimeTypeTpl:  nil,
+			urlTpl:       nil,
+			when:         partWhen,
+			cacheControl: cloneCacheControl(part.CacheControl),
+		}, extractVarsFromTree(textTpl.Tree), nil
+	case partKindMedia:
+		mediaTypeTpl, err := parsePartTemplate(root, name+"_media_type", part.MediaType)
+		if err != nil {
+			return parsedPart{}, nil, fmt.Errorf("%w: %s media_type: %w", ErrTemplateParse, label, err)
+		}
+		mimeTypeTpl, err := parsePartTemplate(root, name+"_mime_type", part.MIMEType)
+		if err != nil {
+			return parsedPart{}, nil, fmt.Errorf("%w: %s mime_type: %w", ErrTemplateParse, label, err)
+		}
+		urlTpl, err := parsePartTemplate(root, name+"_url", part.URL)
+		if err != nil {
+			return parsedPart{}, nil, fmt.Errorf("%w: %s url: %w", ErrTemplateParse, label, err)
+		}
+		vars := slices.Concat(
+			extractVarsFromTree(mediaTypeTpl.Tree),
+			extractVarsFromTree(mimeTypeTpl.Tree),
+			extractVarsFromTree(urlTpl.Tree),
+		)
+		return parsedPart{
+			kind:         partKindMedia,
+			textTpl:      nil,
+			mediaTypeTpl: mediaTypeTpl,
+			mimeTypeTpl:  mimeTypeTpl,
+			urlTpl:       urlTpl,
+			when:         partWhen,
+			cacheControl: cloneCacheControl(part.CacheControl),
+		}, vars, nil
+	default:
+		return parsedPart{}, nil, fmt.Errorf("%w: %s: unknown type %q", ErrTemplateParse, label, part.Type)
+	}
+}
+
+// render renders one message with vars. Returns nil when the message is excluded by its when condition
+// or every part is excluded by part conditions.
+func (pm *parsedMessage) render(vars map[string]any, label string) (*ChatMessage, error) {
+	ok, err := pm.when.test(vars)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrTemplateRender, label, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	var contentParts []ContentPart
+	for j, part := range pm.parts {
+		partLabel := fmt.Sprintf("%s part %d", label, j)
+		ok, err := part.when.test(vars)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrTemplateRender, partLabel, err)
+		}
+		if !ok {
+			continue
+		}
+		cp, err := part.render(vars, partLabel)
+		if err != nil {
+			return nil, err
+		}
+		contentParts = append(contentParts, cp)
+	}
+	if len(contentParts) == 0 && len(pm.parts) > 0 {
+		// Every part was excluded by its when condition: drop the message instead of sending it empty.
+		return nil, nil
+	}
+	return &ChatMessage{
+		Role:         pm.role,
+		Content:      contentParts,
+		CacheControl: cloneCacheControl(pm.cacheControl),
+		Metadata:     maps.Clone(pm.metadata),
+	}, nil
+}
+
+// renderLoop renders a for_each message: one message (or one message per turn) for each list element.
+func (pm *parsedMessage) renderLoop(vars map[string]any, label string) ([]ChatMessage, error) {
+	var out []ChatMessage
+	err := pm.eachItem(vars, label, func(scope map[string]any, itemLabel string) error {
+		if len(pm.turns) == 0 {
+			msg, err := pm.render(scope, itemLabel)
+			if msg != nil {
+				out = append(out, *msg)
 			}
-			mediaType, err := executeTemplateString(part.mediaTypeTpl, merged)
+			return err
+		}
+		ok, err := pm.when.test(scope)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrTemplateRender, itemLabel, err)
+		}
+		if !ok {
+			return nil
+		}
+		for t := range pm.turns {
+			msg, err := pm.turns[t].render(scope, fmt.Sprintf("%s turn %d", itemLabel, t))
 			if err != nil {
-				return fmt.Errorf(
-					"%w: message %d part %d (role %s) media_type: %w",
-					ErrTemplateRender,
-					i,
-					j,
-					pm.role,
-					err,
-				)
+				return err
 			}
-			mimeType, err := executeTemplateString(part.mimeTypeTpl, merged)
-			if err != nil {
-				return fmt.Errorf(
-					"%w: message %d part %d (role %s) mime_type: %w",
-					ErrTemplateRender,
-					i,
-					j,
-					pm.role,
-					err,
-				)
+			if msg != nil {
+				out = append(out, *msg)
 			}
-			if _, err := normalizeMediaType(mediaType, mimeType); err != nil {
-				return fmt.Errorf(
-					"%w: message %d part %d (role %s) media_type: %w",
-					ErrTemplateRender,
-					i,
-					j,
-					pm.role,
-					err,
-				)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// eachItem calls fn for every element of the for_each list with item and index added to a copy of vars.
+// Input variables named item or index are an error rather than being shadowed by the loop.
+// A missing or nil list yields no iterations.
+func (pm *parsedMessage) eachItem(
+	vars map[string]any,
+	label string,
+	fn func(scope map[string]any, itemLabel string) error,
+) error {
+	for _, name := range []string{loopItemVar, loopIndexVar} {
+		if _, ok := vars[name]; ok {
+			return fmt.Errorf(
+				"%w: %s: input variable %q conflicts with the for_each loop variable",
+				ErrTemplateRender,
+				label,
+				name,
+			)
+		}
+	}
+	list, _ := whenPath{path: pm.forEach}.eval(vars)
+	if isNilValue(list) {
+		return nil
+	}
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf(
+			"%w: %s: for_each %q must be a list, got %T",
+			ErrTemplateRender,
+			label,
+			strings.Join(pm.forEach, "."),
+			list,
+		)
+	}
+	for idx := range rv.Len() {
+		scope := maps.Clone(vars)
+		scope[loopItemVar] = rv.Index(idx).Interface()
+		scope[loopIndexVar] = idx
+		if err := fn(scope, fmt.Sprintf("%s item %d", label, idx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render executes the part templates with vars.
+func (p parsedPart) render(vars map[string]any, label string) (ContentPart, error) {
+	switch p.kind {
+	case partKindText:
+		rendered, err := executeTemplateString(p.textTpl, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrTemplateRender, label, err)
+		}
+		return TextPart{Text: rendered, CacheControl: cloneCacheControl(p.cacheControl)}, nil
+	case partKindMedia:
+		mediaType, err := executeTemplateString(p.mediaTypeTpl, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s media_type: %w", ErrTemplateRender, label, err)
+		}
+		mimeType, err := executeTemplateString(p.mimeTypeTpl, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s mime_type: %w", ErrTemplateRender, label, err)
+		}
+		url, err := executeTemplateString(p.urlTpl, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s url: %w", ErrTemplateRender, label, err)
+		}
+		mediaType, err = normalizeMediaType(mediaType, mimeType)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s media_type: %w", ErrTemplateRender, label, err)
+		}
+		return MediaPart{
+			MediaType:    mediaType,
+			MIMEType:     mimeType,
+			URL:          url,
+			CacheControl: cloneCacheControl(p.cacheControl),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s: unknown type %q", ErrTemplateRender, label, p.kind)
+	}
+}
+
+// validate dry-runs every part template of the message with vars (ignores when conditions).
+func (pm *parsedMessage) validate(vars map[string]any, label string) error {
+	for j, part := range pm.parts {
+		for _, tmpl := range part.templates() {
+			if tmpl == nil {
+				continue
+			}
+			if err := tmpl.Execute(io.Discard, vars); err != nil {
+				return fmt.Errorf("%w: %s part %d (role %s): %w", ErrTemplateRender, label, j, pm.role, err)
 			}
 		}
+		if part.kind != partKindMedia {
+			continue
+		}
+		mediaType, err := executeTemplateString(part.mediaTypeTpl, vars)
+		if err != nil {
+			return fmt.Errorf("%w: %s part %d (role %s) media_type: %w", ErrTemplateRender, label, j, pm.role, err)
+		}
+		mimeType, err := executeTemplateString(part.mimeTypeTpl, vars)
+		if err != nil {
+			return fmt.Errorf("%w: %s part %d (role %s) mime_type: %w", ErrTemplateRender, label, j, pm.role, err)
+		}
+		if _, err := normalizeMediaType(mediaType, mimeType); err != nil {
+			return fmt.Errorf("%w: %s part %d (role %s) media_type: %w", ErrTemplateRender, label, j, pm.role, err)
+		}
 	}
 	return nil
 }
@@ -3,6 +3,8 @@ package prompty
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 )
 
 // ToolValidator validates a tool call without coupling prompty to a concrete tool registry.
@@ -10,6 +12,40 @@ type ToolValidator interface {
 	ValidateToolCall(name string, argsJSON string) error
 }
 
+// SchemaToolValidator is a ToolValidator that checks tool arguments against ToolDefinition.Parameters
+// (see ValidateJSON). Unknown tools are rejected; tools without Parameters accept any JSON arguments.
+type SchemaToolValidator struct {
+	tools map[string]ToolDefinition
+}
+
+// Ensures SchemaToolValidator implements ToolValidator.
+var _ ToolValidator = (*SchemaToolValidator)(nil)
+
+// NewSchemaToolValidator returns a validator for tools, typically exec.Tools.
+func NewSchemaToolValidator(tools []ToolDefinition) *SchemaToolValidator {
+	v := &SchemaToolValidator{tools: make(map[string]ToolDefinition, len(tools))}
+	for _, tool := range tools {
+		v.tools[tool.Name] = tool
+	}
+	return v
+}
+
+// ValidateToolCall implements ToolValidator. Empty arguments count as {}. The error lists every violation with
+// its JSON path, so it can be sent back to the model as the tool result.
+func (v *SchemaToolValidator) ValidateToolCall(name string, argsJSON string) error {
+	tool, ok := v.tools[name]
+	if !ok {
+		return fmt.Errorf("tool %q is not defined", name)
+	}
+	if strings.TrimSpace(argsJSON) == "" {
+		argsJSON = "{}"
+	}
+	if err := ValidateJSON(tool.Parameters, []byte(argsJSON)); err != nil {
+		return fmt.Errorf("tool %q arguments: %w", name, err)
+	}
+	return nil
+}
+
 // ExecuteWithToolValidation performs one model call and validates tool call arguments.
 func ExecuteWithToolValidation(
 	ctx context.Context,
@@ -46,6 +82,9 @@ func ExecuteWithToolValidation(
 	invalidErrs := make([]error, 0, len(toolCalls))
 	for i, toolCall := range toolCalls {
 		callErrs[i] = validator.ValidateToolCall(toolCall.Name, toolCall.Args)
+		if errors.Is(callErrs[i], ErrInvalidSchema) {
+			return workExec, fmt.Errorf("tool validation: %w", callErrs[i]) // the model cannot fix the schema
+		}
 		if callErrs[i] != nil {
 			invalidErrs = append(invalidErrs, callErrs[i])
 		}
@@ -53,6 +53,28 @@ func TestExecuteWithToolValidation_InvalidToolCallReturnsToolCallError(t *testin
 	assert.Equal(t, "city must be a string", part.Content[0].(TextPart).Text)
 }
 
+func TestExecuteWithToolValidation_InvalidSchemaIsNotAToolResult(t *testing.T) {
+	t.Parallel()
+
+	invoker := &scriptedInvoker{
+		generate: func(context.Context, *PromptExecution) (*Response, error) {
+			return NewResponse([]ContentPart{ToolCallPart{ID: "tool-1", Name: "lookup", Args: `{"city":"Oslo"}`}}), nil
+		},
+	}
+	validator := NewSchemaToolValidator([]ToolDefinition{{
+		Name: "lookup",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string", "pattern": `\p{Bad}`}},
+		},
+	}})
+
+	_, err := ExecuteWithToolValidation(context.Background(), invoker, SimplePrompt("hi"), validator)
+	require.ErrorIs(t, err, ErrInvalidSchema)
+	var toolErr *ToolCallError
+	assert.NotErrorAs(t, err, &toolErr)
+}
+
 func TestExecuteWithToolValidation_MultipleInvalidToolCallsReturnAllResults(t *testing.T) {
 	t.Parallel()
 
@@ -154,3 +176,41 @@ func TestExecuteWithToolValidation_NilValidatorWithToolCall(t *testing.T) {
 	require.Len(t, result.Messages, 1)
 	assert.Contains(t, err.Error(), "validator is nil")
 }
+
+func TestSchemaToolValidator(t *testing.T) {
+	t.Parallel()
+
+	tools := []ToolDefinition{
+		{Name: "lookup", Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			"required":   []string{"city"},
+		}},
+		{Name: "now"},
+	}
+	invoker := &scriptedInvoker{
+		generate: func(context.Context, *PromptExecution) (*Response, error) {
+			return NewResponse([]ContentPart{
+				ToolCallPart{ID: "tool-1", Name: "lookup", Args: `{"city":1}`},
+				ToolCallPart{ID: "tool-2", Name: "now"},
+				ToolCallPart{ID: "tool-3", Name: "search", Args: `{}`},
+			}), nil
+		},
+	}
+	exec := SimplePrompt("hi")
+	exec.Tools = tools
+	_, err := ExecuteWithToolValidation(context.Background(), invoker, exec, NewSchemaToolValidator(exec.Tools))
+	require.ErrorIs(t, err, ErrSchemaViolation)
+	var toolErr *ToolCallError
+	require.ErrorAs(t, err, &toolErr)
+	require.Len(t, toolErr.ToolResults, 3)
+	text := func(i int) string { return toolErr.ToolResults[i].(ToolResultPart).Content[0].(TextPart).Text }
+	assert.Equal(t, `tool "lookup" arguments: prompty: JSON does not match schema: $.city: expected string, got integer`, text(0))
+	assert.Contains(t, text(1), "must be regenerated")
+	assert.Equal(t, `tool "search" is not defined`, text(2))
+
+	v := NewSchemaToolValidator(tools)
+	require.NoError(t, v.ValidateToolCall("lookup", `{"city":"Paris"}`))
+	require.ErrorIs(t, v.ValidateToolCall("lookup", ""), ErrSchemaViolation)
+	require.Error(t, v.ValidateToolCall("lookup", `{"city":`))
+}
@@ -0,0 +1,409 @@
+package prompty
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VersionLatest is the constraint that selects the highest stable version (or the highest pre-release
+// when no stable version exists): "support_agent@latest".
+const VersionLatest = "latest"
+
+// Versioner is optional. When implemented by a registry, Versions returns the semantic versions available
+// for id (canonical form without "v", ascending), and GetTemplate accepts "id@constraint"
+// (exact "1.2.0" or "v1.2.0", "latest", or a range such as "^1.2", "~1.2.3", ">=1.0 <2", "1.x").
+type Versioner interface {
+	Versions(ctx context.Context, id string) ([]string, error)
+}
+
+// SplitVersionedID splits "id@constraint" into id and constraint; ok is false when s has no "@".
+func SplitVersionedID(s string) (id, constraint string, ok bool) {
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+// Version is a semantic version MAJOR.MINOR.PATCH[-PRERELEASE]; build metadata ("+...") is ignored.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseVersion parses a full semantic version with an optional "v" prefix (e.g. "v1.2.0", "1.2.0-rc.1").
+func ParseVersion(s string) (Version, error) {
+	parts, n, pre, err := parsePartialVersion(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if n != 3 {
+		return Version{}, fmt.Errorf("%w: %q is not MAJOR.MINOR.PATCH", ErrInvalidVersion, s)
+	}
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2], Prerelease: pre}, nil
+}
+
+// String returns the canonical form without "v" (e.g. "1.2.0-rc.1").
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 following semver precedence (a pre-release sorts before its release).
+func (v Version) Compare(o Version) int {
+	if c := compareInts(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func (v Version) sameCore(o Version) bool {
+	return v.Major == o.Major && v.Minor == o.Minor && v.Patch == o.Patch
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease compares dot-separated identifiers: numeric ones numerically and below alphanumeric ones;
+// no pre-release ranks above any pre-release.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = compareInts(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(as), len(bs))
+}
+
+// parsePartialVersion parses "v1", "1.2", "1.2.x", "1.2.3-rc.1+build": n is the number of numeric components
+// before the first wildcard (x, X, *) or the end.
+func parsePartialVersion(s string) (parts [3]int, n int, pre string, err error) {
+	raw := s
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, pre = s[:i], s[i+1:]
+		if pre == "" || strings.Contains(pre, "..") || strings.HasPrefix(pre, ".") || strings.HasSuffix(pre, ".") {
+			return parts, 0, "", fmt.Errorf("%w: bad pre-release in %q", ErrInvalidVersion, raw)
+		}
+	}
+	fields := strings.Split(s, ".")
+	if s == "" || len(fields) > 3 {
+		return parts, 0, "", fmt.Errorf("%w: %q", ErrInvalidVersion, raw)
+	}
+	wild := false
+	for i, f := range fields {
+		if f == "x" || f == "X" || f == "*" {
+			wild = true
+			continue
+		}
+		if wild {
+			return parts, 0, "", fmt.Errorf("%w: number after wildcard in %q", ErrInvalidVersion, raw)
+		}
+		v, convErr := strconv.Atoi(f)
+		if convErr != nil || v < 0 || (len(f) > 1 && f[0] == '0') || f[0] == '+' {
+			return parts, 0, "", fmt.Errorf("%w: %q", ErrInvalidVersion, raw)
+		}
+		parts[i] = v
+		n = i + 1
+	}
+	if pre != "" && n != 3 {
+		return parts, 0, "", fmt.Errorf("%w: pre-release needs MAJOR.MINOR.PATCH in %q", ErrInvalidVersion, raw)
+	}
+	return parts, n, pre, nil
+}
+
+// comparator is one bound of a range: op is one of "=", ">", ">=", "<", "<=", or "*" (matches everything).
+type comparator struct {
+	op string
+	v  Version
+}
+
+func (c comparator) match(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return true
+	}
+}
+
+// VersionConstraint is a parsed version constraint: alternatives separated by "||", each a set of
+// space- or comma-separated terms that must all hold. Build with [ParseVersionConstraint].
+type VersionConstraint struct {
+	latest bool
+	alts   [][]comparator
+}
+
+// ParseVersionConstraint parses "latest", an exact version, or npm-style ranges: "^1.2", "~1.2.3", "1.x", "*",
+// ">=1.0.0 <2.0.0", ">=1.2, <1.5", "^1 || ^2". Partial versions fill missing components as ranges ("1.2" = "1.2.x").
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return VersionConstraint{}, fmt.Errorf("%w: empty constraint", ErrInvalidVersion)
+	}
+	if s == VersionLatest {
+		return VersionConstraint{latest: true, alts: nil}, nil
+	}
+	var c VersionConstraint
+	for alt := range strings.SplitSeq(s, "||") {
+		fields := strings.Fields(strings.ReplaceAll(alt, ",", " "))
+		if len(fields) == 0 {
+			return VersionConstraint{}, fmt.Errorf("%w: empty alternative in %q", ErrInvalidVersion, s)
+		}
+		var terms []comparator
+		for i := 0; i < len(fields); i++ {
+			term := fields[i]
+			if strings.TrimLeft(term, "<>=~^") == "" && i+1 < len(fields) {
+				// Operator separated from its version: ">= 1.2".
+				i++
+				term += fields[i]
+			}
+			cs, err := parseTerm(term)
+			if err != nil {
+				return VersionConstraint{}, err
+			}
+			terms = append(terms, cs...)
+		}
+		c.alts = append(c.alts, terms)
+	}
+	return c, nil
+}
+
+// parseTerm turns one operator+version term into comparators.
+func parseTerm(term string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	parts, n, pre, err := parsePartialVersion(strings.TrimPrefix(term, op))
+	if err != nil {
+		return nil, err
+	}
+	lo := Version{Major: parts[0], Minor: parts[1], Patch: parts[2], Prerelease: pre}
+	next := func(k int) Version {
+		switch k {
+		case 1:
+			return Version{Major: parts[0] + 1, Minor: 0, Patch: 0, Prerelease: ""}
+		case 2:
+			return Version{Major: parts[0], Minor: parts[1] + 1, Patch: 0, Prerelease: ""}
+		default:
+			return Version{Major: parts[0], Minor: parts[1], Patch: parts[2] + 1, Prerelease: ""}
+		}
+	}
+	anything := []comparator{{op: "*", v: Version{}}}
+	rangeTo := func(hi Version) []comparator {
+		return []comparator{{op: ">=", v: lo}, {op: "<", v: hi}}
+	}
+	switch op {
+	case "", "=":
+		switch n {
+		case 0:
+			return anything, nil
+		case 3:
+			return []comparator{{op: "=", v: lo}}, nil
+		default:
+			return rangeTo(next(n)), nil
+		}
+	case ">", "<":
+		if n == 0 {
+			return nil, fmt.Errorf("%w: %q matches nothing", ErrInvalidVersion, term)
+		}
+		if op == ">" && n < 3 {
+			return []comparator{{op: ">=", v: next(n)}}, nil
+		}
+		return []comparator{{op: op, v: lo}}, nil
+	case ">=":
+		return []comparator{{op: ">=", v: lo}}, nil
+	case "<=":
+		if n == 0 {
+			return anything, nil
+		}
+		if n < 3 {
+			return []comparator{{op: "<", v: next(n)}}, nil
+		}
+		return []comparator{{op: "<=", v: lo}}, nil
+	case "~":
+		switch n {
+		case 0:
+			return anything, nil
+		case 1:
+			return rangeTo(next(1)), nil
+		default:
+			return rangeTo(next(2)), nil
+		}
+	default: // "^": allow changes that do not modify the left-most non-zero component.
+		switch {
+		case n == 0:
+			return anything, nil
+		case parts[0] > 0 || n == 1:
+			return rangeTo(next(1)), nil
+		case parts[1] > 0 || n == 2:
+			return rangeTo(next(2)), nil
+		default:
+			return rangeTo(next(3)), nil
+		}
+	}
+}
+
+// Match reports whether v satisfies the constraint. Pre-releases only match a term set that names a
+// pre-release of the same MAJOR.MINOR.PATCH (npm semantics); "latest" matches every version.
+func (c VersionConstraint) Match(v Version) bool {
+	if c.latest {
+		return true
+	}
+	for _, terms := range c.alts {
+		ok := true
+		allowPre := v.Prerelease == ""
+		for _, t := range terms {
+			if !t.match(v) {
+				ok = false
+				break
+			}
+			if t.v.Prerelease != "" && t.v.sameCore(v) {
+				allowPre = true
+			}
+		}
+		if ok && allowPre {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveVersion returns the entry of versions that best satisfies constraint: the highest match, where
+// "latest" prefers the highest stable version. Entries that are not valid versions are ignored.
+// Returns ErrInvalidVersion for a bad constraint and ErrVersionNotFound (with ErrTemplateNotFound) when nothing matches.
+func ResolveVersion(versions []string, constraint string) (string, error) {
+	c, err := ParseVersionConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	best, bestStable := -1, -1
+	var bestV, bestStableV Version
+	for i, s := range versions {
+		v, parseErr := ParseVersion(s)
+		if parseErr != nil || !c.Match(v) {
+			continue
+		}
+		if best < 0 || v.Compare(bestV) > 0 {
+			best, bestV = i, v
+		}
+		if v.Prerelease == "" && (bestStable < 0 || v.Compare(bestStableV) > 0) {
+			bestStable, bestStableV = i, v
+		}
+	}
+	if c.latest && bestStable >= 0 {
+		return versions[bestStable], nil
+	}
+	if best < 0 {
+		return "", fmt.Errorf("%w: %q (available: %s; %w)",
+			ErrVersionNotFound, constraint, strings.Join(versions, ", "), ErrTemplateNotFound)
+	}
+	return versions[best], nil
+}
+
+// SortVersions sorts valid semantic versions ascending in canonical form (no "v"), dropping duplicates
+// and entries that do not parse.
+func SortVersions(versions []string) []string {
+	parsed := make([]Version, 0, len(versions))
+	for _, s := range versions {
+		if v, err := ParseVersion(s); err == nil {
+			parsed = append(parsed, v)
+		}
+	}
+	slices.SortFunc(parsed, Version.Compare)
+	out := make([]string, 0, len(parsed))
+	for i, v := range parsed {
+		if i > 0 && v.Compare(parsed[i-1]) == 0 {
+			continue
+		}
+		out = append(out, v.String())
+	}
+	return out
+}
+
+// VersionedPath returns the manifest path (slash, no extension) of a version in the versioned layout,
+// shared by the registries: VersionedPath("support_agent", "1.2.0") = "support_agent/v1.2.0".
+func VersionedPath(id, version string) string {
+	return id + "/v" + version
+}
+
+// ParseVersionedPath reports whether stem (slash path without extension) is a manifest in the versioned layout,
+// "{id}/v{version}" or the env overlay "{id}/v{version}.{env}", and returns its parts (version in canonical form).
+func ParseVersionedPath(stem string) (id, version, env string, ok bool) {
+	slash := strings.LastIndex(stem, "/")
+	if slash <= 0 || !strings.HasPrefix(stem[slash+1:], "v") {
+		return "", "", "", false
+	}
+	id, name := stem[:slash], stem[slash+2:]
+	if v, err := ParseVersion(name); err == nil && v.String() == name {
+		return id, name, "", true
+	}
+	dot := strings.LastIndex(name, ".")
+	if dot <= 0 {
+		return "", "", "", false
+	}
+	if v, err := ParseVersion(name[:dot]); err == nil && v.String() == name[:dot] {
+		return id, name[:dot], name[dot+1:], true
+	}
+	return "", "", "", false
+}
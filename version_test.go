@@ -0,0 +1,108 @@
+package prompty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+	v, err := ParseVersion("v1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+	assert.Equal(t, Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}, v)
+	assert.Equal(t, "1.2.3-rc.1", v.String())
+	for _, bad := range []string{"", "1", "1.2", "1.2.x", "01.2.3", "1.2.3-", "a.b.c", "1.2.3.4", "2024-01-02T10:00:00Z"} {
+		_, err := ParseVersion(bad)
+		require.ErrorIs(t, err, ErrInvalidVersion, bad)
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	t.Parallel()
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11",
+		"1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "1.10.0", "2.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, err := ParseVersion(ordered[i-1])
+		require.NoError(t, err)
+		b, err := ParseVersion(ordered[i])
+		require.NoError(t, err)
+		assert.Equal(t, -1, a.Compare(b), "%s < %s", a, b)
+		assert.Equal(t, 1, b.Compare(a), "%s > %s", b, a)
+	}
+	assert.Equal(t, []string{"1.0.0-rc.1", "1.0.0", "1.10.0"}, SortVersions([]string{"v1.10.0", "1.0.0", "bad", "1.0.0-rc.1", "v1.0.0"}))
+}
+
+func TestResolveVersion(t *testing.T) {
+	t.Parallel()
+	versions := []string{"0.1.0", "0.1.5", "0.2.0", "1.0.0", "1.2.0", "1.2.7", "1.3.0-rc.1", "2.0.0-beta.1"}
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"latest", "1.2.7"},
+		{"*", "1.2.7"},
+		{"1.2.0", "1.2.0"},
+		{"=v1.2.0", "1.2.0"},
+		{"1", "1.2.7"},
+		{"1.x", "1.2.7"},
+		{"1.2", "1.2.7"},
+		{"^1.0.0", "1.2.7"},
+		{"^0.1.0", "0.1.5"},
+		{"^0.1", "0.1.5"},
+		{"^0", "0.2.0"},
+		{"~1.2.0", "1.2.7"},
+		{"~1", "1.2.7"},
+		{">=1.0 <1.2.5", "1.2.0"},
+		{">= 1.0, < 1.2", "1.0.0"},
+		{">1.1", "1.2.7"},
+		{">1.2.0", "1.2.7"},
+		{"<=1.2", "1.2.7"},
+		{"<1", "0.2.0"},
+		{"^0.1 || ^1", "1.2.7"},
+		{">=1.3.0-rc.0", "1.3.0-rc.1"},
+		{"2.0.0-beta.1", "2.0.0-beta.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			t.Parallel()
+			got, err := ResolveVersion(versions, tt.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ResolveVersion(versions, "^3")
+	require.ErrorIs(t, err, ErrVersionNotFound)
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = ResolveVersion(versions, ">=2")
+	require.ErrorIs(t, err, ErrVersionNotFound, "pre-releases need an explicit pre-release bound")
+	got, err := ResolveVersion([]string{"0.1.0-draft", "0.1.0-draft.2"}, VersionLatest)
+	require.NoError(t, err)
+	assert.Equal(t, "0.1.0-draft.2", got, "latest falls back to pre-releases when there is no release")
+	for _, bad := range []string{"", "^", ">x", "1.2.3 ||", "1.x.3", "~>1"} {
+		_, err := ResolveVersion(versions, bad)
+		require.ErrorIs(t, err, ErrInvalidVersion, bad)
+	}
+}
+
+func TestValidateVersionedID(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, ValidateVersionedID("support/agent"))
+	require.NoError(t, ValidateVersionedID("support/agent@^1.2"))
+	require.NoError(t, ValidateVersionedID("support/agent@latest"))
+	require.ErrorIs(t, ValidateVersionedID("support/agent@"), ErrInvalidVersion)
+	require.ErrorIs(t, ValidateVersionedID("agent.yaml@1.0.0"), ErrInvalidName)
+
+	id, version, env, ok := ParseVersionedPath("support/agent/v1.2.0.prod")
+	require.True(t, ok)
+	assert.Equal(t, []string{"support/agent", "1.2.0", "prod"}, []string{id, version, env})
+	assert.Equal(t, "support/agent/v1.2.0", VersionedPath("support/agent", "1.2.0"))
+	for _, stem := range []string{"v1.2.0", "agent/1.2.0", "agent/v1.2", "agent/v01.2.0", "agent/router.prod"} {
+		_, _, _, ok := ParseVersionedPath(stem)
+		assert.False(t, ok, stem)
+	}
+}
@@ -0,0 +1,586 @@
+package prompty
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errWhenSyntax is wrapped by when-expression parse errors.
+var errWhenSyntax = errors.New("invalid when expression")
+
+// whenExpr is a parsed `when:` condition of a message or content part.
+// Grammar: ||, &&, !, ==, !=, <, <=, >, >=, parentheses, string ("..." or '...', with Go escapes), number,
+// bool and nil literals and variable paths (tier, user.plan, optionally with a leading dot as in templates).
+// Truthiness follows text/template `if` (zero values are false).
+type whenExpr struct {
+	src  string
+	root whenNode
+	vars []string // top-level variable names in order of appearance
+}
+
+type whenNode interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type whenLit struct{ v any }
+
+type whenPath struct{ path []string }
+
+type whenNot struct{ x whenNode }
+
+type whenLogic struct {
+	op   string // "&&" or "||"
+	l, r whenNode
+}
+
+type whenCmp struct {
+	op   string
+	l, r whenNode
+}
+
+// parseWhen parses a when expression. Empty src returns nil (always true).
+func parseWhen(src string) (*whenExpr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	toks, err := lexWhen(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &whenParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("%w: unexpected %q in %q", errWhenSyntax, p.toks[p.pos].text, src)
+	}
+	e := &whenExpr{src: src, root: root}
+	walkWhen(root, func(n whenNode) {
+		if pn, ok := n.(whenPath); ok && !slices.Contains(e.vars, pn.path[0]) {
+			e.vars = append(e.vars, pn.path[0])
+		}
+	})
+	return e, nil
+}
+
+// test evaluates the condition against vars. A nil expression is always true.
+func (e *whenExpr) test(vars map[string]any) (bool, error) {
+	if e == nil {
+		return true, nil
+	}
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("when %q: %w", e.src, err)
+	}
+	return whenTruth(v), nil
+}
+
+// variables returns the top-level variable names referenced by e (nil for an empty condition).
+func (e *whenExpr) variables() []string {
+	if e == nil {
+		return nil
+	}
+	return slices.Clone(e.vars)
+}
+
+// WhenVariables returns the top-level variables referenced by a `when:` expression, each with a
+// JSON Schema type hint inferred from usage ("string", "number", "boolean", "object" or "" if unknown).
+// Used by code generators to type condition variables that are not declared in input_schema.
+func WhenVariables(expr string) (map[string]string, error) {
+	e, err := parseWhen(expr)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(e.vars))
+	hint := func(p whenPath, typ string) {
+		if len(p.path) > 1 {
+			out[p.path[0]] = jsonTypeObject
+			return
+		}
+		if cur, ok := out[p.path[0]]; !ok || cur == jsonTypeBoolean || cur == "" {
+			out[p.path[0]] = typ
+		}
+	}
+	walkWhen(e.root, func(n whenNode) {
+		switch x := n.(type) {
+		case whenPath:
+			if _, ok := out[x.path[0]]; !ok {
+				hint(x, jsonTypeBoolean)
+			}
+		case whenCmp:
+			lp, lok := x.l.(whenPath)
+			rp, rok := x.r.(whenPath)
+			ll, llok := x.l.(whenLit)
+			rl, rlok := x.r.(whenLit)
+			switch {
+			case lok && rlok:
+				hint(lp, literalJSONType(rl.v))
+			case rok && llok:
+				hint(rp, literalJSONType(ll.v))
+			case lok && rok:
+				hint(lp, "")
+				hint(rp, "")
+			}
+		}
+	})
+	return out, nil
+}
+
+func literalJSONType(v any) string {
+	switch v.(type) {
+	case string:
+		return jsonTypeString
+	case float64:
+		return jsonTypeNumber
+	case bool:
+		return jsonTypeBoolean
+	default:
+		return ""
+	}
+}
+
+// JSON Schema type names used for when-variable hints.
+const (
+	jsonTypeString  = "string"
+	jsonTypeNumber  = "number"
+	jsonTypeBoolean = "boolean"
+	jsonTypeObject  = "object"
+)
+
+// walkWhen visits nodes in pre-order, left to right.
+func walkWhen(n whenNode, visit func(whenNode)) {
+	visit(n)
+	switch x := n.(type) {
+	case whenNot:
+		walkWhen(x.x, visit)
+	case whenLogic:
+		walkWhen(x.l, visit)
+		walkWhen(x.r, visit)
+	case whenCmp:
+		walkWhen(x.l, visit)
+		walkWhen(x.r, visit)
+	}
+}
+
+func (n whenLit) eval(map[string]any) (any, error) { return n.v, nil }
+
+func (n whenPath) eval(vars map[string]any) (any, error) {
+	var cur any = vars
+	for _, name := range n.path {
+		cur = lookupField(cur, name)
+		if cur == nil {
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
+
+func (n whenNot) eval(vars map[string]any) (any, error) {
+	v, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !whenTruth(v), nil
+}
+
+func (n whenLogic) eval(vars map[string]any) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	lt := whenTruth(l)
+	if (n.op == "&&" && !lt) || (n.op == "||" && lt) {
+		return lt, nil
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return whenTruth(r), nil
+}
+
+func (n whenCmp) eval(vars map[string]any) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return whenEqual(l, r), nil
+	case "!=":
+		return !whenEqual(l, r), nil
+	}
+	c, err := whenCompare(l, r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default: // ">="
+		return c >= 0, nil
+	}
+}
+
+// lookupField returns v[name] for string-keyed maps or the exported struct field name; nil if absent.
+func lookupField(v any, name string) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		mv := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !mv.IsValid() {
+			return nil
+		}
+		return mv.Interface()
+	case reflect.Struct:
+		fv := rv.FieldByName(name)
+		if !fv.IsValid() || !fv.CanInterface() {
+			return nil
+		}
+		return fv.Interface()
+	default:
+		return nil
+	}
+}
+
+func whenTruth(v any) bool {
+	if v == nil {
+		return false
+	}
+	truth, ok := template.IsTrue(v)
+	return ok && truth
+}
+
+func isNilValue(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+func whenNumber(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func whenString(v any) (string, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return rv.String(), true
+	}
+	return "", false
+}
+
+func whenEqual(l, r any) bool {
+	if isNilValue(l) || isNilValue(r) {
+		return isNilValue(l) && isNilValue(r)
+	}
+	if ln, ok := whenNumber(l); ok {
+		rn, ok := whenNumber(r)
+		return ok && ln == rn
+	}
+	if ls, ok := whenString(l); ok {
+		rs, ok := whenString(r)
+		return ok && ls == rs
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func whenCompare(l, r any) (int, error) {
+	if ln, ok := whenNumber(l); ok {
+		if rn, ok := whenNumber(r); ok {
+			switch {
+			case ln < rn:
+				return -1, nil
+			case ln > rn:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+	if ls, ok := whenString(l); ok {
+		if rs, ok := whenString(r); ok {
+			return strings.Compare(ls, rs), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot order %T and %T", l, r)
+}
+
+// --- lexer and parser ---
+
+type whenTokKind int
+
+const (
+	whenTokOp whenTokKind = iota
+	whenTokIdent
+	whenTokString
+	whenTokNumber
+)
+
+type whenTok struct {
+	kind whenTokKind
+	text string
+}
+
+// unquoteWhenString unquotes a string literal. Double-quoted strings use Go escapes; single-quoted strings take
+// the same escapes plus \' for the quote, and a bare " needs no escape.
+func unquoteWhenString(lit string) (string, error) {
+	if lit[0] == '"' {
+		return strconv.Unquote(lit)
+	}
+	body := lit[1 : len(lit)-1]
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == '\\' && i+1 < len(body):
+			b.WriteString(body[i : i+2])
+			i++
+		case c == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return strconv.Unquote(b.String())
+}
+
+func lexWhen(src string) ([]whenTok, error) {
+	var toks []whenTok
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "&&"), strings.HasPrefix(src[i:], "||"),
+			strings.HasPrefix(src[i:], "=="), strings.HasPrefix(src[i:], "!="),
+			strings.HasPrefix(src[i:], "<="), strings.HasPrefix(src[i:], ">="):
+			toks = append(toks, whenTok{kind: whenTokOp, text: src[i : i+2]})
+			i += 2
+		case strings.ContainsRune("!<>()", rune(c)):
+			toks = append(toks, whenTok{kind: whenTokOp, text: string(c)})
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("%w: unterminated string in %q", errWhenSyntax, src)
+			}
+			toks = append(toks, whenTok{kind: whenTokString, text: src[i : end+1]})
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(src) && (src[end] == '.' || (src[end] >= '0' && src[end] <= '9')) {
+				end++
+			}
+			toks = append(toks, whenTok{kind: whenTokNumber, text: src[i:end]})
+			i = end
+		default:
+			n := whenIdentLen(src[i:])
+			if n == 0 {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, fmt.Errorf("%w: unexpected character %q in %q", errWhenSyntax, r, src)
+			}
+			toks = append(toks, whenTok{kind: whenTokIdent, text: src[i : i+n]})
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+// whenIdentLen returns the byte length of the variable path at the start of s: Unicode letters, digits, '_' and
+// '.' (not starting with a digit), decoded as UTF-8 like Go identifiers in templates; 0 when s does not start one.
+func whenIdentLen(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r != '.' && r != '_' && !unicode.IsLetter(r) && (n == 0 || !unicode.IsDigit(r)) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+type whenParser struct {
+	toks []whenTok
+	pos  int
+}
+
+func (p *whenParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != whenTokOp {
+		return "", false
+	}
+	if slices.Contains(ops, p.toks[p.pos].text) {
+		return p.toks[p.pos].text, true
+	}
+	return "", false
+}
+
+func (p *whenParser) parseOr() (whenNode, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("||"); !ok {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = whenLogic{op: "||", l: l, r: r}
+	}
+}
+
+func (p *whenParser) parseAnd() (whenNode, error) {
+	l, err := p.parseCmp()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("&&"); !ok {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseCmp()
+		if err != nil {
+			return nil, err
+		}
+		l = whenLogic{op: "&&", l: l, r: r}
+	}
+}
+
+func (p *whenParser) parseCmp() (whenNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peekOp("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return l, nil
+	}
+	p.pos++
+	r, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return whenCmp{op: op, l: l, r: r}, nil
+}
+
+func (p *whenParser) parseUnary() (whenNode, error) {
+	if _, ok := p.peekOp("!"); ok {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whenNot{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *whenParser) parsePrimary() (whenNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("%w: unexpected end of expression", errWhenSyntax)
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	switch tok.kind {
+	case whenTokOp:
+		if tok.text != "(" {
+			return nil, fmt.Errorf("%w: unexpected %q", errWhenSyntax, tok.text)
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peekOp(")"); !ok {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", errWhenSyntax)
+		}
+		p.pos++
+		return x, nil
+	case whenTokString:
+		v, err := unquoteWhenString(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: string %s: %w", errWhenSyntax, tok.text, err)
+		}
+		return whenLit{v: v}, nil
+	case whenTokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: number %s: %w", errWhenSyntax, tok.text, err)
+		}
+		return whenLit{v: v}, nil
+	default:
+		return identNode(tok.text)
+	}
+}
+
+func identNode(text string) (whenNode, error) {
+	switch text {
+	case "true":
+		return whenLit{v: true}, nil
+	case "false":
+		return whenLit{v: false}, nil
+	case "nil", "null":
+		return whenLit{v: nil}, nil
+	}
+	path := strings.Split(strings.TrimPrefix(text, "."), ".")
+	if slices.Contains(path, "") {
+		return nil, fmt.Errorf("%w: invalid variable %q", errWhenSyntax, text)
+	}
+	return whenPath{path: path}, nil
+}
@@ -0,0 +1,145 @@
+package prompty
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhenExpr_Eval(t *testing.T) {
+	t.Parallel()
+	type plan struct{ Tier string }
+	vars := map[string]any{
+		"has_docs": true,
+		"tier":     "pro",
+		"count":    3,
+		"ratio":    0.5,
+		"empty":    "",
+		"docs":     []string{},
+		"user":     map[string]any{"plan": "free", "age": int64(30)},
+		"acct":     &plan{Tier: "gold"},
+		"größe":    4,
+		"名前":       "ann",
+		"quote":    "it's",
+		"said":     `say "hi"`,
+		"line":     "a\tb\\",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`has_docs && tier == "pro"`, true},
+		{`has_docs && tier == 'basic'`, false},
+		{`quote == 'it\'s'`, true},
+		{`said == 'say \"hi\"'`, true},
+		{`said == 'say "hi"' && quote == "it's"`, true},
+		{`line == 'a\tb\\'`, true},
+		{`!has_docs || tier != "pro"`, false},
+		{`count > 2 && count <= 3`, true},
+		{`ratio < 1`, true},
+		{`count == 3.0`, true},
+		{`empty`, false},
+		{`docs`, false},
+		{`!docs`, true},
+		{`missing`, false},
+		{`missing == nil`, true},
+		{`user.plan == "free" && user.age >= 18`, true},
+		{`.user.plan == "free"`, true},
+		{`acct.Tier == "gold"`, true},
+		{`(has_docs || missing) && !(tier == "free")`, true},
+		{`tier > "a"`, true},
+		{`größe > 3 && 名前 == "ann"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			e, err := parseWhen(tt.expr)
+			require.NoError(t, err)
+			got, err := e.test(vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWhenExpr_Errors(t *testing.T) {
+	t.Parallel()
+	for _, expr := range []string{`a &&`, `(a`, `a == "x`, `a # b`, `a b`, `a..b`, "a \u00b7 b"} {
+		_, err := parseWhen(expr)
+		require.Error(t, err, expr)
+		assert.True(t, errors.Is(err, errWhenSyntax), expr)
+	}
+	e, err := parseWhen(`count < "x"`)
+	require.NoError(t, err)
+	_, err = e.test(map[string]any{"count": 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot order")
+
+	e, err = parseWhen("  ")
+	require.NoError(t, err)
+	assert.Nil(t, e)
+}
+
+func TestWhenVariables(t *testing.T) {
+	t.Parallel()
+	got, err := WhenVariables(`has_docs && tier == "pro" && limit > 2 && user.plan == "x" && a == b`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"has_docs": "boolean",
+		"tier":     "string",
+		"limit":    "number",
+		"user":     "object",
+		"a":        "",
+		"b":        "",
+	}, got)
+
+	_, err = WhenVariables(`a ==`)
+	require.Error(t, err)
+}
+
+func TestFormat_WhenMessageAndPart(t *testing.T) {
+	t.Parallel()
+	tpl, err := NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleSystem, Content: TextContent("Base")},
+		{Role: RoleSystem, When: `has_docs && tier == "pro"`, Content: TextContent("Docs: {{ .docs }}")},
+		{Role: RoleUser, Content: []TemplatePart{
+			{Type: "text", Text: "Q: {{ .query }}"},
+			{Type: "text", Text: " (verbose)", When: "verbose"},
+		}},
+		{Role: RoleUser, Content: []TemplatePart{{Type: "text", Text: "only if verbose", When: "verbose"}}},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"has_docs", "tier", "docs", "query", "verbose"}, tpl.requiredFromAST)
+
+	exec, err := tpl.Format(map[string]any{
+		"has_docs": true, "tier": "pro", "docs": "d1", "query": "q", "verbose": false,
+	})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 3)
+	assert.Equal(t, "Docs: d1", exec.Messages[1].Content[0].(TextPart).Text)
+	require.Len(t, exec.Messages[2].Content, 1)
+
+	exec, err = tpl.Format(map[string]any{
+		"has_docs": true, "tier": "free", "docs": "d1", "query": "q", "verbose": true,
+	})
+	require.NoError(t, err)
+	require.Len(t, exec.Messages, 3)
+	assert.Equal(t, "Base", exec.Messages[0].Content[0].(TextPart).Text)
+	require.Len(t, exec.Messages[1].Content, 2)
+	assert.Equal(t, "only if verbose", exec.Messages[2].Content[0].(TextPart).Text)
+
+	_, err = tpl.Format(map[string]any{"has_docs": true, "docs": "d1", "query": "q", "verbose": true})
+	require.ErrorIs(t, err, ErrMissingVariable)
+}
+
+func TestNewChatPromptTemplate_InvalidWhen(t *testing.T) {
+	t.Parallel()
+	_, err := NewChatPromptTemplate([]MessageTemplate{{Role: RoleUser, When: "a &&", Content: TextContent("x")}})
+	require.ErrorIs(t, err, ErrTemplateParse)
+	_, err = NewChatPromptTemplate([]MessageTemplate{
+		{Role: RoleUser, Content: []TemplatePart{{Type: "text", Text: "x", When: "(a"}}},
+	})
+	require.ErrorIs(t, err, ErrTemplateParse)
+}